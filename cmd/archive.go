@@ -0,0 +1,445 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// archiveSource is a file or a directory copied to an archive.
+type archiveSource struct {
+	fsys fs.FS
+	name string
+	// dest is the path of the source relative to the root directory of the
+	// archive. It defaults to name.
+	dest string
+	// exclude returns true for the paths of fsys which must not be copied.
+	exclude func(name string) (bool, error)
+}
+
+// fileSource returns the archive source for the given file or directory on
+// disk.
+func fileSource(file string) archiveSource {
+	return archiveSource{
+		fsys: newDirFS(filepath.Dir(file)),
+		name: filepath.Base(file),
+	}
+}
+
+// linkFS is a file system which doesn't follow symbolic links.
+type linkFS interface {
+	fs.FS
+	// Lstat returns the info of the file without following symbolic
+	// links.
+	Lstat(name string) (fs.FileInfo, error)
+	// ReadLink returns the target of a symbolic link.
+	ReadLink(name string) (string, error)
+}
+
+// dirFS is the file system of a directory on disk, like os.DirFS, which
+// preserves symbolic links.
+type dirFS struct {
+	fs.FS
+	dir string
+}
+
+func newDirFS(dir string) dirFS {
+	return dirFS{FS: os.DirFS(dir), dir: dir}
+}
+
+func (d dirFS) Lstat(name string) (fs.FileInfo, error) {
+	return os.Lstat(filepath.Join(d.dir, filepath.FromSlash(name)))
+}
+
+func (d dirFS) ReadLink(name string) (string, error) {
+	return os.Readlink(filepath.Join(d.dir, filepath.FromSlash(name)))
+}
+
+// walkSource walks the files of the source like fs.WalkDir, except that a
+// source which is a symbolic link isn't followed.
+func walkSource(src archiveSource, fn fs.WalkDirFunc) error {
+	if lfs, ok := src.fsys.(linkFS); ok {
+		info, err := lfs.Lstat(src.name)
+		if err != nil {
+			return fn(src.name, nil, err)
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fn(src.name, fs.FileInfoToDirEntry(info), nil)
+		}
+	}
+	return fs.WalkDir(src.fsys, src.name, fn)
+}
+
+// archiveEntry is a file, a directory or a symbolic link of an archive.
+type archiveEntry struct {
+	name    string
+	mode    fs.FileMode
+	size    int64
+	modTime time.Time
+	// open returns the content of regular files.
+	open func() (io.ReadCloser, error)
+	// link is the target of symbolic links.
+	link string
+}
+
+// dataEntry returns an archive entry for a generated file.
+func dataEntry(name string, data []byte, modTime time.Time) archiveEntry {
+	return archiveEntry{
+		name:    name,
+		mode:    0o644,
+		size:    int64(len(data)),
+		modTime: modTime,
+		open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(string(data))), nil
+		},
+	}
+}
+
+// collectArchiveEntries returns the entries of an archive whose root
+// directory is dir and which contains the given sources. Directories are
+// copied recursively and symbolic links are preserved with their target.
+// Permissions are normalized to 0755 for directories and executables and to
+// 0644 for other files. If modTime isn't zero, it replaces the modification time of all
+// files.
+func collectArchiveEntries(dir string, sources []archiveSource, modTime time.Time) ([]archiveEntry, error) {
+	rootTime := modTime
+	if rootTime.IsZero() {
+		rootTime = time.Now()
+	}
+	entries := []archiveEntry{{name: dir, mode: fs.ModeDir | 0o755, modTime: rootTime}}
+	// seen records whether the archived paths are directories, which may be
+	// shared by several sources.
+	seen := map[string]bool{dir: true}
+
+	for _, src := range sources {
+		src := src
+		dest := src.dest
+		if dest == "" {
+			dest = src.name
+		}
+		err := walkSource(src, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if src.exclude != nil {
+				excluded, err := src.exclude(p)
+				if err != nil {
+					return err
+				}
+				if excluded {
+					if d.IsDir() {
+						return fs.SkipDir
+					}
+					return nil
+				}
+			}
+			if p == src.name {
+				// Create the parent directories of sources copied to a
+				// subdirectory of the archive.
+				for parent := path.Dir(dest); parent != "."; parent = path.Dir(parent) {
+					name := path.Join(dir, parent)
+					if _, ok := seen[name]; !ok {
+						seen[name] = true
+						entries = append(entries, archiveEntry{name: name, mode: fs.ModeDir | 0o755, modTime: rootTime})
+					}
+				}
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			e := archiveEntry{
+				name:    path.Join(dir, dest, strings.TrimPrefix(p, src.name)),
+				modTime: info.ModTime(),
+			}
+			if isDir, ok := seen[e.name]; ok {
+				if isDir && d.IsDir() {
+					return nil
+				}
+				return fmt.Errorf("duplicate file %s in archive", e.name)
+			}
+			seen[e.name] = d.IsDir()
+			if !modTime.IsZero() {
+				e.modTime = modTime
+			}
+
+			switch {
+			case d.IsDir():
+				e.mode = fs.ModeDir | 0o755
+			case info.Mode().IsRegular():
+				e.mode = 0o644
+				if info.Mode()&0o111 != 0 {
+					e.mode = 0o755
+				}
+				e.size = info.Size()
+				e.open = func() (io.ReadCloser, error) { return src.fsys.Open(p) }
+			case info.Mode()&fs.ModeSymlink != 0:
+				lfs, ok := src.fsys.(linkFS)
+				if !ok {
+					return fmt.Errorf("can't read symbolic link %s", p)
+				}
+				link, err := lfs.ReadLink(p)
+				if err != nil {
+					return err
+				}
+				e.mode = fs.ModeSymlink | 0o777
+				e.link = filepath.ToSlash(link)
+			default:
+				return fmt.Errorf("unsupported file type %s for %s", info.Mode().Type(), p)
+			}
+			entries = append(entries, e)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// sortedEntries returns the entries sorted by name so that archives don't
+// depend on the order of the sources. Directories always come before their
+// content.
+func sortedEntries(entries []archiveEntry) []archiveEntry {
+	sorted := make([]archiveEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].name < sorted[j].name
+	})
+	return sorted
+}
+
+// manifestFilename is the name of the manifest added to the root directory
+// of archives.
+const manifestFilename = "MANIFEST.txt"
+
+// archiveManifest returns the manifest of the archive whose root directory is
+// dir, listing the mode and the SHA256 checksum of each regular file with its
+// path relative to dir. Symbolic links are listed with their target instead
+// of a checksum.
+func archiveManifest(dir string, entries []archiveEntry) ([]byte, error) {
+	var b strings.Builder
+	for _, e := range sortedEntries(entries) {
+		name := strings.TrimPrefix(e.name, dir+"/")
+		switch {
+		case e.mode.IsDir():
+			continue
+		case e.mode&fs.ModeSymlink != 0:
+			fmt.Fprintf(&b, "%04o %s -> %s\n", e.mode.Perm(), name, e.link)
+		default:
+			r, err := e.open()
+			if err != nil {
+				return nil, err
+			}
+			h := sha256.New()
+			_, err = io.Copy(h, r)
+			r.Close()
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&b, "%04o %x  %s\n", e.mode.Perm(), h.Sum(nil), name)
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+// Archive formats of the tarball configuration.
+const (
+	archiveFormatTar = "tar"
+	archiveFormatZIP = "zip"
+)
+
+// defaultArchiveFormats are the archive formats used when the configuration
+// doesn't list any. Windows systems don't have tar available by default, so
+// archives are additionally produced in the common zip format.
+var defaultArchiveFormats = []TarballFormat{
+	{Format: archiveFormatTar},
+	{Format: archiveFormatZIP, Platforms: []string{"windows"}},
+}
+
+// archiveFormats returns the archive formats to produce for the platform.
+func archiveFormats(formats []TarballFormat, platform string) ([]string, error) {
+	if len(formats) == 0 {
+		formats = defaultArchiveFormats
+	}
+	var res []string
+	for _, f := range formats {
+		if f.Format != archiveFormatTar && f.Format != archiveFormatZIP {
+			return nil, fmt.Errorf("unsupported archive format %q, must be one of %s or %s", f.Format, archiveFormatTar, archiveFormatZIP)
+		}
+		match, err := platformFilter(f.Platforms)
+		if err != nil {
+			return nil, err
+		}
+		if match(platform) && !stringInSlice(f.Format, res) {
+			res = append(res, f.Format)
+		}
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no archive format configured for %s", platform)
+	}
+	return res, nil
+}
+
+// tarballExtension returns the file extension of tarballs compressed with the
+// given algorithm.
+func tarballExtension(compression string) (string, error) {
+	switch compression {
+	case "", "gzip":
+		return ".tar.gz", nil
+	case "zstd":
+		return ".tar.zst", nil
+	case "xz":
+		return ".tar.xz", nil
+	}
+	return "", fmt.Errorf("unsupported tarball compression %q, must be one of gzip, zstd or xz", compression)
+}
+
+// createArchive creates the file at the given path and writes the archive to
+// it using the given writer function.
+func createArchive(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := write(f); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// writeTarball writes a tarball compressed with the given algorithm and
+// containing the entries to w. Files are owned by root.
+func writeTarball(w io.Writer, entries []archiveEntry, compression string) error {
+	var (
+		cw  io.WriteCloser
+		err error
+	)
+	switch compression {
+	case "", "gzip":
+		cw = gzip.NewWriter(w)
+	case "zstd":
+		cw, err = zstd.NewWriter(w)
+	case "xz":
+		cw, err = xz.NewWriter(w)
+	default:
+		err = fmt.Errorf("unsupported tarball compression %q", compression)
+	}
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(cw)
+	for _, e := range sortedEntries(entries) {
+		hdr := &tar.Header{
+			Name:    e.name,
+			Mode:    int64(e.mode.Perm()),
+			ModTime: e.modTime.Truncate(time.Second),
+		}
+		switch {
+		case e.mode.IsDir():
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+		case e.mode&fs.ModeSymlink != 0:
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = e.link
+		default:
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = e.size
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if err := copyEntry(tw, e); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return cw.Close()
+}
+
+// writeZIP writes a ZIP archive containing the entries to w. File modes are
+// stored as Unix attributes and symbolic links as files whose content is the
+// link target, like Info-ZIP does.
+func writeZIP(w io.Writer, entries []archiveEntry) error {
+	zw := zip.NewWriter(w)
+	for _, e := range sortedEntries(entries) {
+		hdr := &zip.FileHeader{
+			Name:     e.name,
+			Method:   zip.Deflate,
+			Modified: e.modTime,
+		}
+		if e.mode.IsDir() {
+			hdr.Name += "/"
+			hdr.Method = zip.Store
+		}
+		hdr.SetMode(e.mode)
+
+		f, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if e.mode&fs.ModeSymlink != 0 {
+			if _, err := io.WriteString(f, e.link); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := copyEntry(f, e); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func copyEntry(w io.Writer, e archiveEntry) error {
+	if e.open == nil {
+		return nil
+	}
+	r, err := e.open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	n, err := io.Copy(w, r)
+	if err != nil {
+		return err
+	}
+	if n != e.size {
+		return fmt.Errorf("%s changed while being archived", e.name)
+	}
+	return nil
+}
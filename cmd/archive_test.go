@@ -0,0 +1,431 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+var (
+	testArchiveTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	testArchiveFS   = fstest.MapFS{
+		"foo":              {Data: []byte("binary"), Mode: 0o700},
+		"doc/LICENSE":      {Data: []byte("license"), Mode: 0o600},
+		"doc/examples/a.y": {Data: []byte("example"), Mode: 0o664},
+		"other/file":       {Data: []byte("ignored")},
+	}
+)
+
+func testArchiveEntries(t *testing.T, names ...string) []archiveEntry {
+	t.Helper()
+	var sources []archiveSource
+	for _, name := range names {
+		sources = append(sources, archiveSource{fsys: testArchiveFS, name: name})
+	}
+	entries, err := collectArchiveEntries("foo-1.0.0", sources, testArchiveTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+type testFile struct {
+	mode    int64
+	content string
+}
+
+func TestWriteZIP(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeZIP(&buf, testArchiveEntries(t, "foo", "doc")); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]testFile{}
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !f.Modified.Equal(testArchiveTime) {
+			t.Fatalf("%s: expected modification time %v, got %v", f.Name, testArchiveTime, f.Modified)
+		}
+		got[f.Name] = testFile{mode: int64(f.Mode().Perm()), content: string(b)}
+	}
+
+	exp := map[string]testFile{
+		"foo-1.0.0/":                 {mode: 0o755},
+		"foo-1.0.0/foo":              {mode: 0o755, content: "binary"},
+		"foo-1.0.0/doc/":             {mode: 0o755},
+		"foo-1.0.0/doc/LICENSE":      {mode: 0o644, content: "license"},
+		"foo-1.0.0/doc/examples/":    {mode: 0o755},
+		"foo-1.0.0/doc/examples/a.y": {mode: 0o644, content: "example"},
+	}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected %v, got %v", exp, got)
+	}
+}
+
+func TestWriteTarball(t *testing.T) {
+	entries := testArchiveEntries(t, "foo", "doc")
+
+	for _, tc := range []struct {
+		compression string
+		reader      func(io.Reader) (io.Reader, error)
+	}{
+		{
+			compression: "gzip",
+			reader:      func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		},
+		{
+			compression: "zstd",
+			reader:      func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+		},
+		{
+			compression: "xz",
+			reader:      func(r io.Reader) (io.Reader, error) { return xz.NewReader(r) },
+		},
+	} {
+		t.Run(tc.compression, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeTarball(&buf, entries, tc.compression); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := tc.reader(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr := tar.NewReader(r)
+			var (
+				names []string
+				got   = map[string]testFile{}
+			)
+			for {
+				hdr, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				b, err := io.ReadAll(tr)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if hdr.Uid != 0 || hdr.Gid != 0 || !hdr.ModTime.Equal(testArchiveTime) {
+					t.Fatalf("%s: unexpected header %+v", hdr.Name, hdr)
+				}
+				names = append(names, hdr.Name)
+				got[hdr.Name] = testFile{mode: hdr.Mode, content: string(b)}
+			}
+
+			exp := map[string]testFile{
+				"foo-1.0.0/":                 {mode: 0o755},
+				"foo-1.0.0/foo":              {mode: 0o755, content: "binary"},
+				"foo-1.0.0/doc/":             {mode: 0o755},
+				"foo-1.0.0/doc/LICENSE":      {mode: 0o644, content: "license"},
+				"foo-1.0.0/doc/examples/":    {mode: 0o755},
+				"foo-1.0.0/doc/examples/a.y": {mode: 0o644, content: "example"},
+			}
+			if !reflect.DeepEqual(exp, got) {
+				t.Fatalf("expected %v, got %v", exp, got)
+			}
+			expNames := []string{
+				"foo-1.0.0/",
+				"foo-1.0.0/doc/",
+				"foo-1.0.0/doc/LICENSE",
+				"foo-1.0.0/doc/examples/",
+				"foo-1.0.0/doc/examples/a.y",
+				"foo-1.0.0/foo",
+			}
+			if !reflect.DeepEqual(expNames, names) {
+				t.Fatalf("expected order %q, got %q", expNames, names)
+			}
+		})
+	}
+
+	if err := writeTarball(io.Discard, entries, "bzip2"); err == nil {
+		t.Fatal("expected error for unsupported compression, got none")
+	}
+}
+
+func TestWriteTarballReproducible(t *testing.T) {
+	var a, b bytes.Buffer
+	if err := writeTarball(&a, testArchiveEntries(t, "foo", "doc"), "gzip"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeTarball(&b, testArchiveEntries(t, "doc", "foo"), "gzip"); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Fatal("tarballs with the same content differ")
+	}
+}
+
+func TestCollectArchiveEntriesDuplicate(t *testing.T) {
+	_, err := collectArchiveEntries("foo-1.0.0", []archiveSource{
+		{fsys: testArchiveFS, name: "foo"},
+		{fsys: testArchiveFS, name: "foo"},
+	}, testArchiveTime)
+	if err == nil {
+		t.Fatal("expected error for duplicate files, got none")
+	}
+}
+
+func TestCollectArchiveEntriesDestAndExclude(t *testing.T) {
+	entries, err := collectArchiveEntries("foo-1.0.0", []archiveSource{
+		{fsys: testArchiveFS, name: "doc/LICENSE", dest: "LICENSE"},
+		{fsys: testArchiveFS, name: "doc/examples/a.y"},
+		{
+			fsys: testArchiveFS,
+			name: "doc",
+			dest: "share/doc",
+			exclude: func(name string) (bool, error) {
+				return matchGlob("doc/examples/**", name)
+			},
+		},
+	}, testArchiveTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, e := range sortedEntries(entries) {
+		got = append(got, e.name)
+	}
+	exp := []string{
+		"foo-1.0.0",
+		"foo-1.0.0/LICENSE",
+		"foo-1.0.0/doc",
+		"foo-1.0.0/doc/examples",
+		"foo-1.0.0/doc/examples/a.y",
+		"foo-1.0.0/share",
+		"foo-1.0.0/share/doc",
+		"foo-1.0.0/share/doc/LICENSE",
+	}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected %v, got %v", exp, got)
+	}
+}
+
+func TestArchiveSymlinks(t *testing.T) {
+	entries := append(testArchiveEntries(t, "foo"), archiveEntry{
+		name:    "foo-1.0.0/bar",
+		mode:    fs.ModeSymlink | 0o777,
+		modTime: testArchiveTime,
+		link:    "foo",
+	})
+
+	var zipBuf bytes.Buffer
+	if err := writeZIP(&zipBuf, entries); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(zipBuf.Bytes()), int64(zipBuf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range zr.File {
+		if f.Name != "foo-1.0.0/bar" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Mode()&fs.ModeSymlink == 0 || string(b) != "foo" {
+			t.Fatalf("expected symbolic link to foo, got mode %v and content %q", f.Mode(), b)
+		}
+	}
+
+	var tarBuf bytes.Buffer
+	if err := writeTarball(&tarBuf, entries, "gzip"); err != nil {
+		t.Fatal(err)
+	}
+	gr, err := gzip.NewReader(&tarBuf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			t.Fatal("symbolic link not found in tarball")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Name == "foo-1.0.0/bar" {
+			if hdr.Typeflag != tar.TypeSymlink || hdr.Linkname != "foo" {
+				t.Fatalf("expected symbolic link to foo, got %+v", hdr)
+			}
+			break
+		}
+	}
+}
+
+func TestArchiveFormats(t *testing.T) {
+	for _, tc := range []struct {
+		formats  []TarballFormat
+		platform string
+		exp      []string
+		err      bool
+	}{
+		{platform: "linux/amd64", exp: []string{"tar"}},
+		{platform: "windows/amd64", exp: []string{"tar", "zip"}},
+		{
+			formats:  []TarballFormat{{Format: "tar"}, {Format: "zip", Platforms: []string{"darwin", "windows"}}},
+			platform: "darwin/arm64",
+			exp:      []string{"tar", "zip"},
+		},
+		{
+			formats:  []TarballFormat{{Format: "zip"}},
+			platform: "linux/amd64",
+			exp:      []string{"zip"},
+		},
+		{
+			formats:  []TarballFormat{{Format: "zip", Platforms: []string{"windows"}}},
+			platform: "linux/amd64",
+			err:      true,
+		},
+		{
+			formats:  []TarballFormat{{Format: "rar"}},
+			platform: "linux/amd64",
+			err:      true,
+		},
+	} {
+		got, err := archiveFormats(tc.formats, tc.platform)
+		if tc.err {
+			if err == nil {
+				t.Fatalf("%v %s: expected error, got none", tc.formats, tc.platform)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tc.exp, got) {
+			t.Fatalf("%v %s: expected %v, got %v", tc.formats, tc.platform, tc.exp, got)
+		}
+	}
+}
+
+func TestArchiveManifest(t *testing.T) {
+	entries := append(testArchiveEntries(t, "foo", "doc"), archiveEntry{
+		name:    "foo-1.0.0/bar",
+		mode:    fs.ModeSymlink | 0o777,
+		modTime: testArchiveTime,
+		link:    "foo",
+	})
+	got, err := archiveManifest("foo-1.0.0", entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(got), "\n"), "\n")
+	expLines := []string{
+		"0777 bar -> foo",
+		fmt.Sprintf("0644 %x  doc/LICENSE", sha256.Sum256([]byte("license"))),
+		fmt.Sprintf("0644 %x  doc/examples/a.y", sha256.Sum256([]byte("example"))),
+		fmt.Sprintf("0755 %x  foo", sha256.Sum256([]byte("binary"))),
+	}
+	if !reflect.DeepEqual(expLines, lines) {
+		t.Fatalf("expected %q, got %q", expLines, lines)
+	}
+}
+
+func TestCollectArchiveEntriesSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	for _, d := range []string{"share/doc", "bin"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bin", "foo"), []byte("binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range map[string]string{
+		"bin/foo-link":  "foo",
+		"share/doc/bin": "../../bin",
+		"latest":        "share",
+	} {
+		if err := os.Symlink(target, filepath.Join(dir, link)); err != nil {
+			t.Skipf("symbolic links not supported: %v", err)
+		}
+	}
+
+	entries, err := collectArchiveEntries("foo-1.0.0", []archiveSource{
+		fileSource(filepath.Join(dir, "bin")),
+		fileSource(filepath.Join(dir, "share")),
+		fileSource(filepath.Join(dir, "latest")),
+	}, testArchiveTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]string{}
+	for _, e := range entries {
+		switch {
+		case e.mode&fs.ModeSymlink != 0:
+			got[e.name] = "-> " + e.link
+		case e.mode.IsDir():
+			got[e.name] = "dir"
+		default:
+			got[e.name] = e.mode.String()
+		}
+	}
+	exp := map[string]string{
+		"foo-1.0.0":               "dir",
+		"foo-1.0.0/bin":           "dir",
+		"foo-1.0.0/bin/foo":       "-rwxr-xr-x",
+		"foo-1.0.0/bin/foo-link":  "-> foo",
+		"foo-1.0.0/share":         "dir",
+		"foo-1.0.0/share/doc":     "dir",
+		"foo-1.0.0/share/doc/bin": "-> ../../bin",
+		"foo-1.0.0/latest":        "-> share",
+	}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected %v, got %v", exp, got)
+	}
+}
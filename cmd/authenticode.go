@@ -0,0 +1,95 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/prometheus/promu/util/sh"
+)
+
+// defaultTimestampURL is the RFC 3161 timestamping server of the Authenticode
+// signatures, whose timestamp keeps them valid after the certificate expires.
+const defaultTimestampURL = "http://timestamp.digicert.com"
+
+// authenticodeSign signs the windows binary in place with Authenticode: with
+// the configured external signer, or else with jsign and the KMS key of the
+// key provider or osslsigncode and the configured certificate, the signature
+// being timestamped.
+func authenticodeSign(binary string) error {
+	c := config.Codesign.Windows
+	fmt.Println(" > signing", binary)
+	if len(c.Command) > 0 {
+		return sh.RunCommand(c.Command[0], append(c.Command[1:], binary)...)
+	}
+	timestamp := c.Timestamp
+	if timestamp == "" {
+		timestamp = defaultTimestampURL
+	}
+	if config.Codesign.KeyProvider.kms() {
+		args, err := jsignArgs(binary, timestamp)
+		if err != nil {
+			return err
+		}
+		return sh.RunCommand(envOr("JSIGN", "jsign"), args...)
+	}
+
+	dir, err := os.MkdirTemp("", "promu-authenticode")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	p12, err := secretFile(dir, c.P12, c.P12Env, "certificate.p12", true)
+	if err != nil {
+		return err
+	}
+	if p12 == "" {
+		return errors.New("neither codesign.windows.p12 nor codesign.windows.command is configured")
+	}
+	args := []string{"sign", "-pkcs12", p12, "-h", "sha256"}
+	password, err := secretFile(dir, c.Password, c.PasswordEnv, "password", false)
+	if err != nil {
+		return err
+	}
+	if password != "" {
+		args = append(args, "-readpass", password)
+	}
+	name := config.Windows.Product
+	if name == "" {
+		name = projInfo.Name
+	}
+	args = append(args, "-n", name)
+	if config.Package.Homepage != "" {
+		args = append(args, "-i", config.Package.Homepage)
+	}
+
+	fi, err := os.Stat(binary)
+	if err != nil {
+		return err
+	}
+	// osslsigncode doesn't sign in place.
+	signed := binary + ".signed"
+	args = append(args, "-ts", timestamp, "-in", binary, "-out", signed)
+	if err := sh.RunCommand(envOr("OSSLSIGNCODE", "osslsigncode"), args...); err != nil {
+		os.Remove(signed)
+		return err
+	}
+	if err := os.Chmod(signed, fi.Mode()); err != nil {
+		return err
+	}
+	return os.Rename(signed, binary)
+}
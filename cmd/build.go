@@ -17,6 +17,7 @@ package cmd
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path"
@@ -70,35 +71,70 @@ OUTER:
 	return binaries, nil
 }
 
-func buildBinary(ext string, prefix string, ldflags string, tags []string, binary Binary) {
+// buildOptions are the options of the builds of the binaries.
+type buildOptions struct {
+	ext     string
+	prefix  string
+	ldflags string
+	tags    []string
+	// flags are the flags of go build.
+	flags string
+	cgo   bool
+	// stdout receives the output of the builds, os.Stdout if nil.
+	stdout io.Writer
+	// env are the variables, in the form key=value, added to the
+	// environment of the builds.
+	env []string
+}
+
+func buildBinary(o buildOptions, binary Binary) error {
+	stdout := o.stdout
+	if stdout == nil {
+		stdout = os.Stdout
+	}
 	info("Building binary: " + binary.Name)
-	binaryName := fmt.Sprintf("%s%s", binary.Name, ext)
-	fmt.Printf(" >   %s\n", binaryName)
+	binaryName := fmt.Sprintf("%s%s", binary.Name, o.ext)
+	fmt.Fprintf(stdout, " >   %s\n", binaryName)
 
 	repoPath := config.Repository.Path
-	flags := config.Build.Flags
+
+	if goos == "windows" && windowsResourcesEnabled() {
+		syso, err := writeWindowsResources(binary, goarch, projInfo)
+		if err != nil {
+			return fmt.Errorf("failed to generate windows resources for %s: %w", binary.Name, err)
+		}
+		defer os.Remove(syso)
+	}
 
 	params := []string{
 		"build",
-		"-o", path.Join(prefix, binaryName),
-		"-ldflags", ldflags,
+		"-o", path.Join(o.prefix, binaryName),
+		"-ldflags", o.ldflags,
 	}
 
-	params = append(params, sh.SplitParameters(flags)...)
-	if len(tags) > 0 {
-		params = append(params, "-tags", strings.Join(tags, ","))
+	params = append(params, sh.SplitParameters(o.flags)...)
+	if len(o.tags) > 0 {
+		params = append(params, "-tags", strings.Join(o.tags, ","))
 	}
 	params = append(params, path.Join(repoPath, binary.Path))
 	info("Building binary: " + "go " + strings.Join(params, " "))
-	if err := sh.RunCommand("go", params...); err != nil {
-		fatal(fmt.Errorf("command failed: %s: %w", strings.Join(params, " "), err))
+	cgo := "CGO_ENABLED=0"
+	if o.cgo {
+		cgo = "CGO_ENABLED=1"
+	}
+	if err := sh.RunCommandWithEnv(stdout, os.Stderr, append([]string{cgo}, o.env...), "go", params...); err != nil {
+		return fmt.Errorf("command failed: %s: %w", strings.Join(params, " "), err)
 	}
+	return nil
 }
 
-func buildAll(ext string, prefix string, ldflags string, tags []string, binaries []Binary) {
+func buildAll(o buildOptions, binaries []Binary) error {
 	for _, binary := range binaries {
-		buildBinary(ext, prefix, ldflags, tags, binary)
+		if err := buildBinary(o, binary); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 func runBuild(binariesString string) {
@@ -114,28 +150,24 @@ func runBuild(binariesString string) {
 	}
 
 	var (
-		cgo    = config.Go.CGo
-		prefix = config.Build.Prefix
-
-		ext      string
 		binaries = config.Build.Binaries
-		ldflags  string
+		o        = buildOptions{
+			prefix:  config.Build.Prefix,
+			ldflags: getLdflags(projInfo, os.Getenv(sourceDateEpoch)),
+			tags:    getTags(config.Build.Tags),
+			flags:   config.Build.Flags,
+			cgo:     config.Go.CGo,
+		}
 	)
 
 	if goos == "windows" {
-		ext = ".exe"
+		o.ext = ".exe"
 	}
 
-	ldflags = getLdflags(projInfo)
-
-	os.Setenv("CGO_ENABLED", "0")
-	if cgo {
-		os.Setenv("CGO_ENABLED", "1")
-	}
-	defer os.Unsetenv("CGO_ENABLED")
-
 	if binariesString == "all" {
-		buildAll(ext, prefix, ldflags, getTags(config.Build.Tags), binaries)
+		if err := buildAll(o, binaries); err != nil {
+			fatal(err)
+		}
 		return
 	}
 
@@ -145,28 +177,40 @@ func runBuild(binariesString string) {
 		fatal(fmt.Errorf("validation of given binary names for build command failed: %w", err))
 	}
 
-	for _, binary := range binariesToBuild {
-		buildBinary(ext, prefix, ldflags, getTags(config.Build.Tags), binary)
+	if err := buildAll(o, binariesToBuild); err != nil {
+		fatal(err)
 	}
 }
 
-func getLdflags(info repository.Info) string {
+// ldflagsFuncs returns the functions of the build.ldflags template, the host
+// and the user being "reproducible" in reproducible builds.
+func ldflagsFuncs(buildDate time.Time, reproducible bool) template.FuncMap {
+	host, user := HostFunc, UserFunc
+	if reproducible {
+		host = func() string { return "reproducible" }
+		user = func() (interface{}, error) { return "reproducible", nil }
+	}
+	return template.FuncMap{
+		"date":     buildDate.UTC().Format,
+		"host":     host,
+		"repoPath": RepoPathFunc,
+		"user":     user,
+	}
+}
+
+// getLdflags returns the ldflags of the build of the project, sourceDate
+// being the build date in seconds since the epoch like SOURCE_DATE_EPOCH, or
+// empty for the current time.
+func getLdflags(info repository.Info, sourceDate string) string {
 	var ldflags []string
 
 	if len(strings.TrimSpace(config.Build.LDFlags)) > 0 {
-		buildDate := getBuildDate()
 		var (
-			tmplOutput = new(bytes.Buffer)
-			fnMap      = template.FuncMap{
-				"date":     buildDate.UTC().Format,
-				"host":     HostFunc,
-				"repoPath": RepoPathFunc,
-				"user":     UserFunc,
-			}
+			tmplOutput  = new(bytes.Buffer)
 			ldflagsTmpl = config.Build.LDFlags
 		)
 
-		tmpl, err := template.New("ldflags").Funcs(fnMap).Parse(ldflagsTmpl)
+		tmpl, err := template.New("ldflags").Funcs(ldflagsFuncs(getBuildDate(sourceDate), sourceDate != "")).Parse(ldflagsTmpl)
 		if err != nil {
 			fatal(fmt.Errorf("Failed to parse ldflags text/template: %w", err))
 		}
@@ -192,10 +236,11 @@ func getLdflags(info repository.Info) string {
 	return strings.Join(ldflags, " ")
 }
 
-func getBuildDate() time.Time {
+// getBuildDate returns the build date of sourceDate, in seconds since the
+// epoch, or the current time if it is empty.
+func getBuildDate(sourceDate string) time.Time {
 	var buildDate time.Time
 
-	sourceDate := os.Getenv(sourceDateEpoch)
 	if sourceDate == "" {
 		buildDate = time.Now()
 	} else {
@@ -210,9 +255,6 @@ func getBuildDate() time.Time {
 }
 
 func HostFunc() string {
-	if isReproducibleBuild() {
-		return "reproducible"
-	}
 	hostname, err := os.Hostname()
 	if err != nil {
 		return "unknown-host"
@@ -222,9 +264,6 @@ func HostFunc() string {
 
 // UserFunc returns the current username.
 func UserFunc() (interface{}, error) {
-	if isReproducibleBuild() {
-		return "reproducible", nil
-	}
 	// os/user.Current() doesn't always work without CGO
 	return shellOutput("whoami"), nil
 }
@@ -0,0 +1,870 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Masterminds/semver/v3"
+	kingpin "github.com/alecthomas/kingpin/v2"
+	"github.com/google/go-github/v25/github"
+	"golang.org/x/oauth2"
+
+	"github.com/prometheus/promu/pkg/changelog"
+	"github.com/prometheus/promu/pkg/repository"
+	"github.com/prometheus/promu/util/sh"
+)
+
+// Levels of the version bumps.
+const (
+	bumpMajor = "major"
+	bumpMinor = "minor"
+	bumpPatch = "patch"
+	bumpPre   = "pre"
+	// bumpAuto infers the level from the kinds of the changes.
+	bumpAuto = "auto"
+)
+
+// Sources of the changes of the version bumps.
+const (
+	bumpSourcePulls   = "pulls"
+	bumpSourceCommits = "commits"
+)
+
+const (
+	// kindLabelPrefix is the default prefix of the labels of the pull
+	// requests giving the kinds of their changes, e.g. "kind/bugfix".
+	kindLabelPrefix = "kind/"
+	// skipLabel is the default label excluding a pull request from the
+	// changelog.
+	skipLabel = "changelog/skip"
+)
+
+var (
+	bumpcmd      = app.Command("bump", "Bump the version of the VERSION file and add the CHANGELOG.md entry of the pull requests merged since its tag")
+	bumpLevelSet bool
+	bumpLevel    = bumpcmd.Flag("level", "Level of the bump: major, minor, patch, pre for a pre-release of the next minor version or the next iteration of the current one, or auto to infer it from the kinds of the changes (default is minor, or auto with --source=commits)").
+			PreAction(func(c *kingpin.ParseContext) error {
+			bumpLevelSet = true
+			return nil
+		}).Default(bumpMinor).Enum(bumpMajor, bumpMinor, bumpPatch, bumpPre, bumpAuto)
+	bumpSource = bumpcmd.Flag("source", "Source of the changes: pulls for the labels of the merged pull requests, commits for the Conventional Commits messages").
+			Default(bumpSourcePulls).Enum(bumpSourcePulls, bumpSourceCommits)
+	bumpChangelog = bumpcmd.Flag("changelog", "Path to CHANGELOG.md, in the directory of --component if any").
+			Default("CHANGELOG.md").String()
+	bumpFragments = bumpcmd.Flag("fragments", "Directory of the changelog fragments compiled into the entry with the pull requests, in the directory of --component if any").
+			Default(changelog.FragmentsDir).String()
+	bumpDate = bumpcmd.Flag("date", "Date of the entry in YYYY-MM-DD format (defaults to today)").
+			Default("").String()
+	bumpDryRun = bumpcmd.Flag("dry-run", "Print the new version and its changelog entry without writing them").
+			Bool()
+	bumpFinalize = bumpcmd.Flag("finalize", "Bump the current pre-release to its final version, whose entry merges those of the pre-releases, ignoring --level").
+			Bool()
+	bumpOffline = bumpcmd.Flag("offline", "Read the pull requests from their merge or squashed commits, whose kinds are given by their Changelog-Kind trailers, without accessing the GitHub API").
+			Bool()
+	bumpPush = bumpcmd.Flag("push", "Commit the bump on the release-X.Y branch, push it to the remote and open its pull request").
+			Bool()
+	bumpRemote = bumpcmd.Flag("remote", "Remote to push the branch to").
+			Default("origin").String()
+	bumpComponent = bumpcmd.Flag("component", "Directory of the component to bump, with its own VERSION and changelog files and <dir>/vX.Y.Z tags, whose changes are those touching it").
+			String()
+)
+
+// bumpOptions are the options of a version bump.
+type bumpOptions struct {
+	level     string
+	source    string
+	changelog string
+	// fragments is the directory of the changelog fragments.
+	fragments string
+	date      string
+	dryRun    bool
+	finalize  bool
+	offline   bool
+	push      bool
+	remote    string
+	// component is the directory of the component, empty for the project.
+	component string
+}
+
+// bumpChange is a change merged since the last release.
+type bumpChange struct {
+	// Kind is the slash-separated kinds of the change, empty if it isn't
+	// listed in the changelog.
+	Kind   string
+	Title  string
+	PR     int
+	Author string
+	Labels []string
+	// Dependency is whether the change is a dependency update, listed in
+	// the Dependencies section of the entry instead.
+	Dependency bool
+}
+
+// fragment returns the changelog fragment of the change.
+func (c bumpChange) fragment() changelog.Fragment {
+	return changelog.Fragment{Kind: c.Kind, Description: c.Title, PR: c.PR}
+}
+
+// gitCommit is a commit of the history of the project.
+type gitCommit struct {
+	Hash    string
+	Author  string
+	Subject string
+	Body    string
+}
+
+func runBump(o bumpOptions) error {
+	ctx := context.Background()
+	return bump(ctx, githubClient(ctx), o)
+}
+
+// bump bumps the version of the VERSION file and adds the changelog entry of
+// the new version, listing the pull requests of the GitHub client merged
+// since the tag of the current version.
+func bump(ctx context.Context, client *github.Client, o bumpOptions) error {
+	// The components have their own VERSION and changelog files, and tags
+	// prefixed with their directory.
+	info, tagPrefix, err := componentInfo(o.component)
+	if err != nil {
+		return err
+	}
+	versionFile, paths := "VERSION", config.Bump.Paths
+	if o.component != "" {
+		versionFile = filepath.Join(o.component, "VERSION")
+		paths = []string{o.component}
+		o.changelog = filepath.Join(o.component, o.changelog)
+		o.fragments = filepath.Join(o.component, o.fragments)
+	}
+	current, err := info.ToSemver()
+	if err != nil {
+		return fmt.Errorf("invalid semver version: %w", err)
+	}
+	if o.offline && o.push {
+		return errors.New("--push opens a pull request with the GitHub API, it can't be used with --offline")
+	}
+	// The next version of --finalize is known, the others may depend on the
+	// changes.
+	var next *semver.Version
+	if o.finalize {
+		if next, err = finalVersion(current); err != nil {
+			return err
+		}
+	}
+	d, err := changelogDate(o.date)
+	if err != nil {
+		return err
+	}
+
+	commits, err := gitCommits(tagPrefix + current.String())
+	if err != nil {
+		return err
+	}
+	if len(paths) > 0 {
+		commits, err = commitsTouching(commits, paths)
+		if err != nil {
+			return err
+		}
+	}
+	var changes []bumpChange
+	switch {
+	case o.source == bumpSourceCommits:
+		changes = commitChanges(commits)
+	case o.offline:
+		changes, err = offlineChanges(commits)
+	default:
+		changes, err = pullRequestChanges(ctx, client, commits)
+	}
+	if err != nil {
+		return err
+	}
+	var (
+		fragments    []changelog.Fragment
+		dependencies []bumpChange
+	)
+	for _, c := range changes {
+		switch {
+		case c.Dependency:
+			dependencies = append(dependencies, c)
+		case c.Kind != "":
+			fragments = append(fragments, c.fragment())
+		}
+	}
+
+	draft, err := draftChangelogEntry(o.changelog, o.fragments, fragments)
+	if err != nil {
+		return err
+	}
+	if next != nil {
+		if err := foldPreReleases(draft, next); err != nil {
+			return err
+		}
+	}
+	// The releases of dependency updates only have an entry too.
+	if len(draft.changes) == 0 && len(dependencies) == 0 {
+		return draft.errEmpty(o.fragments)
+	}
+	if next == nil {
+		level := o.level
+		if level == bumpAuto {
+			levels, err := bumpLevels()
+			if err != nil {
+				return err
+			}
+			level = inferBumpLevel(draft.changes, levels)
+			fmt.Println(" > inferred bump level", level)
+		}
+		if next, err = nextVersion(current, level); err != nil {
+			return err
+		}
+	}
+	entry, err := draft.entry(next.String(), d)
+	if err != nil {
+		return err
+	}
+	if len(dependencies) > 0 {
+		entry.Text = strings.TrimLeft(entry.Text+"\n\n"+dependenciesSection(entry.Format, dependencies), "\n")
+	}
+	if config.Bump.Template != "" {
+		text, err := renderBumpEntry(config.Bump.Template, info, entry, changes)
+		if err != nil {
+			return fmt.Errorf("failed to render the changelog entry: %w", err)
+		}
+		entry.Text = text
+	}
+	content := draft.insert(entry)
+	if o.dryRun {
+		fmt.Printf("%s\n%s", next, entry)
+		return nil
+	}
+	if err := os.WriteFile(o.changelog, content, 0o644); err != nil {
+		return err
+	}
+	fmt.Printf(" >   %s: added %s with %d changes\n", o.changelog, entry.Name(), len(entry.Changes))
+	if err := removeFragments(draft.fragments); err != nil {
+		return err
+	}
+	if err := os.WriteFile(versionFile, []byte(next.String()+"\n"), 0o644); err != nil {
+		return err
+	}
+	fmt.Println(" > bumped version", current, "to", next)
+	if !o.push {
+		return nil
+	}
+	return pushBump(ctx, client, o.remote, tagPrefix, next, entry, append([]string{o.changelog, versionFile}, fragmentFiles(draft.fragments)...))
+}
+
+// componentInfo returns the project info whose version is that of the
+// VERSION file of the component directory, and the prefix of the tags of its
+// versions, e.g. collectors/foo/v. The component is the project itself if
+// the directory is empty.
+func componentInfo(dir string) (repository.Info, string, error) {
+	info := projInfo
+	if dir == "" {
+		return info, "v", nil
+	}
+	component := path.Clean(filepath.ToSlash(dir))
+	b, err := os.ReadFile(filepath.Join(dir, "VERSION"))
+	if err != nil {
+		return info, "", fmt.Errorf("failed to read the version of component %s: %w", component, err)
+	}
+	info.Version = strings.TrimSpace(string(b))
+	return info, component + "/v", nil
+}
+
+// pushBump commits the files of the bump on the release-X.Y branch of the
+// version, pushes it to the remote and opens its pull request into the
+// current branch, requesting the reviews of bump.reviewers. The bumps of the
+// release branches themselves, e.g. of patch releases, are committed on a
+// bump-vX.Y.Z branch instead. The branches of the components are prefixed
+// with their directory, e.g. release-collectors-foo-X.Y.
+func pushBump(ctx context.Context, client *github.Client, remote, tagPrefix string, version *semver.Version, entry *changelog.Entry, files []string) error {
+	base := projInfo.Branch
+	component := strings.TrimSuffix(tagPrefix, "v")
+	branch := fmt.Sprintf("release-%s%d.%d", strings.ReplaceAll(component, "/", "-"), version.Major(), version.Minor())
+	if branch == base {
+		branch = "bump-" + strings.ReplaceAll(tagPrefix, "/", "-") + version.String()
+	}
+	title := "Release " + version.String()
+	if component != "" {
+		title = fmt.Sprintf("Release %s %s", strings.TrimSuffix(component, "/"), version)
+	}
+	for _, args := range [][]string{
+		{"checkout", "-b", branch},
+		// The removed fragments are staged too.
+		append([]string{"add", "--all", "--"}, files...),
+		{"commit", "-m", title},
+		{"push", "--set-upstream", remote, branch},
+	} {
+		if err := sh.RunCommand("git", args...); err != nil {
+			return fmt.Errorf("failed to push branch %s: %w", branch, err)
+		}
+	}
+	fmt.Println(" > pushed branch", branch, "to", remote)
+
+	pr, _, err := client.PullRequests.Create(ctx, projInfo.Owner, projInfo.Name, &github.NewPullRequest{
+		Title: github.String(title),
+		Head:  github.String(branch),
+		Base:  github.String(base),
+		Body:  github.String(entry.Text),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open the pull request of branch %s: %w", branch, err)
+	}
+	fmt.Println(" > opened pull request", pr.GetHTMLURL())
+	if len(config.Bump.Reviewers) == 0 {
+		return nil
+	}
+	// The reviewers of the form org/team are teams.
+	var reviewers github.ReviewersRequest
+	for _, r := range config.Bump.Reviewers {
+		if _, team, ok := strings.Cut(r, "/"); ok {
+			reviewers.TeamReviewers = append(reviewers.TeamReviewers, team)
+			continue
+		}
+		reviewers.Reviewers = append(reviewers.Reviewers, r)
+	}
+	if _, _, err := client.PullRequests.RequestReviewers(ctx, projInfo.Owner, projInfo.Name, pr.GetNumber(), reviewers); err != nil {
+		return fmt.Errorf("failed to request the reviews of pull request #%d: %w", pr.GetNumber(), err)
+	}
+	return nil
+}
+
+// fragmentFiles returns the files of the fragments.
+func fragmentFiles(fragments []changelog.Fragment) []string {
+	files := make([]string, 0, len(fragments))
+	for _, f := range fragments {
+		files = append(files, f.File)
+	}
+	return files
+}
+
+// bumpEntryData is the data of the changelog entry template.
+type bumpEntryData struct {
+	Version string
+	// Date is the date of the entry in YYYY-MM-DD format.
+	Date string
+	// Project is the project, whose Version is the current one, of the
+	// component if any.
+	Project repository.Info
+	// Changelog is the entry of the version, whose Text is the default
+	// text of the entry.
+	Changelog *changelog.Entry
+	// Kinds are the changes of the entry grouped by kinds, in the order of
+	// the entry.
+	Kinds []bumpEntryKind
+	// PullRequests are the changes merged since the current version,
+	// including those which aren't listed in the changelog.
+	PullRequests []bumpChange
+	// Dependencies are the dependency updates, whose collapsed section
+	// ends the default text of the entry.
+	Dependencies []bumpChange
+	// Contributors are the sorted authors of the listed changes.
+	Contributors []string
+}
+
+// bumpEntryKind is the changes of some kinds in the changelog entry
+// template.
+type bumpEntryKind struct {
+	// Kind is the slash-separated kinds of the changes, e.g.
+	// "ENHANCEMENT/BUGFIX".
+	Kind    string
+	Changes changelog.Changes
+}
+
+// renderBumpEntry renders the changelog entry template, returning the text
+// of the entry.
+func renderBumpEntry(file string, info repository.Info, entry *changelog.Entry, changes []bumpChange) (string, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := template.New(filepath.Base(file)).Option("missingkey=error").Parse(string(b))
+	if err != nil {
+		return "", err
+	}
+
+	data := bumpEntryData{
+		Version:      entry.Version,
+		Date:         entry.Date.Format("2006-01-02"),
+		Project:      info,
+		Changelog:    entry,
+		PullRequests: changes,
+	}
+	for _, c := range changes {
+		if c.Dependency {
+			data.Dependencies = append(data.Dependencies, c)
+		}
+	}
+	contributors := map[string]bool{}
+	for _, c := range entry.Changes {
+		kind := c.Kinds.String()
+		if n := len(data.Kinds); n == 0 || data.Kinds[n-1].Kind != kind {
+			data.Kinds = append(data.Kinds, bumpEntryKind{Kind: kind})
+		}
+		data.Kinds[len(data.Kinds)-1].Changes = append(data.Kinds[len(data.Kinds)-1].Changes, c)
+		for _, author := range c.Authors {
+			contributors[author] = true
+		}
+	}
+	for _, c := range changes {
+		if c.Kind != "" && c.Author != "" && !c.Dependency {
+			contributors[c.Author] = true
+		}
+	}
+	for author := range contributors {
+		data.Contributors = append(data.Contributors, author)
+	}
+	sort.Strings(data.Contributors)
+
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, data); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(text.String(), "\n"), nil
+}
+
+// nextVersion returns the version following the current one at the level.
+// The pre-releases are those of the next minor version, with the identifier
+// of bump.pre_release, and a pre-release with this identifier is followed by
+// the next iteration, e.g. rc.0 by rc.1.
+func nextVersion(current *semver.Version, level string) (*semver.Version, error) {
+	var next semver.Version
+	switch level {
+	case bumpMajor:
+		next = current.IncMajor()
+	case bumpMinor:
+		next = current.IncMinor()
+	case bumpPatch:
+		next = current.IncPatch()
+	case bumpPre:
+		id := orDefault(config.Bump.PreRelease, "rc")
+		base, pre := *current, id+".0"
+		if current.Prerelease() == "" {
+			base = current.IncMinor()
+		} else if prefix, n, ok := strings.Cut(current.Prerelease(), "."); ok && prefix == id {
+			i, err := strconv.Atoi(n)
+			if err != nil {
+				return nil, fmt.Errorf("invalid iteration of pre-release %s: %w", current, err)
+			}
+			pre = fmt.Sprintf("%s.%d", id, i+1)
+		}
+		var err error
+		next, err = base.SetPrerelease(pre)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pre-release identifier: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown bump level %q", level)
+	}
+	return &next, nil
+}
+
+// finalVersion returns the final version of the current pre-release.
+func finalVersion(current *semver.Version) (*semver.Version, error) {
+	if current.Prerelease() == "" {
+		return nil, fmt.Errorf("version %s isn't a pre-release", current)
+	}
+	final, err := current.SetPrerelease("")
+	if err != nil {
+		return nil, err
+	}
+	return &final, nil
+}
+
+// foldPreReleases moves the changes of the entries of the pre-releases of the
+// version into the draft, oldest first, and removes the entries.
+func foldPreReleases(draft *changelogDraft, version *semver.Version) error {
+	entries, err := draft.format.ReadEntries(bytes.NewReader(draft.content))
+	if err != nil {
+		return fmt.Errorf("invalid changelog %s: %w", draft.path, err)
+	}
+	var changes changelog.Changes
+	for i := len(entries) - 1; i >= 0; i-- {
+		v, err := semver.NewVersion(entries[i].Version)
+		if err != nil || v.Prerelease() == "" {
+			continue
+		}
+		if final, _ := v.SetPrerelease(""); !final.Equal(version) {
+			continue
+		}
+		changes = append(changes, entries[i].Changes...)
+		draft.content = changelog.RemoveEntry(draft.content, draft.format, entries[i].Version)
+	}
+	draft.changes = append(changes, draft.changes...)
+	return nil
+}
+
+// defaultBumpLevels are the levels of the bumps releasing the kinds of
+// changes, patch for the kinds which aren't listed.
+var defaultBumpLevels = map[string]string{
+	"CHANGE":  bumpMajor,
+	"FEATURE": bumpMinor,
+}
+
+// bumpLevels returns the levels of the bumps releasing the kinds of changes,
+// the default ones overridden by bump.levels.
+func bumpLevels() (map[string]string, error) {
+	levels := make(map[string]string, len(defaultBumpLevels)+len(config.Bump.Levels))
+	for k, level := range defaultBumpLevels {
+		levels[k] = level
+	}
+	for name, level := range config.Bump.Levels {
+		kinds := changelog.ParseKinds(name)
+		if len(kinds) != 1 {
+			return nil, fmt.Errorf("invalid kind %q in bump.levels", name)
+		}
+		switch level {
+		case bumpMajor, bumpMinor, bumpPatch:
+		default:
+			return nil, fmt.Errorf("invalid level %q of %s in bump.levels, expected major, minor or patch", level, name)
+		}
+		levels[kinds[0].String()] = level
+	}
+	return levels, nil
+}
+
+// inferBumpLevel returns the highest of the levels of the kinds of the
+// changes, patch if none is listed.
+func inferBumpLevel(changes changelog.Changes, levels map[string]string) string {
+	rank := map[string]int{bumpPatch: 0, bumpMinor: 1, bumpMajor: 2}
+	level := bumpPatch
+	for _, c := range changes {
+		for _, k := range c.Kinds {
+			if l, ok := levels[k.String()]; ok && rank[l] > rank[level] {
+				level = l
+			}
+		}
+	}
+	return level
+}
+
+// gitCommits returns the commits of HEAD which aren't reachable from the
+// revision, oldest first.
+func gitCommits(since string) ([]gitCommit, error) {
+	var out bytes.Buffer
+	if err := sh.RunCommandWithOutput(&out, os.Stderr, "git", "log", "--reverse", "--format=%H%x1f%an%x1f%s%x1f%b%x1e", since+"..HEAD"); err != nil {
+		return nil, fmt.Errorf("failed to list the commits since %s: %w", since, err)
+	}
+	return parseGitLog(out.String()), nil
+}
+
+// commitsTouching returns the commits changing files in the paths, compared
+// to their first parent.
+func commitsTouching(commits []gitCommit, paths []string) ([]gitCommit, error) {
+	var touching []gitCommit
+	for _, c := range commits {
+		var out bytes.Buffer
+		if err := sh.RunCommandWithOutput(&out, os.Stderr, "git", "diff", "--name-only", c.Hash+"^1", c.Hash); err != nil {
+			return nil, fmt.Errorf("failed to list the files changed by commit %s: %w", c.Hash, err)
+		}
+		for _, file := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			if inPaths(file, paths) {
+				touching = append(touching, c)
+				break
+			}
+		}
+	}
+	return touching, nil
+}
+
+// inPaths returns whether the slash-separated file is one of the paths or in
+// one of their directories.
+func inPaths(file string, paths []string) bool {
+	for _, p := range paths {
+		p = strings.TrimSuffix(path.Clean(filepath.ToSlash(p)), "/")
+		if p == "." || file == p || strings.HasPrefix(file, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseGitLog parses the commits of git log in the format of gitCommits.
+func parseGitLog(out string) []gitCommit {
+	var commits []gitCommit
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.SplitN(strings.TrimLeft(record, "\n"), "\x1f", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		commits = append(commits, gitCommit{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Subject: fields[2],
+			Body:    strings.TrimSpace(fields[3]),
+		})
+	}
+	return commits
+}
+
+var (
+	// reMergeCommit matches the subject of the merge commit of a pull
+	// request, its submatch being the number of the pull request.
+	reMergeCommit = regexp.MustCompile(`^Merge pull request #(\d+) from `)
+	// reSquashCommit matches the subject of a squashed pull request, e.g.
+	// "Fix something (#1234)", its submatch being the number of the pull
+	// request.
+	reSquashCommit = regexp.MustCompile(`\(#(\d+)\)$`)
+)
+
+// pullRequestNumber returns the number of the pull request merged by the
+// commit, 0 if there is none.
+func pullRequestNumber(c gitCommit) int {
+	m := reMergeCommit.FindStringSubmatch(c.Subject)
+	if m == nil {
+		m = reSquashCommit.FindStringSubmatch(c.Subject)
+	}
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+// pullRequestChanges returns the changes of the pull requests merged by the
+// commits, whose kinds are given by their labels: those of bump.labels.kinds
+// and those with the kind prefix. The pull requests with the skip label, and
+// those without kind, which are reported, aren't listed in the changelog.
+func pullRequestChanges(ctx context.Context, client *github.Client, commits []gitCommit) ([]bumpChange, error) {
+	labels := config.Bump.Labels
+	prefix, skipName := orDefault(labels.KindPrefix, kindLabelPrefix), orDefault(labels.Skip, skipLabel)
+	labelKinds := make(map[string]changelog.Kinds, len(labels.Kinds))
+	for label, name := range labels.Kinds {
+		kinds := changelog.ParseKinds(name)
+		if len(kinds) != len(strings.Split(name, "/")) {
+			return nil, fmt.Errorf("invalid kinds %q of label %q in bump.labels.kinds", name, label)
+		}
+		labelKinds[label] = kinds
+	}
+
+	var changes []bumpChange
+	seen := map[int]bool{}
+	for _, commit := range commits {
+		number := pullRequestNumber(commit)
+		if number == 0 || seen[number] {
+			continue
+		}
+		seen[number] = true
+		pr, _, err := client.PullRequests.Get(ctx, projInfo.Owner, projInfo.Name, number)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pull request #%d: %w", number, err)
+		}
+		c := bumpChange{
+			Title:  strings.TrimSpace(pr.GetTitle()),
+			PR:     number,
+			Author: pr.GetUser().GetLogin(),
+		}
+		var kinds changelog.Kinds
+		skip := false
+		for _, label := range pr.Labels {
+			name := label.GetName()
+			c.Labels = append(c.Labels, name)
+			switch {
+			case name == skipName:
+				skip = true
+			case labelKinds[name] != nil:
+				kinds = append(kinds, labelKinds[name]...)
+			case strings.HasPrefix(name, prefix):
+				// The labels of unknown kinds are ignored.
+				if k := changelog.ParseKinds(strings.ToUpper(strings.TrimPrefix(name, prefix))); len(k) == 1 {
+					kinds = append(kinds, k[0])
+				}
+			}
+		}
+		if !skip {
+			// The kinds are deduplicated and sorted.
+			c.Kind = changelog.ParseKinds(kinds.String()).String()
+			c.Dependency = isDependencyUpdate(c)
+			if c.Kind == "" && !c.Dependency {
+				warn(fmt.Errorf("pull request #%d has no label of its kind, it isn't listed in the changelog", number))
+			}
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+var (
+	// reChangelogTrailer matches the trailers of the commits giving the
+	// kinds of their changes, e.g. "Changelog-Kind: BUGFIX", and excluding
+	// them from the changelog, e.g. "Changelog-Skip: true", its submatches
+	// being the key and the value.
+	reChangelogTrailer = regexp.MustCompile(`(?im)^changelog-(kind|skip):[ \t]*(.+?)[ \t]*$`)
+)
+
+// offlineChanges returns the changes of the pull requests merged by the
+// commits, read from the commits only: their titles are the bodies of the
+// merge commits and the subjects of the squashed commits, and their kinds are
+// given by the Changelog-Kind trailers of the commits. The pull requests with
+// the Changelog-Skip trailer, and those without kind, which are reported,
+// aren't listed in the changelog.
+func offlineChanges(commits []gitCommit) ([]bumpChange, error) {
+	var changes []bumpChange
+	seen := map[int]bool{}
+	for _, commit := range commits {
+		number := pullRequestNumber(commit)
+		if number == 0 || seen[number] {
+			continue
+		}
+		seen[number] = true
+		c := bumpChange{
+			Title:  strings.TrimSpace(reSquashCommit.ReplaceAllString(commit.Subject, "")),
+			PR:     number,
+			Author: commit.Author,
+		}
+		if reMergeCommit.MatchString(commit.Subject) {
+			// The merge commits are authored by the maintainers.
+			c.Author = ""
+			if title, _, _ := strings.Cut(commit.Body, "\n"); strings.TrimSpace(title) != "" {
+				c.Title = strings.TrimSpace(title)
+			}
+		}
+		var kinds changelog.Kinds
+		skip := false
+		for _, m := range reChangelogTrailer.FindAllStringSubmatch(commit.Body, -1) {
+			if strings.EqualFold(m[1], "skip") {
+				skip = true
+				continue
+			}
+			k := changelog.ParseKinds(strings.ToUpper(m[2]))
+			if len(k) != len(strings.Split(m[2], "/")) {
+				return nil, fmt.Errorf("invalid Changelog-Kind trailer %q of pull request #%d", m[2], number)
+			}
+			kinds = append(kinds, k...)
+		}
+		if !skip {
+			c.Kind = changelog.ParseKinds(kinds.String()).String()
+			c.Dependency = isDependencyUpdate(c)
+			if c.Kind == "" && !c.Dependency {
+				warn(fmt.Errorf("pull request #%d has no Changelog-Kind trailer, it isn't listed in the changelog", number))
+			}
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+var (
+	// reConventionalCommit matches the subject of a Conventional Commit, e.g.
+	// "feat(api)!: add foo", its submatches being the type, the breaking
+	// change mark and the description.
+	reConventionalCommit = regexp.MustCompile(`^(\w+)(?:\([^)]*\))?(!)?: (.+)$`)
+	// reBreakingChange matches the footer of a breaking change in the body of
+	// a Conventional Commit.
+	reBreakingChange = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE: `)
+)
+
+// commitChanges returns the changes of the Conventional Commits: the breaking
+// changes are CHANGEs, the feat commits FEATUREs and the fix commits BUGFIXes.
+// The other commits aren't listed in the changelog.
+func commitChanges(commits []gitCommit) []bumpChange {
+	var changes []bumpChange
+	for _, commit := range commits {
+		m := reConventionalCommit.FindStringSubmatch(commit.Subject)
+		if m == nil {
+			continue
+		}
+		c := bumpChange{
+			Title:  strings.TrimSpace(reSquashCommit.ReplaceAllString(m[3], "")),
+			PR:     pullRequestNumber(commit),
+			Author: commit.Author,
+		}
+		c.Dependency = isDependencyUpdate(c)
+		// The descriptions are capitalized like the other changes.
+		if r, size := utf8.DecodeRuneInString(c.Title); r != utf8.RuneError {
+			c.Title = string(unicode.ToUpper(r)) + c.Title[size:]
+		}
+		switch {
+		case m[2] == "!" || reBreakingChange.MatchString(commit.Body):
+			c.Kind = "CHANGE"
+		case strings.EqualFold(m[1], "feat"):
+			c.Kind = "FEATURE"
+		case strings.EqualFold(m[1], "fix"):
+			c.Kind = "BUGFIX"
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+// Default authors and label of the dependency updates.
+var (
+	dependencyAuthors = []string{"dependabot[bot]", "renovate[bot]"}
+	dependencyLabel   = "dependencies"
+)
+
+// isDependencyUpdate returns whether the change is a dependency update,
+// according to its author or its label of bump.dependencies.
+func isDependencyUpdate(c bumpChange) bool {
+	authors := config.Bump.Dependencies.Authors
+	if len(authors) == 0 {
+		authors = dependencyAuthors
+	}
+	for _, author := range authors {
+		if c.Author == author {
+			return true
+		}
+	}
+	label := orDefault(config.Bump.Dependencies.Label, dependencyLabel)
+	for _, l := range c.Labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// dependenciesSection returns the collapsed Dependencies section of the
+// entry in the format, listing the dependency updates.
+func dependenciesSection(format changelog.Format, dependencies []bumpChange) string {
+	bullet := "* "
+	if format == changelog.KeepAChangelog {
+		bullet = "- "
+	}
+	lines := []string{"<details>", "<summary>Dependencies</summary>", ""}
+	for _, c := range dependencies {
+		line := bullet + c.Title
+		if c.PR != 0 {
+			line += fmt.Sprintf(" #%d", c.PR)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(append(lines, "", "</details>"), "\n")
+}
+
+// githubClient returns a client of the GitHub API, authenticated with
+// GITHUB_TOKEN if defined.
+func githubClient(ctx context.Context) *github.Client {
+	var hc *http.Client
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		hc = oauth2.NewClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+	}
+	return github.NewClient(hc)
+}
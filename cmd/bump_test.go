@@ -0,0 +1,763 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/google/go-github/v25/github"
+
+	"github.com/prometheus/promu/pkg/changelog"
+	"github.com/prometheus/promu/pkg/repository"
+)
+
+func TestNextVersion(t *testing.T) {
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+
+	for _, tc := range []struct {
+		current, level, preRelease, exp string
+	}{
+		{current: "1.2.3", level: bumpMajor, exp: "2.0.0"},
+		{current: "1.2.3", level: bumpMinor, exp: "1.3.0"},
+		{current: "1.2.3", level: bumpPatch, exp: "1.2.4"},
+		{current: "1.2.3", level: bumpPre, exp: "1.3.0-rc.0"},
+		{current: "1.2.3", level: bumpPre, preRelease: "beta", exp: "1.3.0-beta.0"},
+		{current: "1.3.0-rc.0", level: bumpPre, exp: "1.3.0-rc.1"},
+		{current: "1.3.0-rc.9", level: bumpPre, exp: "1.3.0-rc.10"},
+		{current: "1.3.0-beta.2", level: bumpPre, exp: "1.3.0-rc.0"},
+		{current: "1.3.0-rc.1", level: bumpPatch, exp: "1.3.0"},
+	} {
+		config.Bump.PreRelease = tc.preRelease
+		next, err := nextVersion(semver.MustParse(tc.current), tc.level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next.String() != tc.exp {
+			t.Errorf("%s %s: expected %s, got %s", tc.current, tc.level, tc.exp, next)
+		}
+	}
+
+	config.Bump.PreRelease = "r_c"
+	if _, err := nextVersion(semver.MustParse("1.2.3"), bumpPre); err == nil {
+		t.Fatal("expected error for an invalid pre-release identifier, got none")
+	}
+	config.Bump.PreRelease = ""
+	if _, err := nextVersion(semver.MustParse("1.3.0-rc.x"), bumpPre); err == nil {
+		t.Fatal("expected error for an invalid pre-release iteration, got none")
+	}
+}
+
+func TestFinalVersion(t *testing.T) {
+	final, err := finalVersion(semver.MustParse("1.3.0-rc.2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final.String() != "1.3.0" {
+		t.Fatalf("expected 1.3.0, got %s", final)
+	}
+	if _, err := finalVersion(semver.MustParse("1.3.0")); err == nil {
+		t.Fatal("expected error but got nil")
+	}
+}
+
+func TestPullRequestNumber(t *testing.T) {
+	for subject, exp := range map[string]int{
+		"Merge pull request #1234 from alice/foo": 1234,
+		"Fix something (#42)":                     42,
+		"Fix something (#42) again":               0,
+		"Fix #42":                                 0,
+		"Merge branch 'release-1.0'":              0,
+	} {
+		if got := pullRequestNumber(gitCommit{Subject: subject}); got != exp {
+			t.Errorf("%q: expected %d, got %d", subject, exp, got)
+		}
+	}
+}
+
+func TestBump(t *testing.T) {
+	// The fake git prints the commits since v1.0.0: two merged pull requests
+	// and a squashed one, a direct commit, and the merge of a pull request
+	// whose change is a fragment.
+	fakeCommand(t, "git", `[ "$*" = "log --reverse --format=%H%x1f%an%x1f%s%x1f%b%x1e v1.0.0..HEAD" ] || exit 1
+printf 'a\037alice\037Merge pull request #1 from alice/foo\037Add foo\n\036\n'
+printf 'b\037bob\037Fix bar (#2)\037\036\n'
+printf 'c\037bob\037Update the CI configuration\037\036\n'
+printf 'd\037carol\037Merge pull request #3 from carol/skip\037Refactor\n\036\n'
+printf 'e\037dave\037Do something (#4)\037\036\n'
+printf 'f\037erin\037Merge pull request #6 from erin/unlabeled\037Something\n\036\n'
+`)
+	gh, client := newFakeGitHub(t)
+	for number, labels := range map[int][]string{
+		1: {"kind/feature"},
+		2: {"kind/bugfix", "kind/enhancement", "kind/unknown"},
+		3: {"kind/cleanup", "changelog/skip"},
+		4: {"kind/bugfix"},
+		6: nil,
+	} {
+		pr := &github.PullRequest{Number: github.Int(number), Title: github.String(map[int]string{1: "Add foo", 2: "Fix bar", 3: "Refactor", 4: "Do something", 6: "Something"}[number])}
+		for _, label := range labels {
+			pr.Labels = append(pr.Labels, &github.Label{Name: github.String(label)})
+		}
+		gh.pulls[number] = pr
+	}
+
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer func(c *Config, info repository.Info) { config, projInfo = c, info }(config, projInfo)
+	config = NewConfig()
+	projInfo = repository.Info{Owner: "owner", Name: "repo", Version: "1.0.0"}
+
+	content := "## Unreleased\n\n* [CHANGE] Change something. #5\n\n## 1.0.0 / 2026-01-01\n\n* [FEATURE] Initial release.\n"
+	if err := os.WriteFile("CHANGELOG.md", []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fragment, err := changelog.WriteFragment(changelog.FragmentsDir, changelog.Fragment{Kind: "BUGFIX", Description: "Do something better.", PR: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := bumpOptions{level: bumpMinor, changelog: "CHANGELOG.md", fragments: changelog.FragmentsDir, date: "2026-02-01", dryRun: true}
+	if err := bump(context.Background(), client, o); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat("VERSION"); err == nil {
+		t.Fatal("expected no VERSION file to be written by a dry run")
+	}
+
+	o.dryRun = false
+	if err := bump(context.Background(), client, o); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile("CHANGELOG.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `## Unreleased
+
+## 1.1.0 / 2026-02-01
+
+* [CHANGE] Change something. #5
+* [FEATURE] Add foo #1
+* [ENHANCEMENT/BUGFIX] Fix bar #2
+* [BUGFIX] Do something better. #4
+
+## 1.0.0 / 2026-01-01
+
+* [FEATURE] Initial release.
+`
+	if string(b) != exp {
+		t.Fatalf("expected changelog:\n%s\ngot:\n%s", exp, b)
+	}
+	if b, err := os.ReadFile("VERSION"); err != nil || string(b) != "1.1.0\n" {
+		t.Fatalf("expected VERSION 1.1.0, got %q (%v)", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, fragment)); !os.IsNotExist(err) {
+		t.Fatalf("expected the fragment to be removed, got %v", err)
+	}
+}
+
+func TestCommitChanges(t *testing.T) {
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+
+	changes := commitChanges([]gitCommit{
+		{Author: "alice", Subject: "feat(api): add foo (#1)"},
+		{Author: "bob", Subject: "fix: fix bar"},
+		{Author: "bob", Subject: "feat!: remove baz"},
+		{Author: "carol", Subject: "refactor: rename qux", Body: "BREAKING CHANGE: qux is renamed."},
+		{Author: "carol", Subject: "chore: update the CI configuration"},
+		{Author: "dave", Subject: "Update the README"},
+	})
+	exp := []bumpChange{
+		{Kind: "FEATURE", Title: "Add foo", PR: 1, Author: "alice"},
+		{Kind: "BUGFIX", Title: "Fix bar", Author: "bob"},
+		{Kind: "CHANGE", Title: "Remove baz", Author: "bob"},
+		{Kind: "CHANGE", Title: "Rename qux", Author: "carol"},
+		{Title: "Update the CI configuration", Author: "carol"},
+	}
+	if !reflect.DeepEqual(changes, exp) {
+		t.Fatalf("expected %+v, got %+v", exp, changes)
+	}
+}
+
+func TestInferBumpLevel(t *testing.T) {
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+
+	for _, tc := range []struct {
+		kinds  string
+		levels map[string]string
+		exp    string
+	}{
+		{kinds: "", exp: bumpPatch},
+		{kinds: "BUGFIX", exp: bumpPatch},
+		{kinds: "ENHANCEMENT,BUGFIX", exp: bumpPatch},
+		{kinds: "FEATURE,BUGFIX", exp: bumpMinor},
+		{kinds: "BUGFIX,CHANGE", exp: bumpMajor},
+		{kinds: "BUGFIX,CHANGE", levels: map[string]string{"CHANGE": bumpMinor}, exp: bumpMinor},
+		{kinds: "ENHANCEMENT/BUGFIX", levels: map[string]string{"ENHANCEMENT": bumpMinor}, exp: bumpMinor},
+		{kinds: "FEATURE", levels: map[string]string{"FEATURE": bumpPatch}, exp: bumpPatch},
+	} {
+		config.Bump.Levels = tc.levels
+		levels, err := bumpLevels()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var changes changelog.Changes
+		for _, k := range strings.Split(tc.kinds, ",") {
+			changes = append(changes, changelog.Change{Kinds: changelog.ParseKinds(k)})
+		}
+		if got := inferBumpLevel(changes, levels); got != tc.exp {
+			t.Errorf("%s %v: expected %s, got %s", tc.kinds, tc.levels, tc.exp, got)
+		}
+	}
+
+	for _, levels := range []map[string]string{
+		{"CLEANUP": bumpPatch},
+		{"CHANGE/FEATURE": bumpMajor},
+		{"CHANGE": bumpPre},
+	} {
+		config.Bump.Levels = levels
+		if _, err := bumpLevels(); err == nil {
+			t.Errorf("%v: expected error but got nil", levels)
+		}
+	}
+}
+
+func TestBumpCommits(t *testing.T) {
+	fakeCommand(t, "git", `printf 'a\037alice\037feat: add foo (#1)\037\036\n'
+printf 'b\037bob\037fix(bar): fix bar\037\036\n'
+printf 'c\037bob\037docs: document bar\037\036\n'
+`)
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer func(c *Config, info repository.Info) { config, projInfo = c, info }(config, projInfo)
+	config = NewConfig()
+	projInfo = repository.Info{Owner: "owner", Name: "repo", Version: "1.0.0"}
+
+	// The level is inferred from the kinds of the changes, without any
+	// access to the GitHub API.
+	o := bumpOptions{level: bumpAuto, source: bumpSourceCommits, changelog: "CHANGELOG.md", fragments: changelog.FragmentsDir, date: "2026-02-01"}
+	if err := bump(context.Background(), nil, o); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile("CHANGELOG.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "## 1.1.0 / 2026-02-01\n\n* [FEATURE] Add foo #1\n* [BUGFIX] Fix bar\n"
+	if string(b) != exp {
+		t.Fatalf("expected changelog:\n%s\ngot:\n%s", exp, b)
+	}
+	if b, err := os.ReadFile("VERSION"); err != nil || string(b) != "1.1.0\n" {
+		t.Fatalf("expected VERSION 1.1.0, got %q (%v)", b, err)
+	}
+}
+
+func TestBumpTemplate(t *testing.T) {
+	fakeCommand(t, "git", `printf 'a\037alice\037feat: add foo (#1)\037\036\n'
+printf 'b\037bob\037fix(bar): fix bar\037\036\n'
+printf 'c\037alice\037fix: fix baz (#3)\037\036\n'
+printf 'd\037carol\037docs: document bar\037\036\n'
+`)
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := filepath.Abs("../doc/examples/prometheus/changelog-entry.md.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer func(c *Config, info repository.Info) { config, projInfo = c, info }(config, projInfo)
+	config = NewConfig()
+	config.Bump.Template = tmpl
+	projInfo = repository.Info{Owner: "owner", Name: "repo", Version: "1.0.0"}
+
+	o := bumpOptions{level: bumpPatch, source: bumpSourceCommits, changelog: "CHANGELOG.md", fragments: changelog.FragmentsDir, date: "2026-02-01"}
+	if err := bump(context.Background(), nil, o); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile("CHANGELOG.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `## 1.0.1 / 2026-02-01
+
+### FEATURE
+
+* [FEATURE] Add foo #1
+
+### BUGFIX
+
+* [BUGFIX] Fix bar
+* [BUGFIX] Fix baz #3
+
+Thanks to @alice, @bob for their contributions to repo 1.0.1.
+`
+	if string(b) != exp {
+		t.Fatalf("expected changelog:\n%s\ngot:\n%s", exp, b)
+	}
+
+	// The errors of the template are reported before writing anything.
+	if err := os.WriteFile("invalid.tmpl", []byte("{{ .Missing }}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config.Bump.Template = "invalid.tmpl"
+	projInfo.Version = "1.0.1"
+	if err := bump(context.Background(), nil, o); err == nil {
+		t.Fatal("expected error but got nil")
+	}
+	if b, err := os.ReadFile("VERSION"); err != nil || string(b) != "1.0.1\n" {
+		t.Fatalf("expected VERSION 1.0.1, got %q (%v)", b, err)
+	}
+}
+
+func TestPullRequestChangesLabels(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	for number, labels := range map[int][]string{
+		1: {"bug"},
+		2: {"type: feature", "enhancement"},
+		3: {"bug", "no-changelog"},
+		4: {"kind/bugfix"},
+	} {
+		pr := &github.PullRequest{Number: github.Int(number), Title: github.String("Change")}
+		for _, label := range labels {
+			pr.Labels = append(pr.Labels, &github.Label{Name: github.String(label)})
+		}
+		gh.pulls[number] = pr
+	}
+	defer func(c *Config, info repository.Info) { config, projInfo = c, info }(config, projInfo)
+	config = NewConfig()
+	config.Bump.Labels.KindPrefix = "type: "
+	config.Bump.Labels.Skip = "no-changelog"
+	config.Bump.Labels.Kinds = map[string]string{"bug": "BUGFIX", "enhancement": "ENHANCEMENT"}
+	projInfo = repository.Info{Owner: "owner", Name: "repo"}
+
+	var commits []gitCommit
+	for _, subject := range []string{"Fix (#1)", "Add (#2)", "Skip (#3)", "Other (#4)"} {
+		commits = append(commits, gitCommit{Subject: subject})
+	}
+	changes, err := pullRequestChanges(context.Background(), client, commits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for _, c := range changes {
+		kinds = append(kinds, c.Kind)
+	}
+	if exp := []string{"BUGFIX", "FEATURE/ENHANCEMENT", "", ""}; !reflect.DeepEqual(kinds, exp) {
+		t.Fatalf("expected kinds %q, got %q", exp, kinds)
+	}
+
+	config.Bump.Labels.Kinds = map[string]string{"bug": "BUG"}
+	if _, err := pullRequestChanges(context.Background(), client, commits); err == nil {
+		t.Fatal("expected error but got nil")
+	}
+}
+
+func TestBumpPush(t *testing.T) {
+	// The fake git prints the commits and logs the other commands.
+	log := filepath.Join(t.TempDir(), "git.log")
+	fakeCommand(t, "git", `if [ "$1" = log ]; then
+  printf 'a\037alice\037feat: add foo (#1)\037\036\n'
+  exit 0
+fi
+echo "$*" >> `+log+`
+`)
+	gh, client := newFakeGitHub(t)
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer func(c *Config, info repository.Info) { config, projInfo = c, info }(config, projInfo)
+	config = NewConfig()
+	config.Bump.Reviewers = []string{"alice", "prometheus/release-shepherds"}
+	fragment, err := changelog.WriteFragment(changelog.FragmentsDir, changelog.Fragment{Kind: "BUGFIX", Description: "Fix bar.", PR: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		branch, version, expBranch string
+	}{
+		{branch: "main", version: "1.0.0", expBranch: "release-1.1"},
+		// The patch releases are bumped on their release branch.
+		{branch: "release-1.1", version: "1.1.0", expBranch: "bump-v1.1.1"},
+	} {
+		if err := os.Remove(log); err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		projInfo = repository.Info{Owner: "owner", Name: "repo", Branch: tc.branch, Version: tc.version}
+		level := bumpMinor
+		if tc.branch != "main" {
+			level = bumpPatch
+		}
+		o := bumpOptions{level: level, source: bumpSourceCommits, changelog: "CHANGELOG.md", fragments: changelog.FragmentsDir, date: "2026-02-01", push: true, remote: "upstream"}
+		if err := bump(context.Background(), client, o); err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(log)
+		if err != nil {
+			t.Fatal(err)
+		}
+		next := semver.MustParse(tc.version)
+		if level == bumpMinor {
+			*next = next.IncMinor()
+		} else {
+			*next = next.IncPatch()
+		}
+		files := "CHANGELOG.md VERSION"
+		if tc.branch == "main" {
+			files += " " + fragment
+		}
+		exp := "checkout -b " + tc.expBranch + "\nadd --all -- " + files + "\ncommit -m Release " + next.String() + "\npush --set-upstream upstream " + tc.expBranch + "\n"
+		if string(b) != exp {
+			t.Fatalf("expected git commands:\n%s\ngot:\n%s", exp, b)
+		}
+	}
+
+	if len(gh.pulls) != 2 {
+		t.Fatalf("expected 2 pull requests, got %d", len(gh.pulls))
+	}
+	pr := gh.pulls[1]
+	if pr.GetTitle() != "Release 1.1.0" || pr.GetHead().GetRef() != "release-1.1" || pr.GetBase().GetRef() != "main" {
+		t.Fatalf("unexpected pull request %q from %s into %s", pr.GetTitle(), pr.GetHead().GetRef(), pr.GetBase().GetRef())
+	}
+	if exp := "* [FEATURE] Add foo #1\n* [BUGFIX] Fix bar. #2"; pr.GetBody() != exp {
+		t.Fatalf("expected body %q, got %q", exp, pr.GetBody())
+	}
+	if pr := gh.pulls[2]; pr.GetHead().GetRef() != "bump-v1.1.1" || pr.GetBase().GetRef() != "release-1.1" {
+		t.Fatalf("unexpected pull request from %s into %s", pr.GetHead().GetRef(), pr.GetBase().GetRef())
+	}
+	exp := github.ReviewersRequest{Reviewers: []string{"alice"}, TeamReviewers: []string{"release-shepherds"}}
+	for _, number := range []int{1, 2} {
+		if !reflect.DeepEqual(gh.reviewers[number], exp) {
+			t.Fatalf("expected reviewers %+v of #%d, got %+v", exp, number, gh.reviewers[number])
+		}
+	}
+}
+
+func TestInPaths(t *testing.T) {
+	for _, tc := range []struct {
+		file  string
+		paths []string
+		exp   bool
+	}{
+		{file: "collectors/foo/foo.go", paths: []string{"collectors/foo"}, exp: true},
+		{file: "collectors/foo/foo.go", paths: []string{"./collectors/foo/"}, exp: true},
+		{file: "collectors/foo", paths: []string{"collectors/foo"}, exp: true},
+		{file: "collectors/foobar/foo.go", paths: []string{"collectors/foo"}, exp: false},
+		{file: "go.mod", paths: []string{"collectors/foo", "go.mod"}, exp: true},
+		{file: "README.md", paths: []string{"collectors/foo"}, exp: false},
+		{file: "README.md", paths: []string{"."}, exp: true},
+	} {
+		if got := inPaths(tc.file, tc.paths); got != tc.exp {
+			t.Errorf("%s in %q: expected %t, got %t", tc.file, tc.paths, tc.exp, got)
+		}
+	}
+}
+
+func TestBumpComponent(t *testing.T) {
+	// The fake git lists the commits since the tag of the component, and
+	// the files changed by each of them.
+	fakeCommand(t, "git", `case "$*" in
+"log --reverse --format=%H%x1f%an%x1f%s%x1f%b%x1e collectors/foo/v1.0.0..HEAD")
+  printf 'a\037alice\037feat: add foo (#1)\037\036\n'
+  printf 'b\037bob\037fix: fix bar (#2)\037\036\n'
+  printf 'c\037bob\037fix: fix foo (#3)\037\036\n' ;;
+"diff --name-only a^1 a") printf 'collectors/foo/foo.go\ncollectors/foo/foo_test.go\n' ;;
+"diff --name-only b^1 b") printf 'collectors/bar/bar.go\n' ;;
+"diff --name-only c^1 c") printf 'README.md\ncollectors/foo/foo.go\n' ;;
+*) exit 1 ;;
+esac
+`)
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer func(c *Config, info repository.Info) { config, projInfo = c, info }(config, projInfo)
+	config = NewConfig()
+	projInfo = repository.Info{Owner: "owner", Name: "repo", Version: "2.0.0"}
+
+	component := filepath.Join("collectors", "foo")
+	if err := os.MkdirAll(component, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(component, "VERSION"), []byte("1.0.0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	o := bumpOptions{level: bumpMinor, source: bumpSourceCommits, changelog: "CHANGELOG.md", fragments: changelog.FragmentsDir, date: "2026-02-01", component: component}
+	if err := bump(context.Background(), nil, o); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(component, "CHANGELOG.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "## 1.1.0 / 2026-02-01\n\n* [FEATURE] Add foo #1\n* [BUGFIX] Fix foo #3\n"
+	if string(b) != exp {
+		t.Fatalf("expected changelog:\n%s\ngot:\n%s", exp, b)
+	}
+	if b, err := os.ReadFile(filepath.Join(component, "VERSION")); err != nil || string(b) != "1.1.0\n" {
+		t.Fatalf("expected VERSION 1.1.0, got %q (%v)", b, err)
+	}
+	for _, file := range []string{"CHANGELOG.md", "VERSION"} {
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Fatalf("expected no %s in the project directory, got %v", file, err)
+		}
+	}
+}
+
+func TestBumpDependencies(t *testing.T) {
+	fakeCommand(t, "git", `printf 'a\037alice\037Add foo (#1)\037\036\n'
+printf 'b\037dependabot[bot]\037Bump golang.org/x/net from 0.1.0 to 0.2.0 (#2)\037\036\n'
+printf 'c\037bob\037Update prometheus/common (#3)\037\036\n'
+printf 'd\037renovate[bot]\037Update Go (#4)\037\036\n'
+`)
+	gh, client := newFakeGitHub(t)
+	for number, pr := range map[int]struct {
+		title, author string
+		labels        []string
+	}{
+		1: {title: "Add foo", author: "alice", labels: []string{"kind/feature"}},
+		2: {title: "Bump golang.org/x/net from 0.1.0 to 0.2.0", author: "dependabot[bot]", labels: []string{"kind/enhancement"}},
+		3: {title: "Update prometheus/common", author: "bob", labels: []string{"dependencies"}},
+		4: {title: "Update Go", author: "renovate[bot]", labels: []string{"changelog/skip"}},
+	} {
+		gh.pulls[number] = &github.PullRequest{Number: github.Int(number), Title: github.String(pr.title), User: &github.User{Login: github.String(pr.author)}}
+		for _, label := range pr.labels {
+			gh.pulls[number].Labels = append(gh.pulls[number].Labels, &github.Label{Name: github.String(label)})
+		}
+	}
+
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer func(c *Config, info repository.Info) { config, projInfo = c, info }(config, projInfo)
+	config = NewConfig()
+	projInfo = repository.Info{Owner: "owner", Name: "repo", Version: "1.0.0"}
+
+	o := bumpOptions{level: bumpMinor, changelog: "CHANGELOG.md", fragments: changelog.FragmentsDir, date: "2026-02-01"}
+	if err := bump(context.Background(), client, o); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile("CHANGELOG.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `## 1.1.0 / 2026-02-01
+
+* [FEATURE] Add foo #1
+
+<details>
+<summary>Dependencies</summary>
+
+* Bump golang.org/x/net from 0.1.0 to 0.2.0 #2
+* Update prometheus/common #3
+
+</details>
+`
+	if string(b) != exp {
+		t.Fatalf("expected changelog:\n%s\ngot:\n%s", exp, b)
+	}
+
+	// The releases of dependency updates only are bumped too, with the
+	// dependency updates of the configured author.
+	for _, number := range []int{1, 2} {
+		gh.pulls[number].Labels = []*github.Label{{Name: github.String("changelog/skip")}}
+	}
+	config.Bump.Dependencies.Authors = []string{"bob"}
+	config.Bump.Dependencies.Label = "deps"
+	projInfo.Version = "1.1.0"
+	o.level = bumpPatch
+	if err := bump(context.Background(), client, o); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := readChangelogEntry("CHANGELOG.md", "1.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "<details>\n<summary>Dependencies</summary>\n\n* Update prometheus/common #3\n\n</details>"; strings.TrimSpace(entry.Text) != exp {
+		t.Fatalf("expected entry %q, got %q", exp, entry.Text)
+	}
+}
+
+func TestBumpFinalize(t *testing.T) {
+	fakeCommand(t, "git", `[ "$*" = "log --reverse --format=%H%x1f%an%x1f%s%x1f%b%x1e v1.1.0-rc.1..HEAD" ] || exit 1
+printf 'a\037alice\037fix: fix baz (#4)\037\036\n'
+`)
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer func(c *Config, info repository.Info) { config, projInfo = c, info }(config, projInfo)
+	config = NewConfig()
+	projInfo = repository.Info{Owner: "owner", Name: "repo", Version: "1.1.0-rc.1"}
+
+	content := `## 1.1.0-rc.1 / 2026-01-20
+
+* [BUGFIX] Fix bar. #3
+
+## 1.1.0-rc.0 / 2026-01-10
+
+* [FEATURE] Add foo. #1
+* [BUGFIX] Fix foo. #2
+
+## 1.0.0 / 2026-01-01
+
+* [FEATURE] Initial release.
+`
+	if err := os.WriteFile("CHANGELOG.md", []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	o := bumpOptions{level: bumpMinor, source: bumpSourceCommits, changelog: "CHANGELOG.md", fragments: changelog.FragmentsDir, date: "2026-02-01", finalize: true}
+	if err := bump(context.Background(), nil, o); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile("CHANGELOG.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `## 1.1.0 / 2026-02-01
+
+* [FEATURE] Add foo. #1
+* [BUGFIX] Fix foo. #2
+* [BUGFIX] Fix bar. #3
+* [BUGFIX] Fix baz #4
+
+## 1.0.0 / 2026-01-01
+
+* [FEATURE] Initial release.
+`
+	if string(b) != exp {
+		t.Fatalf("expected changelog:\n%s\ngot:\n%s", exp, b)
+	}
+	if b, err := os.ReadFile("VERSION"); err != nil || string(b) != "1.1.0\n" {
+		t.Fatalf("expected VERSION 1.1.0, got %q (%v)", b, err)
+	}
+
+	// The final versions can't be finalized.
+	projInfo.Version = "1.1.0"
+	if err := bump(context.Background(), nil, o); err == nil {
+		t.Fatal("expected error but got nil")
+	}
+}
+
+func TestBumpOffline(t *testing.T) {
+	fakeCommand(t, "git", `printf 'a\037alice\037Merge pull request #1 from bob/foo\037Add foo\n\nChangelog-Kind: feature\n\036\n'
+printf 'b\037bob\037Fix bar (#2)\037* Fix bar\n\nchangelog-kind: ENHANCEMENT/BUGFIX\n\036\n'
+printf 'c\037dependabot[bot]\037Bump golang.org/x/net from 0.1.0 to 0.2.0 (#3)\037\036\n'
+printf 'd\037carol\037Refactor (#4)\037Changelog-Kind: BUGFIX\nChangelog-Skip: true\n\036\n'
+printf 'e\037carol\037Update the README (#5)\037\036\n'
+printf 'f\037carol\037Update the CI configuration\037Changelog-Kind: BUGFIX\n\036\n'
+`)
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer func(c *Config, info repository.Info) { config, projInfo = c, info }(config, projInfo)
+	config = NewConfig()
+	projInfo = repository.Info{Owner: "owner", Name: "repo", Version: "1.0.0"}
+
+	// There is no GitHub client to access.
+	o := bumpOptions{level: bumpMinor, changelog: "CHANGELOG.md", fragments: changelog.FragmentsDir, date: "2026-02-01", offline: true}
+	if err := bump(context.Background(), nil, o); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile("CHANGELOG.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `## 1.1.0 / 2026-02-01
+
+* [FEATURE] Add foo #1
+* [ENHANCEMENT/BUGFIX] Fix bar #2
+
+<details>
+<summary>Dependencies</summary>
+
+* Bump golang.org/x/net from 0.1.0 to 0.2.0 #3
+
+</details>
+`
+	if string(b) != exp {
+		t.Fatalf("expected changelog:\n%s\ngot:\n%s", exp, b)
+	}
+
+	o.push = true
+	if err := bump(context.Background(), nil, o); err == nil {
+		t.Fatal("expected error for --push with --offline, got none")
+	}
+}
+
+func TestOfflineChangesInvalidKind(t *testing.T) {
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+
+	if _, err := offlineChanges([]gitCommit{{Subject: "Fix bar (#2)", Body: "Changelog-Kind: BUG"}}); err == nil {
+		t.Fatal("expected error but got nil")
+	}
+}
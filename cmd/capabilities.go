@@ -0,0 +1,81 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	installNotesFilename = "INSTALL_NOTES.txt"
+)
+
+// capabilitiesPattern matches a capability set as understood by setcap(8),
+// e.g. "cap_net_raw+ep" or "cap_net_raw,cap_net_admin=eip".
+var capabilitiesPattern = regexp.MustCompile(`^cap_[a-z_]+(,cap_[a-z_]+)*[=+-][eip]+$`)
+
+// binariesWithCapabilities returns the binaries requiring file capabilities.
+func binariesWithCapabilities(binaries []Binary) ([]Binary, error) {
+	var caps []Binary
+	for _, binary := range binaries {
+		if binary.Capabilities == "" {
+			continue
+		}
+		if !capabilitiesPattern.MatchString(binary.Capabilities) {
+			return nil, fmt.Errorf("invalid capabilities %q for binary %s", binary.Capabilities, binary.Name)
+		}
+		caps = append(caps, binary)
+	}
+	return caps, nil
+}
+
+// capabilitiesScript returns the shell commands setting the file capabilities
+// of the binaries installed in dir. It is used by newPackage for the
+// post-install scripts of the deb, rpm and archlinux packages, and for the
+// install notes of tarballs.
+func capabilitiesScript(binaries []Binary, dir string) string {
+	var b strings.Builder
+	for _, binary := range binaries {
+		fmt.Fprintf(&b, "setcap %s %s\n", binary.Capabilities, path.Join(dir, binary.Name))
+	}
+	return b.String()
+}
+
+// installNotes returns the content of the install notes shipped in linux
+// tarballs, or an empty string if no binary requires file capabilities.
+func installNotes(binaries []Binary) (string, error) {
+	caps, err := binariesWithCapabilities(binaries)
+	if err != nil || len(caps) == 0 {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(`Some binaries require file capabilities to work as expected when they
+don't run as root. Set them as root after extracting the archive:
+
+`)
+	for _, line := range strings.Split(strings.TrimSpace(capabilitiesScript(caps, ".")), "\n") {
+		fmt.Fprintf(&b, "    %s\n", line)
+	}
+	b.WriteString(`
+Capabilities are stored in extended attributes, they are lost when the
+binaries are copied or moved without preserving them (e.g. "cp" without
+"--preserve=xattr").
+`)
+	return b.String(), nil
+}
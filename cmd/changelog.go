@@ -0,0 +1,557 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus/promu/pkg/changelog"
+)
+
+var (
+	changelogcmd = app.Command("changelog", "Manage the changelog")
+
+	changelogRendercmd  = changelogcmd.Command("render", "Compile the changelog fragments into a new CHANGELOG.md entry")
+	changelogRenderPath = changelogRendercmd.Flag("location", "Path to CHANGELOG.md").
+				Default("CHANGELOG.md").String()
+	changelogRenderFragments = changelogRendercmd.Flag("fragments", "Directory of the changelog fragments").
+					Default(changelog.FragmentsDir).String()
+	changelogRenderVersion = changelogRendercmd.Flag("version", "Version of the entry (defaults to the current version)").
+				Default("").String()
+	changelogRenderDate = changelogRendercmd.Flag("date", "Date of the entry in YYYY-MM-DD format (defaults to today)").
+				Default("").String()
+	changelogRenderKeep = changelogRendercmd.Flag("keep", "Keep the fragment files after rendering").Bool()
+
+	changelogAddcmd       = changelogcmd.Command("add", "Write the changelog fragment of a change, compiled into CHANGELOG.md by changelog render")
+	changelogAddFragments = changelogAddcmd.Flag("fragments", "Directory of the changelog fragments").
+				Default(changelog.FragmentsDir).String()
+	changelogAddKind = changelogAddcmd.Flag("kind", "Slash-separated kinds of the change").
+				Required().String()
+	changelogAddPR = changelogAddcmd.Flag("pr", "Number of the pull request of the change, naming the fragment file").
+			Int()
+	changelogAddDescription = changelogAddcmd.Flag("description", "Description of the change (defaults to the title of the pull request)").
+				Default("").String()
+
+	changelogBackportcmd  = changelogcmd.Command("backport", "Add the change of a backported pull request to the CHANGELOG.md entry of a patch release")
+	changelogBackportPath = changelogBackportcmd.Flag("location", "Path to CHANGELOG.md").
+				Default("CHANGELOG.md").String()
+	changelogBackportVersion = changelogBackportcmd.Flag("version", "Version of the patch release").
+					Required().String()
+	changelogBackportPR = changelogBackportcmd.Flag("pr", "Number of the backported pull request").
+				Required().Int()
+	changelogBackportKind = changelogBackportcmd.Flag("kind", "Slash-separated kinds of the change").
+				Default("BUGFIX").String()
+	changelogBackportDescription = changelogBackportcmd.Flag("description", "Description of the change (defaults to the title of the pull request)").
+					Default("").String()
+	changelogBackportDate = changelogBackportcmd.Flag("date", "Date of the entry in YYYY-MM-DD format if it needs to be created (defaults to today)").
+				Default("").String()
+
+	changelogCherryPickcmd  = changelogcmd.Command("cherry-pick", "Add a change to the CHANGELOG.md entry of a release of a maintenance branch, ordered by kinds")
+	changelogCherryPickPath = changelogCherryPickcmd.Flag("location", "Path to CHANGELOG.md").
+				Default("CHANGELOG.md").String()
+	changelogCherryPickPR = changelogCherryPickcmd.Flag("from-pr", "Number of the pull request whose title is the change").
+				Int()
+	changelogCherryPickKind = changelogCherryPickcmd.Flag("kind", "Slash-separated kinds of the change if it has none").
+				Default("BUGFIX").String()
+	changelogCherryPickDate = changelogCherryPickcmd.Flag("date", "Date of the entry in YYYY-MM-DD format if it needs to be created (defaults to today)").
+				Default("").String()
+	changelogCherryPickVersion = changelogCherryPickcmd.Arg("version", "Version of the release").
+					Required().String()
+	changelogCherryPickChange = changelogCherryPickcmd.Arg("change", `Change line, e.g. "[BUGFIX] Fix something. #1234"`).
+					String()
+
+	changelogExportcmd  = changelogcmd.Command("export", "Print the entries of CHANGELOG.md as structured data")
+	changelogExportPath = changelogExportcmd.Flag("location", "Path to CHANGELOG.md").
+				Default("CHANGELOG.md").String()
+	changelogExportFormat = changelogExportcmd.Flag("format", "Output format").
+				Default("json").Enum("json", "yaml")
+)
+
+// exportedEntry is a changelog entry printed by changelog export.
+type exportedEntry struct {
+	Version string           `json:"version" yaml:"version"`
+	Date    string           `json:"date" yaml:"date"`
+	Changes []exportedChange `json:"changes" yaml:"changes"`
+	Text    string           `json:"text" yaml:"text"`
+}
+
+// exportedChange is a change of an entry printed by changelog export.
+type exportedChange struct {
+	Kinds      []string `json:"kinds" yaml:"kinds"`
+	Text       string   `json:"text" yaml:"text"`
+	References []int    `json:"references,omitempty" yaml:"references,omitempty"`
+	Authors    []string `json:"authors,omitempty" yaml:"authors,omitempty"`
+}
+
+func runChangelogRender(path, dir, version, date string, keep bool) error {
+	if version == "" {
+		version = projInfo.Version
+	}
+	d, err := changelogDate(date)
+	if err != nil {
+		return err
+	}
+
+	content, entry, fragments, err := compileChangelogEntry(path, dir, version, d, nil)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return err
+	}
+	fmt.Printf(" >   %s: added %s with %d changes\n", path, entry.Name(), len(entry.Changes))
+
+	if keep {
+		return nil
+	}
+	return removeFragments(fragments)
+}
+
+// compileChangelogEntry returns the content of the changelog at path with
+// the new entry of the version, the entry and the fragments of dir compiled
+// into it. The entry lists the unreleased changes of the changelog, then the
+// changes of the fragments and the given ones, except those of a pull request
+// already listed.
+func compileChangelogEntry(path, dir, version string, d time.Time, extra []changelog.Fragment) ([]byte, *changelog.Entry, []changelog.Fragment, error) {
+	draft, err := draftChangelogEntry(path, dir, extra)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(draft.changes) == 0 {
+		return nil, nil, nil, draft.errEmpty(dir)
+	}
+	entry, err := draft.entry(version, d)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return draft.insert(entry), entry, draft.fragments, nil
+}
+
+// changelogDraft holds the changes of the next entry of a changelog, whose
+// version may depend on them.
+type changelogDraft struct {
+	path    string
+	content []byte
+	format  changelog.Format
+	order   changelog.KindOrder
+	changes changelog.Changes
+	// fragments are the fragments whose changes are in the entry.
+	fragments []changelog.Fragment
+}
+
+// draftChangelogEntry returns the draft of the entry of compileChangelogEntry.
+func draftChangelogEntry(path, dir string, extra []changelog.Fragment) (*changelogDraft, error) {
+	format, err := changelogFormat()
+	if err != nil {
+		return nil, err
+	}
+	order, err := changelogKindOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	fragments, err := changelog.ReadFragments(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid changelog fragment: %w", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	// The unreleased changes are folded into the entry, before the changes
+	// of the fragments with the same kinds.
+	unreleased, err := format.ReadUnreleased(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("invalid unreleased changes: %w", err)
+	}
+	var changes changelog.Changes
+	if unreleased != nil {
+		changes = unreleased.Changes
+	}
+	changes = append(changes, format.NewEntry("", time.Time{}, fragments, order).Changes...)
+	listed := map[int]bool{}
+	for _, c := range changes {
+		for _, ref := range c.References {
+			listed[ref] = true
+		}
+	}
+	for _, c := range format.NewEntry("", time.Time{}, extra, order).Changes {
+		if len(c.References) == 0 || !listed[c.References[0]] {
+			changes = append(changes, c)
+		}
+	}
+	return &changelogDraft{
+		path:      path,
+		content:   content,
+		format:    format,
+		order:     order,
+		changes:   changes,
+		fragments: fragments,
+	}, nil
+}
+
+// errEmpty returns the error of a draft without changes compiled from the
+// fragments of dir.
+func (dr *changelogDraft) errEmpty(dir string) error {
+	return fmt.Errorf("no changelog fragment found in %s and no unreleased change in %s", dir, dr.path)
+}
+
+// entry returns the entry of the version listing the changes.
+func (dr *changelogDraft) entry(version string, d time.Time) (*changelog.Entry, error) {
+	if _, err := dr.format.ReadEntry(bytes.NewReader(dr.content), version); err == nil {
+		return nil, fmt.Errorf("%s already contains an entry for version %s", dr.path, version)
+	}
+	return dr.format.NewEntryFromChanges(version, d, dr.changes, dr.order), nil
+}
+
+// insert returns the content of the changelog with the entry, replacing the
+// unreleased changes.
+func (dr *changelogDraft) insert(entry *changelog.Entry) []byte {
+	return changelog.InsertEntry(clearUnreleased(dr.content), entry)
+}
+
+// removeFragments removes the files of the fragments compiled into the
+// changelog.
+func removeFragments(fragments []changelog.Fragment) error {
+	for _, f := range fragments {
+		if err := os.Remove(f.File); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runChangelogAdd(dir, kind string, pr int, description string) error {
+	if description == "" {
+		if pr <= 0 {
+			return errors.New("--description is required without --pr")
+		}
+		var err error
+		description, err = pullRequestTitle(pr)
+		if err != nil {
+			return fmt.Errorf("failed to get the title of pull request #%d: %w", pr, err)
+		}
+	}
+	f := changelog.Fragment{Kind: kind, Description: description, PR: pr}
+	path, err := changelog.WriteFragment(dir, f)
+	if err != nil {
+		return fmt.Errorf("invalid change: %w", err)
+	}
+	fmt.Printf(" >   %s: added %q\n", path, f.Change().Text)
+	return nil
+}
+
+func runChangelogBackport(path, version string, pr int, kind, description, date string) error {
+	if description == "" {
+		var err error
+		description, err = pullRequestTitle(pr)
+		if err != nil {
+			return fmt.Errorf("failed to get the title of pull request #%d: %w", pr, err)
+		}
+	}
+	return backportChange(path, version, changelog.Fragment{Kind: kind, Description: description, PR: pr}, date)
+}
+
+func runChangelogCherryPick(path, version, text string, pr int, kind, date string) error {
+	switch {
+	case text != "" && pr > 0:
+		return errors.New("the change and --from-pr are mutually exclusive")
+	case pr > 0:
+		return runChangelogBackport(path, version, pr, kind, "", date)
+	case text == "":
+		return errors.New("either the change or --from-pr is required")
+	}
+	return backportChange(path, version, parseChangeLine(text, kind), date)
+}
+
+// parseChangeLine returns the fragment of a change line such as
+// "* [BUGFIX] Fix something. #1234", whose kinds default to the given ones.
+func parseChangeLine(text, kind string) changelog.Fragment {
+	text = strings.TrimSpace(text)
+	for _, bullet := range []string{"* ", "- "} {
+		text = strings.TrimPrefix(text, bullet)
+	}
+	if m := reChangeLine.FindStringSubmatch(text); m != nil {
+		kind, text = m[1], m[2]
+	}
+	return changelog.Fragment{Kind: kind, Description: text}
+}
+
+// backportChange adds the change of the fragment to the changelog entry of
+// the patch release, created if needed, after the existing changes with the
+// same kinds.
+func backportChange(path, version string, f changelog.Fragment, date string) error {
+	if format, err := changelogFormat(); err != nil {
+		return err
+	} else if format != changelog.Prometheus {
+		return fmt.Errorf("backports aren't supported by the %s changelog format", format)
+	}
+	order, err := changelogKindOrder()
+	if err != nil {
+		return err
+	}
+	d, err := changelogDate(date)
+	if err != nil {
+		return err
+	}
+	if err := f.Validate(); err != nil {
+		return fmt.Errorf("invalid change: %w", err)
+	}
+	change := f.Change()
+
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	entry, err := changelog.ReadEntry(bytes.NewReader(content), version)
+	if err != nil {
+		// Create the entry of the patch release.
+		entry = changelog.NewEntry(version, d, []changelog.Fragment{f}, order)
+		content = changelog.InsertEntry(content, entry)
+	} else {
+		for _, pr := range change.References {
+			if regexp.MustCompile(fmt.Sprintf(`#%d\b`, pr)).MatchString(entry.Text) {
+				return fmt.Errorf("%s already references pull request #%d", entry.Name(), pr)
+			}
+		}
+		for _, c := range entry.Changes {
+			if c.Text == change.Text {
+				return fmt.Errorf("%s already lists %q", entry.Name(), change.Text)
+			}
+		}
+		content, err = addChangelogChange(content, version, change, order)
+		if err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return err
+	}
+	fmt.Printf(" >   %s: added %q to %s\n", path, change.Text, entry.Name())
+	return nil
+}
+
+// changelogFormat returns the configured format of CHANGELOG.md.
+func changelogFormat() (changelog.Format, error) {
+	return changelog.ParseFormat(config.Changelog.Format)
+}
+
+// changelogKindOrder returns the configured order of the changes by kinds.
+func changelogKindOrder() (changelog.KindOrder, error) {
+	return changelog.ParseKindOrder(config.Changelog.KindOrder)
+}
+
+// runChangelogExport writes all the entries of the changelog to w in the
+// output format, json or yaml.
+func runChangelogExport(w io.Writer, path, output string) error {
+	format, err := changelogFormat()
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	entries, err := format.ReadEntries(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	exported := make([]exportedEntry, 0, len(entries))
+	for _, entry := range entries {
+		e := exportedEntry{
+			Version: entry.Version,
+			Date:    entry.Date.Format("2006-01-02"),
+			Changes: make([]exportedChange, 0, len(entry.Changes)),
+			Text:    entry.Text,
+		}
+		for _, c := range entry.Changes {
+			kinds := make([]string, 0, len(c.Kinds))
+			for _, k := range c.Kinds {
+				kinds = append(kinds, k.String())
+			}
+			e.Changes = append(e.Changes, exportedChange{
+				Kinds:      kinds,
+				Text:       c.Text,
+				References: c.References,
+				Authors:    c.Authors,
+			})
+		}
+		exported = append(exported, e)
+	}
+
+	switch output {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(exported)
+	case "yaml":
+		b, err := yaml.Marshal(exported)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+	return fmt.Errorf("unknown export format %q", output)
+}
+
+// readChangelogEntry reads the entry of the version from the changelog file
+// in the configured format, checking that the changes of the Prometheus
+// format are ordered by kinds.
+func readChangelogEntry(path, version string) (*changelog.Entry, error) {
+	format, err := changelogFormat()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entry, err := format.ReadEntry(f, version)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := checkChangelogOrder(format, entry); err != nil {
+		return nil, fmt.Errorf("invalid changelog entry: %w", err)
+	}
+	return entry, nil
+}
+
+// checkChangelogOrder returns an error if the changes of the entry in the
+// Prometheus format aren't ordered by kinds. Keep a Changelog entries group
+// them in sections instead.
+func checkChangelogOrder(format changelog.Format, entry *changelog.Entry) error {
+	if format != changelog.Prometheus {
+		return nil
+	}
+	order, err := changelogKindOrder()
+	if err != nil {
+		return err
+	}
+	return entry.Changes.Sorted(order)
+}
+
+// changelogDate parses the date of a changelog entry, defaulting to today.
+func changelogDate(date string) (time.Time, error) {
+	if date == "" {
+		return time.Now().UTC(), nil
+	}
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date: %w", err)
+	}
+	return d, nil
+}
+
+// pullRequestTitle returns the title of the pull request of the project on
+// GitHub. GITHUB_TOKEN is used if defined.
+func pullRequestTitle(number int) (string, error) {
+	ctx := context.Background()
+	pr, _, err := githubClient(ctx).PullRequests.Get(ctx, projInfo.Owner, projInfo.Name, number)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(pr.GetTitle()), nil
+}
+
+// addChangelogChange returns the changelog content with the change added to
+// the entry of the version, after the existing changes with the same kinds,
+// in the order of the kinds.
+func addChangelogChange(content []byte, version string, change changelog.Change, order changelog.KindOrder) ([]byte, error) {
+	reHeader := regexp.MustCompile(fmt.Sprintf(`^#{1,2} %s / `, regexp.QuoteMeta(version)))
+	lines := strings.SplitAfter(string(content), "\n")
+
+	header := -1
+	for i, line := range lines {
+		if reHeader.MatchString(line) {
+			header = i
+			break
+		}
+	}
+	if header < 0 {
+		return nil, fmt.Errorf("no changelog entry for version %s", version)
+	}
+
+	// Insert the change before the first change which must come after it or,
+	// failing that, after the last line of the entry.
+	pos := header + 1
+	for i := header + 1; i < len(lines) && !strings.HasPrefix(lines[i], "## "); i++ {
+		line := strings.TrimRight(lines[i], "\n")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if m := reChangeKinds.FindStringSubmatch(line); m != nil {
+			existing := changelog.NewChange(line, changelog.ParseKinds(m[1]))
+			if (changelog.Changes{change, existing}).Sorted(order) == nil && (changelog.Changes{existing, change}).Sorted(order) != nil {
+				pos = i
+				break
+			}
+		}
+		pos = i + 1
+	}
+	if pos == header+1 {
+		// Empty entry.
+		lines = append(lines[:pos], append([]string{"\n"}, lines[pos:]...)...)
+		pos++
+	}
+	if !strings.HasSuffix(lines[pos-1], "\n") {
+		lines[pos-1] += "\n"
+	}
+
+	lines = append(lines[:pos], append([]string{change.Text + "\n"}, lines[pos:]...)...)
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// reChangeLine matches a change line without its leading "* ", its first
+// submatch being its kinds and the second its description.
+var reChangeLine = regexp.MustCompile(`^\[([^\]]+)\]\s*(.*)$`)
+
+// reChangeKinds matches the kinds of a change line.
+var reChangeKinds = regexp.MustCompile(`^\* \[([^\]]+)\]`)
+
+// clearUnreleased returns the changelog content with the changes of the
+// unreleased section removed, keeping its header for the next changes.
+func clearUnreleased(content []byte) []byte {
+	lines := strings.SplitAfter(string(content), "\n")
+	for i, line := range lines {
+		if !changelog.IsUnreleased(strings.TrimRight(line, "\n")) {
+			continue
+		}
+		j := i + 1
+		for ; j < len(lines) && !strings.HasPrefix(lines[j], "## "); j++ {
+		}
+		if !strings.HasSuffix(line, "\n") {
+			lines[i] += "\n"
+		}
+		return []byte(strings.Join(append(lines[:i+1], lines[j:]...), ""))
+	}
+	return content
+}
@@ -0,0 +1,141 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v25/github"
+)
+
+var (
+	changelogDiffcmd  = changelogcmd.Command("diff", "Compare the CHANGELOG.md entry of a version with the body of its GitHub release")
+	changelogDiffPath = changelogDiffcmd.Flag("location", "Path to CHANGELOG.md").
+				Default("CHANGELOG.md").String()
+	changelogDiffUpdate = changelogDiffcmd.Flag("update", "Update the body of the release to the changelog entry instead of failing").
+				Bool()
+	changelogDiffVersion = changelogDiffcmd.Arg("version", "Version of the release, the version of the project by default").
+				String()
+)
+
+func runChangelogDiff(path, version string, update bool) error {
+	if config.Release.NotesTemplate != "" {
+		return errors.New("changelog diff doesn't support the release notes rendered from release.notes_template")
+	}
+	if version == "" {
+		version = projInfo.Version
+	}
+	version = strings.TrimPrefix(version, "v")
+	entry, err := readChangelogEntry(path, version)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := releaseContext()
+	defer cancel()
+	targets, err := releaseTargets(ctx)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, t := range targets {
+		if err := diffReleaseBody(ctx, t.client, t.owner, t.repo, "v"+version, entry.Text, update); err != nil {
+			if len(targets) > 1 {
+				err = fmt.Errorf("%s: %w", t, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// diffReleaseBody compares the body of the release of the tag with the
+// changelog text, printing their differences. It returns an error if they
+// differ, unless the body is updated to the text.
+func diffReleaseBody(ctx context.Context, client *github.Client, owner, repo, tag, text string, update bool) error {
+	release, err := findRelease(ctx, client, owner, repo, tag)
+	if err != nil {
+		return err
+	}
+	if release == nil {
+		return fmt.Errorf("no release found for %s", tag)
+	}
+
+	// GitHub stores the bodies edited in the browser with CRLF line endings.
+	body := strings.TrimSpace(strings.ReplaceAll(release.GetBody(), "\r\n", "\n"))
+	text = strings.TrimSpace(text)
+	if body == text {
+		fmt.Println(" > release", tag, "is up to date with the changelog")
+		return nil
+	}
+	for _, line := range diffLines(strings.Split(text, "\n"), strings.Split(body, "\n")) {
+		fmt.Println(line)
+	}
+	if !update {
+		return fmt.Errorf("the body of release %s differs from the changelog", tag)
+	}
+
+	_, _, err = client.Repositories.EditRelease(ctx, owner, repo, release.GetID(), &github.RepositoryRelease{Body: &text})
+	if err != nil {
+		return fmt.Errorf("failed to update release %s: %w", tag, err)
+	}
+	fmt.Println(" > updated the body of release", tag)
+	return nil
+}
+
+// diffLines returns the lines of a and b prefixed by "-" if they are only in
+// a, "+" if they are only in b and " " otherwise, computed from their longest
+// common subsequence.
+func diffLines(a, b []string) []string {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and
+	// b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, " "+a[i])
+			i++
+			j++
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "-"+a[i])
+			i++
+		default:
+			lines = append(lines, "+"+b[j])
+			j++
+		}
+	}
+	return lines
+}
@@ -0,0 +1,563 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v25/github"
+
+	"github.com/prometheus/promu/pkg/changelog"
+)
+
+func TestAddChangelogChange(t *testing.T) {
+	in := `## 1.1.0 / 2024-02-01
+
+* [FEATURE] Some feature.
+
+## 1.0.1 / 2024-01-15
+
+* [CHANGE] Some change.
+* [BUGFIX] Some fix.
+* [BUGFIX] Another fix.
+
+## 1.0.0 / 2024-01-02
+
+`
+	for _, tc := range []struct {
+		version string
+		change  string
+		exp     string
+		err     bool
+	}{
+		{
+			version: "1.0.1",
+			change:  "* [ENHANCEMENT] Backported enhancement. #3",
+			exp: `## 1.1.0 / 2024-02-01
+
+* [FEATURE] Some feature.
+
+## 1.0.1 / 2024-01-15
+
+* [CHANGE] Some change.
+* [ENHANCEMENT] Backported enhancement. #3
+* [BUGFIX] Some fix.
+* [BUGFIX] Another fix.
+
+## 1.0.0 / 2024-01-02
+
+`,
+		},
+		{
+			version: "1.0.1",
+			change:  "* [BUGFIX] Backported fix. #3",
+			exp: `## 1.1.0 / 2024-02-01
+
+* [FEATURE] Some feature.
+
+## 1.0.1 / 2024-01-15
+
+* [CHANGE] Some change.
+* [BUGFIX] Some fix.
+* [BUGFIX] Another fix.
+* [BUGFIX] Backported fix. #3
+
+## 1.0.0 / 2024-01-02
+
+`,
+		},
+		{
+			version: "1.0.0",
+			change:  "* [BUGFIX] Backported fix. #3",
+			exp: `## 1.1.0 / 2024-02-01
+
+* [FEATURE] Some feature.
+
+## 1.0.1 / 2024-01-15
+
+* [CHANGE] Some change.
+* [BUGFIX] Some fix.
+* [BUGFIX] Another fix.
+
+## 1.0.0 / 2024-01-02
+
+* [BUGFIX] Backported fix. #3
+
+`,
+		},
+		{
+			version: "0.9.0",
+			change:  "* [BUGFIX] Backported fix. #3",
+			err:     true,
+		},
+	} {
+		change := changelog.Change{Text: tc.change, Kinds: changelog.ParseKinds(reChangeKinds.FindStringSubmatch(tc.change)[1])}
+		got, err := addChangelogChange([]byte(in), tc.version, change, nil)
+		if tc.err {
+			if err == nil {
+				t.Fatalf("%s: expected error, got none", tc.version)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tc.exp {
+			t.Fatalf("expected:\n%s\ngot:\n%s", tc.exp, got)
+		}
+	}
+}
+
+func TestChangelogRenderUnreleased(t *testing.T) {
+	defer func(c *Config) { config = c }(config)
+
+	for _, tc := range []struct {
+		name      string
+		format    string
+		in        string
+		fragments map[string]string
+		exp       string
+		err       bool
+	}{
+		{
+			name: "prometheus",
+			in: `# Changelog
+
+## main / unreleased
+
+* [BUGFIX] Unreleased fix.
+* [FEATURE] Unreleased feature.
+
+## 1.0.0 / 2024-01-02
+
+* [BUGFIX] Some fix.
+`,
+			fragments: map[string]string{"1.yml": "kind: FEATURE\ndescription: Fragment feature.\npr: 2\n"},
+			exp: `# Changelog
+
+## main / unreleased
+
+## 1.1.0 / 2024-02-01
+
+* [FEATURE] Unreleased feature.
+* [FEATURE] Fragment feature. #2
+* [BUGFIX] Unreleased fix.
+
+## 1.0.0 / 2024-01-02
+
+* [BUGFIX] Some fix.
+`,
+		},
+		{
+			name:   "keepachangelog without fragments",
+			format: "keepachangelog",
+			in: `# Changelog
+
+## [Unreleased]
+
+### Fixed
+
+- Unreleased fix.
+`,
+			exp: `# Changelog
+
+## [Unreleased]
+
+## [1.1.0] - 2024-02-01
+
+### Fixed
+
+- Unreleased fix.
+`,
+		},
+		{
+			name: "nothing to release",
+			in:   "## Unreleased\n\n## 1.0.0 / 2024-01-02\n",
+			err:  true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config = NewConfig()
+			config.Changelog.Format = tc.format
+
+			dir := t.TempDir()
+			path, fragments := filepath.Join(dir, "CHANGELOG.md"), filepath.Join(dir, changelog.FragmentsDir)
+			if err := os.MkdirAll(fragments, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(tc.in), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			for name, content := range tc.fragments {
+				if err := os.WriteFile(filepath.Join(fragments, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := runChangelogRender(path, fragments, "1.1.0", "2024-02-01", false)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tc.exp {
+				t.Fatalf("expected:\n%s\ngot:\n%s", tc.exp, b)
+			}
+			if err := runCheckChangelog(path, "1.1.0", fragments, false); err != nil {
+				t.Fatalf("expected a valid changelog, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckChangelogAll(t *testing.T) {
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+
+	for _, tc := range []struct {
+		name string
+		in   string
+		err  string
+	}{
+		{
+			name: "valid",
+			in: `## Unreleased
+
+## 1.1.0 / 2024-02-01
+
+* [FEATURE] Some feature.
+
+## 1.0.1 / 2024-02-01
+
+## 1.0.0 / 2024-01-02
+
+* [CHANGE] Some change.
+* [BUGFIX] Some fix.
+`,
+		},
+		{
+			name: "unordered versions",
+			in: `## 1.0.0 / 2024-02-01
+
+## 1.1.0 / 2024-02-01
+`,
+			err: "entry 1.1.0 / 2024-02-01 should be before 1.0.0 / 2024-02-01",
+		},
+		{
+			name: "unordered dates",
+			in: `## 1.1.0 / 2024-01-02
+
+## 1.0.0 / 2024-02-01
+`,
+			err: "entry 1.0.0 / 2024-02-01 is dated after 1.1.0 / 2024-01-02",
+		},
+		{
+			name: "duplicate versions",
+			in: `## 1.0.0 / 2024-02-01
+
+## 1.0.0 / 2024-02-01
+`,
+			err: "duplicate entry for version 1.0.0",
+		},
+		{
+			name: "unsorted changes",
+			in: `## 1.1.0 / 2024-02-01
+
+## 1.0.0 / 2024-01-02
+
+* [BUGFIX] Some fix.
+* [CHANGE] Some change.
+`,
+			err: "invalid changelog entry 1.0.0 / 2024-01-02",
+		},
+		{
+			name: "invalid version",
+			in: `## next / 2024-02-01
+`,
+			err: "invalid version of entry next / 2024-02-01",
+		},
+		{
+			name: "invalid header",
+			in: `## 1.0.0
+`,
+			err: `:1: invalid entry header "## 1.0.0"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+			if err := os.WriteFile(path, []byte(tc.in), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			err := runCheckChangelog(path, "", filepath.Join(t.TempDir(), "fragments"), true)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestChangelogExport(t *testing.T) {
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	err := os.WriteFile(path, []byte(`## Unreleased
+
+## 1.1.0 / 2024-02-01
+
+* [FEATURE] Some feature. #2 (@alice)
+* [BUGFIX] Some fix.
+
+## 1.0.0 / 2024-01-02
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		format string
+		exp    string
+	}{
+		{
+			format: "json",
+			exp: `[
+  {
+    "version": "1.1.0",
+    "date": "2024-02-01",
+    "changes": [
+      {
+        "kinds": [
+          "FEATURE"
+        ],
+        "text": "* [FEATURE] Some feature. #2 (@alice)",
+        "references": [
+          2
+        ],
+        "authors": [
+          "alice"
+        ]
+      },
+      {
+        "kinds": [
+          "BUGFIX"
+        ],
+        "text": "* [BUGFIX] Some fix."
+      }
+    ],
+    "text": "* [FEATURE] Some feature. #2 (@alice)\n* [BUGFIX] Some fix."
+  },
+  {
+    "version": "1.0.0",
+    "date": "2024-01-02",
+    "changes": [],
+    "text": ""
+  }
+]
+`,
+		},
+		{
+			format: "yaml",
+			exp: `- version: 1.1.0
+  date: "2024-02-01"
+  changes:
+  - kinds:
+    - FEATURE
+    text: '* [FEATURE] Some feature. #2 (@alice)'
+    references:
+    - 2
+    authors:
+    - alice
+  - kinds:
+    - BUGFIX
+    text: '* [BUGFIX] Some fix.'
+  text: |-
+    * [FEATURE] Some feature. #2 (@alice)
+    * [BUGFIX] Some fix.
+- version: 1.0.0
+  date: "2024-01-02"
+  changes: []
+  text: ""
+`,
+		},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := runChangelogExport(&b, path, tc.format); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tc.exp {
+				t.Fatalf("expected:\n%s\ngot:\n%s", tc.exp, b.String())
+			}
+		})
+	}
+}
+
+func TestDiffReleaseBody(t *testing.T) {
+	f, client := newFakeGitHub(t)
+	f.releases = []*github.RepositoryRelease{{
+		ID:      github.Int64(1),
+		TagName: github.String("v1.0.0"),
+		Body:    github.String("* [FEATURE] Foo.\r\n* [BUGFIX] Bar.\r\n"),
+	}}
+	ctx := context.Background()
+
+	if err := diffReleaseBody(ctx, client, "owner", "repo", "v1.0.0", "* [FEATURE] Foo.\n* [BUGFIX] Bar.", false); err != nil {
+		t.Fatalf("expected the same body, got %v", err)
+	}
+	if err := diffReleaseBody(ctx, client, "owner", "repo", "v1.1.0", "", false); err == nil {
+		t.Fatalf("expected error for a missing release, got none")
+	}
+
+	text := "* [FEATURE] Foo.\n* [BUGFIX] Baz."
+	if err := diffReleaseBody(ctx, client, "owner", "repo", "v1.0.0", text, false); err == nil {
+		t.Fatalf("expected error for a different body, got none")
+	}
+	if err := diffReleaseBody(ctx, client, "owner", "repo", "v1.0.0", text, true); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.releases[0].GetBody(); got != text {
+		t.Fatalf("expected updated body %q, got %q", text, got)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	a := []string{"a", "b", "c", "d"}
+	b := []string{"a", "c", "e", "d", "f"}
+	exp := []string{" a", "-b", " c", "+e", " d", "+f"}
+	if got := diffLines(a, b); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected %q, got %q", exp, got)
+	}
+}
+
+func TestChangelogCherryPick(t *testing.T) {
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+
+	in := `## 1.1.0 / 2024-02-01
+
+* [FEATURE] Some feature. #2
+
+## 1.0.1 / 2024-01-15
+
+* [CHANGE] Some change. #3
+* [BUGFIX] Some fix. #4
+
+## 1.0.0 / 2024-01-02
+`
+	for _, tc := range []struct {
+		name    string
+		version string
+		change  string
+		kind    string
+		exp     string
+		err     bool
+	}{
+		{
+			name:    "ordered",
+			version: "1.0.1",
+			change:  "* [ENHANCEMENT] Some enhancement. #5",
+			exp: `## 1.1.0 / 2024-02-01
+
+* [FEATURE] Some feature. #2
+
+## 1.0.1 / 2024-01-15
+
+* [CHANGE] Some change. #3
+* [ENHANCEMENT] Some enhancement. #5
+* [BUGFIX] Some fix. #4
+
+## 1.0.0 / 2024-01-02
+`,
+		},
+		{
+			name:    "default kind",
+			version: "1.0.1",
+			change:  "Another fix.",
+			kind:    "bugfix",
+			exp: `## 1.1.0 / 2024-02-01
+
+* [FEATURE] Some feature. #2
+
+## 1.0.1 / 2024-01-15
+
+* [CHANGE] Some change. #3
+* [BUGFIX] Some fix. #4
+* [BUGFIX] Another fix.
+
+## 1.0.0 / 2024-01-02
+`,
+		},
+		{
+			name:    "already referenced",
+			version: "1.0.1",
+			change:  "[BUGFIX] Same fix. #4",
+			err:     true,
+		},
+		{
+			name:    "unknown kind",
+			version: "1.0.1",
+			change:  "[IMPROVEMENT] Something.",
+			err:     true,
+		},
+		{
+			name:    "no change",
+			version: "1.0.1",
+			err:     true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+			if err := os.WriteFile(path, []byte(in), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			err := runChangelogCherryPick(path, tc.version, tc.change, 0, tc.kind, "2024-02-10")
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tc.exp {
+				t.Fatalf("expected:\n%s\ngot:\n%s", tc.exp, b)
+			}
+		})
+	}
+}
@@ -16,12 +16,22 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 
+	"github.com/Masterminds/semver/v3"
+
 	"github.com/prometheus/promu/pkg/changelog"
+	"github.com/prometheus/promu/util/pool"
+	"github.com/prometheus/promu/util/sh"
 )
 
 var (
@@ -34,6 +44,14 @@ var (
 				Default(".go").Strings()
 	headerLength = checkLicensescmd.Flag("length", "The number of lines to read from the head of the file").
 			Short('n').Default("10").Int()
+	checkLicExcludes = checkLicensescmd.Flag("exclude", "Glob of the paths to skip relative to the location, e.g. 'third_party' or '**/*.pb.go', may be used multiple times").
+				Strings()
+	checkLicGitignore = checkLicensescmd.Flag("gitignore", "Skip the files ignored by git").
+				Default("true").Bool()
+	checkLicSPDX = checkLicensescmd.Flag("spdx", "Require a SPDX-License-Identifier header matching the license expression instead of a copyright header").
+			Bool()
+	checkLicLicense = checkLicensescmd.Flag("license", "SPDX license expression of the files, package.license by default").
+			String()
 	checkLicLocation = checkLicensescmd.Arg("location", "Directory path to check licenses").
 				Default(".").Strings()
 
@@ -42,76 +60,213 @@ var (
 				Default("CHANGELOG.md").String()
 	checkChangelogVersion = checkChangelogcmd.Flag("version", "Version to check (defaults to the current version)").
 				Default("").String()
+	checkChangelogFragments = checkChangelogcmd.Flag("fragments", "Directory of the changelog fragments to validate").
+				Default(changelog.FragmentsDir).String()
+	checkChangelogAll = checkChangelogcmd.Flag("all", "Check all the entries instead of the one of the version").
+				Bool()
 )
 
-func runCheckLicenses(path string, n int, extensions []string) {
+// defaultLicenseExcludes are the directories of third-party code skipped by
+// check licenses, at any depth.
+var defaultLicenseExcludes = []string{"**/.git", "**/vendor", "**/third_party", "**/node_modules"}
+
+// licenseCheck configures the check of the license headers of the files.
+type licenseCheck struct {
+	// n is the number of lines of the header of the files.
+	n          int
+	extensions []string
+	// excludes are the globs of the skipped files and directories.
+	excludes []string
+	// spdx is the SPDX license expression required in the headers, a
+	// copyright header being required instead if empty.
+	spdx string
+}
+
+// spdxLicense returns the SPDX license expression required with --spdx, the
+// one of package.license by default, or an empty string without --spdx.
+func spdxLicense(spdx bool, license string) string {
+	if !spdx {
+		return ""
+	}
+	if license == "" {
+		license = config.Package.License
+	}
+	if license == "" {
+		fatal(errors.New("--spdx requires a license expression, set with --license or package.license"))
+	}
+	return license
+}
+
+// runCheckLicenses returns the files of the directory without valid license
+// header as check findings.
+func runCheckLicenses(path string, c licenseCheck, gitignore bool) error {
 	path = fmt.Sprintf("%s%c", filepath.Clean(path), filepath.Separator)
 
-	filesMissingHeaders, err := checkLicenses(path, n, extensions)
+	c.excludes = append(c.excludes, defaultLicenseExcludes...)
+	if gitignore {
+		ignored, err := gitIgnored(path)
+		if err != nil {
+			return fmt.Errorf("Failed to list the files ignored by git: %w", err)
+		}
+		c.excludes = append(c.excludes, ignored...)
+	}
+	violations, err := c.check(path)
 	if err != nil {
-		fatal(fmt.Errorf("Failed to check files for license header: %w", err))
+		return fmt.Errorf("Failed to check files for license header: %w", err)
 	}
-
-	for _, file := range filesMissingHeaders {
-		fmt.Println(file)
+	errs := make([]error, 0, len(violations))
+	for _, v := range violations {
+		errs = append(errs, v)
 	}
+	return errors.Join(errs...)
 }
 
-func checkLicenses(path string, n int, extensions []string) ([]string, error) {
-	var missingHeaders []string
-	walkFunc := func(filepath string, f os.FileInfo, err error) error {
+// check returns the files of the directory with one of the extensions whose
+// first lines have no valid license header. The files and directories
+// matching one of the exclude globs, relative to the directory, are skipped.
+// The files are checked concurrently.
+func (c licenseCheck) check(path string) ([]checkFinding, error) {
+	var files []string
+	err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if f.IsDir() {
-			return nil
+		rel, err := relativeSlashPath(path, file)
+		if err != nil {
+			return err
 		}
-
-		if strings.HasPrefix(filepath, "vendor/") {
+		excluded, err := matchAnyGlob(c.excludes, rel)
+		if err != nil {
+			return fmt.Errorf("invalid exclude glob: %w", err)
+		}
+		switch {
+		case excluded && d.IsDir() && rel != ".":
+			return filepath.SkipDir
+		case excluded || d.IsDir():
 			return nil
 		}
 
-		if !suffixInSlice(f.Name(), extensions) {
-			return nil
+		if suffixInSlice(d.Name(), c.extensions) {
+			files = append(files, file)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		file, err := os.Open(filepath)
-		if err != nil {
+	messages := make([]string, len(files))
+	workers := pool.New(context.Background(), runtime.NumCPU(), true)
+	for i, file := range files {
+		i, file := i, file
+		workers.Go(func(context.Context) error {
+			message, err := c.checkFile(file)
+			messages[i] = message
 			return err
-		}
+		})
+	}
+	if err := workers.Wait(); err != nil {
+		return nil, err
+	}
 
-		defer file.Close()
+	violations := []checkFinding{}
+	for i, message := range messages {
+		if message != "" {
+			violations = append(violations, checkFinding{File: files[i], Message: message})
+		}
+	}
+	return violations, nil
+}
 
-		pass := false
-		scanner := bufio.NewScanner(file)
-		for i := 0; i < n; i++ {
-			scanner.Scan()
+// checkFile returns why the file has no valid license header, or an empty
+// string if it has one.
+func (c licenseCheck) checkFile(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
 
-			if err = scanner.Err(); err != nil {
-				return err
-			}
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for i := 0; i < c.n && scanner.Scan(); i++ {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("%s: %w", file, err)
+	}
+	return c.checkHeader(lines), nil
+}
 
-			if stringContainedInSlice(strings.ToLower(scanner.Text()), validHeaderStrings) {
-				pass = true
+// checkHeader returns why the header lines of a file aren't a valid license
+// header, or an empty string if they are.
+func (c licenseCheck) checkHeader(lines []string) string {
+	if c.spdx == "" {
+		for _, line := range lines {
+			if stringContainedInSlice(strings.ToLower(line), validHeaderStrings) {
+				return ""
 			}
 		}
+		return "missing license header"
+	}
 
-		if !pass {
-			missingHeaders = append(missingHeaders, filepath)
+	for _, line := range lines {
+		_, expr, ok := strings.Cut(line, spdxIdentifierTag)
+		if !ok {
+			continue
+		}
+		// Remove the end of block comments.
+		expr = strings.TrimSpace(expr)
+		for _, end := range []string{"*/", "-->"} {
+			expr = strings.TrimSpace(strings.TrimSuffix(expr, end))
+		}
+		if !strings.EqualFold(strings.Join(strings.Fields(expr), " "), strings.Join(strings.Fields(c.spdx), " ")) {
+			return fmt.Sprintf("license %q doesn't match %q", expr, c.spdx)
 		}
+		return ""
+	}
+	return "missing " + spdxIdentifierTag + " header"
+}
 
-		return nil
+// spdxIdentifierTag precedes the SPDX license expression of a file.
+const spdxIdentifierTag = "SPDX-License-Identifier:"
+
+// relativeSlashPath returns the slash-separated path of target relative to
+// base.
+func relativeSlashPath(base, target string) (string, error) {
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return "", err
 	}
+	return filepath.ToSlash(rel), nil
+}
 
-	err := filepath.Walk(path, walkFunc)
+// gitIgnored returns the paths of the files and directories of the directory
+// ignored by git, relative to it. It returns none outside of a git
+// repository.
+func gitIgnored(dir string) ([]string, error) {
+	if err := exec.Command("git", "-C", dir, "rev-parse", "--is-inside-work-tree").Run(); err != nil {
+		return nil, nil
+	}
+	var out bytes.Buffer
+	err := sh.RunCommandWithOutput(&out, os.Stderr, "git", "-C", dir, "ls-files", "-z", "--others", "--ignored", "--exclude-standard", "--directory")
 	if err != nil {
 		return nil, err
 	}
-
-	return missingHeaders, nil
+	var ignored []string
+	for _, p := range strings.Split(out.String(), "\x00") {
+		if p = strings.TrimSuffix(p, "/"); p != "" {
+			// Escape the special characters of the globs.
+			ignored = append(ignored, globEscaper.Replace(p))
+		}
+	}
+	return ignored, nil
 }
 
+// globEscaper escapes the special characters of globs.
+var globEscaper = strings.NewReplacer(`*`, `\*`, `?`, `\?`, `[`, `\[`, `\`, `\\`)
+
 func stringContainedInSlice(needle string, haystack []string) bool {
 	exists := false
 	for _, h := range haystack {
@@ -136,7 +291,45 @@ func suffixInSlice(needle string, haystack []string) bool {
 	return exists
 }
 
-func runCheckChangelog(path string, version string) error {
+func runCheckChangelog(path string, version string, fragmentsDir string, all bool) error {
+	if _, err := changelog.ReadFragments(fragmentsDir); err != nil {
+		return fmt.Errorf("invalid changelog fragment: %w", err)
+	}
+
+	if all {
+		if err := checkChangelogEntries(path); err != nil {
+			return err
+		}
+	} else if err := checkChangelogEntry(path, version); err != nil {
+		return err
+	}
+
+	// The unreleased section is optional but must be valid.
+	format, err := changelogFormat()
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	unreleased, err := format.ReadUnreleased(f)
+	if err != nil {
+		return fmt.Errorf("%s: invalid unreleased changes: %w", path, err)
+	}
+	if unreleased == nil {
+		return nil
+	}
+	if err := checkChangelogOrder(format, unreleased); err != nil {
+		return fmt.Errorf("invalid unreleased changes: %w", err)
+	}
+	return nil
+}
+
+// checkChangelogEntry checks the changelog entry of the version, the
+// current version if empty.
+func checkChangelogEntry(path, version string) error {
 	if version == "" {
 		_, err := projInfo.ToSemver()
 		if err != nil {
@@ -146,22 +339,57 @@ func runCheckChangelog(path string, version string) error {
 		version = projInfo.Version
 	}
 
+	_, err := readChangelogEntry(path, version)
+	return err
+}
+
+// checkChangelogEntries checks all the entries of the changelog: their
+// headers, their versions strictly decreasing, their dates not increasing and
+// the order of their changes. All the invalid entries are reported.
+func checkChangelogEntries(path string) error {
+	format, err := changelogFormat()
+	if err != nil {
+		return err
+	}
 	f, err := os.Open(path)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
-
-	entry, err := changelog.ReadEntry(f, version)
+	entries, err := format.ReadEntries(f)
 	if err != nil {
-		return fmt.Errorf("%s: %w", path, err)
+		return lineFinding(path, err)
 	}
 
-	// Check that the changes are ordered correctly.
-	err = entry.Changes.Sorted()
-	if err != nil {
-		return fmt.Errorf("invalid changelog entry: %w", err)
-	}
+	var (
+		errs []error
+		prev *changelog.Entry
+		// prevVersion is the version of prev, nil if invalid.
+		prevVersion *semver.Version
+	)
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if seen[entry.Version] {
+			errs = append(errs, checkFinding{File: path, Message: fmt.Sprintf("duplicate entry for version %s", entry.Version)})
+		}
+		seen[entry.Version] = true
 
-	return nil
+		v, err := semver.NewVersion(entry.Version)
+		if err != nil {
+			errs = append(errs, checkFinding{File: path, Message: fmt.Sprintf("invalid version of entry %s: %v", entry.Name(), err)})
+		}
+		if prev != nil {
+			if v != nil && prevVersion != nil && !v.LessThan(prevVersion) {
+				errs = append(errs, checkFinding{File: path, Message: fmt.Sprintf("entry %s should be before %s", entry.Name(), prev.Name())})
+			}
+			if entry.Date.After(prev.Date) {
+				errs = append(errs, checkFinding{File: path, Message: fmt.Sprintf("entry %s is dated after %s", entry.Name(), prev.Name())})
+			}
+		}
+		if err := checkChangelogOrder(format, entry); err != nil {
+			errs = append(errs, checkFinding{File: path, Message: fmt.Sprintf("invalid changelog entry %s: %v", entry.Name(), err)})
+		}
+		prev, prevVersion = entry, v
+	}
+	return errors.Join(errs...)
 }
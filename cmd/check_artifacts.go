@@ -0,0 +1,241 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"debug/buildinfo"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+var (
+	checkArtifactscmd               = checkcmd.Command("artifacts", "Check the contents of the archives of the project version")
+	checkArtifactsSkipBinaryVersion = checkArtifactscmd.Flag("skip-binary-version", "Don't check that the binaries are built with the project version").
+					Bool()
+	checkArtifactsLocation = checkArtifactscmd.Arg("location", "Directory of the archives").
+				Default(".tarballs").String()
+)
+
+// archiveExtensions are the extensions of the archives written by promu.
+var archiveExtensions = []string{".tar.gz", ".tar.zst", ".tar.xz", ".zip"}
+
+func runCheckArtifacts(location string, skipBinaryVersion bool) error {
+	files, err := os.ReadDir(location)
+	if err != nil {
+		return err
+	}
+	var (
+		errs    []error
+		checked int
+		prefix  = fmt.Sprintf("%s-%s.", projInfo.Name, projInfo.Version)
+	)
+	for _, f := range files {
+		name, ext, ok := splitArchiveExtension(f.Name())
+		if f.IsDir() || !ok || !strings.HasPrefix(name, prefix) || strings.HasSuffix(name, debugInfoSuffix) {
+			continue
+		}
+		platform, ok := artifactPlatform(name)
+		if !ok {
+			continue
+		}
+		checked++
+		file := filepath.Join(location, f.Name())
+		for _, err := range joinedErrors(checkArchive(file, name, ext, platform, skipBinaryVersion)) {
+			errs = append(errs, checkFinding{File: file, Message: err.Error()})
+		}
+	}
+	if checked == 0 {
+		return fmt.Errorf("no archive of %s %s found in %s", projInfo.Name, projInfo.Version, location)
+	}
+	return errors.Join(errs...)
+}
+
+// splitArchiveExtension splits the file name of an archive into its name and
+// its extension, returning false if it isn't an archive.
+func splitArchiveExtension(filename string) (string, string, bool) {
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(filename, ext) {
+			return strings.TrimSuffix(filename, ext), ext, true
+		}
+	}
+	return "", "", false
+}
+
+// checkArchive checks that the archive of the platform, whose root
+// directory is name, holds the executable binaries of the project, built
+// with its version unless skipBinaryVersion is true, and the files of
+// tarball.files.
+func checkArchive(file, name, ext, platform string, skipBinaryVersion bool) error {
+	binaries := map[string]bool{}
+	for _, b := range config.Build.Binaries {
+		binary := b.Name
+		if strings.HasPrefix(platform, "windows/") {
+			binary += ".exe"
+		}
+		binaries[path.Join(name, binary)] = true
+	}
+	expected := map[string]bool{}
+	for f := range binaries {
+		expected[f] = true
+	}
+	for _, f := range config.Tarball.Files {
+		match, err := platformFilter(f.Platforms)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Path, err)
+		}
+		// The files matched by globs aren't known in advance.
+		if match(platform) && !isGlob(f.Path) {
+			expected[path.Join(name, path.Base(filepath.ToSlash(f.Path)))] = true
+		}
+	}
+
+	var errs []error
+	err := walkArchive(file, ext, func(entry string, mode fs.FileMode, r io.Reader) error {
+		entry = strings.TrimSuffix(entry, "/")
+		if !expected[entry] {
+			return nil
+		}
+		delete(expected, entry)
+		if !binaries[entry] {
+			return nil
+		}
+		if !mode.IsRegular() || mode&0o111 == 0 {
+			errs = append(errs, fmt.Errorf("binary %s isn't an executable file (%s)", entry, mode))
+			return nil
+		}
+		if skipBinaryVersion {
+			return nil
+		}
+		if err := checkBinaryVersion(r, projInfo.Version); err != nil {
+			errs = append(errs, fmt.Errorf("binary %s: %w", entry, err))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for f := range expected {
+		errs = append(errs, fmt.Errorf("missing %s", f))
+	}
+	return errors.Join(errs...)
+}
+
+// walkArchive calls fn for each entry of the archive with the given
+// extension, r reading the content of the entry.
+func walkArchive(file, ext string, fn func(name string, mode fs.FileMode, r io.Reader) error) error {
+	if ext == ".zip" {
+		zr, err := zip.OpenReader(file)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			r, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = fn(f.Name, f.Mode(), r)
+			r.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader
+	switch ext {
+	case ".tar.gz":
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		r = gr
+	case ".tar.zst":
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	case ".tar.xz":
+		if r, err = xz.NewReader(f); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported archive extension %q", ext)
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(hdr.Name, hdr.FileInfo().Mode(), tr); err != nil {
+			return err
+		}
+	}
+}
+
+// checkBinaryVersion checks that the Go binary is built with the version,
+// either as the version of its main module or in its -ldflags, as reported
+// by go version -m.
+func checkBinaryVersion(r io.Reader, version string) error {
+	tmp, err := os.CreateTemp("", "promu-binary")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if _, err := io.Copy(tmp, r); err != nil {
+		return err
+	}
+
+	info, err := buildinfo.Read(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to read the build info: %w", err)
+	}
+	if info.Main.Version == "v"+version {
+		return nil
+	}
+	for _, s := range info.Settings {
+		if s.Key == "-ldflags" && strings.Contains(s.Value, version) {
+			return nil
+		}
+	}
+	return fmt.Errorf("not built with version %s", version)
+}
@@ -0,0 +1,112 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"text/template"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+var checkConfigcmd = checkcmd.Command("config", "Check that the config file is valid")
+
+// runCheckConfig loads the config file and reports all its errors: unknown
+// keys, wrong types and the invalid values which would only fail the
+// commands using them.
+func runCheckConfig(filename string) error {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	config = NewConfig()
+	if err := yaml.UnmarshalStrict(b, config); err != nil {
+		// The type errors are reported at once, each at its line.
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return lineFinding(filename, err)
+		}
+		var errs []error
+		for _, e := range typeErr.Errors {
+			errs = append(errs, lineFinding(filename, errors.New(e)))
+		}
+		return errors.Join(errs...)
+	}
+
+	var errs []error
+	for _, err := range checkConfig() {
+		errs = append(errs, checkFinding{File: filename, Message: err.Error()})
+	}
+	return errors.Join(errs...)
+}
+
+// checkConfig returns the errors of the values of the loaded config.
+func checkConfig() []error {
+	var errs []error
+
+	for _, b := range config.Build.Binaries {
+		if b.Name == "" {
+			errs = append(errs, fmt.Errorf("build.binaries: binary of path %q has no name", b.Path))
+		}
+		if fi, err := os.Stat(b.Path); err != nil {
+			errs = append(errs, fmt.Errorf("build.binaries: binary %s: %w", b.Name, err))
+		} else if !fi.IsDir() {
+			errs = append(errs, fmt.Errorf("build.binaries: path %s of binary %s isn't a directory", b.Path, b.Name))
+		}
+	}
+
+	if config.Build.LDFlags != "" {
+		tmpl, err := template.New("ldflags").Funcs(ldflagsFuncs(time.Now(), false)).Parse(config.Build.LDFlags)
+		if err == nil {
+			err = tmpl.Execute(io.Discard, projInfo)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("build.ldflags: %w", err))
+		}
+	}
+
+	for _, platform := range config.Crossbuild.Platforms {
+		re, err := regexp.Compile(platform)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("crossbuild.platforms: %w", err))
+			continue
+		}
+		if len(inSliceRE(re, defaultPlatforms)) == 0 {
+			errs = append(errs, fmt.Errorf("crossbuild.platforms: %q matches no known platform", platform))
+		}
+	}
+
+	if _, err := changelogFormat(); err != nil {
+		errs = append(errs, fmt.Errorf("changelog.format: %w", err))
+	}
+	if _, err := changelogKindOrder(); err != nil {
+		errs = append(errs, fmt.Errorf("changelog.kind_order: %w", err))
+	}
+	if _, err := releaseProvider(); err != nil {
+		errs = append(errs, fmt.Errorf("release.provider: %w", err))
+	}
+	if c := config.Codesign; (c.Password != "" || c.PasswordEnv != "") && c.P12 == "" && c.P12Env == "" {
+		errs = append(errs, errors.New("codesign.password: no p12 certificate to decrypt"))
+	}
+	if err := config.Codesign.KeyProvider.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("codesign.key_provider: %w", err))
+	}
+	return errs
+}
@@ -0,0 +1,170 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/prometheus/promu/util/sh"
+)
+
+var (
+	checkGoModcmd      = checkcmd.Command("go-mod", "Check that go.mod and go.sum are tidy, the module cache verified and vendor/ up to date")
+	checkGoModLocation = checkGoModcmd.Arg("location", "Directory of the Go module").
+				Default(".").String()
+)
+
+func runCheckGoMod(dir string) error {
+	var errs []error
+	// go mod tidy -diff prints the changes to go.mod and go.sum and fails if
+	// there are any. The output of go is written to the standard error, the
+	// standard output being the report of the check.
+	if err := sh.RunCommandWithOutput(os.Stderr, os.Stderr, "go", "-C", dir, "mod", "tidy", "-diff"); err != nil {
+		errs = append(errs, checkFinding{
+			File:    filepath.Join(dir, "go.mod"),
+			Message: fmt.Sprintf("go.mod or go.sum isn't tidy, run go mod tidy: %v", err),
+		})
+	}
+	if err := sh.RunCommandWithOutput(os.Stderr, os.Stderr, "go", "-C", dir, "mod", "verify"); err != nil {
+		errs = append(errs, fmt.Errorf("failed to verify the dependencies: %w", err))
+	}
+
+	vendor := filepath.Join(dir, "vendor")
+	if _, err := os.Stat(vendor); err == nil {
+		if err := checkVendor(dir, vendor); err != nil {
+			errs = append(errs, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkVendor checks that the vendor directory of the module is the one
+// written by go mod vendor, each file which differs being a finding.
+func checkVendor(dir, vendor string) error {
+	tmp, err := os.MkdirTemp("", "promu-vendor")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	expected := filepath.Join(tmp, "vendor")
+	// The output directory of go mod vendor is relative to the module.
+	if abs, err := filepath.Abs(expected); err == nil {
+		expected = abs
+	}
+	if err := sh.RunCommandWithOutput(os.Stderr, os.Stderr, "go", "-C", dir, "mod", "vendor", "-o", expected); err != nil {
+		return fmt.Errorf("failed to vendor the dependencies: %w", err)
+	}
+
+	diffs, err := diffDirs(expected, vendor)
+	if err != nil {
+		return err
+	}
+	messages := map[byte]string{
+		'-': "missing from vendor/, run go mod vendor",
+		'+': "not vendored by go mod vendor, run go mod vendor",
+		'~': "differs from go mod vendor, run go mod vendor",
+	}
+	errs := make([]error, 0, len(diffs))
+	for _, d := range diffs {
+		errs = append(errs, checkFinding{
+			File:    filepath.Join(vendor, filepath.FromSlash(d[1:])),
+			Message: messages[d[0]],
+		})
+	}
+	return errors.Join(errs...)
+}
+
+// diffDirs returns the files which differ between the expected and actual
+// directories: "+name" for the extra files, "-name" for the missing ones and
+// "~name" for the modified ones.
+func diffDirs(expected, actual string) ([]string, error) {
+	files := func(root string) (map[string]string, error) {
+		m := map[string]string{}
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := relativeSlashPath(root, p)
+			if err != nil {
+				return err
+			}
+			m[rel] = p
+			return nil
+		})
+		return m, err
+	}
+	exp, err := files(expected)
+	if err != nil {
+		return nil, err
+	}
+	act, err := files(actual)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(exp)+len(act))
+	for name := range exp {
+		names = append(names, name)
+	}
+	for name := range act {
+		if _, ok := exp[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var diffs []string
+	for _, name := range names {
+		e, inExp := exp[name]
+		a, inAct := act[name]
+		switch {
+		case !inAct:
+			diffs = append(diffs, "-"+name)
+		case !inExp:
+			diffs = append(diffs, "+"+name)
+		default:
+			same, err := sameContent(e, a)
+			if err != nil {
+				return nil, err
+			}
+			if !same {
+				diffs = append(diffs, "~"+name)
+			}
+		}
+	}
+	return diffs, nil
+}
+
+// sameContent reports whether the two files have the same content.
+func sameContent(a, b string) (bool, error) {
+	ba, err := os.ReadFile(a)
+	if err != nil {
+		return false, err
+	}
+	bb, err := os.ReadFile(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(ba, bb), nil
+}
@@ -0,0 +1,231 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+var checkFormat = checkcmd.Flag("format", "Output format of the violations").
+	Default("text").Enum("text", "json", "sarif")
+
+// checkFinding is a violation reported by a check. The checks return their
+// findings as errors, joined with errors.Join.
+type checkFinding struct {
+	// File is the path of the file at fault, empty if none.
+	File string `json:"file,omitempty"`
+	// Line is the line of the file at fault, 0 if unknown.
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+func (f checkFinding) Error() string {
+	switch {
+	case f.File == "":
+		return f.Message
+	case f.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", f.File, f.Line, f.Message)
+	}
+	return fmt.Sprintf("%s: %s", f.File, f.Message)
+}
+
+// reLineError matches the errors of the parsers reporting their line, such
+// as "yaml: line 3: mapping values are not allowed in this context".
+var reLineError = regexp.MustCompile(`(?s)^(?:\w+: )?line (\d+): (.*)$`)
+
+// lineFinding returns the finding of the error of the file, at the line
+// reported by the error if any.
+func lineFinding(file string, err error) checkFinding {
+	f := checkFinding{File: file, Message: err.Error()}
+	if m := reLineError.FindStringSubmatch(f.Message); m != nil {
+		f.Line, _ = strconv.Atoi(m[1])
+		f.Message = m[2]
+	}
+	return f
+}
+
+// checkReport is the result of a check in the json format.
+type checkReport struct {
+	Check    string         `json:"check"`
+	Findings []checkFinding `json:"findings"`
+}
+
+// joinedErrors returns the errors joined with errors.Join in err, recursively.
+func joinedErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	j, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var errs []error
+	for _, e := range j.Unwrap() {
+		errs = append(errs, joinedErrors(e)...)
+	}
+	return errs
+}
+
+// checkFindings returns the findings of the error returned by a check, the
+// errors which aren't findings being reported without file.
+func checkFindings(err error) []checkFinding {
+	findings := []checkFinding{}
+	for _, e := range joinedErrors(err) {
+		f, ok := e.(checkFinding)
+		if !ok {
+			f = checkFinding{Message: e.Error()}
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// runCheck reports the findings of the check in the output format and exits
+// with a nonzero code if there are any.
+func runCheck(name string, err error) {
+	findings := checkFindings(err)
+	if err := writeCheckReport(os.Stdout, name, *checkFormat, findings); err != nil {
+		fatal(err)
+	}
+	if len(findings) > 0 {
+		os.Exit(1)
+	}
+}
+
+// writeCheckReport writes the findings of the check in the format: one
+// finding per line for text, a checkReport for json and a SARIF log for
+// sarif.
+func writeCheckReport(w io.Writer, name, format string, findings []checkFinding) error {
+	var v interface{}
+	switch format {
+	case "json":
+		v = checkReport{Check: name, Findings: findings}
+	case "sarif":
+		v = newSARIFLog(name, findings)
+	default:
+		for _, f := range findings {
+			if _, err := fmt.Fprintln(w, f.Error()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// The types below are the subset of the SARIF 2.1.0 format used to report
+// the findings to code scanning integrations, see
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html.
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// newSARIFLog returns the SARIF log of the findings of the check, whose rule
+// is named after the check.
+func newSARIFLog(name string, findings []checkFinding) sarifLog {
+	description := "promu check " + name
+	if cmd := checkcmd.GetCommand(name); cmd != nil {
+		description = cmd.Model().Help
+	}
+	ruleID := "check-" + name
+
+	results := make([]sarifResult, 0, len(findings))
+	for _, f := range findings {
+		r := sarifResult{
+			RuleID:  ruleID,
+			Level:   "error",
+			Message: sarifMessage{Text: f.Message},
+		}
+		if f.File != "" {
+			loc := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(f.File)}}
+			if f.Line > 0 {
+				loc.Region = &sarifRegion{StartLine: f.Line}
+			}
+			r.Locations = []sarifLocation{{PhysicalLocation: loc}}
+		}
+		results = append(results, r)
+	}
+	return sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "promu",
+				InformationURI: "https://github.com/prometheus/promu",
+				Rules:          []sarifRule{{ID: ruleID, ShortDescription: sarifMessage{Text: description}}},
+			}},
+			Results: results,
+		}},
+	}
+}
@@ -0,0 +1,301 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	checkReproducibilitycmd     = checkcmd.Command("reproducibility", "Build the binaries twice, or once to compare them with a release asset, and report the differences")
+	checkReproducibilityAgainst = checkReproducibilitycmd.Flag("against", "Path or URL of a binary or archive to compare the build with instead of a second build").
+					String()
+	checkReproducibilityBinaries = checkReproducibilitycmd.Arg("binary-names", "Comma separated list of binaries to check").
+					Default("all").String()
+)
+
+// runCheckReproducibility builds the binaries with -trimpath and
+// SOURCE_DATE_EPOCH, the time of the HEAD commit by default, then builds
+// them again or reads them from the binary or archive against, and reports
+// the binaries which differ with their differing sections.
+func runCheckReproducibility(binariesString, against string) error {
+	binaries := config.Build.Binaries
+	if binariesString != "all" {
+		var err error
+		if binaries, err = validateBinaryNames(strings.Split(binariesString, ","), binaries); err != nil {
+			return err
+		}
+	}
+
+	epoch := os.Getenv(sourceDateEpoch)
+	if epoch == "" {
+		epoch = shellOutput("git log -1 --format=%ct")
+		if epoch == "" {
+			return fmt.Errorf("failed to read the time of the HEAD commit, set %s", sourceDateEpoch)
+		}
+	}
+	o := buildOptions{
+		ldflags: getLdflags(projInfo, epoch),
+		tags:    getTags(config.Build.Tags),
+		flags:   config.Build.Flags,
+		cgo:     config.Go.CGo,
+		// The output of the builds isn't part of the report of the check.
+		stdout: os.Stderr,
+		env:    []string{sourceDateEpoch + "=" + epoch},
+	}
+	if !stringInSlice("-trimpath", strings.Fields(o.flags)) {
+		o.flags = strings.TrimSpace(o.flags + " -trimpath")
+	}
+	if goos == "windows" {
+		o.ext = ".exe"
+	}
+	build := func() (string, error) {
+		dir, err := os.MkdirTemp("", "promu-reproducibility")
+		if err != nil {
+			return "", err
+		}
+		o.prefix = dir
+		if err := buildAll(o, binaries); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+		return dir, nil
+	}
+
+	dir, err := build()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	var (
+		other     string
+		reference func(name string) ([]byte, error)
+	)
+	if against == "" {
+		other = "the second build"
+		dir2, err := build()
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir2)
+		reference = func(name string) ([]byte, error) {
+			return os.ReadFile(filepath.Join(dir2, name))
+		}
+	} else {
+		other = against
+		assets, err := readAssetBinaries(against)
+		if err != nil {
+			return err
+		}
+		// A binary is compared with the binary to check whatever its name.
+		if _, _, ok := splitArchiveExtension(path.Base(against)); !ok && len(binaries) == 1 {
+			for _, b := range assets {
+				assets = map[string][]byte{binaries[0].Name + o.ext: b}
+			}
+		}
+		reference = func(name string) ([]byte, error) {
+			b, ok := assets[name]
+			if !ok {
+				return nil, fmt.Errorf("%s not found in %s", name, against)
+			}
+			return b, nil
+		}
+	}
+
+	var errs []error
+	for _, binary := range binaries {
+		name := binary.Name + o.ext
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		ref, err := reference(name)
+		if err != nil {
+			errs = append(errs, checkFinding{File: name, Message: err.Error()})
+			continue
+		}
+		if bytes.Equal(b, ref) {
+			continue
+		}
+		sections, err := diffSections(b, ref)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		message := fmt.Sprintf("differs from %s", other)
+		if len(sections) > 0 {
+			message += fmt.Sprintf(" in sections %s", strings.Join(sections, ", "))
+		}
+		errs = append(errs, checkFinding{File: name, Message: message})
+	}
+	return errors.Join(errs...)
+}
+
+// readAssetBinaries returns the content of the binary at the path or URL, or
+// of the regular files of the archive, by base name.
+func readAssetBinaries(asset string) (map[string][]byte, error) {
+	file := asset
+	if strings.HasPrefix(asset, "http://") || strings.HasPrefix(asset, "https://") {
+		tmp, err := os.MkdirTemp("", "promu-asset")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tmp)
+		file = filepath.Join(tmp, path.Base(asset))
+		if err := downloadFile(asset, file); err != nil {
+			return nil, err
+		}
+	}
+
+	_, ext, ok := splitArchiveExtension(filepath.Base(file))
+	if !ok {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		return map[string][]byte{filepath.Base(file): b}, nil
+	}
+	binaries := map[string][]byte{}
+	err := walkArchive(file, ext, func(name string, mode fs.FileMode, r io.Reader) error {
+		if !mode.IsRegular() {
+			return nil
+		}
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		binaries[path.Base(name)] = b
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", asset, err)
+	}
+	return binaries, nil
+}
+
+// downloadFile writes the content at the URL to the file.
+func downloadFile(url, file string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// binarySection is a section of an executable.
+type binarySection struct {
+	name string
+	data []byte
+}
+
+// binarySections returns the sections of the ELF, Mach-O or PE executable.
+func binarySections(b []byte) ([]binarySection, error) {
+	var sections []binarySection
+	add := func(name string, data func() ([]byte, error)) error {
+		d, err := data()
+		if err != nil {
+			return fmt.Errorf("section %s: %w", name, err)
+		}
+		sections = append(sections, binarySection{name: name, data: d})
+		return nil
+	}
+
+	r := bytes.NewReader(b)
+	if f, err := elf.NewFile(r); err == nil {
+		for _, s := range f.Sections {
+			// The sections without data in the file are only allocated.
+			if s.Type == elf.SHT_NULL || s.Type == elf.SHT_NOBITS {
+				continue
+			}
+			if err := add(s.Name, s.Data); err != nil {
+				return nil, err
+			}
+		}
+		return sections, nil
+	}
+	if f, err := macho.NewFile(r); err == nil {
+		for _, s := range f.Sections {
+			if err := add(s.Seg+","+s.Name, s.Data); err != nil {
+				return nil, err
+			}
+		}
+		return sections, nil
+	}
+	if f, err := pe.NewFile(r); err == nil {
+		for _, s := range f.Sections {
+			if err := add(s.Name, s.Data); err != nil {
+				return nil, err
+			}
+		}
+		return sections, nil
+	}
+	return nil, errors.New("unknown executable format")
+}
+
+// diffSections returns the names of the sections which differ between the
+// executables, in the order of the first one, the sections of only one of
+// them included.
+func diffSections(a, b []byte) ([]string, error) {
+	sa, err := binarySections(a)
+	if err != nil {
+		return nil, err
+	}
+	sb, err := binarySections(b)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string][]byte{}
+	for _, s := range sb {
+		data[s.name] = s.data
+	}
+
+	var diffs []string
+	seen := map[string]bool{}
+	for _, s := range sa {
+		seen[s.name] = true
+		if d, ok := data[s.name]; !ok || !bytes.Equal(s.data, d) {
+			diffs = append(diffs, s.name)
+		}
+	}
+	for _, s := range sb {
+		if !seen[s.name] {
+			diffs = append(diffs, s.name)
+		}
+	}
+	return diffs, nil
+}
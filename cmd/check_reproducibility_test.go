@@ -0,0 +1,88 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckReproducibility(t *testing.T) {
+	// The fake go writes its environment and arguments into the binary.
+	fakeCommand(t, "go", `while [ $# -gt 0 ]; do
+  case "$1" in
+    -o) out="$2"; shift 2; continue ;;
+  esac
+  args="$args $1"
+  shift
+done
+echo "$CGO_ENABLED $SOURCE_DATE_EPOCH$args" > "$out"
+`)
+	fakeCommand(t, "git", `echo 1700000000`)
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+	config.Build.Binaries = []Binary{{Name: "foo", Path: "./cmd/foo"}}
+	config.Build.Flags = "-a"
+	config.Go.CGo = true
+	t.Setenv(sourceDateEpoch, "")
+	t.Setenv("CGO_ENABLED", "")
+
+	against := filepath.Join(t.TempDir(), "foo")
+	exp := "1 1700000000 build -ldflags -X main.Version=" + projInfo.Version + " -extldflags '-static' -a -trimpath cmd/foo\n"
+	if err := os.WriteFile(against, []byte(exp), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	if err := runCheckReproducibility("all", against); err != nil {
+		t.Fatal(err)
+	}
+	if err := runCheckReproducibility("all", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Build.Flags != "-a" {
+		t.Errorf("expected the build flags to be unchanged, got %q", config.Build.Flags)
+	}
+	if os.Stdout != stdout {
+		t.Error("expected os.Stdout to be unchanged")
+	}
+	for _, env := range []string{"CGO_ENABLED", sourceDateEpoch} {
+		if v := os.Getenv(env); v != "" {
+			t.Errorf("expected %s to be unset, got %q", env, v)
+		}
+	}
+}
+
+func TestCheckReproducibilityFailedBuild(t *testing.T) {
+	fakeCommand(t, "go", "exit 1")
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+	config.Build.Binaries = []Binary{{Name: "foo", Path: "./cmd/foo"}}
+	t.Setenv(sourceDateEpoch, "1700000000")
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	if err := runCheckReproducibility("all", ""); err == nil {
+		t.Fatal("expected error but got nil")
+	}
+	// The directory of the failed build is removed.
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) > 0 {
+		t.Fatalf("expected no temporary directory, got %s", entries[0].Name())
+	}
+}
@@ -0,0 +1,544 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/promu/pkg/repository"
+)
+
+// writeFiles writes the files of the given content under the directory.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCheckLicenses(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.go":                    "// Copyright 2026 The Prometheus Authors\npackage main\n",
+		"missing.go":                 "package main\n",
+		"README.md":                  "# Foo\n",
+		"pkg/vendor/foo/foo.go":      "package foo\n",
+		"third_party/foo/foo.go":     "package foo\n",
+		"api/foo.pb.go":              "package api\n",
+		"api/nested/third_party.go":  "package nested\n",
+		"internal/excluded/bar.go":   "package excluded\n",
+		"internal/included/bar.go":   "package included\n",
+		"internal/included/bar_test": "package included\n",
+	})
+
+	c := licenseCheck{
+		n:          10,
+		extensions: []string{".go"},
+		excludes:   append([]string{"**/*.pb.go", "internal/excluded"}, defaultLicenseExcludes...),
+	}
+	violations, err := c.check(dir + string(filepath.Separator))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range violations {
+		got = append(got, v.File)
+	}
+	exp := []string{
+		filepath.Join(dir, "api", "nested", "third_party.go"),
+		filepath.Join(dir, "internal", "included", "bar.go"),
+		filepath.Join(dir, "missing.go"),
+	}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected files %q, got %q", exp, got)
+	}
+}
+
+func TestCheckLicenseHeader(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		spdx   string
+		header string
+		exp    string
+	}{
+		{name: "copyright", header: "// Copyright 2026 The Prometheus Authors\n"},
+		{name: "generated", header: "// Code generated by protoc-gen-go. DO NOT EDIT.\n"},
+		{name: "missing", header: "package main\n", exp: "missing license header"},
+		{
+			name:   "spdx",
+			spdx:   "Apache-2.0",
+			header: "// Copyright The Prometheus Authors\n// SPDX-License-Identifier: Apache-2.0\n",
+		},
+		{
+			name:   "spdx expression",
+			spdx:   "Apache-2.0 OR MIT",
+			header: "/* SPDX-License-Identifier: apache-2.0  or  MIT */\n",
+		},
+		{
+			name:   "spdx mismatch",
+			spdx:   "Apache-2.0",
+			header: "# SPDX-License-Identifier: MIT\n",
+			exp:    `license "MIT" doesn't match "Apache-2.0"`,
+		},
+		{
+			name:   "spdx missing",
+			spdx:   "Apache-2.0",
+			header: "// Copyright 2026 The Prometheus Authors\n",
+			exp:    "missing SPDX-License-Identifier: header",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := licenseCheck{spdx: tc.spdx}
+			if got := c.checkHeader(strings.Split(tc.header, "\n")); got != tc.exp {
+				t.Fatalf("expected %q, got %q", tc.exp, got)
+			}
+		})
+	}
+}
+
+func TestGitIgnored(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	dir := t.TempDir()
+	if ignored, err := gitIgnored(dir); err != nil || ignored != nil {
+		t.Fatalf("expected no ignored files outside of a git repository, got %q, %v", ignored, err)
+	}
+
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	writeFiles(t, dir, map[string]string{
+		".gitignore":       "/build/\n*.gen.go\n",
+		"main.go":          "package main\n",
+		"build/foo.go":     "package foo\n",
+		"api/types.go":     "package api\n",
+		"api/types.gen.go": "package api\n",
+	})
+	ignored, err := gitIgnored(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"api/types.gen.go", "build"}; !reflect.DeepEqual(exp, ignored) {
+		t.Fatalf("expected ignored files %q, got %q", exp, ignored)
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	defer func(c *Config) { config = c }(config)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"cmd/foo/main.go": "package main\n"})
+
+	for _, tc := range []struct {
+		name   string
+		config string
+		errs   []string
+	}{
+		{
+			name: "valid",
+			config: `build:
+  binaries:
+    - name: foo
+      path: ` + filepath.Join(dir, "cmd", "foo") + `
+  ldflags: -X main.revision={{.Revision}} -X main.date={{date "20060102"}}
+crossbuild:
+  platforms:
+    - linux/amd64
+    - darwin
+`,
+		},
+		{
+			name: "unknown key and wrong type",
+			config: `build:
+  binary: foo
+crossbuild:
+  platforms: linux
+`,
+			errs: []string{"field binary not found", "cannot unmarshal !!str `linux`"},
+		},
+		{
+			name: "invalid values",
+			config: `build:
+  binaries:
+    - name: foo
+      path: ` + filepath.Join(dir, "cmd", "bar") + `
+  ldflags: -X main.date={{now}}
+crossbuild:
+  platforms:
+    - linux/amd64
+    - plan9
+changelog:
+  kind_order: [FEATURES]
+`,
+			errs: []string{
+				"build.binaries: binary foo: stat",
+				`build.ldflags: template: ldflags:1: function "now" not defined`,
+				`crossbuild.platforms: "plan9" matches no known platform`,
+				`changelog.kind_order: unknown kind "FEATURES"`,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".promu.yml")
+			if err := os.WriteFile(path, []byte(tc.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			err := runCheckConfig(path)
+			if len(tc.errs) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected errors, got none")
+			}
+			for _, exp := range tc.errs {
+				if !strings.Contains(err.Error(), exp) {
+					t.Errorf("expected error containing %q, got %v", exp, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckArtifacts(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a binary")
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/go.mod":  "module example.com/foo\n\ngo 1.21\n",
+		"src/main.go": "package main\n\nvar version string\n\nfunc main() { println(version) }\n",
+		"LICENSE":     "Apache License\n",
+	})
+	build := func(version string) string {
+		out := filepath.Join(dir, "build-"+version, "foo")
+		cmd := exec.Command("go", "build", "-o", out, "-ldflags", "-X main.version="+version, ".")
+		cmd.Dir = filepath.Join(dir, "src")
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "CGO_ENABLED=0")
+		if b, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go build: %v: %s", err, b)
+		}
+		return out
+	}
+
+	defer func(c *Config, info repository.Info) { config, projInfo = c, info }(config, projInfo)
+	projInfo = repository.Info{Name: "foo", Version: "1.0.0"}
+	config = NewConfig()
+	config.Tarball.Files = []TarballFile{{Path: filepath.Join(dir, "LICENSE")}, {Path: "NOTICE", Platforms: []string{"windows"}}}
+
+	for _, tc := range []struct {
+		name    string
+		version string
+		files   []string
+		mode    os.FileMode
+		err     string
+	}{
+		{name: "valid", version: "1.0.0", files: []string{"LICENSE"}, mode: 0o755},
+		{name: "missing file", version: "1.0.0", mode: 0o755, err: "missing foo-1.0.0.linux-amd64/LICENSE"},
+		{name: "not executable", version: "1.0.0", files: []string{"LICENSE"}, mode: 0o644, err: "isn't an executable file"},
+		{name: "wrong version", version: "0.9.0", files: []string{"LICENSE"}, mode: 0o755, err: "not built with version 1.0.0"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			binary := build(tc.version)
+			if err := os.Chmod(binary, tc.mode); err != nil {
+				t.Fatal(err)
+			}
+			sources := []archiveSource{fileSource(binary)}
+			for _, f := range tc.files {
+				sources = append(sources, fileSource(filepath.Join(dir, f)))
+			}
+			entries, err := collectArchiveEntries("foo-1.0.0.linux-amd64", sources, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			location := t.TempDir()
+			err = createArchive(filepath.Join(location, "foo-1.0.0.linux-amd64.tar.gz"), func(w io.Writer) error {
+				return writeTarball(w, entries, "gzip")
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = runCheckArtifacts(location, false)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error containing %q, got %v", tc.err, err)
+			}
+		})
+	}
+
+	if err := runCheckArtifacts(t.TempDir(), false); err == nil {
+		t.Fatalf("expected error without archive, got none")
+	}
+}
+
+func TestDiffDirs(t *testing.T) {
+	expected, actual := t.TempDir(), t.TempDir()
+	writeFiles(t, expected, map[string]string{
+		"modules.txt":     "# example.com/a v1.0.0\n",
+		"example.com/a/a": "a",
+		"example.com/b/b": "b",
+	})
+	writeFiles(t, actual, map[string]string{
+		"modules.txt":     "# example.com/a v0.9.0\n",
+		"example.com/a/a": "a",
+		"example.com/c/c": "c",
+	})
+	diffs, err := diffDirs(expected, actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"-example.com/b/b", "+example.com/c/c", "~modules.txt"}; !reflect.DeepEqual(exp, diffs) {
+		t.Fatalf("expected %q, got %q", exp, diffs)
+	}
+}
+
+func TestCheckGoMod(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":     "module example.com/foo\n\ngo 1.21\n\nrequire example.com/bar v0.0.0\n\nreplace example.com/bar => ./bar\n",
+		"main.go":    "package main\n\nimport \"example.com/bar\"\n\nfunc main() { bar.Bar() }\n",
+		"bar/go.mod": "module example.com/bar\n\ngo 1.21\n",
+		"bar/bar.go": "package bar\n\nfunc Bar() {}\n",
+	})
+	if err := runCheckGoMod(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "mod", "vendor")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go mod vendor: %v: %s", err, out)
+	}
+	if err := runCheckGoMod(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFiles(t, dir, map[string]string{"vendor/example.com/bar/bar.go": "package bar\n\nfunc Bar() { println() }\n"})
+	exp := []checkFinding{{
+		File:    filepath.Join(dir, "vendor", "example.com", "bar", "bar.go"),
+		Message: "differs from go mod vendor, run go mod vendor",
+	}}
+	if got := checkFindings(runCheckGoMod(dir)); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected findings %v, got %v", exp, got)
+	}
+}
+
+func TestCheckFindings(t *testing.T) {
+	err := errors.Join(
+		checkFinding{File: "main.go", Message: "missing license header"},
+		errors.Join(errors.New("failed"), lineFinding(".promu.yml", errors.New("yaml: line 3: did not find expected key"))),
+	)
+	exp := []checkFinding{
+		{File: "main.go", Message: "missing license header"},
+		{Message: "failed"},
+		{File: ".promu.yml", Line: 3, Message: "did not find expected key"},
+	}
+	if got := checkFindings(err); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected %v, got %v", exp, got)
+	}
+	if got := checkFindings(nil); len(got) != 0 {
+		t.Fatalf("expected no findings, got %v", got)
+	}
+}
+
+func TestWriteCheckReport(t *testing.T) {
+	findings := []checkFinding{
+		{File: "main.go", Message: "missing license header"},
+		{File: ".promu.yml", Line: 3, Message: "unknown field"},
+		{Message: "failed"},
+	}
+	for _, tc := range []struct {
+		format string
+		exp    string
+	}{
+		{
+			format: "text",
+			exp:    "main.go: missing license header\n.promu.yml:3: unknown field\nfailed\n",
+		},
+		{
+			format: "json",
+			exp: `{
+  "check": "licenses",
+  "findings": [
+    {
+      "file": "main.go",
+      "message": "missing license header"
+    },
+    {
+      "file": ".promu.yml",
+      "line": 3,
+      "message": "unknown field"
+    },
+    {
+      "message": "failed"
+    }
+  ]
+}
+`,
+		},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			var b strings.Builder
+			if err := writeCheckReport(&b, "licenses", tc.format, findings); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tc.exp {
+				t.Fatalf("expected:\n%s\ngot:\n%s", tc.exp, b.String())
+			}
+		})
+	}
+
+	var b strings.Builder
+	if err := writeCheckReport(&b, "licenses", "sarif", findings); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal([]byte(b.String()), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("expected a SARIF 2.1.0 log with one run, got %+v", log)
+	}
+	results := log.Runs[0].Results
+	if len(results) != len(findings) {
+		t.Fatalf("expected %d results, got %d", len(findings), len(results))
+	}
+	for _, r := range results {
+		if r.RuleID != "check-licenses" {
+			t.Errorf("expected rule check-licenses, got %q", r.RuleID)
+		}
+	}
+	if loc := results[1].Locations[0].PhysicalLocation; loc.ArtifactLocation.URI != ".promu.yml" || loc.Region == nil || loc.Region.StartLine != 3 {
+		t.Errorf("unexpected location %+v", loc)
+	}
+	if len(results[2].Locations) != 0 {
+		t.Errorf("expected no location, got %+v", results[2].Locations)
+	}
+}
+
+func TestDiffSections(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sections, err := binarySections(b)
+	if err != nil {
+		t.Skipf("unsupported test binary: %v", err)
+	}
+
+	diffs, err := diffSections(b, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Fatalf("expected no differing sections, got %q", diffs)
+	}
+
+	// Modify the first byte of the largest section.
+	largest := sections[0]
+	for _, s := range sections {
+		if len(s.data) > len(largest.data) {
+			largest = s
+		}
+	}
+	i := bytes.Index(b, largest.data)
+	if i < 0 {
+		t.Fatalf("section %s not found", largest.name)
+	}
+	modified := append([]byte(nil), b...)
+	modified[i]++
+	diffs, err = diffSections(b, modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{largest.name}; !reflect.DeepEqual(exp, diffs) {
+		t.Fatalf("expected differing sections %q, got %q", exp, diffs)
+	}
+
+	if _, err := diffSections(b, []byte("not an executable")); err == nil {
+		t.Fatalf("expected error for an unknown format, got none")
+	}
+}
+
+func TestReadAssetBinaries(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"foo":     "foo binary",
+		"LICENSE": "Apache License\n",
+	})
+	entries, err := collectArchiveEntries("foo-1.0.0.linux-amd64", []archiveSource{
+		fileSource(filepath.Join(dir, "foo")),
+		fileSource(filepath.Join(dir, "LICENSE")),
+	}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(dir, "foo-1.0.0.linux-amd64.tar.gz")
+	err = createArchive(archive, func(w io.Writer) error {
+		return writeTarball(w, entries, "gzip")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		asset string
+		exp   map[string][]byte
+	}{
+		{
+			asset: archive,
+			exp:   map[string][]byte{"foo": []byte("foo binary"), "LICENSE": []byte("Apache License\n")},
+		},
+		{
+			asset: filepath.Join(dir, "foo"),
+			exp:   map[string][]byte{"foo": []byte("foo binary")},
+		},
+	} {
+		got, err := readAssetBinaries(tc.asset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tc.exp, got) {
+			t.Fatalf("%s: expected %q, got %q", tc.asset, tc.exp, got)
+		}
+	}
+}
@@ -15,79 +15,532 @@
 package cmd
 
 import (
+	"context"
 	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/prometheus/promu/util/pool"
+	"github.com/prometheus/promu/util/sh"
 )
 
 const (
 	checksumsFilename = "sha256sums.txt"
+	// checksumsManifestFilename is the default file of the JSON manifest.
+	checksumsManifestFilename = "checksums.json"
+
+	signerCosign   = "cosign"
+	signerGPG      = "gpg"
+	signerMinisign = "minisign"
 )
 
 var (
-	checksumcmd      = app.Command("checksum", "Calculate the SHA256 checksum for each file in the given location")
-	checksumLocation = checksumcmd.Arg("location", "Location to checksum").Default(".").Strings()
+	checksumcmd        = app.Command("checksum", "Calculate and publish the checksums of the release files")
+	checksumComputecmd = checksumcmd.Command("compute", "Calculate the checksum, SHA256 by default, of each file in the given location").Default()
+	checksumAlgorithm  = checksumComputecmd.Flag("algorithm", "Hash algorithm of the checksums, may be used multiple times to write the <algorithm>sums.txt file of each").
+				Default("sha256").Enums("sha256", "sha512", "blake2b")
+	checksumPlatforms = checksumComputecmd.Flag("platforms", "Regexp match platforms of the artifacts to checksum, may be used multiple times. Files without platform are skipped.").
+				Short('p').Strings()
+	checksumIncludes = checksumComputecmd.Flag("include", "Glob of the paths to checksum relative to the location, e.g. '**/*.tar.gz', may be used multiple times").
+				Strings()
+	checksumExcludes = checksumComputecmd.Flag("exclude", "Glob of the paths to skip relative to the location, e.g. 'debug/**', may be used multiple times").
+				Strings()
+	checksumOutputs = checksumComputecmd.Flag("output", "Path of the checksums file, <location>/<algorithm>sums.txt by default, may be used multiple times, once per --algorithm in order").
+			Short('o').Strings()
+	checksumBinary = checksumComputecmd.Flag("binary", "Mark the files as read in binary mode with '*', as sha256sum --binary").
+			Bool()
+	checksumFormat = checksumComputecmd.Flag("format", "Format of the checksums: text for sha256sum files, json for a single manifest with the sizes and platforms of the files").
+			Default("text").Enum("text", "json")
+	checksumContents = checksumComputecmd.Flag("contents", "Also write the checksums of the files of the archives, without extracting them, to the .contents file of each checksums file").
+				Bool()
+	checksumAppend = checksumComputecmd.Flag("append", "Update the existing checksums files with the checksums of the files added or modified since they were written only").
+			Bool()
+	checksumSign = checksumComputecmd.Flag("sign", "Sign the checksums files with cosign, gpg or minisign").
+			Enum(signerCosign, signerGPG, signerMinisign)
+	checksumKey = checksumComputecmd.Flag("key", "ID of the GPG key, release.gpg.key or PROMU_GPG_KEY by default, or minisign secret key file, release.minisign by default, signing the checksums files").
+			String()
+	checksumLocation = checksumComputecmd.Arg("location", "Location to checksum").Default(".").Strings()
 )
 
-func runChecksum(path string) {
-	checksums, err := calculateSHA256s(path)
+// checksumHashes are the hash functions of the checksum algorithms. blake2b
+// is BLAKE2b-512, as computed by b2sum.
+var checksumHashes = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+	"blake2b": func() hash.Hash {
+		// New512 only fails with a key longer than 64 bytes.
+		h, _ := blake2b.New512(nil)
+		return h
+	},
+}
+
+// checksumsFile returns the name of the checksums file of the algorithm, e.g.
+// sha256sums.txt.
+func checksumsFile(algorithm string) string {
+	return algorithm + "sums.txt"
+}
+
+// isChecksumsOutput returns whether the file has the name of a checksums
+// file written by promu checksum or promu release: the checksums file of an
+// algorithm, the manifest, or the checksums file of their contents.
+func isChecksumsOutput(path string) bool {
+	name := filepath.Base(path)
+	if name == checksumsManifestFilename || name == contentsChecksumsFile(checksumsManifestFilename) {
+		return true
+	}
+	for algorithm := range checksumHashes {
+		if name == checksumsFile(algorithm) || name == contentsChecksumsFile(checksumsFile(algorithm)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checksumOptions are the options of promu checksum.
+type checksumOptions struct {
+	algorithms []string
+	// outputs are the paths of the checksums files, one per algorithm, or
+	// of the manifest in the json format.
+	outputs []string
+	// format is text for sha256sum files or json for a manifest.
+	format   string
+	binary   bool
+	contents bool
+	// append updates the existing checksums files with the checksums of
+	// the new and modified files only.
+	append bool
+	// signer signs the written files if not empty, with key.
+	signer, key string
+}
+
+func runChecksum(path string, o checksumOptions) {
+	switch {
+	case o.format == "json" && len(o.outputs) > 1:
+		fatal(errors.New("a single --output is expected with --format=json"))
+	case o.format == "json" && len(o.outputs) == 0:
+		o.outputs = []string{filepath.Join(path, checksumsManifestFilename)}
+	case o.format == "json":
+	case len(o.outputs) > 0 && len(o.outputs) != len(o.algorithms):
+		fatal(fmt.Errorf("%d --output for %d --algorithm, expected one per algorithm", len(o.outputs), len(o.algorithms)))
+	case len(o.outputs) == 0:
+		for _, algorithm := range o.algorithms {
+			o.outputs = append(o.outputs, filepath.Join(path, checksumsFile(algorithm)))
+		}
+	}
+
+	match, err := checksumFilter(*checksumPlatforms, *checksumIncludes, *checksumExcludes)
+	if err != nil {
+		fatal(err)
+	}
+	// The checksums files of the previous runs, of any algorithm, aren't
+	// checksummed, nor their signatures.
+	outputs := map[string]bool{}
+	for _, output := range o.outputs {
+		for _, f := range []string{output, contentsChecksumsFile(output)} {
+			if rel, err := filepath.Rel(path, f); err == nil {
+				outputs[filepath.ToSlash(rel)] = true
+			}
+		}
+	}
+	skip := func(file string) bool {
+		if isSignatureFile(file) || isCosignFile(file) {
+			file = strings.TrimSuffix(file, filepath.Ext(file))
+		}
+		return outputs[file] || isChecksumsOutput(file)
+	}
+	var previous *previousChecksums
+	if o.append {
+		if previous, err = readPreviousChecksums(o); err != nil {
+			fatal(fmt.Errorf("Failed to read the previous checksums: %w", err))
+		}
+	}
+	include := func(file string) bool {
+		return !skip(file) && match(file) && (previous == nil || previous.changed(path, file, o.algorithms, o.contents))
+	}
+	checksums, err := calculateChecksums(os.DirFS(path), include, o.algorithms)
 	if err != nil {
 		fatal(fmt.Errorf("Failed to calculate checksums: %w", err))
 	}
 
-	file, err := os.Create(filepath.Join(path, checksumsFilename))
+	var contentChecksums map[string][]fileChecksum
+	if o.contents {
+		var archives []string
+		for _, c := range checksums[o.algorithms[0]] {
+			if _, _, ok := splitArchiveExtension(c.filename); ok {
+				archives = append(archives, c.filename)
+			}
+		}
+		contentChecksums, err = calculateArchiveChecksums(path, archives, o.algorithms)
+		if err != nil {
+			fatal(fmt.Errorf("Failed to calculate the checksums of the archive contents: %w", err))
+		}
+	}
+	if previous != nil {
+		checksums, contentChecksums = previous.merge(o.algorithms, checksums, contentChecksums)
+	}
+
+	write := func(output string, write func(path string) error) {
+		if o.signer == "" {
+			err = write(output)
+		} else {
+			err = writeSigned(output, write, o.signer, o.key)
+		}
+		if err != nil {
+			fatal(err)
+		}
+	}
+	if o.format == "json" {
+		manifest := newChecksumManifest(o.algorithms, checksums, contentChecksums)
+		write(o.outputs[0], func(path string) error {
+			return writeChecksumManifest(path, manifest)
+		})
+		return
+	}
+	for i, algorithm := range o.algorithms {
+		write(o.outputs[i], func(path string) error {
+			return writeChecksums(path, checksums[algorithm], o.binary)
+		})
+		if o.contents {
+			write(contentsChecksumsFile(o.outputs[i]), func(path string) error {
+				return writeChecksums(path, contentChecksums[algorithm], o.binary)
+			})
+		}
+	}
+}
+
+// contentsChecksumsFile returns the path of the checksums file of the
+// contents of the archives of the checksums file, e.g.
+// sha256sums.contents.txt for sha256sums.txt.
+func contentsChecksumsFile(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + ".contents" + ext
+}
+
+// calculateArchiveChecksums calculates the checksums of the regular files of
+// the archives of the location with each of the algorithms, by algorithm,
+// streaming the archives without extracting them. The files are named
+// <archive>/<file>, in the order of the archives and of their files. The
+// archives are read concurrently.
+func calculateArchiveChecksums(location string, archives, algorithms []string) (map[string][]fileChecksum, error) {
+	perArchive := make([]map[string][]fileChecksum, len(archives))
+	workers := pool.New(context.Background(), runtime.NumCPU(), true)
+	for i, archive := range archives {
+		i, archive := i, archive
+		workers.Go(func(context.Context) error {
+			_, ext, _ := splitArchiveExtension(archive)
+			checksums := map[string][]fileChecksum{}
+			err := walkArchive(filepath.Join(location, filepath.FromSlash(archive)), ext, func(name string, mode fs.FileMode, r io.Reader) error {
+				if !mode.IsRegular() {
+					return nil
+				}
+				sums, size, err := hashReader(r, algorithms)
+				if err != nil {
+					return err
+				}
+				for j, algorithm := range algorithms {
+					checksums[algorithm] = append(checksums[algorithm], fileChecksum{
+						filename: archive + "/" + strings.TrimPrefix(name, "./"),
+						checksum: sums[j],
+						size:     size,
+					})
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("%s: %w", archive, err)
+			}
+			perArchive[i] = checksums
+			return nil
+		})
+	}
+	if err := workers.Wait(); err != nil {
+		return nil, err
+	}
+
+	checksums := map[string][]fileChecksum{}
+	for _, c := range perArchive {
+		for _, algorithm := range algorithms {
+			checksums[algorithm] = append(checksums[algorithm], c[algorithm]...)
+		}
+	}
+	return checksums, nil
+}
+
+// writeSigned writes the file with write and its signature. Both are written
+// to a temporary directory and then moved next to each other, the existing
+// files being left untouched if either fails.
+func writeSigned(path string, write func(path string) error, signer, key string) error {
+	tmp, err := os.MkdirTemp(filepath.Dir(path), ".promu-checksums")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	file := filepath.Join(tmp, filepath.Base(path))
+	if err := write(file); err != nil {
+		return err
+	}
+	signatures, err := signFile(signer, key, file)
+	if err != nil {
+		return err
+	}
+	// The checksums file is moved last, not to be published unsigned.
+	for _, f := range append(signatures, file) {
+		if err := os.Rename(f, filepath.Join(filepath.Dir(path), filepath.Base(f))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// signFile signs the file with the signer and returns the paths of the
+// signature files written next to it.
+func signFile(signer, key, path string) ([]string, error) {
+	switch signer {
+	case signerCosign:
+		return cosignBlob(path)
+	case signerGPG:
+		if key == "" {
+			key = gpgKey()
+		}
+	case signerMinisign:
+		if key == "" {
+			if !minisignEnabled() {
+				return nil, errors.New("missing minisign secret key, set with --key or release.minisign")
+			}
+			return minisignFiles([]string{path})
+		}
+	}
+	signature, err := detachSign(signer, key, path)
+	if err != nil {
+		return nil, err
+	}
+	return []string{signature}, nil
+}
+
+// checksumFilter returns the filter of the files to checksum, by path
+// relative to the location: the artifacts of the platforms, matching one of
+// the include globs if any and none of the exclude globs.
+func checksumFilter(platforms, includes, excludes []string) (func(path string) bool, error) {
+	match, err := artifactFilter(platforms)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateGlobs(append(append([]string(nil), includes...), excludes...)); err != nil {
+		return nil, err
+	}
+	return func(path string) bool {
+		// The globs are valid, matching them can't fail.
+		if len(includes) > 0 {
+			if ok, _ := matchAnyGlob(includes, path); !ok {
+				return false
+			}
+		}
+		if ok, _ := matchAnyGlob(excludes, path); ok {
+			return false
+		}
+		return match(path)
+	}, nil
+}
+
+// writeChecksums writes the checksums file in the format of sha256sum,
+// sorted by file name, marking the files as read in binary mode if binary is
+// true.
+func writeChecksums(path string, checksums []fileChecksum, binary bool) error {
+	sorted := append([]fileChecksum(nil), checksums...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].filename < sorted[j].filename })
+
+	file, err := os.Create(path)
 	if err != nil {
-		fatal(fmt.Errorf("Failed to create checksums file: %w", err))
+		return fmt.Errorf("Failed to create checksums file: %w", err)
 	}
 	defer file.Close()
-	for _, c := range checksums {
-		if _, err := fmt.Fprintf(file, "%x  %s\n", c.checksum, c.filename); err != nil {
-			fatal(fmt.Errorf("Failed to write to checksums file: %w", err))
+	for _, c := range sorted {
+		if _, err := io.WriteString(file, checksumLine(c, binary)); err != nil {
+			return fmt.Errorf("Failed to write to checksums file: %w", err)
 		}
 	}
+	return file.Close()
 }
 
-type checksumSHA256 struct {
+// checksumLine returns the line of the checksum as written by sha256sum: the
+// checksum, a space, "*" in binary mode else a space, and the file name. As
+// with GNU coreutils, the backslashes and newlines of the file name are
+// escaped, the line then starting with a backslash.
+func checksumLine(c fileChecksum, binary bool) string {
+	mode := " "
+	if binary {
+		mode = "*"
+	}
+	var prefix string
+	filename := checksumEscaper.Replace(c.filename)
+	if filename != c.filename {
+		prefix = `\`
+	}
+	return fmt.Sprintf("%s%x %s%s\n", prefix, c.checksum, mode, filename)
+}
+
+// checksumEscaper escapes the file names of the checksums files.
+var checksumEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`)
+
+// gpgKey returns the ID of the GPG key signing the releases: the configured
+// one, else the one of the PROMU_GPG_KEY environment variable. It is empty
+// if neither is set, gpg using its default key.
+func gpgKey() string {
+	if config.Release.GPG.Key != "" {
+		return config.Release.GPG.Key
+	}
+	return os.Getenv("PROMU_GPG_KEY")
+}
+
+// detachSign writes the detached signature of the file with gpg, signing
+// with the given key ID (the default key if empty), or with minisign,
+// signing with the given secret key file. It returns the path of the
+// signature.
+func detachSign(signer, key, path string) (string, error) {
+	var (
+		signature string
+		args      []string
+	)
+	switch signer {
+	case signerGPG:
+		signature = path + ".asc"
+		args = []string{"--batch", "--yes", "--armor", "--output", signature}
+		if key != "" {
+			args = append(args, "--local-user", key)
+		}
+		args = append(args, "--detach-sign", path)
+	case signerMinisign:
+		if key == "" {
+			return "", errors.New("missing minisign secret key")
+		}
+		signature = path + ".minisig"
+		args = []string{"-S", "-s", key, "-m", path, "-x", signature}
+	default:
+		return "", fmt.Errorf("unsupported signer %q", signer)
+	}
+	if err := sh.RunCommand(signer, args...); err != nil {
+		return "", fmt.Errorf("Failed to sign %s: %w", filepath.Base(path), err)
+	}
+	return signature, nil
+}
+
+type fileChecksum struct {
 	filename string
 	checksum []byte
+	size     int64
 }
 
 // calculateSHA256s calculates the sha256 checksum for each file in the given
-// path and returns a checksumSHA256 type in the order returned of
-// filepath.Walk.
-func calculateSHA256s(path string) ([]checksumSHA256, error) {
-	var checksums []checksumSHA256
-	path = fmt.Sprintf("%s%c", filepath.Clean(path), filepath.Separator)
-	calculateSHA256 := func(filepath string, f os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if f.IsDir() {
-			return nil
+// filesystem for which include returns true (all files if include is nil)
+// and returns a fileChecksum type in the lexical order returned by
+// fs.WalkDir. Files are hashed concurrently.
+func calculateSHA256s(fsys fs.FS, include func(path string) bool) ([]fileChecksum, error) {
+	checksums, err := calculateChecksums(fsys, include, []string{"sha256"})
+	if err != nil {
+		return nil, err
+	}
+	return checksums["sha256"], nil
+}
+
+// calculateChecksums is calculateSHA256s for each of the algorithms, by
+// algorithm. Each file is read once.
+func calculateChecksums(fsys fs.FS, include func(path string) bool, algorithms []string) (map[string][]fileChecksum, error) {
+	for _, algorithm := range algorithms {
+		if _, ok := checksumHashes[algorithm]; !ok {
+			return nil, fmt.Errorf("unsupported checksum algorithm %q", algorithm)
 		}
+	}
 
-		file, err := os.Open(filepath)
+	var files []string
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		defer file.Close()
-
-		hash := sha256.New()
-		if _, err = io.Copy(hash, file); err != nil {
-			return err
+		if !d.IsDir() && (include == nil || include(path)) {
+			files = append(files, path)
 		}
-		checksums = append(checksums, checksumSHA256{
-			filename: strings.TrimPrefix(filepath, path),
-			checksum: hash.Sum(nil),
-		})
-
 		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := map[string][]fileChecksum{}
+	for _, algorithm := range algorithms {
+		checksums[algorithm] = make([]fileChecksum, len(files))
+	}
+	workers := pool.New(context.Background(), runtime.NumCPU(), true)
+	for i, file := range files {
+		i, file := i, file
+		workers.Go(func(context.Context) error {
+			sums, size, err := hashFile(fsys, file, algorithms)
+			if err != nil {
+				return err
+			}
+			// Each goroutine writes distinct elements of the slices.
+			for j, algorithm := range algorithms {
+				checksums[algorithm][i] = fileChecksum{
+					filename: file,
+					checksum: sums[j],
+					size:     size,
+				}
+			}
+			return nil
+		})
 	}
-	if err := filepath.Walk(path, calculateSHA256); err != nil {
+	if err := workers.Wait(); err != nil {
 		return nil, err
 	}
 	return checksums, nil
 }
+
+func sha256File(fsys fs.FS, path string) ([]byte, error) {
+	sums, _, err := hashFile(fsys, path, []string{"sha256"})
+	if err != nil {
+		return nil, err
+	}
+	return sums[0], nil
+}
+
+// hashFile returns the checksums of the file with each of the algorithms and
+// its size.
+func hashFile(fsys fs.FS, path string, algorithms []string) ([][]byte, int64, error) {
+	file, err := fsys.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+	return hashReader(file, algorithms)
+}
+
+// hashReader returns the checksums of the content of the reader with each of
+// the algorithms and its size.
+func hashReader(r io.Reader, algorithms []string) ([][]byte, int64, error) {
+	hashes := make([]hash.Hash, len(algorithms))
+	writers := make([]io.Writer, len(algorithms))
+	for i, algorithm := range algorithms {
+		hashes[i] = checksumHashes[algorithm]()
+		writers[i] = hashes[i]
+	}
+	size, err := io.Copy(io.MultiWriter(writers...), r)
+	if err != nil {
+		return nil, 0, err
+	}
+	sums := make([][]byte, len(hashes))
+	for i, h := range hashes {
+		sums[i] = h.Sum(nil)
+	}
+	return sums, size, nil
+}
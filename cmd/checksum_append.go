@@ -0,0 +1,251 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// previousChecksums are the checksums written by a previous run, updated
+// with --append.
+type previousChecksums struct {
+	// checksums and contents are the checksums of the files and of the
+	// contents of the archives by algorithm.
+	checksums, contents map[string][]fileChecksum
+	// modTime is the modification time of the oldest checksums file.
+	modTime time.Time
+	// sizes are the sizes of the files, only known with the json format.
+	sizes map[string]int64
+}
+
+// readPreviousChecksums reads the checksums files of the options, the
+// missing ones being skipped.
+func readPreviousChecksums(o checksumOptions) (*previousChecksums, error) {
+	p := &previousChecksums{
+		checksums: map[string][]fileChecksum{},
+		contents:  map[string][]fileChecksum{},
+		sizes:     map[string]int64{},
+	}
+	// read calls fn if the file exists, updating modTime.
+	read := func(path string, fn func() error) error {
+		fi, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if p.modTime.IsZero() || fi.ModTime().Before(p.modTime) {
+			p.modTime = fi.ModTime()
+		}
+		if err := fn(); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return nil
+	}
+
+	if o.format == "json" {
+		return p, read(o.outputs[0], func() error {
+			b, err := os.ReadFile(o.outputs[0])
+			if err != nil {
+				return err
+			}
+			var manifest []checksumManifestEntry
+			if err := json.Unmarshal(b, &manifest); err != nil {
+				return err
+			}
+			for _, e := range manifest {
+				p.sizes[e.Name] = e.Size
+				for _, algorithm := range o.algorithms {
+					if err := p.add(p.checksums, algorithm, e.Name, e.Checksums[algorithm], e.Size); err != nil {
+						return err
+					}
+					for _, c := range e.Contents {
+						if err := p.add(p.contents, algorithm, e.Name+"/"+c.Name, c.Checksums[algorithm], c.Size); err != nil {
+							return err
+						}
+					}
+				}
+			}
+			return nil
+		})
+	}
+
+	for i, algorithm := range o.algorithms {
+		files := map[string]map[string][]fileChecksum{o.outputs[i]: p.checksums}
+		if o.contents {
+			files[contentsChecksumsFile(o.outputs[i])] = p.contents
+		}
+		for path, checksums := range files {
+			path, checksums, algorithm := path, checksums, algorithm
+			err := read(path, func() error {
+				c, err := readChecksums(path)
+				checksums[algorithm] = append(checksums[algorithm], c...)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return p, nil
+}
+
+// add adds the hex encoded checksum of the file to the checksums of the
+// algorithm, unless empty.
+func (p *previousChecksums) add(checksums map[string][]fileChecksum, algorithm, filename, checksum string, size int64) error {
+	if checksum == "" {
+		return nil
+	}
+	b, err := hex.DecodeString(checksum)
+	if err != nil {
+		return fmt.Errorf("invalid %s checksum of %s: %w", algorithm, filename, err)
+	}
+	checksums[algorithm] = append(checksums[algorithm], fileChecksum{filename: filename, checksum: b, size: size})
+	return nil
+}
+
+// changed reports whether the file of the location must be checksummed
+// again: it lacks a checksum of one of the algorithms, or the checksums of
+// its contents if contents is true and it's an archive, or it was modified
+// after the checksums files were written, or its size changed.
+func (p *previousChecksums) changed(location, file string, algorithms []string, contents bool) bool {
+	for _, algorithm := range algorithms {
+		if !containsChecksum(p.checksums[algorithm], file, false) {
+			return true
+		}
+		if _, _, ok := splitArchiveExtension(file); ok && contents && !containsChecksum(p.contents[algorithm], file, true) {
+			return true
+		}
+	}
+	fi, err := os.Stat(filepath.Join(location, filepath.FromSlash(file)))
+	if err != nil {
+		return true
+	}
+	if size, ok := p.sizes[file]; ok && size != fi.Size() {
+		return true
+	}
+	return fi.ModTime().After(p.modTime)
+}
+
+// containsChecksum reports whether the checksums contain the file or, if
+// archive is true, a file of the archive.
+func containsChecksum(checksums []fileChecksum, file string, archive bool) bool {
+	for _, c := range checksums {
+		if c.filename == file || archive && strings.HasPrefix(c.filename, file+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// merge returns the new checksums of the files and contents of the archives
+// with the previous checksums of the other files, sorted by file name. The
+// previous checksums of the files which no longer exist are kept.
+func (p *previousChecksums) merge(algorithms []string, checksums, contents map[string][]fileChecksum) (map[string][]fileChecksum, map[string][]fileChecksum) {
+	updated := map[string]bool{}
+	for _, c := range checksums[algorithms[0]] {
+		updated[c.filename] = true
+	}
+	// updatedArchive reports whether the archive of a file of the contents
+	// was checksummed again.
+	updatedArchive := func(name string) bool {
+		for i, c := range name {
+			if c == '/' && updated[name[:i]] {
+				return true
+			}
+		}
+		return false
+	}
+
+	mergedChecksums, mergedContents := map[string][]fileChecksum{}, map[string][]fileChecksum{}
+	for _, algorithm := range algorithms {
+		mergedChecksums[algorithm] = append([]fileChecksum(nil), checksums[algorithm]...)
+		for _, c := range p.checksums[algorithm] {
+			if !updated[c.filename] {
+				mergedChecksums[algorithm] = append(mergedChecksums[algorithm], c)
+			}
+		}
+		sortChecksums(mergedChecksums[algorithm])
+
+		if contents == nil {
+			continue
+		}
+		mergedContents[algorithm] = append([]fileChecksum(nil), contents[algorithm]...)
+		for _, c := range p.contents[algorithm] {
+			if !updatedArchive(c.filename) {
+				mergedContents[algorithm] = append(mergedContents[algorithm], c)
+			}
+		}
+		sortChecksums(mergedContents[algorithm])
+	}
+	if contents == nil {
+		mergedContents = nil
+	}
+	return mergedChecksums, mergedContents
+}
+
+// sortChecksums sorts the checksums by file name.
+func sortChecksums(checksums []fileChecksum) {
+	sort.SliceStable(checksums, func(i, j int) bool { return checksums[i].filename < checksums[j].filename })
+}
+
+// readChecksums reads the checksums file in the format of sha256sum, in text
+// or binary mode.
+func readChecksums(path string) ([]fileChecksum, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var checksums []fileChecksum
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		escaped := strings.HasPrefix(line, `\`)
+		line = strings.TrimPrefix(line, `\`)
+		sum, filename, ok := strings.Cut(line, " ")
+		if !ok || filename == "" || (filename[0] != ' ' && filename[0] != '*') {
+			return nil, fmt.Errorf("line %d: invalid checksum line", n)
+		}
+		b, err := hex.DecodeString(sum)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		filename = filename[1:]
+		if escaped {
+			filename = checksumUnescaper.Replace(filename)
+		}
+		checksums = append(checksums, fileChecksum{filename: filename, checksum: b})
+	}
+	return checksums, scanner.Err()
+}
+
+// checksumUnescaper unescapes the file names escaped by checksumEscaper.
+var checksumUnescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r")
@@ -0,0 +1,87 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+)
+
+// checksumManifestEntry is a file of the JSON checksums manifest.
+type checksumManifestEntry struct {
+	// Name is the path of the file relative to the location, or to the
+	// archive for its contents.
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	// Platform is the platform of the artifact, as parsed from its name.
+	Platform string `json:"platform,omitempty"`
+	// Checksums are the hex encoded checksums by algorithm.
+	Checksums map[string]string `json:"checksums"`
+	// Contents are the files of the archive, with --contents.
+	Contents []checksumManifestEntry `json:"contents,omitempty"`
+}
+
+// newChecksumManifest returns the manifest of the checksums, by algorithm,
+// of the files and of the contents of the archives, if any.
+func newChecksumManifest(algorithms []string, checksums, contents map[string][]fileChecksum) []checksumManifestEntry {
+	// entries returns the entries of the checksums with the given prefix,
+	// trimmed from their names.
+	entries := func(checksums map[string][]fileChecksum, prefix string) []checksumManifestEntry {
+		var (
+			entries []checksumManifestEntry
+			index   = map[string]int{}
+		)
+		for _, algorithm := range algorithms {
+			for _, c := range checksums[algorithm] {
+				if !strings.HasPrefix(c.filename, prefix) {
+					continue
+				}
+				i, ok := index[c.filename]
+				if !ok {
+					i = len(entries)
+					index[c.filename] = i
+					entries = append(entries, checksumManifestEntry{
+						Name:      strings.TrimPrefix(c.filename, prefix),
+						Size:      c.size,
+						Checksums: map[string]string{},
+					})
+				}
+				entries[i].Checksums[algorithm] = hex.EncodeToString(c.checksum)
+			}
+		}
+		return entries
+	}
+
+	manifest := entries(checksums, "")
+	for i, e := range manifest {
+		manifest[i].Platform, _ = artifactPlatform(path.Base(e.Name))
+		if _, _, ok := splitArchiveExtension(e.Name); ok && contents != nil {
+			manifest[i].Contents = entries(contents, e.Name+"/")
+		}
+	}
+	return manifest
+}
+
+// writeChecksumManifest writes the manifest to the file.
+func writeChecksumManifest(path string, manifest []checksumManifestEntry) error {
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
@@ -0,0 +1,185 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-github/v25/github"
+)
+
+var (
+	checksumPublishcmd      = checksumcmd.Command("publish", "Verify the files of the location against the assets of the GitHub release and upload their checksums file to it")
+	checksumPublishLocation = checksumPublishcmd.Arg("location", "Location of the release files").
+				Default(".tarballs").String()
+)
+
+func runChecksumPublish(location string) error {
+	ctx, cancel := releaseContext()
+	defer cancel()
+
+	var files []string
+	err := filepath.Walk(location, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// The checksums file of a previous run is written again.
+		if fi.IsDir() || isChecksumsFile(path) {
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no files to publish the checksums of in %s", location)
+	}
+
+	targets, err := releaseTargets(ctx)
+	if err != nil {
+		return err
+	}
+	return publishChecksums(ctx, targets, location, files)
+}
+
+// publishChecksums verifies that the files of the location are the assets of
+// the release of the project version in each target, then writes their
+// checksums file and uploads it to the releases, replacing the existing one.
+// Nothing is written or uploaded if any file doesn't match its asset.
+func publishChecksums(ctx context.Context, targets []releaseTarget, location string, files []string) error {
+	checksums, err := calculateReleaseChecksums(location, files)
+	if err != nil {
+		return err
+	}
+
+	tag := fmt.Sprintf("v%s", projInfo.Version)
+	// targetError prefixes the error by the target if there are several.
+	targetError := func(t releaseTarget, err error) error {
+		if len(targets) > 1 {
+			return fmt.Errorf("%s: %w", t, err)
+		}
+		return err
+	}
+	var (
+		errs     []error
+		releases = make([]*github.RepositoryRelease, len(targets))
+		existing = make([]map[string]*github.ReleaseAsset, len(targets))
+	)
+	for i, t := range targets {
+		release, err := findRelease(ctx, t.client, t.owner, t.repo, tag)
+		if err == nil && release == nil {
+			err = fmt.Errorf("no release for %s", tag)
+		}
+		if err == nil {
+			releases[i] = release
+			existing[i], err = verifyReleaseAssets(ctx, t, release, checksums)
+		}
+		if err != nil {
+			errs = append(errs, targetError(t, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	written, err := writeReleaseChecksumsFile(location, checksums)
+	if err != nil {
+		return err
+	}
+	for i, t := range targets {
+		for _, path := range written {
+			err := replaceReleaseAsset(ctx, t, releases[i], path, existing[i][filepath.Base(path)])
+			if err != nil {
+				errs = append(errs, targetError(t, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// verifyReleaseAssets checks that the release has an asset of each file with
+// its size and SHA256 checksum. It returns the assets of the release by name.
+func verifyReleaseAssets(ctx context.Context, t releaseTarget, release *github.RepositoryRelease, checksums []fileChecksum) (map[string]*github.ReleaseAsset, error) {
+	assets, err := listReleaseAssets(ctx, t.client, t.owner, t.repo, release)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]*github.ReleaseAsset, len(assets))
+	for _, asset := range assets {
+		existing[asset.GetName()] = asset
+	}
+
+	var errs []error
+	for _, c := range checksums {
+		asset, ok := existing[c.filename]
+		if !ok || incompleteAsset(asset) {
+			errs = append(errs, fmt.Errorf("%q isn't uploaded", c.filename))
+			continue
+		}
+		same := int64(asset.GetSize()) == c.size
+		if same {
+			if same, err = assetHasSHA256(ctx, t, asset, c.checksum); err != nil {
+				errs = append(errs, fmt.Errorf("failed to verify %q: %w", c.filename, err))
+				continue
+			}
+		}
+		if !same {
+			errs = append(errs, fmt.Errorf("%q doesn't match the uploaded asset", c.filename))
+			continue
+		}
+		fmt.Println(" > verified", c.filename)
+	}
+	return existing, errors.Join(errs...)
+}
+
+// replaceReleaseAsset uploads the file to the release, replacing the existing
+// asset unless it already matches the file.
+func replaceReleaseAsset(ctx context.Context, t releaseTarget, release *github.RepositoryRelease, path string, asset *github.ReleaseAsset) error {
+	client, owner, repo := t.client, t.owner, t.repo
+	filename := filepath.Base(path)
+	if asset != nil {
+		same, err := sameAsset(ctx, t, asset, path)
+		if err != nil {
+			return fmt.Errorf("failed to verify existing asset %q: %w", filename, err)
+		}
+		if same {
+			fmt.Println(" > skipped", filename, "already uploaded")
+			return nil
+		}
+		if _, err := client.Repositories.DeleteReleaseAsset(ctx, owner, repo, asset.GetID()); err != nil {
+			return fmt.Errorf("failed to delete existing asset %q: %w", filename, err)
+		}
+	}
+
+	opts, err := uploadOptions(filename)
+	if err != nil {
+		return err
+	}
+	uploaded, err := uploadReleaseAsset(ctx, client, owner, repo, release, opts, path)
+	if err != nil {
+		return fmt.Errorf("failed to upload %q: %w", filename, err)
+	}
+	if err := verifyAsset(ctx, t, uploaded, path); err != nil {
+		return err
+	}
+	fmt.Println(" > uploaded", filename)
+	return nil
+}
@@ -15,11 +15,23 @@
 package cmd
 
 import (
+	"context"
 	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/go-github/v25/github"
+	"golang.org/x/crypto/blake2b"
 )
 
 func TestCalculateSHA256s(t *testing.T) {
@@ -39,17 +51,595 @@ func TestCalculateSHA256s(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	got, err := calculateSHA256s(dir)
+	got, err := calculateSHA256s(os.DirFS(dir), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	want := []checksumSHA256{
+	want := []fileChecksum{
 		{
 			filename: filename,
 			checksum: checksum[:],
+			size:     int64(len(content)),
+		},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want checksums %+v, got %+v", want, got)
+	}
+}
+
+func TestCalculateSHA256sFS(t *testing.T) {
+	var (
+		a = []byte("a")
+		b = []byte("b")
+
+		sumA = sha256.Sum256(a)
+		sumB = sha256.Sum256(b)
+	)
+	got, err := calculateSHA256s(fstest.MapFS{
+		"b.tar.gz":     {Data: b},
+		"a.tar.gz":     {Data: a},
+		"dir/a.tar.gz": {Data: a},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []fileChecksum{
+		{filename: "a.tar.gz", checksum: sumA[:], size: 1},
+		{filename: "b.tar.gz", checksum: sumB[:], size: 1},
+		{filename: "dir/a.tar.gz", checksum: sumA[:], size: 1},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want checksums %+v, got %+v", want, got)
+	}
+}
+
+func TestCalculateChecksums(t *testing.T) {
+	var (
+		a = []byte("a")
+		b = []byte("b")
+
+		sha256A, sha256B = sha256.Sum256(a), sha256.Sum256(b)
+		sha512A, sha512B = sha512.Sum512(a), sha512.Sum512(b)
+		blakeA, blakeB   = blake2b.Sum512(a), blake2b.Sum512(b)
+	)
+	got, err := calculateChecksums(fstest.MapFS{
+		"b.tar.gz": {Data: b},
+		"a.tar.gz": {Data: a},
+	}, nil, []string{"sha512", "blake2b", "sha256"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]fileChecksum{
+		"sha256": {
+			{filename: "a.tar.gz", checksum: sha256A[:], size: 1},
+			{filename: "b.tar.gz", checksum: sha256B[:], size: 1},
+		},
+		"sha512": {
+			{filename: "a.tar.gz", checksum: sha512A[:], size: 1},
+			{filename: "b.tar.gz", checksum: sha512B[:], size: 1},
+		},
+		"blake2b": {
+			{filename: "a.tar.gz", checksum: blakeA[:], size: 1},
+			{filename: "b.tar.gz", checksum: blakeB[:], size: 1},
+		},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want checksums %+v, got %+v", want, got)
+	}
+
+	if _, err := calculateChecksums(fstest.MapFS{}, nil, []string{"md5"}); err == nil {
+		t.Errorf("expected error for an unsupported algorithm, got none")
+	}
+}
+
+func TestChecksumFilter(t *testing.T) {
+	fsys := fstest.MapFS{
+		"foo-1.0.0.linux-amd64.tar.gz":          {Data: []byte("a")},
+		"foo-1.0.0.linux-amd64.tar.gz.sbom":     {Data: []byte("b")},
+		"linux/foo-1.0.0.linux-arm64.tar.gz":    {Data: []byte("c")},
+		"darwin/foo-1.0.0.darwin-arm64.tar.gz":  {Data: []byte("d")},
+		"debug/foo-1.0.0.linux-amd64.debug.zip": {Data: []byte("e")},
+		"README.md":                             {Data: []byte("f")},
+	}
+	for _, tc := range []struct {
+		name                          string
+		platforms, includes, excludes []string
+		exp                           []string
+	}{
+		{
+			name: "all",
+			exp: []string{
+				"README.md",
+				"darwin/foo-1.0.0.darwin-arm64.tar.gz",
+				"debug/foo-1.0.0.linux-amd64.debug.zip",
+				"foo-1.0.0.linux-amd64.tar.gz",
+				"foo-1.0.0.linux-amd64.tar.gz.sbom",
+				"linux/foo-1.0.0.linux-arm64.tar.gz",
+			},
+		},
+		{
+			name:     "include and exclude",
+			includes: []string{"**/*.tar.gz", "**/*.zip"},
+			excludes: []string{"debug/**"},
+			exp: []string{
+				"darwin/foo-1.0.0.darwin-arm64.tar.gz",
+				"foo-1.0.0.linux-amd64.tar.gz",
+				"linux/foo-1.0.0.linux-arm64.tar.gz",
+			},
+		},
+		{
+			name:      "platforms",
+			platforms: []string{"linux"},
+			includes:  []string{"**/*.tar.gz"},
+			exp: []string{
+				"foo-1.0.0.linux-amd64.tar.gz",
+				"linux/foo-1.0.0.linux-arm64.tar.gz",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			match, err := checksumFilter(tc.platforms, tc.includes, tc.excludes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checksums, err := calculateSHA256s(fsys, match)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range checksums {
+				got = append(got, c.filename)
+			}
+			if !reflect.DeepEqual(tc.exp, got) {
+				t.Fatalf("expected %q, got %q", tc.exp, got)
+			}
+		})
+	}
+
+	if _, err := checksumFilter(nil, []string{"[a-"}, nil); err == nil {
+		t.Fatal("expected error for an invalid glob, got none")
+	}
+}
+
+func TestWriteChecksums(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"b.tar.gz":     "b",
+		"a.tar.gz":     "a",
+		"dir/c.tar.gz": "c",
+		`back\slash`:   "d",
+	}
+	var checksums []fileChecksum
+	for name, content := range files {
+		writeFiles(t, dir, map[string]string{name: content})
+		sum := sha256.Sum256([]byte(content))
+		checksums = append(checksums, fileChecksum{filename: name, checksum: sum[:]})
+	}
+
+	for _, tc := range []struct {
+		binary bool
+		exp    string
+	}{
+		{
+			exp: `ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb  a.tar.gz
+3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d  b.tar.gz
+\18ac3e7343f016890c510e93f935261169d9e3f565436429830faf0934f4f8e4  back\\slash
+2e7d2c03a9507ae265ecf5b5356885a53393a2029d241394997265a1a25aefc6  dir/c.tar.gz
+`,
+		},
+		{
+			binary: true,
+			exp: `ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb *a.tar.gz
+3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d *b.tar.gz
+\18ac3e7343f016890c510e93f935261169d9e3f565436429830faf0934f4f8e4 *back\\slash
+2e7d2c03a9507ae265ecf5b5356885a53393a2029d241394997265a1a25aefc6 *dir/c.tar.gz
+`,
+		},
+	} {
+		path := filepath.Join(dir, "SHA256SUMS")
+		if err := writeChecksums(path, checksums, tc.binary); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tc.exp {
+			t.Fatalf("expected:\n%s\ngot:\n%s", tc.exp, got)
+		}
+
+		if _, err := exec.LookPath("sha256sum"); err != nil {
+			continue
+		}
+		cmd := exec.Command("sha256sum", "--check", "--strict", "SHA256SUMS")
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("sha256sum --check: %v: %s", err, out)
+		}
+	}
+}
+
+func TestWriteSigned(t *testing.T) {
+	// The fake gpg writes the signed file to the output, the fake cosign
+	// writes "signed" to the outputs.
+	fakeCommand(t, "gpg", `while [ $# -gt 0 ]; do
+  case "$1" in
+    --output) out="$2"; shift ;;
+    --detach-sign) cp "$2" "$out"; shift ;;
+  esac
+  shift
+done
+`)
+	fakeCommand(t, "cosign", `while [ $# -gt 0 ]; do
+  case "$1" in
+    --output-signature|--output-certificate) echo signed > "$2"; shift ;;
+  esac
+  shift
+done
+`)
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+	sum := sha256.Sum256([]byte("a"))
+	checksums := []fileChecksum{{filename: "a.tar.gz", checksum: sum[:]}}
+	sums := "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb  a.tar.gz\n"
+	write := func(path string) error {
+		return writeChecksums(path, checksums, false)
+	}
+
+	for _, tc := range []struct {
+		signer string
+		exp    map[string]string
+	}{
+		{
+			signer: signerGPG,
+			exp:    map[string]string{"SHA256SUMS": sums, "SHA256SUMS.asc": sums},
+		},
+		{
+			signer: signerCosign,
+			exp:    map[string]string{"SHA256SUMS": sums, "SHA256SUMS.sig": "signed\n", "SHA256SUMS.pem": "signed\n"},
+		},
+	} {
+		t.Run(tc.signer, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := writeSigned(filepath.Join(dir, "SHA256SUMS"), write, tc.signer, "key"); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]string{}
+			for _, e := range entries {
+				b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				got[e.Name()] = string(b)
+			}
+			if !reflect.DeepEqual(tc.exp, got) {
+				t.Fatalf("expected files %q, got %q", tc.exp, got)
+			}
+		})
+	}
+
+	t.Run("failure", func(t *testing.T) {
+		fakeCommand(t, "gpg", "exit 1\n")
+		dir := t.TempDir()
+		path := filepath.Join(dir, "SHA256SUMS")
+		if err := os.WriteFile(path, []byte("previous"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeSigned(path, write, signerGPG, "key"); err == nil {
+			t.Fatal("expected error, got none")
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := os.ReadFile(path); len(entries) != 1 || string(b) != "previous" {
+			t.Fatalf("expected the previous checksums file only, got %v", entries)
+		}
+	})
+}
+
+func TestCalculateArchiveChecksums(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/foo":     "foo binary",
+		"src/LICENSE": "Apache License\n",
+	})
+	entries, err := collectArchiveEntries("foo-1.0.0.linux-amd64", []archiveSource{
+		fileSource(filepath.Join(dir, "src", "foo")),
+		fileSource(filepath.Join(dir, "src", "LICENSE")),
+	}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	location := filepath.Join(dir, "location")
+	if err := os.MkdirAll(filepath.Join(location, "linux"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	archive := "linux/foo-1.0.0.linux-amd64.tar.gz"
+	err = createArchive(filepath.Join(location, archive), func(w io.Writer) error {
+		return writeTarball(w, entries, "gzip")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := calculateArchiveChecksums(location, []string{archive}, []string{"sha256"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sumFoo, sumLicense := sha256.Sum256([]byte("foo binary")), sha256.Sum256([]byte("Apache License\n"))
+	want := map[string][]fileChecksum{
+		"sha256": {
+			{filename: archive + "/foo-1.0.0.linux-amd64/LICENSE", checksum: sumLicense[:], size: 15},
+			{filename: archive + "/foo-1.0.0.linux-amd64/foo", checksum: sumFoo[:], size: 10},
 		},
 	}
 	if !reflect.DeepEqual(want, got) {
 		t.Errorf("want checksums %+v, got %+v", want, got)
 	}
+
+	if _, err := calculateArchiveChecksums(location, []string{"missing.tar.gz"}, []string{"sha256"}); err == nil {
+		t.Errorf("expected error for a missing archive, got none")
+	}
+}
+
+func TestContentsChecksumsFile(t *testing.T) {
+	for in, exp := range map[string]string{
+		"sha256sums.txt":       "sha256sums.contents.txt",
+		".tarballs/SHA512SUMS": ".tarballs/SHA512SUMS.contents",
+	} {
+		if got := contentsChecksumsFile(in); got != exp {
+			t.Errorf("%s: expected %s, got %s", in, exp, got)
+		}
+	}
+}
+
+func TestNewChecksumManifest(t *testing.T) {
+	checksums := map[string][]fileChecksum{
+		"sha256": {
+			{filename: "foo-1.0.0.linux-amd64.tar.gz", checksum: []byte{0x01}, size: 10},
+			{filename: "notes.txt", checksum: []byte{0x02}, size: 3},
+		},
+		"sha512": {
+			{filename: "foo-1.0.0.linux-amd64.tar.gz", checksum: []byte{0x03}, size: 10},
+			{filename: "notes.txt", checksum: []byte{0x04}, size: 3},
+		},
+	}
+	contents := map[string][]fileChecksum{
+		"sha256": {{filename: "foo-1.0.0.linux-amd64.tar.gz/foo-1.0.0.linux-amd64/foo", checksum: []byte{0x05}, size: 5}},
+		"sha512": {{filename: "foo-1.0.0.linux-amd64.tar.gz/foo-1.0.0.linux-amd64/foo", checksum: []byte{0x06}, size: 5}},
+	}
+
+	path := filepath.Join(t.TempDir(), "checksums.json")
+	if err := writeChecksumManifest(path, newChecksumManifest([]string{"sha256", "sha512"}, checksums, contents)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `[
+  {
+    "name": "foo-1.0.0.linux-amd64.tar.gz",
+    "size": 10,
+    "platform": "linux/amd64",
+    "checksums": {
+      "sha256": "01",
+      "sha512": "03"
+    },
+    "contents": [
+      {
+        "name": "foo-1.0.0.linux-amd64/foo",
+        "size": 5,
+        "checksums": {
+          "sha256": "05",
+          "sha512": "06"
+        }
+      }
+    ]
+  },
+  {
+    "name": "notes.txt",
+    "size": 3,
+    "checksums": {
+      "sha256": "02",
+      "sha512": "04"
+    }
+  }
+]
+`
+	if string(got) != exp {
+		t.Fatalf("expected:\n%s\ngot:\n%s", exp, got)
+	}
+}
+
+func TestChecksumAppend(t *testing.T) {
+	for _, format := range []string{"text", "json"} {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{"a.tar.gz": "a", "b.tar.gz": "b"})
+			o := checksumOptions{algorithms: []string{"sha256"}, format: format, append: true}
+			runChecksum(dir, o)
+
+			// The checksum of a is altered to check that it isn't
+			// calculated again.
+			output := filepath.Join(dir, "sha256sums.txt")
+			if format == "json" {
+				output = filepath.Join(dir, "checksums.json")
+			}
+			b, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sumA, sumB := sha256.Sum256([]byte("a")), sha256.Sum256([]byte("b"))
+			altered := strings.Replace(string(b), hex.EncodeToString(sumA[:]), strings.Repeat("0", 64), 1)
+			if err := os.WriteFile(output, []byte(altered), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			modTime := time.Now().Add(-time.Hour)
+			if err := os.Chtimes(output, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range []string{"a.tar.gz", "b.tar.gz"} {
+				if err := os.Chtimes(filepath.Join(dir, name), modTime.Add(-time.Hour), modTime.Add(-time.Hour)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// b is modified and c is added.
+			writeFiles(t, dir, map[string]string{"b.tar.gz": "bb", "c.tar.gz": "c"})
+			sumB = sha256.Sum256([]byte("bb"))
+			sumC := sha256.Sum256([]byte("c"))
+
+			runChecksum(dir, o)
+			previous, err := readPreviousChecksums(checksumOptions{algorithms: []string{"sha256"}, format: format, outputs: []string{output}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range previous.checksums["sha256"] {
+				got = append(got, fmt.Sprintf("%x %s", c.checksum, c.filename))
+			}
+			exp := []string{
+				strings.Repeat("0", 64) + " a.tar.gz",
+				fmt.Sprintf("%x b.tar.gz", sumB),
+				fmt.Sprintf("%x c.tar.gz", sumC),
+			}
+			if !reflect.DeepEqual(exp, got) {
+				t.Fatalf("expected %q, got %q", exp, got)
+			}
+		})
+	}
+}
+
+func TestChecksumSkipsChecksumsFiles(t *testing.T) {
+	dir := t.TempDir()
+	// The checksums files of the previous runs and their signatures.
+	writeFiles(t, dir, map[string]string{
+		"a.tar.gz":                "a",
+		"sha256sums.txt":          "",
+		"sha256sums.txt.asc":      "",
+		"sha256sums.txt.minisig":  "",
+		"sha256sums.contents.txt": "",
+		"checksums.json":          "",
+		"checksums.json.sig":      "",
+		"checksums.json.pem":      "",
+		"custom.txt":              "",
+		"custom.txt.sig":          "",
+	})
+	output := filepath.Join(dir, "custom.txt")
+	runChecksum(dir, checksumOptions{algorithms: []string{"sha512"}, outputs: []string{output}, format: "text"})
+
+	previous, err := readPreviousChecksums(checksumOptions{algorithms: []string{"sha512"}, format: "text", outputs: []string{output}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range previous.checksums["sha512"] {
+		got = append(got, c.filename)
+	}
+	if exp := []string{"a.tar.gz"}; !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected %q, got %q", exp, got)
+	}
+}
+
+func TestReadChecksums(t *testing.T) {
+	sum := sha256.Sum256([]byte("a"))
+	checksums := []fileChecksum{
+		{filename: "a.tar.gz", checksum: sum[:]},
+		{filename: `back\slash`, checksum: sum[:]},
+		{filename: "new\nline", checksum: sum[:]},
+	}
+	for _, binary := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "sha256sums.txt")
+		if err := writeChecksums(path, checksums, binary); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readChecksums(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(checksums, got) {
+			t.Fatalf("expected %v, got %v", checksums, got)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "sha256sums.txt")
+	if err := os.WriteFile(path, []byte("invalid\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readChecksums(path); err == nil {
+		t.Fatal("expected error for an invalid line, got none")
+	}
+}
+
+func TestPublishChecksums(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// assets are the contents of the assets of the release by name.
+		assets map[string]string
+		err    bool
+	}{
+		{
+			name:   "uploaded",
+			assets: map[string]string{"foo.tar.gz": "foo", "bar.tar.gz": "bar"},
+		},
+		{
+			name:   "stale checksums",
+			assets: map[string]string{"foo.tar.gz": "foo", "bar.tar.gz": "bar", checksumsFilename: "stale"},
+		},
+		{
+			name:   "missing asset",
+			assets: map[string]string{"foo.tar.gz": "foo"},
+			err:    true,
+		},
+		{
+			name:   "different asset",
+			assets: map[string]string{"foo.tar.gz": "foo", "bar.tar.gz": "baz"},
+			err:    true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var files []string
+			for _, name := range []string{"bar.tar.gz", "foo.tar.gz"} {
+				path := filepath.Join(dir, name)
+				if err := os.WriteFile(path, []byte(strings.TrimSuffix(name, ".tar.gz")), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				files = append(files, path)
+			}
+			defer func(c *Config, version string) { config, projInfo.Version = c, version }(config, projInfo.Version)
+			config, projInfo.Version = NewConfig(), "1.0.0"
+
+			gh, client := newFakeGitHub(t)
+			gh.releases = []*github.RepositoryRelease{{ID: github.Int64(1), TagName: github.String("v1.0.0")}}
+			for name, content := range tc.assets {
+				gh.addAsset(name, content)
+			}
+			targets := []releaseTarget{{client: client, owner: "owner", repo: "repo", own: true}}
+
+			err := publishChecksums(context.Background(), targets, dir, files)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+			exp := map[string]string{}
+			for name, content := range tc.assets {
+				exp[name] = content
+			}
+			if !tc.err {
+				exp[checksumsFilename] = fmt.Sprintf("%x  bar.tar.gz\n%x  foo.tar.gz\n", sha256.Sum256([]byte("bar")), sha256.Sum256([]byte("foo")))
+			}
+			if got := gh.contents(); !reflect.DeepEqual(exp, got) {
+				t.Fatalf("expected assets %v, got %v", exp, got)
+			}
+			// The checksums file is only written once the files are verified.
+			if _, err := os.Stat(filepath.Join(dir, checksumsFilename)); tc.err != os.IsNotExist(err) {
+				t.Fatalf("expected checksums file written %v, got %v", !tc.err, err)
+			}
+		})
+	}
 }
@@ -15,37 +15,308 @@
 package cmd
 
 import (
+	"context"
+	"debug/macho"
+	"debug/pe"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
 
+	"github.com/prometheus/promu/util/pool"
 	"github.com/prometheus/promu/util/sh"
 )
 
 var (
-	codesigncmd = app.Command("codesign", "Code sign the darwin binary using rcodesign.")
-	binaryPath  = codesigncmd.Arg("path", "Absolute path to binary to be signed").Required().String()
+	codesigncmd     = app.Command("codesign", "Code sign the binaries and verify the signatures of the artifacts")
+	codesignSigncmd = codesigncmd.Command("sign", "Code sign the darwin binaries using rcodesign, or the windows binaries with Authenticode.").Default()
+	codesignWindows = codesignSigncmd.Flag("windows", "Sign the windows binaries with Authenticode using osslsigncode or the configured signer instead").
+			Bool()
+	codesignBestEffort = codesignSigncmd.Flag("best-effort", "Only warn about the binaries which can't be signed instead of failing").
+				Bool()
+	codesignParallelism = codesignSigncmd.Flag("parallelism", "How many binaries to sign in parallel").
+				Default("4").Int()
+	codesignForce = codesignSigncmd.Flag("force", "Sign the binaries again even if already signed with the same identity").
+			Bool()
+	binaryPath = codesignSigncmd.Arg("path", "Path to the binary to be signed, or to a directory whose darwin-amd64 and darwin-arm64, or windows, binaries are signed").
+			Default(".build").String()
 )
 
-func runCodeSign(binaryPath string) {
-	codeSignGoBinary(binaryPath)
+func runCodeSign(path string, windows, bestEffort, force bool, parallelism int) {
+	goos, sign := "darwin", codeSignGoBinary
+	if windows {
+		goos, sign = "windows", authenticodeSign
+	}
+	binaries, err := platformBinaries(path, goos)
+	if err != nil {
+		fatal(err)
+	}
+	if len(binaries) == 0 {
+		fatal(fmt.Errorf("no %s binaries found in %s", goos, path))
+	}
+	cache, err := loadSignCache(signCacheFilename)
+	if err != nil {
+		fatal(err)
+	}
+	if force {
+		cache.artifacts = map[string]signCacheEntry{}
+	}
+	err = signBinaries(binaries, binarySigner{sign: sign, identity: codesignIdentity(goos), cache: cache}, parallelism, bestEffort)
+	if err != nil {
+		fatal(err)
+	}
+}
+
+// binarySigner signs the binaries in place.
+type binarySigner struct {
+	sign func(binary string) error
+	// identity identifies the certificate or key the binaries are signed
+	// with in the cache, which is nil if the binaries are always signed.
+	identity string
+	cache    *signCache
 }
 
-func codeSignGoBinary(binaryPath string) {
+// signBinaries signs the binaries with s, up to parallelism at a
+// time, skipping those of the cache whose signature still verifies. It
+// returns the failures, which are only reported if bestEffort is true.
+func signBinaries(binaries []string, s binarySigner, parallelism int, bestEffort bool) error {
 	var (
-		goVersion              = config.Go.Version
-		dockerMainBuilderImage = fmt.Sprintf("%s:%s-main", dockerBuilderImageName, goVersion)
-		mountPath              = fmt.Sprintf("/%s", filepath.Base(binaryPath))
-		mountPathConcat        = fmt.Sprintf("%s:%s", binaryPath, mountPath)
+		mtx  sync.Mutex
+		errs []error
 	)
+	workers := pool.New(context.Background(), parallelism, false)
+	for _, binary := range binaries {
+		binary := binary
+		workers.Go(func(context.Context) error {
+			verify := func() error { return verifySignatures([]string{binary}, "", "") }
+			if s.cache.signed(binary, s.identity, verify) {
+				fmt.Println(" > skipped", binary, "already signed")
+				return nil
+			}
+			err := s.sign(binary)
+			if err == nil {
+				err = s.cache.add(binary, s.identity)
+			}
+			if err != nil {
+				err = fmt.Errorf("Failed to sign %s: %w", binary, err)
+				if bestEffort {
+					warn(err)
+					return nil
+				}
+				mtx.Lock()
+				errs = append(errs, err)
+				mtx.Unlock()
+			}
+			return nil
+		})
+	}
+	if err := workers.Wait(); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// codesignIdentity returns the identity of the signatures of the binaries of
+// the operating system, from the configuration of their signer.
+func codesignIdentity(goos string) string {
+	c := config.Codesign
+	key := c.KeyProvider.Type + ":" + c.KeyProvider.Key
+	if goos == "windows" {
+		w := c.Windows
+		return fmt.Sprintf("authenticode command=%q key=%s p12=%s p12_env=%s", w.Command, key, w.P12, w.P12Env)
+	}
+	return fmt.Sprintf("rcodesign key=%s p12=%s p12_env=%s identifier=%s entitlements=%s flags=%s",
+		key, c.P12, c.P12Env, c.Identifier, c.Entitlements, strings.Join(c.Flags, ","))
+}
+
+// platformBinaries returns the path if it is a file, else the binaries of
+// the operating system found in the <goos>-<goarch> directories of the path,
+// as written by crossbuild: the Mach-O binaries for darwin and the PE .exe
+// binaries for windows.
+func platformBinaries(path, goos string) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{path}, nil
+	}
+
+	var binaries []string
+	err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || !inPlatformDir(file, goos) {
+			return nil
+		}
+		var f io.Closer
+		switch goos {
+		case "darwin":
+			f, err = macho.Open(file)
+		case "windows":
+			if filepath.Ext(file) != ".exe" {
+				return nil
+			}
+			f, err = pe.Open(file)
+		}
+		if err != nil {
+			// Not a binary, e.g. a LICENSE file.
+			return nil
+		}
+		f.Close()
+		binaries = append(binaries, file)
+		return nil
+	})
+	return binaries, err
+}
+
+// inPlatformDir returns whether the file is in a <goos>-<goarch> directory
+// of the operating system: darwin-amd64 or darwin-arm64 for darwin.
+func inPlatformDir(file, goos string) bool {
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(file)), "/") {
+		switch {
+		case goos == "darwin" && (dir == "darwin-amd64" || dir == "darwin-arm64"):
+			return true
+		case goos != "darwin" && strings.HasPrefix(dir, goos+"-"):
+			return true
+		}
+	}
+	return false
+}
+
+// codeSignGoBinary signs the binary with rcodesign according to the codesign
+// configuration.
+func codeSignGoBinary(binaryPath string) error {
 	fmt.Printf("> using rcodesign to sign the binary file at path %s\n", binaryPath)
 
+	dir, err := os.MkdirTemp("", "promu-codesign")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	args, files, err := codesignArgs(dir)
+	if err != nil {
+		return err
+	}
+
 	// Example:
-	// docker run --entrypoint "rcodesign" --rm -v "/path/to/darwin-arm64/node_exporter:/node_exporter"
-	// quay.io/prometheus/golang-builder:1.21-main sign /node_exporter
-	err := sh.RunCommand("docker", "run", "--entrypoint",
-		"rcodesign", "--rm", "-v", mountPathConcat,
-		dockerMainBuilderImage, "sign", mountPath)
+	// docker run --entrypoint "rcodesign" --rm -v "/path/to/darwin-arm64/node_exporter:/0/node_exporter"
+	// quay.io/prometheus/golang-builder:1.21-main sign /0/node_exporter
+	args = append(append([]string{"sign"}, args...), binaryPath)
+	return rcodesign(args, append(files, binaryPath)...)
+}
+
+// codesignArgs returns the arguments of rcodesign sign for the codesign
+// configuration, and the files they reference. The certificate and the
+// password read from the environment are written to dir.
+func codesignArgs(dir string) ([]string, []string, error) {
+	c := config.Codesign
+	var args, files []string
+	p12, err := secretFile(dir, c.P12, c.P12Env, "certificate.p12", true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p12 != "" {
+		args = append(args, "--p12-file", p12)
+		files = append(files, p12)
+		password, err := secretFile(dir, c.Password, c.PasswordEnv, "password", false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if password != "" {
+			args = append(args, "--p12-password-file", password)
+			files = append(files, password)
+		}
+	}
+	keyArgs, err := rcodesignKeyArgs()
 	if err != nil {
-		fmt.Printf("Couldn't sign the binary as intended: %s", err)
+		return nil, nil, err
+	}
+	args = append(args, keyArgs...)
+	if c.Identifier != "" {
+		args = append(args, "--binary-identifier", c.Identifier)
+	}
+	if c.Entitlements != "" {
+		args = append(args, "--entitlements-xml-file", c.Entitlements)
+		files = append(files, c.Entitlements)
+	}
+	for _, flag := range c.Flags {
+		args = append(args, "--code-signature-flags", flag)
+	}
+	return args, files, nil
+}
+
+// rcodesign runs rcodesign with the given arguments, locally if installed or
+// else in the builder image. The files are mounted into the container and
+// replaced by their path in the container wherever they appear in the
+// arguments.
+func rcodesign(args []string, files ...string) error {
+	if bin := localRcodesign(); bin != "" {
+		return sh.RunCommand(bin, args...)
+	}
+
+	var (
+		dockerMainBuilderImage = fmt.Sprintf("%s:%s-main", dockerBuilderImageName, config.Go.Version)
+		dockerArgs             = []string{"run", "--entrypoint", "rcodesign", "--rm"}
+		mountPaths             = make(map[string]string, len(files))
+	)
+	for i, file := range files {
+		path, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+		mountPaths[file] = fmt.Sprintf("/%d/%s", i, filepath.Base(file))
+		dockerArgs = append(dockerArgs, "-v", path+":"+mountPaths[file])
+	}
+	dockerArgs = append(dockerArgs, dockerMainBuilderImage)
+	for _, arg := range args {
+		if mountPath, ok := mountPaths[arg]; ok {
+			arg = mountPath
+		}
+		dockerArgs = append(dockerArgs, arg)
+	}
+	return sh.RunCommand("docker", dockerArgs...)
+}
+
+// localRcodesign returns the rcodesign binary to run instead of the one of
+// the builder image: the configured one, else the one found in the PATH, if
+// any.
+func localRcodesign() string {
+	if config.Codesign.Rcodesign != "" {
+		return config.Codesign.Rcodesign
+	}
+	path, err := exec.LookPath("rcodesign")
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// secretFile returns the path, or else the path of a file of dir named name
+// holding the value of the environment variable env, base64 decoded if
+// decode is true. It returns an empty path if both are empty.
+func secretFile(dir, path, env, name string, decode bool) (string, error) {
+	if path != "" || env == "" {
+		return path, nil
+	}
+	value := os.Getenv(env)
+	if value == "" {
+		return "", fmt.Errorf("%s not defined", env)
+	}
+	b := []byte(value)
+	if decode {
+		var err error
+		if b, err = base64.StdEncoding.DecodeString(strings.TrimSpace(value)); err != nil {
+			return "", fmt.Errorf("invalid base64 in %s: %w", env, err)
+		}
 	}
+	path = filepath.Join(dir, name)
+	return path, os.WriteFile(path, b, 0o600)
 }
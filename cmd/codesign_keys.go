@@ -0,0 +1,155 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// The key providers hold the signing keys instead of files on disk.
+const (
+	keyProviderGCPKMS   = "gcpkms"
+	keyProviderAWSKMS   = "awskms"
+	keyProviderVault    = "hashivault"
+	keyProviderKeychain = "keychain"
+)
+
+// KeyProvider is the service holding the signing key, see the
+// codesign.key_provider configuration.
+type KeyProvider struct {
+	// Type is gcpkms, awskms, hashivault or keychain.
+	Type string
+	// Key identifies the key: the resource name of the key, e.g.
+	// projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>,
+	// for gcpkms, the ID, ARN or alias of the key for awskms, the name of
+	// the transit key for hashivault and the SHA-256 fingerprint of the
+	// certificate for keychain.
+	Key string
+	// Region is the AWS region of the awskms key.
+	Region string
+	// Address is the URL of the transit secrets engine of Vault, e.g.
+	// https://vault.example.com/v1/transit, used by the windows signatures.
+	Address string
+	// TokenEnv is the environment variable holding the access token of
+	// gcpkms or the Vault token of hashivault, used by the windows
+	// signatures.
+	TokenEnv string `yaml:"token_env"`
+	// Certificate is the PEM file of the certificate chain of the KMS key,
+	// required by the windows signatures.
+	Certificate string
+}
+
+// kms returns whether the key is held by a KMS or Vault.
+func (p KeyProvider) kms() bool {
+	switch p.Type {
+	case keyProviderGCPKMS, keyProviderAWSKMS, keyProviderVault:
+		return true
+	}
+	return false
+}
+
+// validate returns an error if the key provider is set but invalid.
+func (p KeyProvider) validate() error {
+	switch p.Type {
+	case "":
+		return nil
+	case keyProviderGCPKMS, keyProviderAWSKMS, keyProviderVault, keyProviderKeychain:
+	default:
+		return fmt.Errorf("unknown key provider %q, expected gcpkms, awskms, hashivault or keychain", p.Type)
+	}
+	if p.Key == "" {
+		return fmt.Errorf("missing key of the %s key provider", p.Type)
+	}
+	return nil
+}
+
+// cosignKeyRef returns the reference of the KMS key of the key provider in
+// the format of cosign --key, empty if the signatures are keyless.
+func cosignKeyRef() string {
+	p := config.Codesign.KeyProvider
+	switch p.Type {
+	case keyProviderGCPKMS:
+		return "gcpkms://" + p.Key
+	case keyProviderAWSKMS:
+		return "awskms:///" + p.Key
+	case keyProviderVault:
+		return "hashivault://" + p.Key
+	}
+	return ""
+}
+
+// rcodesignKeyArgs returns the arguments of rcodesign sign signing with the
+// certificate of the macOS keychain of the key provider, if any. The KMS keys
+// aren't supported by rcodesign, which signs with the PKCS#12 certificate
+// then.
+func rcodesignKeyArgs() ([]string, error) {
+	p := config.Codesign.KeyProvider
+	if p.Type != keyProviderKeychain {
+		return nil, nil
+	}
+	if localRcodesign() == "" {
+		return nil, errors.New("the keychain key provider requires rcodesign to be installed locally")
+	}
+	return []string{"--keychain-fingerprint", p.Key}, nil
+}
+
+// jsignArgs returns the arguments of jsign signing the windows binary with
+// the KMS key of the key provider.
+func jsignArgs(binary, timestamp string) ([]string, error) {
+	p := config.Codesign.KeyProvider
+	if p.Certificate == "" {
+		return nil, fmt.Errorf("missing certificate of the %s key to sign the windows binaries", p.Type)
+	}
+	if p.TokenEnv != "" && os.Getenv(p.TokenEnv) == "" {
+		return nil, fmt.Errorf("%s not defined", p.TokenEnv)
+	}
+
+	var args []string
+	switch p.Type {
+	case keyProviderGCPKMS:
+		// The keystore is the key ring and the alias the key.
+		ring, key, ok := strings.Cut(p.Key, "/cryptoKeys/")
+		if !ok {
+			return nil, fmt.Errorf("invalid gcpkms key %q, expected projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>", p.Key)
+		}
+		args = []string{"--storetype", "GOOGLECLOUD", "--keystore", ring, "--alias", key}
+	case keyProviderAWSKMS:
+		if p.Region == "" {
+			return nil, errors.New("missing region of the awskms key")
+		}
+		args = []string{"--storetype", "AWS", "--keystore", p.Region, "--alias", p.Key}
+	case keyProviderVault:
+		if p.Address == "" {
+			return nil, errors.New("missing address of the hashivault transit secrets engine")
+		}
+		args = []string{"--storetype", "HASHICORPVAULT", "--keystore", p.Address, "--alias", p.Key}
+	default:
+		return nil, fmt.Errorf("the %s key provider can't sign the windows binaries", p.Type)
+	}
+	if p.TokenEnv != "" {
+		// The token is read by jsign, not to appear in the command line.
+		args = append(args, "--storepass", "env:"+p.TokenEnv)
+	}
+	return append(args,
+		"--certfile", p.Certificate,
+		"--alg", "SHA-256",
+		"--tsaurl", timestamp,
+		"--tsmode", "RFC3161",
+		binary,
+	), nil
+}
@@ -0,0 +1,435 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testMachO returns the header of an arm64 Mach-O executable without load
+// commands.
+func testMachO() []byte {
+	var b []byte
+	for _, v := range []uint32{0xfeedfacf, 0x0100000c, 0, 2, 0, 0, 0, 0} {
+		b = binary.LittleEndian.AppendUint32(b, v)
+	}
+	return b
+}
+
+// testPE returns a PE file with the MS-DOS stub pointing to the amd64 COFF
+// header following it.
+func testPE() []byte {
+	b := make([]byte, 0x80)
+	copy(b, "MZ")
+	binary.LittleEndian.PutUint32(b[0x3c:], 0x80)
+	b = append(b, "PE\x00\x00"...)
+	b = binary.LittleEndian.AppendUint16(b, 0x8664)
+	return append(b, make([]byte, 18)...)
+}
+
+func TestPlatformBinaries(t *testing.T) {
+	dir := t.TempDir()
+	machO, pe := testMachO(), testPE()
+	for name, content := range map[string][]byte{
+		"darwin-arm64/foo":          machO,
+		"darwin-arm64/LICENSE":      []byte("license"),
+		"darwin-amd64/sub/foo":      machO,
+		"linux-amd64/foo":           machO,
+		"darwin-arm64.tar.gz":       machO,
+		"windows-amd64/foo.exe":     pe,
+		"windows-386/foo.exe":       pe,
+		"windows-amd64/foo.txt":     pe,
+		"windows-arm64/LICENSE.exe": []byte("MZ"),
+		"darwin-amd64/NOTICE":       []byte("notice"),
+		"darwin-amd64/foo.symbols":  []byte("symbols"),
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for goos, exp := range map[string][]string{
+		"darwin":  {filepath.Join(dir, "darwin-amd64/sub/foo"), filepath.Join(dir, "darwin-arm64/foo")},
+		"windows": {filepath.Join(dir, "windows-386/foo.exe"), filepath.Join(dir, "windows-amd64/foo.exe")},
+	} {
+		got, err := platformBinaries(dir, goos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(exp, got) {
+			t.Fatalf("%s: expected %v, got %v", goos, exp, got)
+		}
+	}
+
+	// A file is signed whatever its platform.
+	file := filepath.Join(dir, "linux-amd64/foo")
+	if got, err := platformBinaries(file, "darwin"); err != nil || !reflect.DeepEqual([]string{file}, got) {
+		t.Fatalf("expected [%s], got %v (%v)", file, got, err)
+	}
+}
+
+func TestCodesignArgs(t *testing.T) {
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+	dir := t.TempDir()
+
+	args, files, err := codesignArgs(dir)
+	if err != nil || len(args) != 0 || len(files) != 0 {
+		t.Fatalf("expected ad-hoc signature, got %v %v (%v)", args, files, err)
+	}
+
+	t.Setenv("TEST_P12", base64.StdEncoding.EncodeToString([]byte("certificate")))
+	t.Setenv("TEST_PASSWORD", "secret")
+	config.Codesign.P12Env = "TEST_P12"
+	config.Codesign.PasswordEnv = "TEST_PASSWORD"
+	config.Codesign.Identifier = "io.prometheus.foo"
+	config.Codesign.Entitlements = "entitlements.plist"
+	config.Codesign.Flags = []string{"runtime"}
+	args, files, err = codesignArgs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p12, password := filepath.Join(dir, "certificate.p12"), filepath.Join(dir, "password")
+	expArgs := []string{
+		"--p12-file", p12,
+		"--p12-password-file", password,
+		"--binary-identifier", "io.prometheus.foo",
+		"--entitlements-xml-file", "entitlements.plist",
+		"--code-signature-flags", "runtime",
+	}
+	if !reflect.DeepEqual(expArgs, args) {
+		t.Fatalf("expected args %v, got %v", expArgs, args)
+	}
+	if exp := []string{p12, password, "entitlements.plist"}; !reflect.DeepEqual(exp, files) {
+		t.Fatalf("expected files %v, got %v", exp, files)
+	}
+	for path, exp := range map[string]string{p12: "certificate", password: "secret"} {
+		if b, err := os.ReadFile(path); err != nil || string(b) != exp {
+			t.Fatalf("expected %s to hold %q, got %q (%v)", path, exp, b, err)
+		}
+	}
+
+	config.Codesign.P12Env = "TEST_UNDEFINED"
+	if _, _, err := codesignArgs(dir); err == nil {
+		t.Fatal("expected an error for an undefined environment variable")
+	}
+}
+
+func TestAuthenticodeSign(t *testing.T) {
+	// The fake osslsigncode writes its arguments to the output file.
+	fakeCommand(t, "osslsigncode", `out=
+for arg in "$@"; do
+  [ "$prev" = "-out" ] && out="$arg"
+  prev="$arg"
+done
+echo "$@" > "$out"
+`)
+	defer func(c *Config, name string) { config, projInfo.Name = c, name }(config, projInfo.Name)
+	config, projInfo.Name = NewConfig(), "foo"
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "foo.exe")
+	if err := os.WriteFile(binary, []byte("MZ"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := authenticodeSign(binary); err == nil {
+		t.Fatal("expected an error without certificate")
+	}
+
+	config.Codesign.Windows.P12 = "cert.p12"
+	config.Codesign.Windows.Timestamp = "http://timestamp.example.com"
+	if err := authenticodeSign(binary); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := fmt.Sprintf("sign -pkcs12 cert.p12 -h sha256 -n foo -ts http://timestamp.example.com -in %s -out %s.signed\n", binary, binary)
+	if string(b) != exp {
+		t.Fatalf("expected %q, got %q", exp, b)
+	}
+	if fi, err := os.Stat(binary); err != nil || fi.Mode().Perm() != 0o755 {
+		t.Fatalf("expected the mode of the binary to be kept, got %v (%v)", fi.Mode(), err)
+	}
+
+	// The external signer signs the binary in place.
+	config.Codesign.Windows.Command = []string{"sh", "-c", `echo signed > "$0"`}
+	if err := authenticodeSign(binary); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(binary); err != nil || string(b) != "signed\n" {
+		t.Fatalf("expected the binary signed by the command, got %q (%v)", b, err)
+	}
+}
+
+func TestAuthenticodeSignKMS(t *testing.T) {
+	// The fake jsign writes its arguments to the binary.
+	fakeCommand(t, "jsign", `for arg in "$@"; do last="$arg"; done
+echo "$@" > "$last"
+`)
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+	config.Codesign.KeyProvider = KeyProvider{
+		Type:     keyProviderGCPKMS,
+		Key:      "projects/p/locations/global/keyRings/r/cryptoKeys/k",
+		TokenEnv: "GCP_TOKEN",
+	}
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "foo.exe")
+	if err := os.WriteFile(binary, []byte("MZ"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := authenticodeSign(binary); err == nil {
+		t.Fatal("expected an error without certificate")
+	}
+	config.Codesign.KeyProvider.Certificate = "chain.pem"
+	if err := authenticodeSign(binary); err == nil {
+		t.Fatal("expected an error without token")
+	}
+
+	t.Setenv("GCP_TOKEN", "secret")
+	if err := authenticodeSign(binary); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "--storetype GOOGLECLOUD --keystore projects/p/locations/global/keyRings/r --alias k --storepass env:GCP_TOKEN --certfile chain.pem --alg SHA-256 --tsaurl " + defaultTimestampURL + " --tsmode RFC3161 " + binary + "\n"
+	if string(b) != exp {
+		t.Fatalf("expected %q, got %q", exp, b)
+	}
+}
+
+func TestCosignBlobKMS(t *testing.T) {
+	// The fake cosign writes its arguments to the signature file.
+	fakeCommand(t, "cosign", `while [ $# -gt 0 ]; do
+  [ "$1" = "--output-signature" ] && out="$2"
+  shift
+done
+echo signed > "$out"
+`)
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+	config.Codesign.KeyProvider = KeyProvider{Type: keyProviderAWSKMS, Key: "alias/release"}
+	file := filepath.Join(t.TempDir(), "foo.tar.gz")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The signatures of a KMS key have no certificate.
+	written, err := cosignBlob(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{file + ".sig"}; !reflect.DeepEqual(exp, written) {
+		t.Fatalf("expected %v, got %v", exp, written)
+	}
+	if ref := cosignKeyRef(); ref != "awskms:///alias/release" {
+		t.Fatalf("unexpected key reference %q", ref)
+	}
+}
+
+func TestRcodesignLocal(t *testing.T) {
+	// The fake rcodesign writes its arguments to its last one.
+	script := `for arg in "$@"; do last="$arg"; done
+echo "$@" > "$last"
+`
+	fakeCommand(t, "rcodesign", script)
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+	binary := filepath.Join(t.TempDir(), "foo")
+
+	// The files are passed as is, without the mounts of the builder image.
+	if err := rcodesign([]string{"sign", binary}, binary); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(binary); err != nil || string(b) != "sign "+binary+"\n" {
+		t.Fatalf("expected the binary signed by rcodesign, got %q (%v)", b, err)
+	}
+
+	config.Codesign.Rcodesign = filepath.Join(t.TempDir(), "rcodesign")
+	if err := os.WriteFile(config.Codesign.Rcodesign, []byte("#!/bin/sh\necho configured > \"$2\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := rcodesign([]string{"sign", binary}, binary); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(binary); err != nil || string(b) != "configured\n" {
+		t.Fatalf("expected the binary signed by the configured rcodesign, got %q (%v)", b, err)
+	}
+}
+
+func TestSignBinaries(t *testing.T) {
+	var signed []string
+	sign := func(binary string) error {
+		if binary == "bad" {
+			return errors.New("failed")
+		}
+		signed = append(signed, binary)
+		return nil
+	}
+
+	// A failure doesn't prevent signing the other binaries.
+	err := signBinaries([]string{"foo", "bad", "bar"}, binarySigner{sign: sign}, 1, false)
+	if err == nil || err.Error() != "Failed to sign bad: failed" {
+		t.Fatalf("expected the failure of bad, got %v", err)
+	}
+	if exp := []string{"foo", "bar"}; !reflect.DeepEqual(exp, signed) {
+		t.Fatalf("expected %v signed, got %v", exp, signed)
+	}
+
+	if err := signBinaries([]string{"bad"}, binarySigner{sign: sign}, 1, true); err != nil {
+		t.Fatalf("expected no error with best effort, got %v", err)
+	}
+}
+
+func TestSignBinariesCache(t *testing.T) {
+	// The fake rcodesign fails to verify the Mach-O binaries.
+	fakeCommand(t, "rcodesign", "exit 1\n")
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+	dir := t.TempDir()
+	foo, bar, macho := filepath.Join(dir, "foo"), filepath.Join(dir, "bar"), filepath.Join(dir, "macho")
+	for path, b := range map[string][]byte{foo: nil, bar: nil, macho: testMachO()} {
+		if err := os.WriteFile(path, b, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var (
+		mtx    sync.Mutex
+		signed []string
+	)
+	sign := func(binary string) error {
+		mtx.Lock()
+		signed = append(signed, filepath.Base(binary))
+		mtx.Unlock()
+		f, err := os.OpenFile(binary, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = f.WriteString("signed")
+		return err
+	}
+	run := func(identity string, binaries ...string) []string {
+		t.Helper()
+		// The cache is read again as by another run.
+		cache, err := loadSignCache(filepath.Join(dir, signCacheFilename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		signed = nil
+		if err := signBinaries(binaries, binarySigner{sign: sign, identity: identity, cache: cache}, 2, false); err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(signed)
+		return signed
+	}
+
+	if got, exp := run("a", foo, bar), []string{"bar", "foo"}; !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected %v signed, got %v", exp, got)
+	}
+	if got := run("a", foo, bar); len(got) > 0 {
+		t.Fatalf("expected the signed binaries to be skipped, got %v signed", got)
+	}
+	// The binaries are signed again with another identity or once changed.
+	if got, exp := run("b", foo), []string{"foo"}; !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected %v signed with another identity, got %v", exp, got)
+	}
+	if err := os.WriteFile(bar, []byte("rebuilt"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := run("a", bar), []string{"bar"}; !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected %v signed once changed, got %v", exp, got)
+	}
+	// The binaries whose signature doesn't verify are signed again.
+	run("a", macho)
+	if got, exp := run("a", macho), []string{"macho"}; !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected %v signed again, got %v", exp, got)
+	}
+}
+
+func TestCodesignVerify(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	// The fake commands log their name and last argument, gpg failing.
+	for _, name := range []string{"rcodesign", "osslsigncode", "cosign", "gpg"} {
+		script := fmt.Sprintf(`for arg in "$@"; do last="$arg"; done
+echo "%s $(basename "$last")" >> %s
+`, name, log)
+		if name == "gpg" {
+			script += "exit 1\n"
+		}
+		fakeCommand(t, name, script)
+	}
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+
+	artifacts := filepath.Join(dir, "artifacts")
+	for name, content := range map[string][]byte{
+		"darwin-arm64/foo":      testMachO(),
+		"windows-amd64/foo.exe": testPE(),
+		"foo.tar.gz":            nil,
+		"foo.tar.gz.sig":        nil,
+		"foo.tar.gz.pem":        nil,
+		"foo.deb":               nil,
+		"foo.deb.asc":           nil,
+		"bar.tar.gz.sig":        nil,
+		"README.md":             nil,
+	} {
+		path := filepath.Join(artifacts, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := runCodesignVerify(artifacts, "identity", defaultCosignIssuer)
+	if err == nil || err.Error() != filepath.Join(artifacts, "foo.deb")+": invalid gpg signature: exit status 1" {
+		t.Fatalf("expected the gpg signature to be invalid, got %v", err)
+	}
+	b, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(strings.TrimSpace(string(b)), "\n")
+	sort.Strings(got)
+	exp := []string{"cosign foo.tar.gz", "gpg foo.deb", "osslsigncode foo.exe", "rcodesign foo"}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected verifications %v, got %v", exp, got)
+	}
+
+	if err := runCodesignVerify(filepath.Join(artifacts, "README.md"), "identity", defaultCosignIssuer); err == nil {
+		t.Fatal("expected an error for an unsigned file")
+	}
+}
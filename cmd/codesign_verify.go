@@ -0,0 +1,166 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/promu/util/sh"
+)
+
+// defaultCosignIssuer is the OIDC issuer of the keyless cosign signatures
+// made in GitHub Actions.
+const defaultCosignIssuer = "https://token.actions.githubusercontent.com"
+
+var (
+	codesignVerifycmd      = codesigncmd.Command("verify", "Verify the macOS, Authenticode, cosign and GPG signatures of the artifacts")
+	codesignVerifyIdentity = codesignVerifycmd.Flag("certificate-identity-regexp", "Regexp of the identity of the cosign certificates, the workflows of the GitHub repository of the project by default").
+				String()
+	codesignVerifyIssuer = codesignVerifycmd.Flag("certificate-oidc-issuer", "OIDC issuer of the cosign certificates").
+				Default(defaultCosignIssuer).String()
+	codesignVerifyPath = codesignVerifycmd.Arg("path", "Path of the artifact, or of a directory whose artifacts are verified").
+				Default(".tarballs").String()
+)
+
+// signatureCheck verifies the signature of a file.
+type signatureCheck struct {
+	file string
+	// kind is the kind of signature, e.g. cosign.
+	kind   string
+	verify func() error
+}
+
+// runCodesignVerify verifies the signatures of the artifact at the path, or
+// of the artifacts of the directory, reporting all the invalid ones.
+func runCodesignVerify(path, identity, issuer string) error {
+	if identity == "" {
+		identity = defaultCosignIdentity()
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	var files []string
+	if fi.IsDir() {
+		err := filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		// The detached signatures of the file are verified too.
+		files = []string{path, path + ".sig", path + ".asc"}
+	}
+
+	var checks []signatureCheck
+	for _, file := range files {
+		checks = append(checks, signatureChecks(file, identity, issuer)...)
+	}
+	if len(checks) == 0 {
+		return fmt.Errorf("no signed artifacts found in %s", path)
+	}
+	var errs []error
+	for _, c := range checks {
+		if err := c.verify(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid %s signature: %w", c.file, c.kind, err))
+			continue
+		}
+		fmt.Printf(" > verified the %s signature of %s\n", c.kind, c.file)
+	}
+	return errors.Join(errs...)
+}
+
+// defaultCosignIdentity returns the regexp of the identity of the keyless
+// cosign certificates of the GitHub workflows of the project.
+func defaultCosignIdentity() string {
+	return fmt.Sprintf("^https://github\\.com/%s/%s/", regexp.QuoteMeta(projInfo.Owner), regexp.QuoteMeta(projInfo.Name))
+}
+
+// signatureChecks returns the checks of the signatures of the file, if it is
+// signed: the cosign signatures (<file>.sig), keyless with their
+// certificates (<file>.pem) or of the KMS key of the key provider, and the
+// GPG signatures (<file>.asc) of the files next to them,
+// the signatures of the Mach-O binaries, the notarization tickets stapled to
+// the macOS packages and disk images and the Authenticode signatures of the
+// windows binaries.
+func signatureChecks(file, identity, issuer string) []signatureCheck {
+	exists := func(path string) bool {
+		fi, err := os.Stat(path)
+		return err == nil && fi.Mode().IsRegular()
+	}
+	if !exists(file) {
+		return nil
+	}
+	blob := strings.TrimSuffix(strings.TrimSuffix(file, ".sig"), ".asc")
+
+	switch ext := filepath.Ext(file); {
+	case ext == ".sig" && exists(blob) && exists(blob+".pem"):
+		return []signatureCheck{{file: blob, kind: signerCosign, verify: func() error {
+			return sh.RunCommand(signerCosign, "verify-blob",
+				"--signature", file,
+				"--certificate", blob+".pem",
+				"--certificate-identity-regexp", identity,
+				"--certificate-oidc-issuer", issuer,
+				blob)
+		}}}
+	case ext == ".sig" && exists(blob) && cosignKeyRef() != "":
+		// The signature of the KMS key of the key provider.
+		return []signatureCheck{{file: blob, kind: signerCosign, verify: func() error {
+			return sh.RunCommand(signerCosign, "verify-blob", "--signature", file, "--key", cosignKeyRef(), blob)
+		}}}
+	case ext == ".asc" && exists(blob):
+		return []signatureCheck{{file: blob, kind: signerGPG, verify: func() error {
+			return sh.RunCommand(signerGPG, "--batch", "--verify", file, blob)
+		}}}
+	case ext == ".pkg" || ext == ".dmg":
+		// stapler is only available on macOS.
+		return []signatureCheck{{file: file, kind: "notarization", verify: func() error {
+			return sh.RunCommand("xcrun", "stapler", "validate", file)
+		}}}
+	case ext == ".exe":
+		f, err := pe.Open(file)
+		if err != nil {
+			return nil
+		}
+		f.Close()
+		return []signatureCheck{{file: file, kind: "Authenticode", verify: func() error {
+			return sh.RunCommand(envOr("OSSLSIGNCODE", "osslsigncode"), "verify", "-in", file)
+		}}}
+	}
+	f, err := macho.Open(file)
+	if err != nil {
+		return nil
+	}
+	f.Close()
+	return []signatureCheck{{file: file, kind: "macOS", verify: func() error {
+		return rcodesign([]string{"verify", file}, file)
+	}}}
+}
@@ -15,19 +15,22 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	kingpin "github.com/alecthomas/kingpin/v2"
-	"go.uber.org/atomic"
 
+	"github.com/prometheus/promu/util/pool"
 	"github.com/prometheus/promu/util/sh"
 )
 
@@ -83,19 +86,30 @@ var (
 			return nil
 		}).Default("false").Bool()
 	parallelFlag       = crossbuildcmd.Flag("parallelism", "How many builds to run in parallel").Default("1").Int()
-	parallelThreadFlag = crossbuildcmd.Flag("parallelism-thread", "Index of the parallel build").Default("-1").Int()
-	goFlagSet          bool
-	goFlag             = crossbuildcmd.Flag("go", "Golang builder version to use (e.g. 1.11)").
-				PreAction(func(c *kingpin.ParseContext) error {
+	parallelThreadFlag = crossbuildcmd.Flag("parallelism-thread", "Zero-based index of the parallel build, only this part of the platforms is built").Default("-1").Int()
+	shardFlag          = crossbuildcmd.Flag("shard", "Only build the N-th of M shards of the platforms (e.g. 2/3), replaces --parallelism and --parallelism-thread").
+				PlaceHolder("N/M").String()
+	listShardsFlag = crossbuildcmd.Flag("list-shards", "Print the platforms of each of the --parallelism shards as JSON and exit").Bool()
+	logsFlag       = crossbuildcmd.Flag("logs", "Also write the output of each platform build to .build/logs/<platform>.log").Bool()
+	cleanupFlag    = crossbuildcmd.Flag("cleanup", "Remove the builder containers left over by interrupted runs and exit").Bool()
+	dryRunFlag     = crossbuildcmd.Flag("dry-run", "Print the platforms, builder image and threads that would be used and exit").Bool()
+	goFlagSet      bool
+	goFlag         = crossbuildcmd.Flag("go", "Golang builder version to use (e.g. 1.11)").
+			PreAction(func(c *kingpin.ParseContext) error {
 			goFlagSet = true
 			return nil
 		}).String()
+	autoGoFlag       = crossbuildcmd.Flag("auto-go", "Use the Golang builder version matching the go directive of go.mod").Bool()
 	platformsFlagSet bool
 	platformsFlag    = crossbuildcmd.Flag("platforms", "Regexp match platforms to build, may be used multiple times.").Short('p').
 				PreAction(func(c *kingpin.ParseContext) error {
 			platformsFlagSet = true
 			return nil
 		}).Strings()
+	codesignFlag = crossbuildcmd.Flag("codesign", "With tarballs, code sign the darwin binaries like promu codesign before archiving them, failing if any can't be signed").
+			Bool()
+	codesignBestEffortFlag = crossbuildcmd.Flag("codesign-best-effort", "With --codesign, only warn about the binaries which can't be signed").
+				Bool()
 	// kingpin doesn't currently support using the crossbuild command and the
 	// crossbuild tarball subcommand at the same time, so we treat the
 	// tarball subcommand as an optional arg
@@ -111,6 +125,12 @@ func runCrossbuild() {
 		runCrossbuildTarballs()
 		return
 	}
+	if *cleanupFlag {
+		if err := cleanupContainers(); err != nil {
+			fatal(err)
+		}
+		return
+	}
 
 	if crossBuildCgoFlagSet {
 		config.Go.CGo = *crossBuildCgoFlag
@@ -118,9 +138,25 @@ func runCrossbuild() {
 	if goFlagSet {
 		config.Go.Version = *goFlag
 	}
+	if err := checkBuilderGoVersion("go.mod", *autoGoFlag); err != nil {
+		fatal(err)
+	}
 	if platformsFlagSet {
 		config.Crossbuild.Platforms = *platformsFlag
 	}
+	if *shardFlag != "" {
+		index, total, err := parseShard(*shardFlag)
+		if err != nil {
+			fatal(err)
+		}
+		*parallelFlag, *parallelThreadFlag = total, index
+	}
+	if *parallelFlag < 1 {
+		fatal(fmt.Errorf("invalid parallelism %d, must be at least 1", *parallelFlag))
+	}
+	if *parallelThreadFlag >= *parallelFlag {
+		fatal(fmt.Errorf("invalid parallelism thread %d, must be lower than the parallelism (%d)", *parallelThreadFlag, *parallelFlag))
+	}
 
 	var (
 		allPlatforms     []string
@@ -135,7 +171,10 @@ func runCrossbuild() {
 		dockerMainBuilderImage = fmt.Sprintf("%s:%s-main", dockerBuilderImageName, goVersion)
 	)
 
-	var filteredPlatforms []string
+	var (
+		filteredPlatforms []string
+		matches           = make(map[string][]string, len(platforms))
+	)
 	for _, platform := range platforms {
 		p := regexp.MustCompile(platform)
 		if filteredPlatforms = inSliceRE(p, defaultPlatforms); len(filteredPlatforms) > 0 {
@@ -143,6 +182,7 @@ func runCrossbuild() {
 		} else {
 			unknownPlatforms = append(unknownPlatforms, platform)
 		}
+		matches[platform] = filteredPlatforms
 	}
 
 	// Remove duplicates, e.g. if linux/arm and linux/arm64 is specified, there
@@ -153,18 +193,135 @@ func runCrossbuild() {
 		warn(fmt.Errorf("unknown/unhandled platforms: %s", unknownPlatforms))
 	}
 
-	if !cgo {
-		// In non-CGO, use the `base` image without any crossbuild toolchain.
-		pg := &platformGroup{"base", dockerBaseBuilderImage, allPlatforms}
-		if err := pg.Build(repoPath); err != nil {
-			fatal(fmt.Errorf("The %s builder docker image exited unexpectedly: %w", pg.Name, err))
+	if *listShardsFlag {
+		if err := printShards(allPlatforms, *parallelFlag); err != nil {
+			fatal(err)
 		}
-	} else {
+		return
+	}
+
+	// In non-CGO, use the `base` image without any crossbuild toolchain.
+	pg := &platformGroup{"base", dockerBaseBuilderImage, allPlatforms}
+	if cgo {
 		// In CGO, use the `main` image with crossbuild toolchain.
-		pg := &platformGroup{"main", dockerMainBuilderImage, allPlatforms}
-		if err := pg.Build(repoPath); err != nil {
-			fatal(fmt.Errorf("The %s builder docker image exited unexpectedly: %w", pg.Name, err))
+		pg = &platformGroup{"main", dockerMainBuilderImage, allPlatforms}
+	}
+
+	if *dryRunFlag {
+		printCrossbuildPlan(platforms, matches, pg)
+		return
+	}
+
+	removeContainersOnInterrupt()
+
+	if err := pg.Build(repoPath); err != nil {
+		fatal(fmt.Errorf("The %s builder docker image exited unexpectedly: %w", pg.Name, err))
+	}
+}
+
+// goDirectivePattern matches the go directive of go.mod files.
+var goDirectivePattern = regexp.MustCompile(`(?m)^go\s+(\d+\.\d+)(\.\d+)?\s*(//.*)?$`)
+
+// checkBuilderGoVersion returns an error if the Go version of the builder
+// image is older than the go directive of the go.mod file, before the
+// compilation fails in the containers. With auto, the builder version is set
+// to the version of the go directive instead. Builder images are tagged by
+// minor version, so patch versions are ignored.
+func checkBuilderGoVersion(goModPath string, auto bool) error {
+	content, err := os.ReadFile(goModPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
 		}
+		return err
+	}
+	m := goDirectivePattern.FindSubmatch(content)
+	if m == nil {
+		return nil
+	}
+	required := string(m[1])
+
+	if auto {
+		if config.Go.Version != required {
+			info(fmt.Sprintf("Using Golang builder version %s required by %s", required, goModPath))
+		}
+		config.Go.Version = required
+		return nil
+	}
+
+	cmp, err := compareGoVersions(config.Go.Version, required)
+	if err != nil {
+		return fmt.Errorf("invalid Golang builder version: %w", err)
+	}
+	if cmp < 0 {
+		return fmt.Errorf("%s requires go %s but the Golang builder version is %s, set go.version to %s in the configuration or use --auto-go", goModPath, required, config.Go.Version, required)
+	}
+	return nil
+}
+
+// compareGoVersions compares Go versions of the form 1.N[.P] by major and
+// minor version only.
+func compareGoVersions(a, b string) (int, error) {
+	parse := func(v string) ([2]int, error) {
+		var res [2]int
+		parts := strings.SplitN(v, ".", 3)
+		if len(parts) < 2 {
+			return res, fmt.Errorf("malformed Go version %q", v)
+		}
+		for i := range res {
+			n, err := strconv.Atoi(parts[i])
+			if err != nil {
+				return res, fmt.Errorf("malformed Go version %q", v)
+			}
+			res[i] = n
+		}
+		return res, nil
+	}
+	va, err := parse(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parse(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := range va {
+		if va[i] != vb[i] {
+			if va[i] < vb[i] {
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// printCrossbuildPlan prints what each platform regexp matched and how the
+// builds are split between the builder containers.
+func printCrossbuildPlan(regexps []string, matches map[string][]string, pg *platformGroup) {
+	fmt.Println("> platform regexps:")
+	for _, re := range regexps {
+		m := matches[re]
+		if len(m) == 0 {
+			fmt.Printf("  %s: no match\n", re)
+			continue
+		}
+		fmt.Printf("  %s: %s\n", re, strings.Join(m, " "))
+	}
+
+	fmt.Printf("> %d platform(s) to build: %s\n", len(pg.Platforms), strings.Join(pg.Platforms, " "))
+	fmt.Printf("> %s builder image: %s\n", pg.Name, pg.DockerImage)
+
+	for p := 0; p < *parallelFlag; p++ {
+		if *parallelThreadFlag != -1 && *parallelThreadFlag != p {
+			continue
+		}
+		shard := shardPlatforms(pg.Platforms, p, *parallelFlag)
+		if len(shard) == 0 {
+			fmt.Printf("> thread %d (shard %d/%d): nothing to build\n", p, p+1, *parallelFlag)
+			continue
+		}
+		fmt.Printf("> thread %d (shard %d/%d): %s\n", p, p+1, *parallelFlag, strings.Join(shard, " "))
 	}
 }
 
@@ -178,35 +335,54 @@ func (pg platformGroup) Build(repoPath string) error {
 	if *parallelThreadFlag != -1 {
 		return pg.buildThread(repoPath, *parallelThreadFlag)
 	}
-	err := sh.RunCommand("docker", "pull", pg.DockerImage)
-	if err != nil {
+	if err := pullImages([]string{pg.DockerImage}); err != nil {
 		return err
 	}
-	var wg sync.WaitGroup
-	wg.Add(*parallelFlag)
-	atomicErr := atomic.NewError(nil)
+	workers := pool.New(context.Background(), *parallelFlag, false)
 	for p := 0; p < *parallelFlag; p++ {
-		go func(p int) {
-			defer wg.Done()
-			if err := pg.buildThread(repoPath, p); err != nil {
-				atomicErr.Store(err)
-			}
-		}(p)
+		p := p
+		workers.Go(func(context.Context) error {
+			return pg.buildThread(repoPath, p)
+		})
 	}
-	wg.Wait()
-	return atomicErr.Load()
+	return workers.Wait()
 }
 
-func (pg platformGroup) buildThread(repoPath string, p int) error {
-	minb := p * len(pg.Platforms) / *parallelFlag
-	maxb := (p + 1) * len(pg.Platforms) / *parallelFlag
-	if maxb > len(pg.Platforms) {
-		maxb = len(pg.Platforms)
+// pullImages pulls the builder images concurrently before any build starts
+// and prints their digest.
+func pullImages(images []string) error {
+	workers := pool.New(context.Background(), len(images), true)
+	for _, image := range images {
+		image := image
+		workers.Go(func(context.Context) error {
+			if err := sh.RunCommand("docker", "pull", image); err != nil {
+				return fmt.Errorf("failed to pull %s: %w", image, err)
+			}
+			var digest bytes.Buffer
+			err := sh.RunCommandWithOutput(&digest, os.Stderr, "docker", "image", "inspect", "--format", "{{index .RepoDigests 0}}", image)
+			if err != nil {
+				return fmt.Errorf("failed to inspect %s: %w", image, err)
+			}
+			fmt.Printf("> pulled %s\n", strings.TrimSpace(digest.String()))
+			return nil
+		})
 	}
-	platformsParam := strings.Join(pg.Platforms[minb:maxb], " ")
+	return workers.Wait()
+}
+
+func (pg platformGroup) buildThread(repoPath string, p int) (err error) {
+	shard := shardPlatforms(pg.Platforms, p, *parallelFlag)
+	platformsParam := strings.Join(shard, " ")
 	if len(platformsParam) == 0 {
 		return nil
 	}
+	statusTracker.Add(shard...)
+	defer func() {
+		// Platforms which aren't finished yet failed with the container.
+		for _, platform := range shard {
+			statusTracker.Finish(platform, err)
+		}
+	}()
 
 	fmt.Printf("> running the %s builder docker image\n", pg.Name)
 
@@ -215,15 +391,22 @@ func (pg platformGroup) buildThread(repoPath string, p int) error {
 		return fmt.Errorf("couldn't get current working directory: %w", err)
 	}
 
-	ctrName := "promu-crossbuild-" + pg.Name + strconv.FormatInt(time.Now().Unix(), 10) + "-" + strconv.Itoa(p)
+	ctrName := crossbuildContainerPrefix + pg.Name + strconv.FormatInt(time.Now().Unix(), 10) + "-" + strconv.Itoa(p)
 	err = sh.RunCommand("docker", "create", "-t",
 		"--name", ctrName,
+		"--label", crossbuildContainerLabel,
 		pg.DockerImage,
 		"-i", repoPath,
 		"-p", platformsParam)
 	if err != nil {
 		return err
 	}
+	trackContainer(ctrName)
+	defer func() {
+		if rmErr := removeContainer(ctrName); err == nil {
+			err = rmErr
+		}
+	}()
 
 	err = sh.RunCommand("docker", "cp",
 		cwd+"/.",
@@ -232,18 +415,74 @@ func (pg platformGroup) buildThread(repoPath string, p int) error {
 		return err
 	}
 
-	err = sh.RunCommand("docker", "start", "-a", ctrName)
+	var logDir string
+	if *logsFlag {
+		logDir = filepath.Join(cwd, ".build", crossbuildLogsDir)
+	}
+	out := newPlatformLogWriter(os.Stdout, logDir, fmt.Sprintf("%s-%d", pg.Name, p), strings.Fields(platformsParam))
+	err = sh.RunCommandWithOutput(out, out, "docker", "start", "-a", ctrName)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
 	if err != nil {
 		return err
 	}
 
-	err = sh.RunCommand("docker", "cp", "-a",
+	return sh.RunCommand("docker", "cp", "-a",
 		ctrName+":/app/.build/.",
 		cwd+"/.build")
+}
+
+// shardPlatforms returns the platforms handled by the shard with the given
+// (zero-based) index out of total shards. Platforms are split into contiguous
+// chunks so the partitioning is deterministic for a sorted list.
+func shardPlatforms(platforms []string, index, total int) []string {
+	minb := index * len(platforms) / total
+	maxb := (index + 1) * len(platforms) / total
+	if maxb > len(platforms) {
+		maxb = len(platforms)
+	}
+	return platforms[minb:maxb]
+}
+
+// parseShard parses a shard given as "N/M" where N is between 1 and M. It
+// returns the zero-based index of the shard and the number of shards.
+func parseShard(s string) (int, int, error) {
+	n, m, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid shard %q, expected format: N/M", s)
+	}
+	index, err := strconv.Atoi(n)
 	if err != nil {
-		return err
+		return 0, 0, fmt.Errorf("invalid shard %q: %w", s, err)
+	}
+	total, err := strconv.Atoi(m)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid shard %q: %w", s, err)
+	}
+	if total < 1 || index < 1 || index > total {
+		return 0, 0, fmt.Errorf("invalid shard %q, N must be between 1 and M", s)
+	}
+	return index - 1, total, nil
+}
+
+type shard struct {
+	Shard     string   `json:"shard"`
+	Platforms []string `json:"platforms"`
+}
+
+// printShards prints the non-empty shards as a JSON list which can be used to
+// generate a CI matrix.
+func printShards(platforms []string, total int) error {
+	shards := []shard{}
+	for i := 0; i < total; i++ {
+		if p := shardPlatforms(platforms, i, total); len(p) > 0 {
+			shards = append(shards, shard{Shard: fmt.Sprintf("%d/%d", i+1, total), Platforms: p})
+		}
 	}
-	return sh.RunCommand("docker", "rm", "-f", ctrName)
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(shards)
 }
 
 func removeDuplicates(strings []string) []string {
@@ -0,0 +1,100 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/prometheus/promu/util/sh"
+)
+
+const (
+	crossbuildContainerPrefix = "promu-crossbuild-"
+	crossbuildContainerLabel  = "promu=crossbuild"
+)
+
+// builderContainers tracks the builder containers created by the current run
+// so that they can be removed when promu is interrupted.
+var builderContainers = struct {
+	sync.Mutex
+	names map[string]struct{}
+}{names: map[string]struct{}{}}
+
+func trackContainer(name string) {
+	builderContainers.Lock()
+	defer builderContainers.Unlock()
+	builderContainers.names[name] = struct{}{}
+}
+
+// removeContainer removes the container and its anonymous volumes.
+func removeContainer(name string) error {
+	builderContainers.Lock()
+	delete(builderContainers.names, name)
+	builderContainers.Unlock()
+	return sh.RunCommand("docker", "rm", "-f", "-v", name)
+}
+
+// removeContainersOnInterrupt removes the tracked containers and exits when
+// promu receives SIGINT or SIGTERM.
+func removeContainersOnInterrupt() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-c
+		warn(fmt.Errorf("received %s, removing builder containers", sig))
+
+		builderContainers.Lock()
+		var names []string
+		for name := range builderContainers.names {
+			names = append(names, name)
+		}
+		builderContainers.Unlock()
+
+		for _, name := range names {
+			if err := removeContainer(name); err != nil {
+				printErr(fmt.Errorf("failed to remove container %s: %w", name, err))
+			}
+		}
+		os.Exit(1)
+	}()
+}
+
+// cleanupContainers removes the builder containers left over by previous
+// runs. Containers are matched by label and by name since older versions of
+// promu didn't label them.
+func cleanupContainers() error {
+	var ids []string
+	for _, filter := range []string{"label=" + crossbuildContainerLabel, "name=" + crossbuildContainerPrefix} {
+		out, err := exec.Command("docker", "ps", "--all", "--quiet", "--filter", filter).Output()
+		if err != nil {
+			return fmt.Errorf("failed to list containers: %w", err)
+		}
+		ids = append(ids, strings.Fields(string(out))...)
+	}
+	ids = removeDuplicates(ids)
+	if len(ids) == 0 {
+		fmt.Println("> no builder container to remove")
+		return nil
+	}
+
+	fmt.Printf("> removing %d builder container(s)\n", len(ids))
+	return sh.RunCommand("docker", append([]string{"rm", "-f", "-v"}, ids...)...)
+}
@@ -0,0 +1,128 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	crossbuildLogsDir = "logs"
+)
+
+var (
+	// The builder image prints "# <platform>" before building each platform.
+	platformMarker = regexp.MustCompile(`^#\s+(\S+/\S+)\s*$`)
+
+	// outputMtx serializes the lines written by concurrent builders.
+	outputMtx sync.Mutex
+)
+
+// platformLogWriter prefixes each line written by a builder container with
+// the platform being built, and optionally copies it to a log file per
+// platform.
+type platformLogWriter struct {
+	out       io.Writer
+	logDir    string
+	platforms []string
+
+	buf     []byte
+	current string
+	files   map[string]*os.File
+}
+
+// newPlatformLogWriter returns a writer for a builder container handling the
+// given platforms. Lines printed before the first platform starts building
+// are attributed to the fallback name. Log files are only written if logDir
+// isn't empty.
+func newPlatformLogWriter(out io.Writer, logDir, fallback string, platforms []string) *platformLogWriter {
+	return &platformLogWriter{
+		out:       out,
+		logDir:    logDir,
+		platforms: platforms,
+		current:   fallback,
+		files:     map[string]*os.File{},
+	}
+}
+
+func (w *platformLogWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimRight(string(w.buf[:i]), "\r")
+		w.buf = w.buf[i+1:]
+		if err := w.writeLine(line); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *platformLogWriter) writeLine(line string) error {
+	if m := platformMarker.FindStringSubmatch(line); m != nil && stringInSlice(m[1], w.platforms) {
+		if stringInSlice(w.current, w.platforms) {
+			statusTracker.Finish(w.current, nil)
+		}
+		w.current = m[1]
+		statusTracker.Start(w.current)
+	}
+
+	outputMtx.Lock()
+	_, err := fmt.Fprintf(w.out, "[%s] %s\n", w.current, line)
+	outputMtx.Unlock()
+	if err != nil || w.logDir == "" {
+		return err
+	}
+
+	f, ok := w.files[w.current]
+	if !ok {
+		if err := os.MkdirAll(w.logDir, 0o777); err != nil {
+			return err
+		}
+		name := strings.ReplaceAll(w.current, "/", "-") + ".log"
+		f, err = os.Create(filepath.Join(w.logDir, name))
+		if err != nil {
+			return err
+		}
+		w.files[w.current] = f
+	}
+	_, err = fmt.Fprintln(f, line)
+	return err
+}
+
+// Close flushes any incomplete line and closes the log files.
+func (w *platformLogWriter) Close() error {
+	var err error
+	if len(w.buf) > 0 {
+		err = w.writeLine(strings.TrimRight(string(w.buf), "\r"))
+		w.buf = nil
+	}
+	for _, f := range w.files {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlatformLogWriter(t *testing.T) {
+	var (
+		out bytes.Buffer
+		dir = t.TempDir()
+		w   = newPlatformLogWriter(&out, dir, "base-0", []string{"linux/amd64", "linux/arm64"})
+	)
+	for _, s := range []string{
+		"starting\r\n# linux/amd64\n",
+		">> building\n# linux/arm",
+		"64\n# linux/mips\nno newline",
+	} {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `[base-0] starting
+[linux/amd64] # linux/amd64
+[linux/amd64] >> building
+[linux/arm64] # linux/arm64
+[linux/arm64] # linux/mips
+[linux/arm64] no newline
+`
+	if out.String() != exp {
+		t.Fatalf("expected:\n%s\ngot:\n%s", exp, out.String())
+	}
+
+	for file, exp := range map[string]string{
+		"base-0.log":      "starting\n",
+		"linux-amd64.log": "# linux/amd64\n>> building\n",
+		"linux-arm64.log": "# linux/arm64\n# linux/mips\nno newline\n",
+	} {
+		got, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != exp {
+			t.Fatalf("%s: expected %q, got %q", file, exp, got)
+		}
+	}
+}
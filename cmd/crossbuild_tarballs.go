@@ -15,10 +15,14 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+
+	"github.com/prometheus/promu/util/pool"
 )
 
 func runCrossbuildTarballs() {
@@ -27,22 +31,71 @@ func runCrossbuildTarballs() {
 		fatal(err)
 	}
 
+	var regexps []string
+	if platformsFlagSet {
+		regexps = *platformsFlag
+	}
+	match, err := platformFilter(regexps)
+	if err != nil {
+		fatal(err)
+	}
+
 	fmt.Println(">> building release tarballs")
+	var targets []tarballTarget
 	for _, dir := range dirs {
-		config.Tarball.Prefix = ".tarballs"
+		if dir.Name() == crossbuildLogsDir {
+			continue
+		}
+		platform := strings.Split(dir.Name(), "-")
+		if len(platform) != 2 {
+			fatal(fmt.Errorf("bad .build/%s directory naming, should be <GOOS>-<GOARCH>", dir.Name()))
+		}
+		if !match(platform[0] + "/" + platform[1]) {
+			continue
+		}
+		targets = append(targets, tarballTarget{
+			goos:     platform[0],
+			goarch:   platform[1],
+			binaries: filepath.Join(".build", dir.Name()),
+			prefix:   ".tarballs",
+		})
+		statusTracker.Add(dir.Name())
+	}
 
-		if platform := strings.Split(dir.Name(), "-"); len(platform) == 2 {
-			os.Setenv("GOOS", platform[0])
-			os.Setenv("GOARCH", platform[1])
-		} else {
-			if err := fmt.Errorf("bad .build/%s directory naming, should be <GOOS>-<GOARCH>", platform); err != nil {
+	// The binaries are signed before being archived, so that unsigned
+	// darwin binaries don't end up in the tarballs unnoticed.
+	if *codesignFlag {
+		var binaries []string
+		for _, t := range targets {
+			if t.goos != "darwin" {
+				continue
+			}
+			b, err := platformBinaries(t.binaries, t.goos)
+			if err != nil {
 				fatal(err)
 			}
+			binaries = append(binaries, b...)
+		}
+		if err := signBinaries(binaries, binarySigner{sign: codeSignGoBinary}, runtime.NumCPU(), *codesignBestEffortFlag); err != nil {
+			fatal(err)
 		}
-
-		runTarball(filepath.Join(".build", dir.Name()))
 	}
 
-	defer os.Unsetenv("GOOS")
-	defer os.Unsetenv("GOARCH")
+	workers := pool.New(context.Background(), runtime.NumCPU(), true)
+	for _, t := range targets {
+		t := t
+		workers.Go(func(context.Context) error {
+			name := t.goos + "-" + t.goarch
+			statusTracker.Start(name)
+			err := createTarballs(t)
+			statusTracker.Finish(name, err)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			return nil
+		})
+	}
+	if err := workers.Wait(); err != nil {
+		fatal(err)
+	}
 }
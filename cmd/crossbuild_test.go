@@ -14,6 +14,8 @@
 package cmd
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -25,3 +27,99 @@ func TestRemoveDuplicates(t *testing.T) {
 		t.Fatalf("%q != %q", deduplicate, output)
 	}
 }
+
+func TestShardPlatforms(t *testing.T) {
+	platforms := []string{"darwin/amd64", "linux/386", "linux/amd64", "linux/arm64", "windows/amd64"}
+	var got []string
+	for i := 0; i < 3; i++ {
+		shard := shardPlatforms(platforms, i, 3)
+		if len(shard) == 0 {
+			t.Fatalf("shard %d is empty", i)
+		}
+		got = append(got, shard...)
+	}
+	if !reflect.DeepEqual(got, platforms) {
+		t.Fatalf("%q != %q", got, platforms)
+	}
+
+	if shard := shardPlatforms(platforms[:1], 0, 2); len(shard) != 0 {
+		t.Fatalf("expected empty shard, got %q", shard)
+	}
+}
+
+func TestParseShard(t *testing.T) {
+	for _, tc := range []struct {
+		in    string
+		index int
+		total int
+		err   bool
+	}{
+		{in: "1/1", index: 0, total: 1},
+		{in: "3/4", index: 2, total: 4},
+		{in: "0/4", err: true},
+		{in: "5/4", err: true},
+		{in: "1/0", err: true},
+		{in: "1", err: true},
+		{in: "a/b", err: true},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			index, total, err := parseShard(tc.in)
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if index != tc.index || total != tc.total {
+				t.Fatalf("expected %d/%d, got %d/%d", tc.index, tc.total, index, total)
+			}
+		})
+	}
+}
+
+func TestCheckBuilderGoVersion(t *testing.T) {
+	defer func(c *Config) { config = c }(config)
+
+	goMod := filepath.Join(t.TempDir(), "go.mod")
+	if err := os.WriteFile(goMod, []byte("module example.com/foo\n\ngo 1.22.3\n\ntoolchain go1.23.1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		builder string
+		auto    bool
+		exp     string
+		err     bool
+	}{
+		{builder: "1.22", exp: "1.22"},
+		{builder: "1.23", exp: "1.23"},
+		{builder: "1.21", err: true},
+		{builder: "1.9", err: true},
+		{builder: "1.21", auto: true, exp: "1.22"},
+		{builder: "latest", err: true},
+	} {
+		config = NewConfig()
+		config.Go.Version = tc.builder
+		err := checkBuilderGoVersion(goMod, tc.auto)
+		if tc.err {
+			if err == nil {
+				t.Fatalf("%s: expected error, got none", tc.builder)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.builder, err)
+		}
+		if config.Go.Version != tc.exp {
+			t.Fatalf("%s: expected builder version %s, got %s", tc.builder, tc.exp, config.Go.Version)
+		}
+	}
+
+	config.Go.Version = "1.9"
+	if err := checkBuilderGoVersion(filepath.Join(t.TempDir(), "go.mod"), false); err != nil {
+		t.Fatalf("expected no error without go.mod, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/prometheus/promu/util/sh"
+)
+
+// debugInfoSuffix is the suffix of the archives holding the debug symbols of
+// the binaries.
+const debugInfoSuffix = "-debuginfo"
+
+// splitDebugInfo copies the binary to dir, extracts its debug symbols to a
+// <binary>.debug file next to the copy and strips the copy, which is linked
+// to the debug file with a .gnu_debuglink section so that debuggers find the
+// symbols when both files are in the same directory. The built binary is left
+// untouched. It returns the paths of the stripped copy and of the debug file.
+//
+// objcopy is used unless the OBJCOPY environment variable points to another
+// implementation, e.g. llvm-objcopy to handle Mach-O binaries.
+func splitDebugInfo(binary, dir string) (string, string, error) {
+	var (
+		objcopy  = envOr("OBJCOPY", "objcopy")
+		stripped = filepath.Join(dir, filepath.Base(binary))
+		debug    = stripped + ".debug"
+	)
+	if err := copyFile(binary, stripped); err != nil {
+		return "", "", err
+	}
+	if err := sh.RunCommand(objcopy, "--only-keep-debug", stripped, debug); err != nil {
+		return "", "", fmt.Errorf("failed to extract the debug symbols of %s: %w", binary, err)
+	}
+	if err := sh.RunCommand(objcopy, "--strip-all", "--add-gnu-debuglink="+debug, stripped); err != nil {
+		return "", "", fmt.Errorf("failed to strip %s: %w", binary, err)
+	}
+	return stripped, debug, nil
+}
+
+// copyFile copies the file src to dst, keeping its permissions.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
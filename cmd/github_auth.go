@@ -0,0 +1,199 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/google/go-github/v25/github"
+)
+
+// githubToken returns the token authenticating to the repository of the
+// target: the token of its environment variable, a token of the
+// installation of its GitHub App or a token exchanged for the OIDC token of
+// the GitHub Actions job.
+func githubToken(ctx context.Context, target ReleaseTarget, owner, repo string) (string, error) {
+	if target.App != nil && target.OIDC != nil {
+		return "", fmt.Errorf("%s: app and oidc are mutually exclusive", target.Repository)
+	}
+	if target.TokenEnv != "" && (target.App != nil || target.OIDC != nil) {
+		return "", fmt.Errorf("%s: token_env can't be used with app or oidc", target.Repository)
+	}
+
+	switch {
+	case target.App != nil:
+		key, err := parseAppPrivateKey(os.Getenv(target.App.PrivateKeyEnv))
+		if err != nil {
+			return "", fmt.Errorf("invalid private key in %s: %w", target.App.PrivateKeyEnv, err)
+		}
+		jwt, err := appJWT(target.App.ID, key, time.Now())
+		if err != nil {
+			return "", err
+		}
+		return appInstallationToken(ctx, newGitHubClient(ctx, jwt), target.App.InstallationID, owner, repo)
+	case target.OIDC != nil:
+		return oidcToken(ctx, releaseHTTPClient(), *target.OIDC)
+	}
+
+	tokenEnv := target.TokenEnv
+	if tokenEnv == "" {
+		tokenEnv = "GITHUB_TOKEN"
+	}
+	token := os.Getenv(tokenEnv)
+	if len(token) == 0 {
+		return "", fmt.Errorf("%s not defined", tokenEnv)
+	}
+	return token, nil
+}
+
+// parseAppPrivateKey parses the PEM encoded RSA private key of a GitHub
+// App, in PKCS #1 form as generated by GitHub or in PKCS #8 form.
+func parseAppPrivateKey(s string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("unexpected %T private key, expected RSA", key)
+	}
+	return rsaKey, nil
+}
+
+// appJWT returns the JSON Web Token authenticating as the GitHub App. It is
+// backdated by a minute to allow for clock drift and expires after 9
+// minutes, GitHub refusing tokens valid for more than 10 minutes.
+func appJWT(id int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": fmt.Sprint(id),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// appInstallationToken returns a token of the installation of the GitHub
+// App, the client being authenticated as the app. The installation is
+// looked up from the repository unless its ID is given.
+func appInstallationToken(ctx context.Context, client *github.Client, installationID int64, owner, repo string) (string, error) {
+	if installationID == 0 {
+		installation, _, err := client.Apps.FindRepositoryInstallation(ctx, owner, repo)
+		if err != nil {
+			return "", fmt.Errorf("failed to find the app installation of %s/%s: %w", owner, repo, err)
+		}
+		installationID = installation.GetID()
+	}
+	token, _, err := client.Apps.CreateInstallationToken(ctx, installationID)
+	if err != nil {
+		return "", fmt.Errorf("failed to create an app installation token: %w", err)
+	}
+	return token.GetToken(), nil
+}
+
+// oidcToken requests the OIDC token of the GitHub Actions job, which needs
+// the id-token: write permission, and exchanges it for a GitHub token.
+func oidcToken(ctx context.Context, client *http.Client, o GitHubOIDC) (string, error) {
+	requestURL, requestToken := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL"), os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if requestURL == "" || requestToken == "" {
+		return "", errors.New("no OIDC token available, oidc requires a GitHub Actions job with the id-token: write permission")
+	}
+	exchange, err := url.Parse(o.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid OIDC exchange URL: %w", err)
+	}
+	audience := o.Audience
+	if audience == "" {
+		audience = exchange.Host
+	}
+
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("audience", audience)
+	u.RawQuery = q.Encode()
+	var id struct {
+		Value string `json:"value"`
+	}
+	if err := getJSON(ctx, client, u.String(), requestToken, &id); err != nil {
+		return "", fmt.Errorf("failed to request the OIDC token: %w", err)
+	}
+
+	var exchanged struct {
+		Token string `json:"token"`
+	}
+	if err := getJSON(ctx, client, exchange.String(), id.Value, &exchanged); err != nil {
+		return "", fmt.Errorf("failed to exchange the OIDC token: %w", err)
+	}
+	if exchanged.Token == "" {
+		return "", errors.New("failed to exchange the OIDC token: no token returned")
+	}
+	return exchanged.Token, nil
+}
+
+// getJSON decodes the JSON response of a GET request authenticated with the
+// bearer token.
+func getJSON(ctx context.Context, client *http.Client, u, token string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
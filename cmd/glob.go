@@ -0,0 +1,96 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// isGlob returns true if the pattern contains any of the special characters
+// understood by matchGlob.
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+// matchGlob reports whether the slash-separated name matches the pattern.
+// The pattern syntax is the one of path.Match, extended with "**" which
+// matches any number of path segments, including none.
+func matchGlob(pattern, name string) (bool, error) {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				ok, err := matchSegments(pattern[1:], name[i:])
+				if ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+		if len(name) == 0 {
+			return false, nil
+		}
+		ok, err := path.Match(pattern[0], name[0])
+		if !ok || err != nil {
+			return false, err
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0, nil
+}
+
+// globPrefix returns the leading path segments of the pattern which don't
+// contain any special character, or "." if there is none.
+func globPrefix(pattern string) string {
+	var prefix []string
+	for _, s := range strings.Split(pattern, "/") {
+		if isGlob(s) {
+			break
+		}
+		prefix = append(prefix, s)
+	}
+	if len(prefix) == 0 {
+		return "."
+	}
+	return path.Join(prefix...)
+}
+
+// matchAnyGlob reports whether the name matches any of the patterns.
+func matchAnyGlob(patterns []string, name string) (bool, error) {
+	for _, p := range patterns {
+		ok, err := matchGlob(p, name)
+		if ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// validateGlobs returns an error if one of the patterns is malformed.
+func validateGlobs(patterns []string) error {
+	for _, p := range patterns {
+		for _, s := range strings.Split(p, "/") {
+			if _, err := path.Match(s, ""); err != nil {
+				return fmt.Errorf("invalid glob %q: %w", p, err)
+			}
+		}
+	}
+	return nil
+}
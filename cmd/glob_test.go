@@ -0,0 +1,63 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		name    string
+		exp     bool
+	}{
+		{pattern: "LICENSE", name: "LICENSE", exp: true},
+		{pattern: "*.md", name: "README.md", exp: true},
+		{pattern: "*.md", name: "docs/README.md", exp: false},
+		{pattern: "docs/**", name: "docs", exp: true},
+		{pattern: "docs/**", name: "docs/a/b/c.md", exp: true},
+		{pattern: "docs/**", name: "documentation/a.md", exp: false},
+		{pattern: "docs/**/*.json", name: "docs/dashboards/a.json", exp: true},
+		{pattern: "docs/**/*.json", name: "docs/a.json", exp: true},
+		{pattern: "docs/**/*.json", name: "docs/a.yml", exp: false},
+		{pattern: "**/internal/**", name: "docs/internal/a.md", exp: true},
+		{pattern: "**/internal/**", name: "docs/external/a.md", exp: false},
+	} {
+		t.Run(tc.pattern+" "+tc.name, func(t *testing.T) {
+			got, err := matchGlob(tc.pattern, tc.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.exp {
+				t.Fatalf("expected %v, got %v", tc.exp, got)
+			}
+		})
+	}
+
+	if _, err := matchGlob("[", "a"); err == nil {
+		t.Fatal("expected error for malformed pattern, got none")
+	}
+}
+
+func TestGlobPrefix(t *testing.T) {
+	for pattern, exp := range map[string]string{
+		"docs/**":            "docs",
+		"docs/mixins/*.json": "docs/mixins",
+		"*.md":               ".",
+		"LICENSE":            "LICENSE",
+	} {
+		if got := globPrefix(pattern); got != exp {
+			t.Errorf("%s: expected %q, got %q", pattern, exp, got)
+		}
+	}
+}
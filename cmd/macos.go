@@ -0,0 +1,183 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/prometheus/promu/util/packaging"
+	"github.com/prometheus/promu/util/sh"
+)
+
+const (
+	macOSBinDir      = "/usr/local/bin"
+	launchDaemonsDir = "/Library/LaunchDaemons"
+)
+
+// macOSIdentifier returns the identifier of the macOS package, derived from
+// the repository path by default, e.g. com.github.prometheus.node-exporter.
+func macOSIdentifier() string {
+	if config.Package.MacOS.Identifier != "" {
+		return config.Package.MacOS.Identifier
+	}
+	parts := strings.Split(config.Repository.Path, "/")
+	if host := strings.Split(parts[0], "."); len(host) > 1 {
+		for i, j := 0, len(host)-1; i < j; i, j = i+1, j-1 {
+			host[i], host[j] = host[j], host[i]
+		}
+		parts[0] = strings.Join(host, ".")
+	}
+	return strings.ToLower(strings.ReplaceAll(strings.Join(parts, "."), "_", "-"))
+}
+
+// newMacOSPackage returns the package of the macOS installer of the target.
+func newMacOSPackage(t packageTarget) *packaging.Package {
+	var (
+		macOS      = config.Package.MacOS
+		identifier = macOSIdentifier()
+		modTime    = time.Now()
+	)
+	if isReproducibleBuild() {
+		modTime = getBuildDate(os.Getenv(sourceDateEpoch))
+	}
+	p := &packaging.Package{
+		Name:        config.Package.Name,
+		Version:     projInfo.Version,
+		Arch:        t.goarch,
+		Maintainer:  config.Package.Maintainer,
+		Description: config.Package.Description,
+		Homepage:    config.Package.Homepage,
+		License:     config.Package.License,
+		ModTime:     modTime,
+	}
+	for _, binary := range config.Build.Binaries {
+		p.Files = append(p.Files, packaging.File{
+			Path:   path.Join(macOSBinDir, binary.Name),
+			Mode:   0o755,
+			Source: filepath.Join(t.binaries, binary.Name),
+		})
+	}
+	if macOS.Service != "" {
+		plist := path.Join(launchDaemonsDir, identifier+".plist")
+		p.Files = append(p.Files, packaging.File{
+			Path: plist,
+			Mode: 0o644,
+			Data: []byte(packaging.LaunchdPlist(identifier, append([]string{path.Join(macOSBinDir, macOS.Service)}, macOS.Arguments...))),
+		})
+		p.Scripts.PreInstall = fmt.Sprintf("#!/bin/sh\nlaunchctl bootout system/%s 2>/dev/null || true\n", identifier)
+		p.Scripts.PostInstall = fmt.Sprintf("#!/bin/sh\nlaunchctl bootstrap system %s\n", plist)
+	}
+	return p
+}
+
+// createMacOSPkg creates the macOS installer of the target. Its bill of
+// materials is created by mkbom, from bomutils on Linux, unless the MKBOM
+// environment variable points to another implementation.
+//
+// The binaries are signed with the Developer ID Application certificate and
+// the hardened runtime required by notarization if one is configured, or
+// ad-hoc otherwise. The package is signed with the Developer ID Installer
+// certificate and notarized if configured.
+func createMacOSPkg(t packageTarget) error {
+	macOS := config.Package.MacOS
+	p := newMacOSPackage(t)
+	filename, err := packaging.MacOSPkgFilename(p)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "promu-pkg-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "root")
+
+	// The installed tree is created for mkbom, with the binaries being
+	// signed there, and used as the content of the package.
+	for i, f := range p.Files {
+		staged := filepath.Join(root, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(staged), 0o755); err != nil {
+			return err
+		}
+		if f.Source == "" {
+			if err := os.WriteFile(staged, f.Data, f.Mode.Perm()); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := copyFile(f.Source, staged); err != nil {
+			return err
+		}
+		if macOS.Sign.Application == "" {
+			err = codeSignGoBinary(staged)
+		} else {
+			err = signMacOS(macOS.Sign.Application, staged, "--code-signature-flags", "runtime")
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to sign %s: %w", path.Base(f.Path), err)
+		}
+		p.Files[i].Source = staged
+	}
+
+	bom := filepath.Join(dir, "Bom")
+	if err := sh.RunCommand(envOr("MKBOM", "mkbom"), "-u", "0", "-g", "0", root, bom); err != nil {
+		return fmt.Errorf("Failed to create the bill of materials: %w", err)
+	}
+	b, err := os.ReadFile(bom)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(" >  ", filename)
+	out := filepath.Join(config.Package.Prefix, filename)
+	err = createArchive(out, func(w io.Writer) error {
+		return packaging.WriteMacOSPkg(w, p, macOSIdentifier(), b)
+	})
+	if err != nil {
+		return err
+	}
+	if macOS.Sign.Installer != "" {
+		if err := signMacOS(macOS.Sign.Installer, out); err != nil {
+			return fmt.Errorf("Failed to sign %s: %w", filename, err)
+		}
+	}
+	if macOS.Notarize != "" {
+		args := []string{"notary-submit", "--api-key-file", macOS.Notarize, "--staple", out}
+		if err := rcodesign(args, macOS.Notarize, out); err != nil {
+			return fmt.Errorf("Failed to notarize %s: %w", filename, err)
+		}
+	}
+	return nil
+}
+
+// signMacOS signs the file with rcodesign and the PKCS#12 certificate, whose
+// password is read from the configured file if any.
+func signMacOS(p12, file string, flags ...string) error {
+	args := []string{"sign", "--p12-file", p12}
+	files := []string{p12, file}
+	if password := config.Package.MacOS.Sign.Password; password != "" {
+		args = append(args, "--p12-password-file", password)
+		files = append(files, password)
+	}
+	args = append(append(args, flags...), file)
+	return rcodesign(args, files...)
+}
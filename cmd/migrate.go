@@ -0,0 +1,293 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"go/build"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	migratecmd    = app.Command("migrate-from-makefile", "Propose a .promu.yml and promu commands replacing the targets of a Makefile")
+	migrateOutput = migratecmd.Flag("output", "Write the proposed configuration to this file instead of stdout").
+			Short('o').String()
+	migrateMakefiles = migratecmd.Arg("makefiles", "Makefiles to parse, included files are parsed too").
+				Default("Makefile").Strings()
+)
+
+// makefile holds the variables and the targets of a Makefile and the files it
+// includes.
+type makefile struct {
+	vars    map[string]string
+	targets []makeTarget
+}
+
+type makeTarget struct {
+	name   string
+	recipe []string
+}
+
+var (
+	makeVarPattern     = regexp.MustCompile(`^(?:export\s+|override\s+)?([A-Za-z_][A-Za-z0-9_]*)\s*(\?=|:=|::=|\+=|=)\s*(.*)$`)
+	makeTargetPattern  = regexp.MustCompile(`^([^\s:=#][^:=]*?)\s*::?(?:[^=]|$)`)
+	makeIncludePattern = regexp.MustCompile(`^-?include\s+(.+)$`)
+	makeDirectives     = regexp.MustCompile(`^(ifeq|ifneq|ifdef|ifndef|else|endif)\b`)
+	promuCallPattern   = regexp.MustCompile(`(?:\$[({]PROMU[)}]|\bpromu)\s+([a-z][a-z-]*)`)
+)
+
+// parseMakefile parses the variables and targets of the Makefile at the given
+// path, following include directives. Variables aren't expanded and
+// conditionals are ignored, so that all the branches are parsed.
+func parseMakefile(path string, mf *makefile) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return parseMakefileContent(f, filepath.Dir(path), mf)
+}
+
+func parseMakefileContent(r io.Reader, dir string, mf *makefile) error {
+	var (
+		scanner = bufio.NewScanner(r)
+		// current is the index of the targets of the rule being parsed.
+		current []int
+		define  bool
+		line    string
+	)
+	for scanner.Scan() {
+		line += scanner.Text()
+		if strings.HasSuffix(line, `\`) {
+			line = strings.TrimSuffix(line, `\`) + " "
+			continue
+		}
+		raw := line
+		line = ""
+
+		if define {
+			define = strings.TrimSpace(raw) != "endef"
+			continue
+		}
+		if strings.HasPrefix(raw, "\t") {
+			for _, i := range current {
+				mf.targets[i].recipe = append(mf.targets[i].recipe, strings.TrimSpace(raw))
+			}
+			continue
+		}
+
+		trimmed := strings.TrimSpace(raw)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+		case strings.HasPrefix(trimmed, "define "):
+			define = true
+			current = nil
+		case makeDirectives.MatchString(trimmed):
+		case makeIncludePattern.MatchString(trimmed):
+			current = nil
+			for _, inc := range strings.Fields(makeIncludePattern.FindStringSubmatch(trimmed)[1]) {
+				if strings.Contains(inc, "$") {
+					continue
+				}
+				if err := parseMakefile(filepath.Join(dir, inc), mf); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+		case makeVarPattern.MatchString(trimmed):
+			current = nil
+			m := makeVarPattern.FindStringSubmatch(trimmed)
+			name, op, value := m[1], m[2], strings.TrimSpace(m[3])
+			if _, ok := mf.vars[name]; ok && op == "?=" {
+				continue
+			}
+			if op == "+=" && mf.vars[name] != "" {
+				value = mf.vars[name] + " " + value
+			}
+			mf.vars[name] = value
+		case makeTargetPattern.MatchString(trimmed):
+			current = nil
+			for _, name := range strings.Fields(makeTargetPattern.FindStringSubmatch(trimmed)[1]) {
+				if strings.HasPrefix(name, ".") || strings.ContainsAny(name, "$%") {
+					continue
+				}
+				mf.targets = append(mf.targets, makeTarget{name: name})
+				current = append(current, len(mf.targets)-1)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// makeTranslation is the promu equivalent of a Makefile target.
+type makeTranslation struct {
+	target string
+	// command is the promu command replacing the target, empty if there
+	// is none.
+	command string
+	note    string
+}
+
+// translateTargets maps the targets of the Makefile to promu commands.
+// Targets defined several times (e.g. "build" in Makefile and "common-build"
+// in Makefile.common) are translated once.
+func translateTargets(mf *makefile) []makeTranslation {
+	var (
+		res  []makeTranslation
+		seen = map[string]struct{}{}
+	)
+	for _, t := range mf.targets {
+		name := strings.TrimPrefix(t.name, "common-")
+		if _, ok := seen[name]; ok || name == "promu" {
+			continue
+		}
+		seen[name] = struct{}{}
+
+		tr := makeTranslation{target: name}
+		for _, line := range t.recipe {
+			if m := promuCallPattern.FindStringSubmatch(line); m != nil {
+				tr.command = "promu " + m[1]
+				break
+			}
+		}
+		switch {
+		case tr.command != "":
+		case name == "build" || name == "tarball" || name == "crossbuild" || name == "release" || name == "checksum":
+			tr.command = "promu " + name
+		case name == "check_license":
+			tr.command = "promu check licenses"
+		case strings.HasPrefix(name, "docker"):
+			tr.note = "promu doesn't build Docker images"
+		default:
+			tr.note = "no promu equivalent"
+		}
+		res = append(res, tr)
+	}
+	return res
+}
+
+// mainPackages returns the binaries built from the main packages of the
+// repository root and of the cmd/ directory.
+func mainPackages(name string) []Binary {
+	var binaries []Binary
+	if pkg, err := build.ImportDir(".", 0); err == nil && pkg.Name == "main" {
+		binaries = append(binaries, Binary{Name: name, Path: "."})
+	}
+	dirs, _ := filepath.Glob(filepath.Join("cmd", "*"))
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		if pkg, err := build.ImportDir(dir, 0); err == nil && pkg.Name == "main" {
+			binaries = append(binaries, Binary{Name: filepath.Base(dir), Path: "./" + filepath.ToSlash(dir)})
+		}
+	}
+	return binaries
+}
+
+// proposedConfig returns a promu configuration for the repository. The Go
+// version comes from go.mod and CGO is enabled if the Makefile enables it.
+func proposedConfig(mf *makefile, binaries []Binary, goVersion string, files []string) string {
+	var b strings.Builder
+	if goVersion != "" || mf.vars["CGO_ENABLED"] == "1" {
+		b.WriteString("go:\n")
+		if goVersion != "" {
+			fmt.Fprintf(&b, "    version: %s\n", goVersion)
+		}
+		if mf.vars["CGO_ENABLED"] == "1" {
+			b.WriteString("    cgo: true\n")
+		}
+	}
+	fmt.Fprintf(&b, "repository:\n    path: %s\n", projInfo.Repo)
+
+	b.WriteString("build:\n")
+	if len(binaries) > 0 {
+		b.WriteString("    binaries:\n")
+		for _, bin := range binaries {
+			fmt.Fprintf(&b, "        - name: %s\n          path: %s\n", bin.Name, bin.Path)
+		}
+	}
+	if flags := mf.vars["GOFLAGS"]; flags != "" && !strings.Contains(flags, "$") {
+		fmt.Fprintf(&b, "    flags: %s\n", flags)
+	}
+	b.WriteString(`    ldflags: |
+        -X github.com/prometheus/common/version.Version={{.Version}}
+        -X github.com/prometheus/common/version.Revision={{.Revision}}
+        -X github.com/prometheus/common/version.Branch={{.Branch}}
+        -X github.com/prometheus/common/version.BuildUser={{user}}@{{host}}
+        -X github.com/prometheus/common/version.BuildDate={{date "20060102-15:04:05"}}
+`)
+
+	if len(files) > 0 {
+		b.WriteString("tarball:\n    files:\n")
+		for _, f := range files {
+			fmt.Fprintf(&b, "        - %s\n", f)
+		}
+	}
+	return b.String()
+}
+
+func runMigrateFromMakefile(makefiles []string, output string) error {
+	mf := &makefile{vars: map[string]string{}}
+	for _, path := range makefiles {
+		if err := parseMakefile(path, mf); err != nil {
+			return err
+		}
+	}
+
+	var goVersion string
+	if content, err := os.ReadFile("go.mod"); err == nil {
+		if m := goDirectivePattern.FindSubmatch(content); m != nil {
+			goVersion = string(m[1])
+		}
+	}
+	var files []string
+	for _, f := range []string{"LICENSE", "NOTICE"} {
+		if fileExists(f) {
+			files = append(files, f)
+		}
+	}
+	cfg := proposedConfig(mf, mainPackages(projInfo.Name), goVersion, files)
+
+	if output == "" {
+		fmt.Print(cfg)
+	} else {
+		if fileExists(output) {
+			return fmt.Errorf("%s already exists", output)
+		}
+		if err := os.WriteFile(output, []byte(cfg), 0o644); err != nil {
+			return err
+		}
+	}
+
+	translations := translateTargets(mf)
+	fmt.Fprintln(os.Stderr, ">> translated targets")
+	for _, tr := range translations {
+		if tr.command != "" {
+			fmt.Fprintf(os.Stderr, " >   %s: %s\n", tr.target, tr.command)
+		}
+	}
+	fmt.Fprintln(os.Stderr, ">> targets which couldn't be translated")
+	for _, tr := range translations {
+		if tr.command == "" {
+			fmt.Fprintf(os.Stderr, " >   %s: %s\n", tr.target, tr.note)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTranslateTargets(t *testing.T) {
+	in := `
+CGO_ENABLED ?= 1
+CGO_ENABLED ?= 0
+DOCKER_ARCHS ?= amd64 \
+                arm64
+
+define template
+foo:
+	echo foo
+endef
+
+.PHONY: common-build
+common-build: promu
+	@echo ">> building binaries"
+	$(PROMU) build --prefix $(PREFIX)
+
+.PHONY: build
+build: common-build
+
+ifeq ($(GOHOSTOS),linux)
+common-test:
+	go test ./...
+endif
+
+assets web-assets:
+	cd web && npm ci
+
+common-docker: $(BUILD_DOCKER_ARCHS)
+	docker build .
+
+%: common-% ;
+
+publish:
+	promu release .tarballs
+`
+	mf := &makefile{vars: map[string]string{}}
+	if err := parseMakefileContent(strings.NewReader(in), ".", mf); err != nil {
+		t.Fatal(err)
+	}
+	if mf.vars["CGO_ENABLED"] != "1" {
+		t.Fatalf("expected CGO_ENABLED=1, got %q", mf.vars["CGO_ENABLED"])
+	}
+	if got := strings.Join(strings.Fields(mf.vars["DOCKER_ARCHS"]), " "); got != "amd64 arm64" {
+		t.Fatalf("expected DOCKER_ARCHS=amd64 arm64, got %q", got)
+	}
+
+	exp := []makeTranslation{
+		{target: "build", command: "promu build"},
+		{target: "test", note: "no promu equivalent"},
+		{target: "assets", note: "no promu equivalent"},
+		{target: "web-assets", note: "no promu equivalent"},
+		{target: "docker", note: "promu doesn't build Docker images"},
+		{target: "publish", command: "promu release"},
+	}
+	if got := translateTargets(mf); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected %+v, got %+v", exp, got)
+	}
+}
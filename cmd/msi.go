@@ -0,0 +1,103 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/prometheus/promu/util/packaging"
+	"github.com/prometheus/promu/util/sh"
+)
+
+// newMSIPackage returns the package of the Windows installer of the target,
+// whose file paths are relative to the installation directory, and its
+// options.
+func newMSIPackage(t packageTarget) (*packaging.Package, packaging.MSIOptions, error) {
+	p := &packaging.Package{
+		Name:        config.Package.Name,
+		Version:     projInfo.Version,
+		Arch:        t.goarch,
+		Maintainer:  config.Package.Maintainer,
+		Description: config.Package.Description,
+		Homepage:    config.Package.Homepage,
+		License:     config.Package.License,
+	}
+	o := packaging.MSIOptions{
+		Manufacturer:     config.Package.Vendor,
+		UpgradeCode:      config.Package.MSI.UpgradeCode,
+		ServiceArguments: config.Package.MSI.Arguments,
+		Path:             config.Package.MSI.Path,
+	}
+	// The WiX source is compiled in a temporary directory.
+	binaries, err := filepath.Abs(t.binaries)
+	if err != nil {
+		return nil, o, err
+	}
+	for _, binary := range config.Build.Binaries {
+		p.Files = append(p.Files, packaging.File{
+			Path:   "/" + binary.Name + ".exe",
+			Mode:   0o755,
+			Source: filepath.Join(binaries, binary.Name+".exe"),
+		})
+	}
+	if o.Manufacturer == "" {
+		o.Manufacturer = config.Windows.Company
+	}
+	if config.Package.MSI.Service != "" {
+		o.Service = "/" + config.Package.MSI.Service + ".exe"
+	}
+	return p, o, nil
+}
+
+// createMSI creates the Windows installer of the target by compiling its WiX
+// source with wixl, from msitools, unless the WIXL environment variable
+// points to another implementation.
+func createMSI(t packageTarget) error {
+	p, o, err := newMSIPackage(t)
+	if err != nil {
+		return err
+	}
+	filename, err := packaging.MSIFilename(p)
+	if err != nil {
+		return err
+	}
+	arch, err := packaging.MSIArch(t.goarch)
+	if err != nil {
+		return err
+	}
+	var wxs bytes.Buffer
+	if err := packaging.WriteWXS(&wxs, p, o); err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "promu-msi-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	source := filepath.Join(dir, config.Package.Name+".wxs")
+	if err := os.WriteFile(source, wxs.Bytes(), 0o644); err != nil {
+		return err
+	}
+
+	fmt.Println(" >  ", filename)
+	out := filepath.Join(config.Package.Prefix, filename)
+	if err := sh.RunCommand(envOr("WIXL", "wixl"), "--arch", arch, "--output", out, source); err != nil {
+		return fmt.Errorf("Failed to build %s: %w", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,585 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+	"time"
+
+	kingpin "github.com/alecthomas/kingpin/v2"
+
+	"github.com/prometheus/promu/util/packaging"
+	"github.com/prometheus/promu/util/pool"
+)
+
+const (
+	packageFormatDeb       = "deb"
+	packageFormatRPM       = "rpm"
+	packageFormatArchLinux = "archlinux"
+	packageFormatMSI       = "msi"
+	packageFormatMacOS     = "pkg"
+	packageFormatSnap      = "snap"
+
+	// Directories of the systemd units and of the sysusers.d and tmpfiles.d
+	// files installed by packages.
+	systemdUnitDir = "/usr/lib/systemd/system"
+	sysusersDir    = "/usr/lib/sysusers.d"
+	tmpfilesDir    = "/usr/lib/tmpfiles.d"
+	logrotateDir   = "/etc/logrotate.d"
+)
+
+// PackageFile is a file of the repository installed by the Linux packages.
+type PackageFile struct {
+	// Src is the path of the file or directory in the repository.
+	// Directories are installed recursively.
+	Src string
+	// Dst is the absolute path of the installed file or directory.
+	Dst string
+	// Mode overrides the permissions of the installed files.
+	Mode os.FileMode
+	// Config marks configuration files, which aren't replaced on upgrade
+	// if they were modified.
+	Config bool
+}
+
+var (
+	packagecmd      = app.Command("package", "Create packages of the crossbuilt binaries")
+	packagebuildcmd = packagecmd.Command("build", "Create Linux packages and Windows and macOS installers from the crossbuilt binaries").Default()
+
+	packageFormatsSet bool
+	packageFormats    = packagebuildcmd.Flag("format", "Package format (deb, rpm, archlinux, snap, msi or pkg), may be used multiple times. Defaults to the formats of the configuration").
+				Short('f').
+				PreAction(func(c *kingpin.ParseContext) error {
+			packageFormatsSet = true
+			return nil
+		}).
+		Enums(packageFormatDeb, packageFormatRPM, packageFormatArchLinux, packageFormatSnap, packageFormatMSI, packageFormatMacOS)
+	packagePlatforms = packagebuildcmd.Flag("platforms", "Regexp match platforms to package, may be used multiple times.").
+				Short('p').Strings()
+	packagePrefixSet bool
+	packagePrefix    = packagebuildcmd.Flag("prefix", "Specific dir to store packages").
+				PreAction(func(c *kingpin.ParseContext) error {
+			packagePrefixSet = true
+			return nil
+		}).
+		Default(".tarballs").String()
+	packageInspect = packagebuildcmd.Flag("inspect", "Print the files, scripts and metadata of the packages, and the problems found in them, without building them").Bool()
+
+	packageLocation = packagebuildcmd.Arg("location", "Location of the crossbuilt binaries, with one <GOOS>-<GOARCH> directory per platform").
+			Default(".build").String()
+)
+
+// packageTarget holds the parameters of the package of a platform.
+type packageTarget struct {
+	format string
+	goarch string
+	// binaries is the directory of the built binaries.
+	binaries string
+}
+
+// name returns the name of the target in the status and the errors.
+func (t packageTarget) name() string {
+	return t.format + " " + filepath.Base(t.binaries)
+}
+
+func runPackage(location string) {
+	if packageFormatsSet {
+		config.Package.Formats = *packageFormats
+	}
+	if packagePrefixSet {
+		config.Package.Prefix = *packagePrefix
+	}
+	if config.Package.Homepage == "" {
+		config.Package.Homepage = "https://" + config.Repository.Path
+	}
+	if config.Package.User == "" {
+		config.Package.User = config.Package.Name
+	}
+	if config.Package.Group == "" {
+		config.Package.Group = config.Package.User
+	}
+	for _, format := range config.Package.Formats {
+		switch format {
+		case packageFormatDeb, packageFormatRPM, packageFormatArchLinux, packageFormatSnap, packageFormatMSI, packageFormatMacOS:
+		default:
+			fatal(fmt.Errorf("unsupported package format %q", format))
+		}
+	}
+	for field, relations := range map[string]map[string][]string{
+		"depends":    config.Package.Depends,
+		"recommends": config.Package.Recommends,
+		"conflicts":  config.Package.Conflicts,
+		"provides":   config.Package.Provides,
+	} {
+		for format := range relations {
+			switch format {
+			case packageFormatDeb, packageFormatRPM, packageFormatArchLinux:
+			default:
+				fatal(fmt.Errorf("unsupported package format %q in %s", format, field))
+			}
+		}
+	}
+
+	dirs, err := os.ReadDir(location)
+	if err != nil {
+		fatal(err)
+	}
+	match, err := platformFilter(*packagePlatforms)
+	if err != nil {
+		fatal(err)
+	}
+
+	var (
+		targets        []packageTarget
+		archLinuxArchs []string
+	)
+	for _, dir := range dirs {
+		goos, goarch, ok := strings.Cut(dir.Name(), "-")
+		if !dir.IsDir() || !ok || !match(goos+"/"+goarch) {
+			continue
+		}
+		for _, format := range config.Package.Formats {
+			if goos != packageFormatOS(format) {
+				continue
+			}
+			switch format {
+			case packageFormatArchLinux:
+				if _, err := packaging.ArchLinuxArch(goarch); err != nil {
+					warn(fmt.Errorf("skipping Arch Linux package for linux/%s: %w", goarch, err))
+					continue
+				}
+				archLinuxArchs = append(archLinuxArchs, goarch)
+			case packageFormatSnap:
+				if _, err := packaging.SnapArch(goarch); err != nil {
+					warn(fmt.Errorf("skipping snap for linux/%s: %w", goarch, err))
+					continue
+				}
+			case packageFormatMSI:
+				if _, err := packaging.MSIArch(goarch); err != nil {
+					warn(fmt.Errorf("skipping MSI for windows/%s: %w", goarch, err))
+					continue
+				}
+			}
+			targets = append(targets, packageTarget{
+				format:   format,
+				goarch:   goarch,
+				binaries: filepath.Join(location, dir.Name()),
+			})
+		}
+	}
+	if len(targets) == 0 {
+		fatal(fmt.Errorf("no binaries to package found in %s", location))
+	}
+	if *packageInspect {
+		for _, t := range targets {
+			if err := inspectPackage(os.Stdout, t); err != nil {
+				fatal(fmt.Errorf("%s: %w", t.name(), err))
+			}
+		}
+		return
+	}
+
+	fmt.Println(">> building packages")
+	for _, t := range targets {
+		statusTracker.Add(t.name())
+	}
+
+	if err := os.MkdirAll(config.Package.Prefix, 0o777); err != nil {
+		fatal(err)
+	}
+	workers := pool.New(context.Background(), runtime.NumCPU(), true)
+	for _, t := range targets {
+		t := t
+		workers.Go(func(context.Context) error {
+			name := t.name()
+			statusTracker.Start(name)
+			err := createPackage(t)
+			statusTracker.Finish(name, err)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			return nil
+		})
+	}
+	if err := workers.Wait(); err != nil {
+		fatal(err)
+	}
+
+	if len(archLinuxArchs) > 0 {
+		if err := writePKGBUILD(archLinuxArchs); err != nil {
+			fatal(fmt.Errorf("Failed to write PKGBUILD: %w", err))
+		}
+	}
+}
+
+// packageFormatOS returns the operating system of the binaries packaged in
+// the format.
+func packageFormatOS(format string) string {
+	switch format {
+	case packageFormatMSI:
+		return "windows"
+	case packageFormatMacOS:
+		return "darwin"
+	}
+	return "linux"
+}
+
+// createPackage creates the package of the target. Like createTarballs, it
+// only reads the global state.
+func createPackage(t packageTarget) error {
+	switch t.format {
+	case packageFormatMSI:
+		return createMSI(t)
+	case packageFormatMacOS:
+		return createMacOSPkg(t)
+	case packageFormatSnap:
+		return createSnap(t)
+	}
+	p, err := newPackage(t)
+	if err != nil {
+		return err
+	}
+
+	var (
+		filename string
+		write    func(io.Writer, *packaging.Package) error
+	)
+	switch t.format {
+	case packageFormatDeb:
+		filename, err = packaging.DebFilename(p)
+		write = packaging.WriteDeb
+	case packageFormatRPM:
+		filename, err = packaging.RPMFilename(p)
+		write = packaging.WriteRPM
+	case packageFormatArchLinux:
+		filename, err = packaging.ArchLinuxFilename(p)
+		write = packaging.WriteArchLinux
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(" >  ", filename)
+	return createArchive(filepath.Join(config.Package.Prefix, filename), func(w io.Writer) error {
+		return write(w, p)
+	})
+}
+
+// inspectPackage writes the content of the package of the target, with the
+// problems found in it, without building it.
+func inspectPackage(w io.Writer, t packageTarget) error {
+	var (
+		p   *packaging.Package
+		err error
+	)
+	switch t.format {
+	case packageFormatMSI:
+		p, _, err = newMSIPackage(t)
+	case packageFormatMacOS:
+		p = newMacOSPackage(t)
+	case packageFormatSnap:
+		p, _ = newSnapPackage(t)
+	default:
+		p, err = newPackage(t)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, ">> %s\n", t.name())
+	if err := packaging.Inspect(w, p); err != nil {
+		return err
+	}
+	for _, warning := range packaging.Lint(p) {
+		fmt.Fprintln(w, `/!\`, warning)
+	}
+	return nil
+}
+
+// newPackage returns the package of the target, as described by the
+// package section of the configuration.
+func newPackage(t packageTarget) (*packaging.Package, error) {
+	modTime := time.Now()
+	if isReproducibleBuild() {
+		modTime = getBuildDate(os.Getenv(sourceDateEpoch))
+	}
+	p := &packaging.Package{
+		Name:        config.Package.Name,
+		Version:     projInfo.Version,
+		Arch:        t.goarch,
+		Maintainer:  config.Package.Maintainer,
+		Description: config.Package.Description,
+		Homepage:    config.Package.Homepage,
+		License:     config.Package.License,
+		Vendor:      config.Package.Vendor,
+		Depends:     config.Package.Depends[t.format],
+		Recommends:  config.Package.Recommends[t.format],
+		Conflicts:   config.Package.Conflicts[t.format],
+		Provides:    config.Package.Provides[t.format],
+		ModTime:     modTime,
+	}
+
+	for _, binary := range config.Build.Binaries {
+		p.Files = append(p.Files, packaging.File{
+			Path:   path.Join(config.Package.BinDir, binary.Name),
+			Mode:   0o755,
+			Source: filepath.Join(t.binaries, binary.Name),
+		})
+	}
+	system, err := packageSystemFiles(t.format)
+	if err != nil {
+		return nil, err
+	}
+	p.Files = append(p.Files, system...)
+	for _, f := range config.Package.Files {
+		files, err := packageFiles(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Src, err)
+		}
+		p.Files = append(p.Files, files...)
+	}
+
+	for _, s := range []struct {
+		file   string
+		script *string
+	}{
+		{config.Package.Scripts.PreInstall, &p.Scripts.PreInstall},
+		{config.Package.Scripts.PostInstall, &p.Scripts.PostInstall},
+		{config.Package.Scripts.PreRemove, &p.Scripts.PreRemove},
+		{config.Package.Scripts.PostRemove, &p.Scripts.PostRemove},
+	} {
+		if s.file == "" {
+			continue
+		}
+		b, err := os.ReadFile(s.file)
+		if err != nil {
+			return nil, err
+		}
+		*s.script = string(b)
+	}
+
+	// The file capabilities of the binaries are set in every format, if
+	// setcap is installed.
+	caps, err := binariesWithCapabilities(config.Build.Binaries)
+	if err != nil {
+		return nil, err
+	}
+	if len(caps) > 0 {
+		var setcap strings.Builder
+		setcap.WriteString("if command -v setcap >/dev/null 2>&1; then\n")
+		for _, line := range strings.SplitAfter(capabilitiesScript(caps, config.Package.BinDir), "\n") {
+			if line != "" {
+				setcap.WriteString("\t" + line)
+			}
+		}
+		setcap.WriteString("fi\n")
+		p.Scripts.PostInstall = prependScript(p.Scripts.PostInstall, setcap.String())
+	}
+
+	// pacman hooks already apply the sysusers.d and tmpfiles.d files and
+	// reload the systemd units.
+	if t.format != packageFormatArchLinux {
+		var postInstall, postRemove strings.Builder
+		for _, f := range config.Package.Sysusers {
+			fmt.Fprintf(&postInstall, "if command -v systemd-sysusers >/dev/null 2>&1; then\n\tsystemd-sysusers %s\nfi\n", path.Join(sysusersDir, filepath.Base(f)))
+		}
+		for _, f := range config.Package.Tmpfiles {
+			fmt.Fprintf(&postInstall, "if command -v systemd-tmpfiles >/dev/null 2>&1; then\n\tsystemd-tmpfiles --create %s\nfi\n", path.Join(tmpfilesDir, filepath.Base(f)))
+		}
+		if len(config.Package.Systemd) > 0 {
+			reload := "if [ -d /run/systemd/system ]; then\n\tsystemctl daemon-reload\nfi\n"
+			postInstall.WriteString(reload)
+			postRemove.WriteString(reload)
+		}
+		p.Scripts.PostInstall = prependScript(p.Scripts.PostInstall, postInstall.String())
+		p.Scripts.PostRemove = prependScript(p.Scripts.PostRemove, postRemove.String())
+	}
+	return p, nil
+}
+
+// prependScript returns the script with the commands inserted at its
+// beginning, after the shebang line if any.
+func prependScript(script, commands string) string {
+	switch {
+	case commands == "":
+		return script
+	case script == "":
+		return "#!/bin/sh\n" + commands
+	case strings.HasPrefix(script, "#!"):
+		shebang, rest, _ := strings.Cut(script, "\n")
+		return shebang + "\n" + commands + rest
+	}
+	return "#!/bin/sh\n" + commands + script
+}
+
+// packageTemplateData is the data of the templated system files of the
+// packages, e.g. to set the path of the binary and the user in a systemd
+// unit with "ExecStart={{.Binary}}" and "User={{.User}}". The installed
+// path of any binary is also given by the binary function, e.g. {{binary
+// "promtool"}}.
+type packageTemplateData struct {
+	Name    string
+	Version string
+	User    string
+	Group   string
+	BinDir  string
+	// Binary is the installed path of the first binary.
+	Binary string
+	// EnvironmentFile is the installed path of the environment file, empty
+	// if there is none.
+	EnvironmentFile string
+}
+
+// environmentFile returns the installed path of the environment file, which
+// depends on the distribution.
+func environmentFile(format string) string {
+	switch format {
+	case packageFormatRPM:
+		return path.Join("/etc/sysconfig", config.Package.Name)
+	case packageFormatArchLinux:
+		return path.Join("/etc/conf.d", config.Package.Name)
+	}
+	return path.Join("/etc/default", config.Package.Name)
+}
+
+// packageSystemFiles returns the systemd units, the sysusers.d and
+// tmpfiles.d files, the environment file and the logrotate configuration of
+// the package for the given format, rendered as text/template templates. The
+// environment file and the logrotate configuration are configuration files.
+func packageSystemFiles(format string) ([]packaging.File, error) {
+	data := packageTemplateData{
+		Name:    config.Package.Name,
+		Version: projInfo.Version,
+		User:    config.Package.User,
+		Group:   config.Package.Group,
+		BinDir:  config.Package.BinDir,
+	}
+	if len(config.Build.Binaries) > 0 {
+		data.Binary = path.Join(config.Package.BinDir, config.Build.Binaries[0].Name)
+	}
+	if config.Package.Environment != "" {
+		data.EnvironmentFile = environmentFile(format)
+	}
+	funcs := template.FuncMap{
+		"binary": func(name string) string {
+			return path.Join(config.Package.BinDir, name)
+		},
+	}
+
+	type systemFile struct {
+		src    string
+		dst    string
+		config bool
+	}
+	var sources []systemFile
+	for _, unit := range config.Package.Systemd {
+		sources = append(sources, systemFile{src: unit, dst: path.Join(systemdUnitDir, filepath.Base(unit))})
+	}
+	for _, f := range config.Package.Sysusers {
+		sources = append(sources, systemFile{src: f, dst: path.Join(sysusersDir, filepath.Base(f))})
+	}
+	for _, f := range config.Package.Tmpfiles {
+		sources = append(sources, systemFile{src: f, dst: path.Join(tmpfilesDir, filepath.Base(f))})
+	}
+	if config.Package.Environment != "" {
+		sources = append(sources, systemFile{src: config.Package.Environment, dst: data.EnvironmentFile, config: true})
+	}
+	if config.Package.Logrotate != "" {
+		sources = append(sources, systemFile{src: config.Package.Logrotate, dst: path.Join(logrotateDir, config.Package.Name), config: true})
+	}
+
+	files := make([]packaging.File, 0, len(sources))
+	for _, f := range sources {
+		b, err := os.ReadFile(f.src)
+		if err != nil {
+			return nil, err
+		}
+		tmpl, err := template.New(f.src).Funcs(funcs).Option("missingkey=error").Parse(string(b))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse %s: %w", f.src, err)
+		}
+		var content bytes.Buffer
+		if err := tmpl.Execute(&content, data); err != nil {
+			return nil, fmt.Errorf("Failed to execute %s: %w", f.src, err)
+		}
+		files = append(files, packaging.File{
+			Path:   f.dst,
+			Mode:   0o644,
+			Data:   content.Bytes(),
+			Config: f.config,
+		})
+	}
+	return files, nil
+}
+
+// packageFiles returns the files installed for a file of the configuration,
+// walking directories recursively. Permissions are normalized like in the
+// tarballs unless the mode is set, and symbolic links are preserved.
+func packageFiles(f PackageFile) ([]packaging.File, error) {
+	if !path.IsAbs(f.Dst) {
+		return nil, fmt.Errorf("destination %q isn't an absolute path", f.Dst)
+	}
+	var files []packaging.File
+	err := filepath.WalkDir(f.Src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(f.Src, p)
+		if err != nil {
+			return err
+		}
+		file := packaging.File{
+			Path: path.Join(f.Dst, filepath.ToSlash(rel)),
+		}
+		switch {
+		case d.IsDir():
+			file.Mode = fs.ModeDir | 0o755
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			file.Mode = fs.ModeSymlink | 0o777
+			file.Link = link
+		default:
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			file.Mode = 0o644
+			if info.Mode()&0o111 != 0 {
+				file.Mode = 0o755
+			}
+			if f.Mode != 0 {
+				file.Mode = f.Mode.Perm()
+			}
+			file.Source = p
+			file.Config = f.Config
+		}
+		files = append(files, file)
+		return nil
+	})
+	return files, err
+}
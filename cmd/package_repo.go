@@ -0,0 +1,116 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/prometheus/promu/util/packaging"
+	"github.com/prometheus/promu/util/sh"
+)
+
+// repositoryKey is the file of a repository holding the public key which
+// signed its metadata.
+const repositoryKey = "gpg.key"
+
+var (
+	packagerepocmd = packagecmd.Command("repo", "Create the APT and yum repository metadata of the deb and rpm packages of a directory")
+	packageRepoKey = packagerepocmd.Flag("gpg-key", "ID of the GPG key signing the repository metadata").String()
+	packageRepoDir = packagerepocmd.Arg("directory", "Directory of the packages, where the repository metadata is written").
+			Default(".tarballs").String()
+)
+
+// runPackageRepo writes the metadata of a flat APT repository for the deb
+// files and of a yum repository for the rpm files found in dir and its
+// subdirectories. The metadata is signed with gpg if a key is given, and the
+// public key is exported to the repository.
+func runPackageRepo(dir string) {
+	var debs, rpms []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		switch filepath.Ext(path) {
+		case ".deb":
+			debs = append(debs, rel)
+		case ".rpm":
+			rpms = append(rpms, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		fatal(err)
+	}
+	if len(debs) == 0 && len(rpms) == 0 {
+		fatal(fmt.Errorf("no deb or rpm packages found in %s", dir))
+	}
+	sort.Strings(debs)
+	sort.Strings(rpms)
+
+	date := time.Now()
+	if isReproducibleBuild() {
+		date = getBuildDate(os.Getenv(sourceDateEpoch))
+	}
+	fmt.Println(">> writing repository metadata")
+	if len(debs) > 0 {
+		fmt.Printf(" >   APT repository of %d packages\n", len(debs))
+		if err := packaging.WriteAptRepository(dir, debs, config.Package.Name, date); err != nil {
+			fatal(fmt.Errorf("Failed to write the APT repository: %w", err))
+		}
+		release := filepath.Join(dir, packaging.AptRelease)
+		signRepository(release, filepath.Join(dir, "InRelease"), "--clearsign")
+		signRepository(release, release+".gpg", "--armor", "--detach-sign")
+	}
+	if len(rpms) > 0 {
+		fmt.Printf(" >   yum repository of %d packages\n", len(rpms))
+		if err := packaging.WriteYumRepository(dir, rpms, date); err != nil {
+			fatal(fmt.Errorf("Failed to write the yum repository: %w", err))
+		}
+		repomd := filepath.Join(dir, filepath.FromSlash(packaging.YumRepomd))
+		signRepository(repomd, repomd+".asc", "--armor", "--detach-sign")
+	}
+
+	if *packageRepoKey != "" {
+		var key bytes.Buffer
+		if err := sh.RunCommandWithOutput(&key, os.Stderr, "gpg", "--batch", "--armor", "--export", *packageRepoKey); err != nil {
+			fatal(fmt.Errorf("Failed to export the GPG key: %w", err))
+		}
+		if err := os.WriteFile(filepath.Join(dir, repositoryKey), key.Bytes(), 0o644); err != nil {
+			fatal(err)
+		}
+	}
+}
+
+// signRepository signs the metadata file of a repository into the signature
+// file, with the given signing mode, if a GPG key is given.
+func signRepository(file, signature string, mode ...string) {
+	if *packageRepoKey == "" {
+		return
+	}
+	args := append([]string{"--batch", "--yes", "--local-user", *packageRepoKey, "--output", signature}, mode...)
+	if err := sh.RunCommand("gpg", append(args, file)...); err != nil {
+		fatal(fmt.Errorf("Failed to sign %s: %w", file, err))
+	}
+}
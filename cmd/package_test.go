@@ -0,0 +1,56 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import "testing"
+
+func TestPrependScript(t *testing.T) {
+	for _, tc := range []struct {
+		script, commands, expected string
+	}{
+		{"", "", ""},
+		{"echo foo\n", "", "echo foo\n"},
+		{"", "true\n", "#!/bin/sh\ntrue\n"},
+		{"echo foo\n", "true\n", "#!/bin/sh\ntrue\necho foo\n"},
+		{"#!/bin/bash\necho foo\n", "true\n", "#!/bin/bash\ntrue\necho foo\n"},
+		{"#!/bin/bash", "true\n", "#!/bin/bash\ntrue\n"},
+	} {
+		if got := prependScript(tc.script, tc.commands); got != tc.expected {
+			t.Fatalf("expected %q, got %q", tc.expected, got)
+		}
+	}
+}
+
+func TestNewPackageCapabilities(t *testing.T) {
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+	config.Package.Name = "foo"
+	config.Build.Binaries = []Binary{{Name: "foo", Capabilities: "cap_net_raw+ep"}, {Name: "bar"}}
+
+	for _, format := range []string{packageFormatDeb, packageFormatRPM, packageFormatArchLinux} {
+		p, err := newPackage(packageTarget{format: format, goarch: "amd64"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := "#!/bin/sh\nif command -v setcap >/dev/null 2>&1; then\n\tsetcap cap_net_raw+ep /usr/bin/foo\nfi\n"
+		if p.Scripts.PostInstall != exp {
+			t.Fatalf("%s: expected post-install script %q, got %q", format, exp, p.Scripts.PostInstall)
+		}
+	}
+
+	config.Build.Binaries[0].Capabilities = "invalid"
+	if _, err := newPackage(packageTarget{format: packageFormatDeb}); err == nil {
+		t.Fatal("expected an error with invalid capabilities")
+	}
+}
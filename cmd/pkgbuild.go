@@ -0,0 +1,140 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/prometheus/promu/util/packaging"
+)
+
+// writePKGBUILD writes the PKGBUILD of the Arch Linux package of the given
+// architectures, with its .install file if needed, to the <name>-bin
+// directory of the package prefix. The binaries are downloaded from the
+// release tarballs and the other files from the repository at the release
+// tag, except for the templated system files which are written next to the
+// PKGBUILD. The checksums of the tarballs are computed if they are found in
+// .tarballs.
+func writePKGBUILD(goarchs []string) error {
+	p, err := newPackage(packageTarget{format: packageFormatArchLinux})
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(config.Package.Prefix, packaging.PKGBUILDName(p))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	ext, err := tarballExtension(config.Tarball.Compression)
+	if err != nil {
+		return err
+	}
+	var (
+		tag       = "v" + projInfo.Version
+		tarballs  = fmt.Sprintf("%s-%s.linux-", projInfo.Name, projInfo.Version)
+		binaries  = map[string]bool{}
+		sources   []packaging.PKGBUILDSource
+		sourceSet = map[string]bool{}
+	)
+	for _, goarch := range goarchs {
+		tarball := tarballs + goarch + ext
+		sum := localSHA256(filepath.Join(".tarballs", tarball))
+		if sum == "" {
+			warn(fmt.Errorf("%s not found in .tarballs, its checksum won't be checked by the PKGBUILD", tarball))
+		}
+		sources = append(sources, packaging.PKGBUILDSource{
+			Arch:   goarch,
+			Name:   tarball,
+			URL:    fmt.Sprintf("https://%s/releases/download/%s/%s", config.Repository.Path, tag, tarball),
+			SHA256: sum,
+		})
+	}
+	for _, binary := range config.Build.Binaries {
+		binaries[path.Join(config.Package.BinDir, binary.Name)] = true
+	}
+	for i, f := range p.Files {
+		if !f.Mode.IsRegular() {
+			continue
+		}
+		if binaries[f.Path] {
+			p.Files[i].Source = tarballs + "*/" + path.Base(f.Path)
+			continue
+		}
+		if f.Source == "" {
+			name := strings.ReplaceAll(strings.TrimPrefix(f.Path, "/"), "/", "-")
+			if err := os.WriteFile(filepath.Join(dir, name), f.Data, 0o644); err != nil {
+				return err
+			}
+			p.Files[i].Source = name
+			sources = append(sources, packaging.PKGBUILDSource{
+				Name:   name,
+				SHA256: fmt.Sprintf("%x", sha256.Sum256(f.Data)),
+			})
+			continue
+		}
+		src := filepath.ToSlash(f.Source)
+		name := strings.ReplaceAll(src, "/", "-")
+		p.Files[i].Source = name
+		if sourceSet[name] {
+			continue
+		}
+		sourceSet[name] = true
+		sources = append(sources, packaging.PKGBUILDSource{
+			Name:   name,
+			URL:    rawFileURL(tag, src),
+			SHA256: localSHA256(f.Source),
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := packaging.WritePKGBUILD(&buf, p, goarchs, sources); err != nil {
+		return err
+	}
+	fmt.Println(" >  ", filepath.Join(dir, "PKGBUILD"))
+	if err := os.WriteFile(filepath.Join(dir, "PKGBUILD"), buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	if install := packaging.ArchLinuxInstall(p.Scripts); install != "" {
+		name := packaging.PKGBUILDName(p) + ".install"
+		fmt.Println(" >  ", filepath.Join(dir, name))
+		return os.WriteFile(filepath.Join(dir, name), []byte(install), 0o644)
+	}
+	return nil
+}
+
+// rawFileURL returns the URL of the raw content of a file of the repository
+// at the given revision.
+func rawFileURL(revision, file string) string {
+	if repo, ok := strings.CutPrefix(config.Repository.Path, "github.com/"); ok {
+		return fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s", repo, revision, file)
+	}
+	return fmt.Sprintf("https://%s/raw/%s/%s", config.Repository.Path, revision, file)
+}
+
+// localSHA256 returns the hex-encoded SHA256 checksum of the file, or an
+// empty string if it can't be read.
+func localSHA256(file string) string {
+	sum, err := sha256File(os.DirFS(filepath.Dir(file)), filepath.Base(file))
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", sum)
+}
@@ -0,0 +1,77 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+)
+
+// artifactPlatformPattern matches the platform in artifact names such as
+// "prometheus-2.53.0.linux-armv7.tar.gz".
+var artifactPlatformPattern = regexp.MustCompile(`\.(aix|android|darwin|dragonfly|freebsd|illumos|ios|js|linux|netbsd|openbsd|plan9|solaris|wasip1|windows)-([a-z0-9]+)(\.|$)`)
+
+// artifactPlatform returns the platform (e.g. "linux/armv7") of an artifact
+// built by promu, based on its file name.
+func artifactPlatform(filename string) (string, bool) {
+	m := artifactPlatformPattern.FindAllStringSubmatch(path.Base(filename), -1)
+	if len(m) == 0 {
+		return "", false
+	}
+	last := m[len(m)-1]
+	return last[1] + "/" + last[2], true
+}
+
+// platformFilter returns a function reporting whether a platform matches one
+// of the regexps. All platforms match if there is no regexp.
+func platformFilter(regexps []string) (func(platform string) bool, error) {
+	var res []*regexp.Regexp
+	for _, s := range regexps {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid platform regexp %q: %w", s, err)
+		}
+		res = append(res, re)
+	}
+	return func(platform string) bool {
+		if len(res) == 0 {
+			return true
+		}
+		for _, re := range res {
+			if re.MatchString(platform) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// artifactFilter returns a function reporting whether an artifact is built
+// for a platform matching one of the regexps. Artifacts without platform
+// only match if there is no regexp.
+func artifactFilter(regexps []string) (func(filename string) bool, error) {
+	match, err := platformFilter(regexps)
+	if err != nil {
+		return nil, err
+	}
+	return func(filename string) bool {
+		if len(regexps) == 0 {
+			return true
+		}
+		platform, ok := artifactPlatform(filename)
+		return ok && match(platform)
+	}, nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import "testing"
+
+func TestArtifactPlatform(t *testing.T) {
+	for _, tc := range []struct {
+		in  string
+		exp string
+	}{
+		{in: "prometheus-2.53.0.linux-amd64.tar.gz", exp: "linux/amd64"},
+		{in: ".tarballs/prometheus-2.53.0-rc.0.linux-armv7.tar.zst", exp: "linux/armv7"},
+		{in: "windows_exporter-0.1.0.windows-arm64.zip", exp: "windows/arm64"},
+		{in: "foo-1.0.0.darwin-arm64.tar.gz.sha256", exp: "darwin/arm64"},
+		{in: "foo-1.0.0.linux-amd64", exp: "linux/amd64"},
+		{in: "foo-linux-amd64.txt"},
+		{in: "sha256sums.txt"},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			got, ok := artifactPlatform(tc.in)
+			if tc.exp == "" {
+				if ok {
+					t.Fatalf("expected no platform, got %q", got)
+				}
+				return
+			}
+			if got != tc.exp {
+				t.Fatalf("expected %q, got %q", tc.exp, got)
+			}
+		})
+	}
+}
+
+func TestArtifactFilter(t *testing.T) {
+	match, err := artifactFilter([]string{"linux/arm", "darwin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for in, exp := range map[string]bool{
+		"foo-1.0.0.linux-armv7.tar.gz":   true,
+		"foo-1.0.0.darwin-amd64.tar.gz":  true,
+		"foo-1.0.0.linux-amd64.tar.gz":   false,
+		"foo-1.0.0.windows-amd64.tar.gz": false,
+		"sha256sums.txt":                 false,
+	} {
+		if got := match(in); got != exp {
+			t.Errorf("%s: expected %v, got %v", in, exp, got)
+		}
+	}
+
+	match, err = artifactFilter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !match("sha256sums.txt") {
+		t.Error("expected all files to match without regexp")
+	}
+}
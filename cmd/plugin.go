@@ -0,0 +1,169 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// pluginPrefix is the prefix of the executables found in PATH which are
+// exposed as promu commands, e.g. promu-publish for "promu publish".
+const pluginPrefix = "promu-"
+
+// plugins maps the names of the plugin commands to their executables.
+var plugins = map[string]string{}
+
+// registerPlugins discovers the plugins found in PATH and adds them to the
+// commands of the application. Plugins can't override builtin commands and
+// the first executable found in PATH wins.
+func registerPlugins() {
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := pluginName(e)
+			if !ok || app.GetCommand(name) != nil {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			plugins[name] = path
+			app.Command(name, fmt.Sprintf("Run the %s plugin", path)).
+				Arg("args", "Arguments passed to the plugin").Strings()
+		}
+	}
+}
+
+// pluginName returns the command name of the plugin for the given directory
+// entry.
+func pluginName(e fs.DirEntry) (string, bool) {
+	name := e.Name()
+	if !strings.HasPrefix(name, pluginPrefix) || e.IsDir() {
+		return "", false
+	}
+	if runtime.GOOS == "windows" {
+		ext := filepath.Ext(name)
+		if !strings.EqualFold(ext, ".exe") {
+			return "", false
+		}
+		name = strings.TrimSuffix(name, ext)
+	} else {
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode()&0o111 == 0 {
+			return "", false
+		}
+	}
+	name = strings.TrimPrefix(name, pluginPrefix)
+	return name, name != ""
+}
+
+// splitPluginArgs splits the command line arguments into the arguments
+// parsed by promu and the arguments passed verbatim to the plugin, if the
+// command is a plugin.
+func splitPluginArgs(args []string) ([]string, []string, bool) {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--":
+			return args, nil, false
+		case arg == "-c" || arg == "--config":
+			// Skip the value of the flag.
+			i++
+		case strings.HasPrefix(arg, "-"):
+		default:
+			if _, ok := plugins[arg]; !ok {
+				return args, nil, false
+			}
+			return args[:i+1], args[i+1:], true
+		}
+	}
+	return args, nil, false
+}
+
+// runPlugin runs the plugin with the given arguments. The project info and
+// the effective configuration are passed to the plugin through PROMU_*
+// environment variables, the configuration being encoded in JSON with the
+// same keys as the configuration file.
+func runPlugin(name string, args []string) {
+	cfg, err := configJSON(config)
+	if err != nil {
+		fatal(fmt.Errorf("Failed to encode config: %w", err))
+	}
+
+	cmd := exec.Command(plugins[name], args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"PROMU_CONFIG_FILE="+*configFile,
+		"PROMU_CONFIG_JSON="+string(cfg),
+		"PROMU_VERBOSE="+fmt.Sprint(*verbose),
+		"PROMU_PROJECT_NAME="+projInfo.Name,
+		"PROMU_PROJECT_VERSION="+projInfo.Version,
+		"PROMU_PROJECT_OWNER="+projInfo.Owner,
+		"PROMU_PROJECT_REPO="+projInfo.Repo,
+		"PROMU_PROJECT_BRANCH="+projInfo.Branch,
+		"PROMU_PROJECT_REVISION="+projInfo.Revision,
+	)
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+			os.Exit(exitErr.ExitCode())
+		}
+		fatal(fmt.Errorf("Failed to run plugin %s: %w", name, err))
+	}
+}
+
+// configJSON returns the JSON encoding of the configuration, using the keys
+// of the YAML configuration file.
+func configJSON(c *Config) ([]byte, error) {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonValue(v))
+}
+
+// jsonValue converts the maps decoded by the YAML package to maps with
+// string keys which can be encoded in JSON.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = jsonValue(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range v {
+			v[i] = jsonValue(val)
+		}
+	}
+	return v
+}
@@ -0,0 +1,57 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitPluginArgs(t *testing.T) {
+	plugins["publish"] = "/usr/local/bin/promu-publish"
+	defer delete(plugins, "publish")
+
+	for _, tc := range []struct {
+		args        []string
+		expArgs     []string
+		expPlugin   []string
+		expIsPlugin bool
+	}{
+		{
+			args:    []string{"build", "--prefix", "publish"},
+			expArgs: []string{"build", "--prefix", "publish"},
+		},
+		{
+			args:        []string{"publish", "--bucket", "foo", "-v"},
+			expArgs:     []string{"publish"},
+			expPlugin:   []string{"--bucket", "foo", "-v"},
+			expIsPlugin: true,
+		},
+		{
+			args:        []string{"-v", "--config", "publish", "publish", "bar"},
+			expArgs:     []string{"-v", "--config", "publish", "publish"},
+			expPlugin:   []string{"bar"},
+			expIsPlugin: true,
+		},
+		{
+			args:    []string{"--", "publish"},
+			expArgs: []string{"--", "publish"},
+		},
+	} {
+		args, pluginArgs, isPlugin := splitPluginArgs(tc.args)
+		if !reflect.DeepEqual(tc.expArgs, args) || !reflect.DeepEqual(tc.expPlugin, pluginArgs) || tc.expIsPlugin != isPlugin {
+			t.Fatalf("%v: expected %v %v %v, got %v %v %v", tc.args, tc.expArgs, tc.expPlugin, tc.expIsPlugin, args, pluginArgs, isPlugin)
+		}
+	}
+}
@@ -28,6 +28,7 @@ import (
 
 	"github.com/prometheus/promu/pkg/repository"
 	"github.com/prometheus/promu/util/sh"
+	"github.com/prometheus/promu/util/status"
 )
 
 const (
@@ -39,6 +40,114 @@ const (
 type Binary struct {
 	Name string
 	Path string
+	// Capabilities are the file capabilities required by the binary on
+	// Linux (e.g. "cap_net_raw+ep"), set by the post-install scripts of the
+	// packages.
+	Capabilities string
+}
+
+// TarballFile is a file added to the tarballs. It is configured either as a
+// plain path or as a map with the path and the platforms for which the file
+// is added.
+type TarballFile struct {
+	Path string
+	// Platforms are regexps matching the platforms (e.g. "linux/amd64")
+	// which include the file. All platforms include the file if empty.
+	Platforms []string
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (f *TarballFile) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&f.Path); err == nil {
+		return nil
+	}
+	type plain TarballFile
+	return unmarshal((*plain)(f))
+}
+
+// MarshalYAML implements the yaml.Marshaler interface.
+func (f TarballFile) MarshalYAML() (interface{}, error) {
+	if len(f.Platforms) == 0 {
+		return f.Path, nil
+	}
+	type plain TarballFile
+	return plain(f), nil
+}
+
+// TarballFormat is an archive format ("tar" or "zip") produced for the
+// platforms matching a list of regexps, or for all platforms if empty. Like
+// TarballFile, it is configured either as a plain format or as a map.
+type TarballFormat struct {
+	Format    string
+	Platforms []string
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (f *TarballFormat) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&f.Format); err == nil {
+		return nil
+	}
+	type plain TarballFormat
+	return unmarshal((*plain)(f))
+}
+
+// MarshalYAML implements the yaml.Marshaler interface.
+func (f TarballFormat) MarshalYAML() (interface{}, error) {
+	if len(f.Platforms) == 0 {
+		return f.Format, nil
+	}
+	type plain TarballFormat
+	return plain(f), nil
+}
+
+// ReleaseAsset sets the media type and the label of the release assets
+// whose name matches a glob pattern.
+type ReleaseAsset struct {
+	// Name is a path.Match pattern of the asset names.
+	Name string
+	// ContentType is the media type of the assets, guessed from their
+	// extension by default.
+	ContentType string `yaml:"content_type"`
+	// Label is a text/template of the label displayed instead of the name
+	// of the assets, see releaseAssetData.
+	Label string
+}
+
+// ReleaseTarget is a GitHub repository the release is uploaded to.
+type ReleaseTarget struct {
+	// Repository is the owner/name of the repository.
+	Repository string
+	// TokenEnv is the environment variable holding the GitHub token of
+	// the repository, GITHUB_TOKEN by default.
+	TokenEnv string `yaml:"token_env"`
+	// App authenticates as an installation of a GitHub App instead.
+	App *GitHubApp
+	// OIDC authenticates with a token exchanged for the OIDC token of the
+	// GitHub Actions job instead.
+	OIDC *GitHubOIDC `yaml:"oidc"`
+}
+
+// GitHubApp is a GitHub App installed on a repository.
+type GitHubApp struct {
+	// ID is the ID of the app.
+	ID int64
+	// InstallationID is the ID of the installation of the app, found from
+	// the repository by default.
+	InstallationID int64 `yaml:"installation_id"`
+	// PrivateKeyEnv is the environment variable holding the PEM encoded
+	// private key of the app.
+	PrivateKeyEnv string `yaml:"private_key_env"`
+}
+
+// GitHubOIDC is a service exchanging the OIDC token of a GitHub Actions job
+// for a GitHub token, such as Octo STS.
+type GitHubOIDC struct {
+	// URL is the exchange endpoint, requested with the OIDC token as
+	// bearer token and returning the GitHub token as {"token": "..."}.
+	URL string
+	// Audience is the audience of the OIDC token, the host of the URL by
+	// default.
+	Audience string
 }
 
 // Config contains the Promu Command Configuration
@@ -63,8 +172,266 @@ type Config struct {
 		Version string
 	}
 	Tarball struct {
-		Files  []string
-		Prefix string
+		Files       []TarballFile
+		Exclude     []string
+		Prefix      string
+		Compression string
+		Formats     []TarballFormat
+		// Manifest adds a MANIFEST.txt file with the checksums of the files
+		// to the archives and writes the checksum of each archive to a
+		// <archive>.sha256 file.
+		Manifest bool
+		// DebugInfo strips the binaries in the archives and adds their
+		// debug symbols to separate <archive>-debuginfo archives.
+		DebugInfo bool `yaml:"debug_info"`
+	}
+	Package struct {
+		Name        string
+		Maintainer  string
+		Description string
+		Homepage    string
+		License     string
+		Vendor      string
+		Formats     []string
+		Prefix      string
+		// BinDir is the directory where the binaries are installed.
+		BinDir string `yaml:"bin_dir"`
+		Files  []PackageFile
+		// Depends, Recommends, Conflicts and Provides are the
+		// relationships of the Linux packages with other packages, keyed
+		// by package format as package names and version constraints
+		// differ between distributions.
+		Depends    map[string][]string
+		Recommends map[string][]string
+		Conflicts  map[string][]string
+		Provides   map[string][]string
+		// User and Group are the system user and group running the
+		// binaries, available in the templated system files. They default
+		// to the package name.
+		User  string
+		Group string
+		// The following system files are text/template templates, see
+		// packageTemplateData. Systemd units and sysusers.d and tmpfiles.d
+		// files keep their base name while the environment file and the
+		// logrotate configuration are named after the package.
+		Systemd     []string
+		Sysusers    []string
+		Tmpfiles    []string
+		Environment string
+		Logrotate   string
+		// Scripts are the paths of the scripts run when the package is
+		// installed or removed.
+		Scripts struct {
+			PreInstall  string `yaml:"pre_install"`
+			PostInstall string `yaml:"post_install"`
+			PreRemove   string `yaml:"pre_remove"`
+			PostRemove  string `yaml:"post_remove"`
+		}
+		// MSI configures the Windows installers, which install the
+		// binaries into Program Files.
+		MSI struct {
+			// UpgradeCode is the GUID identifying the product across
+			// versions, derived from the package name by default.
+			UpgradeCode string `yaml:"upgrade_code"`
+			// Service is the name of the binary registered as a Windows
+			// service, with its Arguments.
+			Service   string
+			Arguments string
+			// Path adds the installation directory to the system PATH.
+			Path bool
+		}
+		// MacOS configures the macOS installers, which install the
+		// binaries into /usr/local/bin.
+		MacOS struct {
+			// Identifier is the reverse-DNS identifier of the package and
+			// of its launchd daemon, derived from the repository path by
+			// default.
+			Identifier string
+			// Service is the name of the binary run as a launchd daemon,
+			// with its Arguments.
+			Service   string
+			Arguments []string
+			// Sign holds the PKCS#12 files of the Developer ID Application
+			// and Developer ID Installer certificates, signing the binaries
+			// and the package, and the file holding their password.
+			Sign struct {
+				Application string
+				Installer   string
+				Password    string
+			}
+			// Notarize is the App Store Connect API key file used to
+			// notarize the package.
+			Notarize string
+		}
+		// Snap configures the snaps, whose applications are the binaries.
+		Snap struct {
+			// Base is the base snap, core22 by default.
+			Base string
+			// Confinement is strict by default.
+			Confinement string
+			// Plugs are the interfaces connected to the binaries.
+			Plugs []string
+			// Service is the name of the binary run as a daemon, with its
+			// Arguments.
+			Service   string
+			Arguments []string
+			// Image is the container image running snapcraft. The snaps
+			// are only built if it is set.
+			Image string
+		}
+	}
+	Changelog struct {
+		// Format is the format of CHANGELOG.md, prometheus by default or
+		// keepachangelog.
+		Format string
+		// KindOrder is the order of the changes by kinds, the kinds which
+		// aren't listed following in the default order.
+		KindOrder []string `yaml:"kind_order"`
+	}
+	Bump struct {
+		// PreRelease is the identifier of the pre-releases of promu bump, rc
+		// by default.
+		PreRelease string `yaml:"pre_release"`
+		// Levels maps the kinds of the changes to the levels of the bumps
+		// of --level=auto, overriding the default ones: major for CHANGE,
+		// minor for FEATURE and patch for the others.
+		Levels map[string]string
+		// Template is the text/template file of the text of the changelog
+		// entry, see bumpEntryData. The text lists the changes sorted by
+		// kinds if empty.
+		Template string
+		// Labels configures the kinds of the pull requests given by their
+		// labels.
+		Labels struct {
+			// KindPrefix prefixes the labels whose rest is the kind of
+			// the changes, kind/ by default.
+			KindPrefix string `yaml:"kind_prefix"`
+			// Skip is the label excluding a pull request from the
+			// changelog, changelog/skip by default.
+			Skip string
+			// Kinds maps labels to the slash-separated kinds of the
+			// changes, e.g. bug to BUGFIX.
+			Kinds map[string]string
+		}
+		// Reviewers are the users, and the teams of the form org/team,
+		// whose reviews are requested on the pull requests of --push.
+		Reviewers []string
+		// Paths are the files and directories whose changes are bumped,
+		// all by default. The changes of --component are those of its
+		// directory.
+		Paths []string
+		// Dependencies configures the dependency updates, listed in a
+		// collapsed Dependencies section of the entry.
+		Dependencies struct {
+			// Authors are the authors of the dependency updates,
+			// dependabot[bot] and renovate[bot] by default.
+			Authors []string
+			// Label is the label of the dependency updates,
+			// dependencies by default.
+			Label string
+		}
+	}
+	Release struct {
+		// Provider is the hosting service of the releases, github by
+		// default or gitlab.
+		Provider string
+		// NotesTemplate is the text/template file of the body of the
+		// release, see releaseNotesData. The body is the changelog entry
+		// of the version if empty.
+		NotesTemplate string `yaml:"notes_template"`
+		// Images are the container images of the project, without tag,
+		// whose digests for the tag of the release are available in the
+		// notes template.
+		Images []string
+		// Assets set the media type and the label of the uploaded files,
+		// the first matching entry applying.
+		Assets []ReleaseAsset
+		// GPG configures the GPG signatures of the releases.
+		GPG struct {
+			// Key is the ID of the signing key, read from the
+			// PROMU_GPG_KEY environment variable if empty.
+			Key string
+			// SignChecksums uploads a detached GPG signature of the
+			// checksums file written by --checksums, as with
+			// --checksums-signer=gpg.
+			SignChecksums bool `yaml:"sign_checksums"`
+		} `yaml:"gpg"`
+		// Minisign uploads minisign signatures of all the release
+		// files too if a key is set.
+		Minisign struct {
+			// Key is the path of the secret key, which must not be
+			// encrypted.
+			Key string
+			// KeyEnv is the environment variable holding the secret
+			// key, used if Key is empty.
+			KeyEnv string `yaml:"key_env"`
+		}
+		// Sign uploads keyless cosign signatures and certificates of the
+		// archives, packages and checksums files too.
+		Sign bool
+		// Targets are the repositories the release is uploaded to, the
+		// repository of the project by default.
+		Targets []ReleaseTarget
+	}
+	// Codesign configures the signatures of the darwin binaries, ad-hoc
+	// signatures without certificate by default.
+	Codesign struct {
+		// P12 is the PKCS#12 file of the signing certificate, e.g.
+		// Developer ID Application. P12Env is the environment variable
+		// holding it base64 encoded, used if P12 is empty.
+		P12    string `yaml:"p12"`
+		P12Env string `yaml:"p12_env"`
+		// Password is the file holding the password of the PKCS#12 file.
+		// PasswordEnv is the environment variable holding it, used if
+		// Password is empty.
+		Password    string
+		PasswordEnv string `yaml:"password_env"`
+		// Identifier is the identifier of the signatures, the file name
+		// of each binary by default.
+		Identifier string
+		// Entitlements is the plist file of the entitlements granted to
+		// the binaries.
+		Entitlements string
+		// Flags are the code signature flags, e.g. runtime to enable the
+		// hardened runtime required by notarization.
+		Flags []string
+		// Signer signs the archives and packages with promu sign: cosign
+		// keyless signatures by default, gpg or minisign signatures with
+		// the key of the release configuration.
+		Signer string
+		// KeyProvider holds the signing key instead of files on disk: a
+		// key of the macOS keychain signing the darwin binaries, or a KMS
+		// key signing the windows binaries and the cosign signatures.
+		KeyProvider KeyProvider `yaml:"key_provider"`
+		// Rcodesign is the rcodesign binary run instead of the one of the
+		// builder image, which requires Docker. The rcodesign binary
+		// found in the PATH is run by default if any.
+		Rcodesign string
+		// Windows configures the Authenticode signatures of the windows
+		// binaries by promu codesign --windows.
+		Windows struct {
+			// P12, P12Env, Password and PasswordEnv hold the code
+			// signing certificate, like those of the darwin binaries.
+			P12         string `yaml:"p12"`
+			P12Env      string `yaml:"p12_env"`
+			Password    string
+			PasswordEnv string `yaml:"password_env"`
+			// Timestamp is the URL of the RFC 3161 timestamping server,
+			// http://timestamp.digicert.com by default.
+			Timestamp string
+			// Command is an external signer run with the path of each
+			// binary as last argument instead of osslsigncode, e.g. to
+			// sign with a key held by a KMS or HSM. It must sign the
+			// binary in place.
+			Command []string
+		}
+	}
+	Windows struct {
+		Company     string
+		Product     string
+		Description string
+		Copyright   string
+		Icon        string
 	}
 }
 
@@ -77,6 +444,11 @@ func NewConfig() *Config {
 	config.Build.Static = true
 	config.Crossbuild.Platforms = defaultPlatforms
 	config.Tarball.Prefix = "."
+	config.Package.Name = projInfo.Name
+	config.Package.Formats = []string{packageFormatDeb, packageFormatRPM}
+	config.Package.Prefix = ".tarballs"
+	config.Package.BinDir = "/usr/bin"
+	config.Package.Snap.Base = defaultSnapBase
 	config.Go.Version = "1.12"
 	config.Go.CGo = false
 	config.Repository.Path = projInfo.Repo
@@ -91,9 +463,14 @@ var (
 
 	configFile = app.Flag("config", "Path to config file").Short('c').
 			Default(DefaultConfigFilename).String()
-	verbose  = app.Flag("verbose", "Verbose output").Short('v').Bool()
-	config   *Config
-	projInfo repository.Info
+	verbose    = app.Flag("verbose", "Verbose output").Short('v').Bool()
+	statusAddr = app.Flag("status-addr", "Serve the progress of long running commands in JSON on /status and as Prometheus metrics on /metrics at this address (e.g. :9099)").
+			String()
+	// statusTracker tracks the progress of the command if --status-addr is
+	// set, it is nil otherwise.
+	statusTracker *status.Tracker
+	config        *Config
+	projInfo      repository.Info
 
 	// app represents the base command
 	app = kingpin.New("promu", "promu is the utility tool for building and releasing Prometheus projects")
@@ -111,36 +488,206 @@ func Execute() {
 	projInfo, err = repository.NewInfo(warn)
 	checkError(err, "Unable to initialize project info")
 
-	command := kingpin.MustParse(app.Parse(os.Args[1:]))
+	registerPlugins()
+	args, pluginArgs, isPlugin := splitPluginArgs(os.Args[1:])
+
+	command := kingpin.MustParse(app.Parse(args))
+	kingpin.FatalIfError(setVersionFlagDefaults(args), "")
 	sh.Verbose = *verbose
-	initConfig(*configFile)
+	// check config reports the errors of the config file itself.
+	if command != checkConfigcmd.FullCommand() {
+		initConfig(*configFile)
+	}
 
 	info(fmt.Sprintf("Running command: %v %v", command, os.Args[2:]))
 
+	if *statusAddr != "" {
+		statusTracker = status.NewTracker(command)
+		if err := statusTracker.ListenAndServe(*statusAddr); err != nil {
+			fatal(fmt.Errorf("Failed to serve status: %w", err))
+		}
+	}
+
+	if isPlugin {
+		runPlugin(command, pluginArgs)
+		return
+	}
+
 	switch command {
 	case buildcmd.FullCommand():
 		runBuild(optArg(*binariesArg, 0, "all"))
 	case checkLicensescmd.FullCommand():
-		runCheckLicenses(optArg(*checkLicLocation, 0, "."), *headerLength, *sourceExtensions)
+		c := licenseCheck{
+			n:          *headerLength,
+			extensions: *sourceExtensions,
+			excludes:   *checkLicExcludes,
+			spdx:       spdxLicense(*checkLicSPDX, *checkLicLicense),
+		}
+		runCheck("licenses", runCheckLicenses(optArg(*checkLicLocation, 0, "."), c, *checkLicGitignore))
 	case checkChangelogcmd.FullCommand():
-		if err := runCheckChangelog(*checkChangelogPath, *checkChangelogVersion); err != nil {
+		runCheck("changelog", runCheckChangelog(*checkChangelogPath, *checkChangelogVersion, *checkChangelogFragments, *checkChangelogAll))
+	case checkArtifactscmd.FullCommand():
+		runCheck("artifacts", runCheckArtifacts(*checkArtifactsLocation, *checkArtifactsSkipBinaryVersion))
+	case checkGoModcmd.FullCommand():
+		runCheck("go-mod", runCheckGoMod(*checkGoModLocation))
+	case checkReproducibilitycmd.FullCommand():
+		runCheck("reproducibility", runCheckReproducibility(*checkReproducibilityBinaries, *checkReproducibilityAgainst))
+	case checkConfigcmd.FullCommand():
+		runCheck("config", runCheckConfig(*configFile))
+	case changelogRendercmd.FullCommand():
+		if err := runChangelogRender(*changelogRenderPath, *changelogRenderFragments, *changelogRenderVersion, *changelogRenderDate, *changelogRenderKeep); err != nil {
+			fatal(err)
+		}
+	case changelogAddcmd.FullCommand():
+		if err := runChangelogAdd(*changelogAddFragments, *changelogAddKind, *changelogAddPR, *changelogAddDescription); err != nil {
+			fatal(err)
+		}
+	case changelogBackportcmd.FullCommand():
+		if err := runChangelogBackport(*changelogBackportPath, *changelogBackportVersion, *changelogBackportPR, *changelogBackportKind, *changelogBackportDescription, *changelogBackportDate); err != nil {
+			fatal(err)
+		}
+	case changelogCherryPickcmd.FullCommand():
+		if err := runChangelogCherryPick(*changelogCherryPickPath, *changelogCherryPickVersion, *changelogCherryPickChange, *changelogCherryPickPR, *changelogCherryPickKind, *changelogCherryPickDate); err != nil {
+			fatal(err)
+		}
+	case changelogExportcmd.FullCommand():
+		if err := runChangelogExport(os.Stdout, *changelogExportPath, *changelogExportFormat); err != nil {
+			fatal(err)
+		}
+	case changelogDiffcmd.FullCommand():
+		if err := runChangelogDiff(*changelogDiffPath, *changelogDiffVersion, *changelogDiffUpdate); err != nil {
+			fatal(err)
+		}
+	case checksumComputecmd.FullCommand():
+		runChecksum(optArg(*checksumLocation, 0, "."), checksumOptions{
+			algorithms: *checksumAlgorithm,
+			outputs:    *checksumOutputs,
+			format:     *checksumFormat,
+			binary:     *checksumBinary,
+			contents:   *checksumContents,
+			append:     *checksumAppend,
+			signer:     *checksumSign,
+			key:        *checksumKey,
+		})
+	case checksumPublishcmd.FullCommand():
+		if err := runChecksumPublish(*checksumPublishLocation); err != nil {
 			fatal(err)
 		}
-	case checksumcmd.FullCommand():
-		runChecksum(optArg(*checksumLocation, 0, "."))
 	case crossbuildcmd.FullCommand():
 		runCrossbuild()
 	case infocmd.FullCommand():
 		runInfo()
-	case releasecmd.FullCommand():
+	case migratecmd.FullCommand():
+		if err := runMigrateFromMakefile(*migrateMakefiles, *migrateOutput); err != nil {
+			fatal(err)
+		}
+	case packagebuildcmd.FullCommand():
+		runPackage(*packageLocation)
+	case packagerepocmd.FullCommand():
+		runPackageRepo(*packageRepoDir)
+	case publishcmd.FullCommand():
+		runPublish(*publishURL, *publishLocation)
+	case releaseuploadcmd.FullCommand():
 		runRelease(optArg(*releaseLocation, 0, "."))
+	case releasepublishcmd.FullCommand():
+		runReleasePublish(*releasePublishVersion)
+	case releaserollbackcmd.FullCommand():
+		runReleaseRollback(*releaseRollbackVersion)
+	case signcmd.FullCommand():
+		runSign(*signLocation, *signSigner, *signKey, *signForce, *signParallelism)
+	case bumpcmd.FullCommand():
+		level := *bumpLevel
+		if *bumpSource == bumpSourceCommits && !bumpLevelSet {
+			level = bumpAuto
+		}
+		if err := runBump(bumpOptions{
+			level:     level,
+			source:    *bumpSource,
+			changelog: *bumpChangelog,
+			fragments: *bumpFragments,
+			date:      *bumpDate,
+			dryRun:    *bumpDryRun,
+			finalize:  *bumpFinalize,
+			offline:   *bumpOffline,
+			push:      *bumpPush,
+			remote:    *bumpRemote,
+			component: *bumpComponent,
+		}); err != nil {
+			fatal(err)
+		}
+	case tagcmd.FullCommand():
+		if err := runTag(*tagChangelog, *tagComponent, *tagSign, *tagPush, *tagRemote); err != nil {
+			fatal(err)
+		}
 	case tarballcmd.FullCommand():
 		runTarball(optArg(*tarBinariesLocation, 0, "."))
 	case versioncmd.FullCommand():
 		runVersion()
-	case codesigncmd.FullCommand():
-		runCodeSign(*binaryPath)
+	case codesignSigncmd.FullCommand():
+		runCodeSign(*binaryPath, *codesignWindows, *codesignBestEffort, *codesignForce, *codesignParallelism)
+	case codesignVerifycmd.FullCommand():
+		if err := runCodesignVerify(*codesignVerifyPath, *codesignVerifyIdentity, *codesignVerifyIssuer); err != nil {
+			fatal(err)
+		}
+	}
+}
+
+// setVersionFlagDefaults sets the default values of the flags and arguments
+// missing from the command line when a flag named "version" is used, since
+// kingpin skips this step for such flags, assuming it is the flag printing the
+// application version.
+func setVersionFlagDefaults(args []string) error {
+	ctx, err := app.ParseContext(args)
+	if err != nil {
+		return err
+	}
+
+	var (
+		versionFlag bool
+		set         = map[string]struct{}{}
+		flags       = app.Model().Flags
+		cmdArgs     []*kingpin.ArgModel
+	)
+	for _, e := range ctx.Elements {
+		switch c := e.Clause.(type) {
+		case *kingpin.FlagClause:
+			name := c.Model().Name
+			versionFlag = versionFlag || name == "version"
+			set["--"+name] = struct{}{}
+		case *kingpin.ArgClause:
+			set[c.Model().Name] = struct{}{}
+		case *kingpin.CmdClause:
+			m := c.Model()
+			flags = append(flags, m.Flags...)
+			cmdArgs = append(cmdArgs, m.Args...)
+		}
+	}
+	if !versionFlag {
+		return nil
+	}
+
+	setDefault := func(name string, defaults []string, v kingpin.Value) error {
+		if _, ok := set[name]; ok {
+			return nil
+		}
+		for _, d := range defaults {
+			if err := v.Set(d); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, f := range flags {
+		if err := setDefault("--"+f.Name, f.Default, f.Value); err != nil {
+			return err
+		}
+	}
+	for _, a := range cmdArgs {
+		if err := setDefault(a.Name, a.Default, a.Value); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // initConfig reads the given config file into the Config object
@@ -152,6 +699,8 @@ func initConfig(filename string) {
 	config = NewConfig()
 	err = yaml.UnmarshalStrict(configData, config)
 	checkError(err, "Unable to parse config file: "+filename)
+	_, err = changelogKindOrder()
+	checkError(err, "Invalid changelog.kind_order in config file: "+filename)
 }
 
 // info prints the given message only if running in verbose mode
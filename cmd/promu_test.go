@@ -0,0 +1,74 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestTarballFileYAML(t *testing.T) {
+	in := `
+- LICENSE
+- path: example.service
+  platforms: [linux]
+`
+	var files []TarballFile
+	if err := yaml.UnmarshalStrict([]byte(in), &files); err != nil {
+		t.Fatal(err)
+	}
+	exp := []TarballFile{
+		{Path: "LICENSE"},
+		{Path: "example.service", Platforms: []string{"linux"}},
+	}
+	if !reflect.DeepEqual(exp, files) {
+		t.Fatalf("expected %v, got %v", exp, files)
+	}
+
+	out, err := yaml.Marshal(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var roundTrip []TarballFile
+	if err := yaml.UnmarshalStrict(out, &roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(exp, roundTrip) {
+		t.Fatalf("expected %v, got %v", exp, roundTrip)
+	}
+
+	if err := yaml.UnmarshalStrict([]byte("- path: LICENSE\n  platform: linux\n"), &files); err == nil {
+		t.Fatal("expected error for unknown field, got none")
+	}
+}
+
+func TestExampleConfig(t *testing.T) {
+	b, err := os.ReadFile("../doc/examples/prometheus/.promu.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewConfig()
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Package.Scripts.PostInstall != "scripts/postinstall.sh" {
+		t.Fatalf("expected the post-install script scripts/postinstall.sh, got %q", c.Package.Scripts.PostInstall)
+	}
+	if c.Package.MSI.UpgradeCode == "" {
+		t.Fatal("expected the upgrade code of the MSI")
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/promu/util/sh"
+)
+
+var (
+	publishcmd = app.Command("publish", "Upload the release files to object storage: Amazon S3, Google Cloud Storage or Azure Blob Storage")
+	publishSSE = publishcmd.Flag("sse", "Server-side encryption of the S3 objects").
+			Enum("AES256", "aws:kms")
+	publishKMSKey = publishcmd.Flag("kms-key", "Encryption key of the objects: KMS key ID for S3 with --sse=aws:kms, Cloud KMS key name for GCS or encryption scope for Azure").
+			String()
+	publishNoIndex = publishcmd.Flag("no-index", "Don't upload the index.html and manifest.json files listing the published files").
+			Bool()
+	publishURL = publishcmd.Arg("url", "Destination of the files: s3://bucket/prefix, gs://bucket/prefix or az://container/prefix, the storage account being given by AZURE_STORAGE_ACCOUNT").
+			Required().String()
+	publishLocation = publishcmd.Arg("location", "Location of the files to publish").
+			Default(".tarballs").String()
+)
+
+// contentTypes are the media types of the release files missing from the
+// system MIME types, by extension.
+var contentTypes = map[string]string{
+	".gz":  "application/gzip",
+	".tgz": "application/gzip",
+	".xz":  "application/x-xz",
+	".zst": "application/zstd",
+	".zip": "application/zip",
+	".deb": "application/vnd.debian.binary-package",
+	".rpm": "application/x-rpm",
+	".msi": "application/x-msi",
+	".asc": "application/pgp-signature",
+	".txt": "text/plain; charset=utf-8",
+}
+
+// publishDestination is a location in object storage.
+type publishDestination struct {
+	// scheme is s3, gs or az.
+	scheme string
+	// bucket is the bucket, or the container for Azure.
+	bucket string
+	// prefix is the prefix of the object names, without trailing slash.
+	prefix string
+}
+
+// publishManifestFile is a file of manifest.json and index.html.
+type publishManifestFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	OS     string `json:"os,omitempty"`
+	Arch   string `json:"arch,omitempty"`
+}
+
+var publishIndexTemplate = template.Must(template.New("index.html").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{ .Name }} {{ .Version }}</title></head>
+<body>
+<h1>{{ .Name }} {{ .Version }}</h1>
+<table>
+<tr><th>File</th><th>OS</th><th>Arch</th><th>Size</th><th>SHA256</th></tr>
+{{- range .Files }}
+<tr><td><a href="{{ .Name }}">{{ .Name }}</a></td><td>{{ .OS }}</td><td>{{ .Arch }}</td><td>{{ .Size }}</td><td><code>{{ .SHA256 }}</code></td></tr>
+{{- end }}
+</table>
+</body>
+</html>
+`))
+
+func runPublish(rawURL, location string) {
+	dst, err := parsePublishDestination(rawURL)
+	if err != nil {
+		fatal(err)
+	}
+	if *publishSSE != "" && dst.scheme != "s3" {
+		fatal(errors.New("--sse can only be used with s3:// destinations"))
+	}
+
+	entries, err := os.ReadDir(location)
+	if err != nil {
+		fatal(err)
+	}
+	var files []publishManifestFile
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		f, err := publishFile(location, e.Name())
+		if err != nil {
+			fatal(err)
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		fatal(fmt.Errorf("no files to publish in %s", location))
+	}
+
+	for _, f := range files {
+		contentType, err := publishContentType(f.Name)
+		if err != nil {
+			fatal(err)
+		}
+		if err := publishObject(dst, filepath.Join(location, f.Name), f.Name, contentType); err != nil {
+			fatal(err)
+		}
+	}
+	if *publishNoIndex {
+		return
+	}
+
+	// The index files are written to a temporary directory so that they
+	// aren't published as release files by a later run.
+	dir, err := os.MkdirTemp("", "promu-publish")
+	if err != nil {
+		fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := writePublishIndex(dir, files); err != nil {
+		fatal(err)
+	}
+	for _, index := range []struct{ name, contentType string }{
+		{"manifest.json", "application/json"},
+		{"index.html", "text/html; charset=utf-8"},
+	} {
+		if err := publishObject(dst, filepath.Join(dir, index.name), index.name, index.contentType); err != nil {
+			fatal(err)
+		}
+	}
+}
+
+// parsePublishDestination parses a s3://, gs:// or az:// URL.
+func parsePublishDestination(rawURL string) (publishDestination, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return publishDestination{}, err
+	}
+	switch u.Scheme {
+	case "s3", "gs", "az":
+	default:
+		return publishDestination{}, fmt.Errorf("unsupported destination %q, expected s3://, gs:// or az://", rawURL)
+	}
+	if u.Host == "" {
+		return publishDestination{}, fmt.Errorf("missing bucket in destination %q", rawURL)
+	}
+	return publishDestination{
+		scheme: u.Scheme,
+		bucket: u.Host,
+		prefix: strings.Trim(u.Path, "/"),
+	}, nil
+}
+
+// object returns the name of the object of the file.
+func (d publishDestination) object(name string) string {
+	return path.Join(d.prefix, name)
+}
+
+// publishFile returns the manifest entry of the file.
+func publishFile(location, name string) (publishManifestFile, error) {
+	fi, err := os.Stat(filepath.Join(location, name))
+	if err != nil {
+		return publishManifestFile{}, err
+	}
+	sum, err := sha256File(os.DirFS(location), name)
+	if err != nil {
+		return publishManifestFile{}, err
+	}
+	f := publishManifestFile{Name: name, Size: fi.Size(), SHA256: hex.EncodeToString(sum)}
+	if platform, ok := artifactPlatform(name); ok {
+		f.OS, f.Arch, _ = strings.Cut(platform, "/")
+	}
+	return f, nil
+}
+
+// publishContentType returns the media type of the file: the content type
+// of the first matching release asset, else the type of its extension.
+func publishContentType(name string) (string, error) {
+	opts, err := uploadOptions(name)
+	if err != nil {
+		return "", err
+	}
+	if opts.MediaType != "" {
+		return opts.MediaType, nil
+	}
+	ext := strings.ToLower(path.Ext(name))
+	if t, ok := contentTypes[ext]; ok {
+		return t, nil
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t, nil
+	}
+	return "application/octet-stream", nil
+}
+
+// publishArgs returns the command uploading the file to the object of the
+// given name.
+func publishArgs(dst publishDestination, file, name, contentType string) []string {
+	object := dst.object(name)
+	switch dst.scheme {
+	case "s3":
+		args := []string{"aws", "s3", "cp", "--no-progress", "--content-type", contentType}
+		if *publishSSE != "" {
+			args = append(args, "--sse", *publishSSE)
+		}
+		if *publishKMSKey != "" {
+			args = append(args, "--sse-kms-key-id", *publishKMSKey)
+		}
+		return append(args, file, fmt.Sprintf("s3://%s/%s", dst.bucket, object))
+	case "gs":
+		args := []string{"gcloud", "storage", "cp", "--content-type=" + contentType}
+		if *publishKMSKey != "" {
+			args = append(args, "--encryption-key="+*publishKMSKey)
+		}
+		return append(args, file, fmt.Sprintf("gs://%s/%s", dst.bucket, object))
+	default:
+		args := []string{"az", "storage", "blob", "upload", "--only-show-errors", "--overwrite",
+			"--container-name", dst.bucket, "--name", object, "--file", file, "--content-type", contentType}
+		if *publishKMSKey != "" {
+			args = append(args, "--encryption-scope", *publishKMSKey)
+		}
+		return args
+	}
+}
+
+// publishObject uploads the file to the object of the given name.
+func publishObject(dst publishDestination, file, name, contentType string) error {
+	args := publishArgs(dst, file, name, contentType)
+	if err := sh.RunCommand(args[0], args[1:]...); err != nil {
+		return fmt.Errorf("failed to publish %s: %w", name, err)
+	}
+	fmt.Println(" > published", name)
+	return nil
+}
+
+// writePublishIndex writes the manifest.json and index.html files listing
+// the files, sorted by name, to the directory.
+func writePublishIndex(dir string, files []publishManifestFile) error {
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	b, err := json.MarshalIndent(files, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+
+	f, err := os.Create(filepath.Join(dir, "index.html"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = publishIndexTemplate.Execute(f, struct {
+		Name, Version string
+		Files         []publishManifestFile
+	}{projInfo.Name, projInfo.Version, files})
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,143 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPublishArgs(t *testing.T) {
+	defer func(sse, key string) { *publishSSE, *publishKMSKey = sse, key }(*publishSSE, *publishKMSKey)
+
+	for _, tc := range []struct {
+		url      string
+		sse, key string
+		exp      []string
+	}{
+		{
+			url: "s3://bucket/prefix/",
+			exp: []string{"aws", "s3", "cp", "--no-progress", "--content-type", "application/gzip", "f.tar.gz", "s3://bucket/prefix/f.tar.gz"},
+		},
+		{
+			url: "s3://bucket",
+			sse: "aws:kms",
+			key: "alias/release",
+			exp: []string{"aws", "s3", "cp", "--no-progress", "--content-type", "application/gzip", "--sse", "aws:kms", "--sse-kms-key-id", "alias/release", "f.tar.gz", "s3://bucket/f.tar.gz"},
+		},
+		{
+			url: "gs://bucket/a/b",
+			key: "projects/p/locations/l/keyRings/r/cryptoKeys/k",
+			exp: []string{"gcloud", "storage", "cp", "--content-type=application/gzip", "--encryption-key=projects/p/locations/l/keyRings/r/cryptoKeys/k", "f.tar.gz", "gs://bucket/a/b/f.tar.gz"},
+		},
+		{
+			url: "az://container/prefix",
+			key: "scope",
+			exp: []string{"az", "storage", "blob", "upload", "--only-show-errors", "--overwrite", "--container-name", "container", "--name", "prefix/f.tar.gz", "--file", "f.tar.gz", "--content-type", "application/gzip", "--encryption-scope", "scope"},
+		},
+	} {
+		t.Run(tc.url, func(t *testing.T) {
+			*publishSSE, *publishKMSKey = tc.sse, tc.key
+			dst, err := parsePublishDestination(tc.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := publishArgs(dst, "f.tar.gz", "f.tar.gz", "application/gzip")
+			if !reflect.DeepEqual(tc.exp, got) {
+				t.Fatalf("expected %q, got %q", tc.exp, got)
+			}
+		})
+	}
+
+	for _, u := range []string{"https://bucket/prefix", "s3:///prefix", "file.tar.gz"} {
+		if _, err := parsePublishDestination(u); err == nil {
+			t.Fatalf("expected error for %q, got none", u)
+		}
+	}
+}
+
+func TestPublishContentType(t *testing.T) {
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+	config.Release.Assets = []ReleaseAsset{{Name: "*.sbom.json", ContentType: "application/spdx+json"}}
+
+	for name, exp := range map[string]string{
+		"foo-1.0.0.linux-amd64.tar.gz": "application/gzip",
+		"foo-1.0.0.windows-amd64.zip":  "application/zip",
+		"foo_1.0.0_amd64.deb":          "application/vnd.debian.binary-package",
+		"sha256sums.txt":               "text/plain; charset=utf-8",
+		"foo.sbom.json":                "application/spdx+json",
+		"foo":                          "application/octet-stream",
+	} {
+		got, err := publishContentType(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != exp {
+			t.Errorf("%s: expected %q, got %q", name, exp, got)
+		}
+	}
+}
+
+func TestWritePublishIndex(t *testing.T) {
+	location := t.TempDir()
+	for name, content := range map[string]string{
+		"foo-1.0.0.linux-amd64.tar.gz": "linux",
+		"sha256sums.txt":               "sums",
+	} {
+		if err := os.WriteFile(filepath.Join(location, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var files []publishManifestFile
+	for _, name := range []string{"sha256sums.txt", "foo-1.0.0.linux-amd64.tar.gz"} {
+		f, err := publishFile(location, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+
+	dir := t.TempDir()
+	if err := writePublishIndex(dir, files); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest []publishManifestFile
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	exp := []publishManifestFile{
+		{Name: "foo-1.0.0.linux-amd64.tar.gz", Size: 5, SHA256: "caf90169eefa5f807d577486b9f795ab86ae2983c5c20806cff959117e90af18", OS: "linux", Arch: "amd64"},
+		{Name: "sha256sums.txt", Size: 4, SHA256: "2220e3c51f099b5308c70ed91977af4887c71fe1f8f6c3fedafe7c9f623f301f"},
+	}
+	if !reflect.DeepEqual(exp, manifest) {
+		t.Fatalf("expected manifest %+v, got %+v", exp, manifest)
+	}
+
+	b, err = os.ReadFile(filepath.Join(dir, "index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `<a href="foo-1.0.0.linux-amd64.tar.gz">`) {
+		t.Fatalf("expected index.html to link the tarball, got:\n%s", b)
+	}
+}
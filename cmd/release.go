@@ -15,200 +15,826 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/google/go-github/v25/github"
 	"golang.org/x/oauth2"
 
-	"github.com/prometheus/promu/pkg/changelog"
+	"github.com/prometheus/promu/util/pool"
 	"github.com/prometheus/promu/util/retry"
 )
 
 var (
-	releasecmd     = app.Command("release", "Upload all release files to the Github release")
-	timeout        = releasecmd.Flag("timeout", "Upload timeout").Duration()
-	allowedRetries = releasecmd.Flag("retry", "Number of retries to perform when upload fails").
-			Default("2").Int()
-	releaseLocation = releasecmd.Arg("location", "Location of files to release").Default(".").Strings()
+	releasecmd        = app.Command("release", "Manage the Github release of the project")
+	timeout           = releasecmd.Flag("timeout", "Timeout of each API request and asset upload or download").Duration()
+	releaseDeadline   = releasecmd.Flag("deadline", "Timeout of the whole command").Duration()
+	releaseTargetRepo = releasecmd.Flag("target-repo", "Release to the given GitHub repository (owner/name) instead of the configured ones, e.g. a scratch repository").
+				String()
+
+	releaseuploadcmd = releasecmd.Command("upload", "Upload all release files to the Github release").Default()
+	allowedRetries   = releaseuploadcmd.Flag("retry", "Number of retries to perform when upload fails").
+				Default("2").Int()
+	releaseParallelism = releaseuploadcmd.Flag("parallelism", "How many assets to upload in parallel").
+				Default("4").Int()
+	releaseResume = releaseuploadcmd.Flag("resume", "Skip the assets already uploaded with the same size and SHA256 digest instead of failing or replacing them").
+			Bool()
+	releaseOverwrite = releaseuploadcmd.Flag("overwrite", "Replace the existing assets of published releases").
+				Bool()
+	releaseSkipExisting = releaseuploadcmd.Flag("skip-existing", "Keep the existing assets of published releases and skip the files").
+				Bool()
+	releaseFailOnExisting = releaseuploadcmd.Flag("fail-on-existing", "Fail if an asset of a published release already exists, the default").
+				Bool()
+	releaseBandwidthLimit = releaseuploadcmd.Flag("bandwidth-limit", "Maximum bandwidth of all the uploads per second, e.g. 10MiB, unlimited by default").
+				Bytes()
+	releaseProgressInterval = releaseuploadcmd.Flag("progress-interval", "Interval between the reports of the progress of each upload, 0 to disable them").
+				Default("10s").Duration()
+	releaseSkipVerify = releaseuploadcmd.Flag("skip-verify", "Don't download the uploaded assets to check their size and SHA256 digest").
+				Bool()
+	releaseCleanup = releaseuploadcmd.Flag("cleanup", "Delete the release created by this run, its assets and its tag once done (requires --target-repo)").
+			Bool()
+	releaseChecksums = releaseuploadcmd.Flag("checksums", "Write the "+checksumsFilename+" file of the uploaded files to the location and upload it too").
+				Bool()
+	releaseChecksumsSigner = releaseuploadcmd.Flag("checksums-signer", "Upload a detached signature of the checksums file too, made with gpg or minisign").
+				Enum(signerGPG, signerMinisign)
+	releaseChecksumsKey = releaseuploadcmd.Flag("checksums-key", "ID of the GPG key, release.gpg.key or PROMU_GPG_KEY by default, or minisign secret key file, signing the checksums file").
+				String()
+	releasePlatforms = releaseuploadcmd.Flag("platforms", "Regexp match platforms of the artifacts to upload, may be used multiple times. Files without platform are skipped.").
+				Short('p').Strings()
+	releaseLocation = releaseuploadcmd.Arg("location", "Location of files to release").Default(".").Strings()
 )
 
-func runRelease(location string) {
-	token := os.Getenv("GITHUB_TOKEN")
-	if len(token) == 0 {
-		fatal(errors.New("GITHUB_TOKEN not defined"))
+// Policies for the existing assets of published releases.
+const (
+	existingFail      = "fail"
+	existingOverwrite = "overwrite"
+	existingSkip      = "skip"
+)
+
+// existingAssetPolicy returns the policy for the existing assets of
+// published releases selected by the flags. The assets of draft releases
+// are always replaced.
+func existingAssetPolicy() (string, error) {
+	var policies []string
+	if *releaseOverwrite {
+		policies = append(policies, existingOverwrite)
 	}
+	if *releaseSkipExisting {
+		policies = append(policies, existingSkip)
+	}
+	if *releaseFailOnExisting {
+		policies = append(policies, existingFail)
+	}
+	switch len(policies) {
+	case 0:
+		return existingFail, nil
+	case 1:
+		return policies[0], nil
+	default:
+		return "", errors.New("--overwrite, --skip-existing and --fail-on-existing are mutually exclusive")
+	}
+}
 
-	ctx := context.Background()
-	if *timeout != time.Duration(0) {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, *timeout)
-		defer cancel()
+// releaseContext returns the context of the command, bounded by the
+// deadline.
+func releaseContext() (context.Context, context.CancelFunc) {
+	if *releaseDeadline != time.Duration(0) {
+		return context.WithTimeout(context.Background(), *releaseDeadline)
 	}
-	client := github.NewClient(
-		oauth2.NewClient(
-			ctx,
-			oauth2.StaticTokenSource(
-				&oauth2.Token{AccessToken: token},
-			),
+	return context.Background(), func() {}
+}
+
+// releaseHTTPClient returns a HTTP client whose requests are bounded by the
+// timeout, so that a slow request doesn't cancel the others.
+func releaseHTTPClient() *http.Client {
+	return &http.Client{Timeout: *timeout}
+}
+
+// newGitHubClient returns a GitHub client authenticated with the token, whose
+// requests are bounded by the timeout.
+func newGitHubClient(ctx context.Context, token string) *github.Client {
+	client := oauth2.NewClient(
+		ctx,
+		oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: token},
 		),
 	)
+	client.Timeout = *timeout
+	return github.NewClient(client)
+}
 
-	semVer, err := projInfo.ToSemver()
-	if err != nil {
-		fatal(err)
+// releaseTarget is a GitHub repository the release is uploaded to.
+type releaseTarget struct {
+	client      *github.Client
+	owner, repo string
+	// token authenticates the downloads of the assets, made without the
+	// GitHub client.
+	token string
+	// own is whether it is the repository of the project, in which the
+	// release is tagged at the current revision.
+	own bool
+	// scratch is whether it is the repository given by --target-repo, whose
+	// releases are rehearsals which may be deleted even once published.
+	scratch bool
+}
+
+func (t releaseTarget) String() string {
+	return t.owner + "/" + t.repo
+}
+
+// releaseTargets returns the repositories of the release with their
+// authenticated clients: the one given by --target-repo, else the configured targets,
+// else the repository of the project.
+func releaseTargets(ctx context.Context) ([]releaseTarget, error) {
+	targets := config.Release.Targets
+	switch {
+	case *releaseTargetRepo != "":
+		targets = []ReleaseTarget{{Repository: *releaseTargetRepo}}
+	case len(targets) == 0:
+		targets = []ReleaseTarget{{Repository: projInfo.Owner + "/" + projInfo.Name}}
 	}
 
-	// Find the GitHub release matching with the tag. We need to list all
-	// releases because it is the only way to get draft releases too.
-	var (
-		release *github.RepositoryRelease
-		opts    = &github.ListOptions{}
-		tag     = fmt.Sprintf("v%s", projInfo.Version)
-	)
+	var all []releaseTarget
+	for _, target := range targets {
+		owner, repo, err := splitRepository(target.Repository)
+		if err != nil {
+			return nil, err
+		}
+		token, err := githubToken(ctx, target, owner, repo)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, releaseTarget{
+			client:  newGitHubClient(ctx, token),
+			owner:   owner,
+			repo:    repo,
+			token:   token,
+			own:     *releaseTargetRepo == "" && owner == projInfo.Owner && repo == projInfo.Name,
+			scratch: *releaseTargetRepo != "",
+		})
+	}
+	return all, nil
+}
+
+// findRelease returns the release of the tag, nil if there is none. All
+// releases are listed because it is the only way to get draft releases too.
+func findRelease(ctx context.Context, client *github.Client, owner, repo, tag string) (*github.RepositoryRelease, error) {
+	opts := &github.ListOptions{PerPage: 100}
 	for {
-		releases, resp, err := client.Repositories.ListReleases(ctx, projInfo.Owner, projInfo.Name, opts)
+		releases, resp, err := client.Repositories.ListReleases(ctx, owner, repo, opts)
 		if err != nil {
-			fatal(fmt.Errorf("failed to list releases: %w", err))
+			return nil, fmt.Errorf("failed to list releases: %w", err)
 		}
 		for _, r := range releases {
 			if r.GetTagName() == tag {
-				release = r
-				break
+				return r, nil
 			}
 		}
-		if release != nil || resp.NextPage == 0 {
-			break
+		if resp.NextPage == 0 {
+			return nil, nil
 		}
 		opts.Page = resp.NextPage
 	}
-	if release == nil {
-		f, err := os.Open("CHANGELOG.md")
+}
+
+func runRelease(location string) {
+	ctx, cancel := releaseContext()
+	defer cancel()
+
+	semVer, err := projInfo.ToSemver()
+	if err != nil {
+		fatal(err)
+	}
+
+	provider, err := releaseProvider()
+	if err != nil {
+		fatal(err)
+	}
+	if *releaseCleanup && *releaseTargetRepo == "" {
+		fatal(errors.New("--cleanup can only be used with --target-repo"))
+	}
+	if *releaseChecksumsSigner != "" && !*releaseChecksums {
+		fatal(errors.New("--checksums-signer can only be used with --checksums"))
+	}
+	policy, err := existingAssetPolicy()
+	if err != nil {
+		fatal(err)
+	}
+	uploadLimiter = newBandwidthLimiter(int64(*releaseBandwidthLimit))
+	if policy == existingOverwrite && provider == providerGitLab {
+		fatal(errors.New("--overwrite is not supported by the gitlab provider"))
+	}
+
+	files, err := releaseFiles(location)
+	if err != nil {
+		fatal(err)
+	}
+	switch provider {
+	case providerGitLab:
+		c, err := newGitLabClient()
 		if err != nil {
 			fatal(err)
 		}
-		defer f.Close()
-
-		entry, err := changelog.ReadEntry(f, projInfo.Version)
+		if err := releaseToGitLab(ctx, c, files); err != nil {
+			fatal(err)
+		}
+		return
+	case providerGitea:
+		c, err := newGiteaClient()
 		if err != nil {
 			fatal(err)
 		}
-		name := entry.Name()
+		if err := releaseToGitea(ctx, c, semVer.Prerelease() != "", files); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	targets, err := releaseTargets(ctx)
+	if err != nil {
+		fatal(err)
+	}
+	// A failed target doesn't prevent releasing to the others, a later run
+	// with --resume completing them.
+	var errs []error
+	for _, t := range targets {
+		if len(targets) > 1 {
+			fmt.Println(">> releasing to", t)
+		}
+		if err := releaseToTarget(ctx, t, semVer.Prerelease() != "", files); err != nil {
+			if len(targets) > 1 {
+				err = fmt.Errorf("%s: %w", t, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		fatal(err)
+	}
+}
+
+// releaseToTarget uploads the files to the release of the project version
+// in the target repository, creating it as a draft if it doesn't exist.
+func releaseToTarget(ctx context.Context, t releaseTarget, prerelease bool, files []string) error {
+	commitish := &projInfo.Revision
+	if !t.own {
+		// The current revision is unlikely to exist in the target repository,
+		// let GitHub use its default branch instead.
+		commitish = nil
+	}
+
+	tag := fmt.Sprintf("v%s", projInfo.Version)
+	release, err := findRelease(ctx, t.client, t.owner, t.repo, tag)
+	if err != nil {
+		return err
+	}
+	created := release == nil
+	if created {
+		name, body, err := releaseNotes(t.String(), tag, githubAssetURL(t.owner, t.repo, tag), files)
+		if err != nil {
+			return err
+		}
 		// Create a draft release if none exists already.
 		draft := true
-		prerelease := semVer.Prerelease() != ""
-		release, _, err = client.Repositories.CreateRelease(
+		release, _, err = t.client.Repositories.CreateRelease(
 			ctx,
-			projInfo.Owner,
-			projInfo.Name,
+			t.owner,
+			t.repo,
 			&github.RepositoryRelease{
 				TagName:         &tag,
-				TargetCommitish: &projInfo.Revision,
+				TargetCommitish: commitish,
 				Name:            &name,
-				Body:            &entry.Text,
+				Body:            &body,
 				Draft:           &draft,
 				Prerelease:      &prerelease,
 			})
 		if err != nil {
-			fatal(fmt.Errorf("failed to create a draft release for %s: %w", projInfo.Version, err))
+			return fmt.Errorf("failed to create a draft release for %s: %w", projInfo.Version, err)
 		}
 	}
 
-	if err := filepath.Walk(location, releaseFile(ctx, client, release)); err != nil {
+	err = uploadFiles(ctx, t, release, files)
+	if *releaseCleanup && !created {
+		warn(fmt.Errorf("release %s existed before this run, it isn't deleted", tag))
+	}
+	if *releaseCleanup && created {
+		cleanupRelease(ctx, t.client, t.owner, t.repo, release)
+	} else if err != nil {
 		// Remove incomplete assets.
 		// See https://developer.github.com/v3/repos/releases/#response-for-upstream-failure
-		opts = &github.ListOptions{}
-		for {
-			assets, resp, err := client.Repositories.ListReleaseAssets(ctx, projInfo.Owner, projInfo.Name, release.GetID(), opts)
-			if err != nil {
-				break
-			}
-			for _, asset := range assets {
-				if strings.EqualFold(asset.GetState(), "starter") {
-					_, _ = client.Repositories.DeleteReleaseAsset(ctx, projInfo.Owner, projInfo.Name, asset.GetID())
-				}
-			}
-			if resp.NextPage == 0 {
-				break
+		assets, _ := listReleaseAssets(ctx, t.client, t.owner, t.repo, release)
+		for _, asset := range assets {
+			if incompleteAsset(asset) {
+				_, _ = t.client.Repositories.DeleteReleaseAsset(ctx, t.owner, t.repo, asset.GetID())
 			}
-			opts.Page = resp.NextPage
 		}
-		fatal(fmt.Errorf("failed to upload all files: %w", err))
 	}
+	if err != nil {
+		return fmt.Errorf("failed to upload all files: %w", err)
+	}
+	return nil
 }
 
-func releaseFile(ctx context.Context, client *github.Client, release *github.RepositoryRelease) func(string, os.FileInfo, error) error {
-	return func(path string, fi os.FileInfo, err error) error {
+// splitRepository splits a repository given as "owner/name".
+func splitRepository(s string) (string, string, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid repository %q, expected format: owner/name", s)
+	}
+	return parts[0], parts[1], nil
+}
+
+// cleanupRelease deletes the release, its assets and its tag. Failures are
+// only reported since the cleanup runs at the very end of a rehearsal. The
+// rehearsals going on with release publish are deleted by release rollback
+// instead.
+func cleanupRelease(ctx context.Context, client *github.Client, owner, repo string, release *github.RepositoryRelease) {
+	if err := deleteRelease(ctx, client, owner, repo, release, true); err != nil {
+		warn(err)
+	}
+}
+
+// deleteRelease deletes the release with its assets, and its tag if
+// requested.
+func deleteRelease(ctx context.Context, client *github.Client, owner, repo string, release *github.RepositoryRelease, tag bool) error {
+	if _, err := client.Repositories.DeleteRelease(ctx, owner, repo, release.GetID()); err != nil {
+		return fmt.Errorf("failed to delete release %q: %w", release.GetName(), err)
+	}
+	fmt.Println(" > deleted release", release.GetName())
+	if !tag {
+		return nil
+	}
+	return deleteTag(ctx, client, owner, repo, release.GetTagName())
+}
+
+// deleteTag deletes the tag if it exists.
+func deleteTag(ctx context.Context, client *github.Client, owner, repo, tag string) error {
+	// Draft releases don't create the tag so it may not exist.
+	resp, err := client.Git.DeleteRef(ctx, owner, repo, "tags/"+tag)
+	if err != nil && (resp == nil || resp.StatusCode != http.StatusUnprocessableEntity) {
+		return fmt.Errorf("failed to delete tag %q: %w", tag, err)
+	}
+	if err == nil {
+		fmt.Println(" > deleted tag", tag)
+	}
+	return nil
+}
+
+// releaseFiles returns the files found in location to upload to the
+// release, with the checksums file written for them and the minisign and
+// cosign signatures if requested. The cosign signatures are those of promu
+// sign, of the archives, packages and checksums files, replacing their
+// signatures found in location.
+func releaseFiles(location string) ([]string, error) {
+	match, err := artifactFilter(*releasePlatforms)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	err = filepath.Walk(location, func(path string, fi os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if fi.IsDir() {
+		if fi.IsDir() || !match(path) {
+			return nil
+		}
+		// Files written by a previous run are written again below.
+		if (*releaseChecksums && isChecksumsFile(path)) ||
+			(minisignEnabled() && strings.HasSuffix(path, ".minisig")) {
 			return nil
 		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if *releaseChecksums {
+		checksums, err := writeReleaseChecksums(location, files)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, checksums...)
+	}
+	if minisignEnabled() {
+		signatures, err := minisignFiles(files)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, signatures...)
+	}
+	if config.Release.Sign {
+		signed := map[string]bool{}
+		for _, path := range files {
+			if isSignableArtifact(path) {
+				signed[path] = true
+			}
+		}
+		var unsigned []string
+		for _, path := range files {
+			switch {
+			case isCosignFile(path) && signed[strings.TrimSuffix(path, filepath.Ext(path))]:
+				// Written again when the artifact is signed below.
+				continue
+			case isCosignFile(path):
+				warn(fmt.Errorf("%s isn't the signature of a released artifact and is uploaded as is", filepath.Base(path)))
+			case !isSignableArtifact(path) && !isSignatureFile(path):
+				warn(fmt.Errorf("%s is uploaded without cosign signature", filepath.Base(path)))
+			}
+			unsigned = append(unsigned, path)
+		}
+		signatures, err := signFiles(unsigned, signerCosign, "", nil, *releaseParallelism)
+		if err != nil {
+			return nil, err
+		}
+		files = append(unsigned, signatures...)
+	}
+	return files, nil
+}
+
+// uploadFiles uploads the files to the release of the target. The status
+// tasks of the files are prefixed by the repository, unless it is the
+// repository of the project.
+func uploadFiles(ctx context.Context, t releaseTarget, release *github.RepositoryRelease, files []string) error {
+	// The existing assets are listed once for all the files, which are
+	// uploaded concurrently. A failed upload doesn't stop the others so
+	// that a resumed release has less to do.
+	assets, err := listReleaseAssets(ctx, t.client, t.owner, t.repo, release)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]*github.ReleaseAsset, len(assets))
+	for _, asset := range assets {
+		existing[asset.GetName()] = asset
+	}
+
+	tasks := make(map[string]string, len(files))
+	for _, path := range files {
+		tasks[path] = path
+		if !t.own {
+			tasks[path] = fmt.Sprintf("%s: %s", t, path)
+		}
+		statusTracker.Add(tasks[path])
+	}
+	workers := pool.New(ctx, *releaseParallelism, false)
+	for _, path := range files {
+		path := path
+		workers.Go(func(ctx context.Context) error {
+			statusTracker.Start(tasks[path])
+			err := releaseFile(ctx, t, release, path, existing[filepath.Base(path)])
+			statusTracker.Finish(tasks[path], err)
+			return err
+		})
+	}
+	return workers.Wait()
+}
+
+// isChecksumsFile returns whether the file is a checksums file written by
+// a previous release, or its signature.
+func isChecksumsFile(path string) bool {
+	switch filepath.Base(path) {
+	case checksumsFilename, checksumsFilename + ".asc", checksumsFilename + ".minisig":
+		return true
+	}
+	return false
+}
+
+// writeReleaseChecksums writes the checksums file of the files into the
+// location, and signs it if requested. The files are listed by base name,
+// like the release assets. It returns the paths of the written files.
+func writeReleaseChecksums(location string, files []string) ([]string, error) {
+	checksums, err := calculateReleaseChecksums(location, files)
+	if err != nil {
+		return nil, err
+	}
+	return writeReleaseChecksumsFile(location, checksums)
+}
 
-		// Check if the asset has already been uploaded and remove it if it is a draft release.
-		filename := filepath.Base(path)
-		opts := &github.ListOptions{}
-		for {
-			assets, resp, err := client.Repositories.ListReleaseAssets(ctx, projInfo.Owner, projInfo.Name, release.GetID(), opts)
+// calculateReleaseChecksums returns the SHA256 checksums of the files of the
+// location by base name, sorted.
+func calculateReleaseChecksums(location string, files []string) ([]fileChecksum, error) {
+	include := make(map[string]bool, len(files))
+	for _, path := range files {
+		rel, err := filepath.Rel(location, path)
+		if err != nil {
+			return nil, err
+		}
+		include[filepath.ToSlash(rel)] = true
+	}
+	checksums, err := calculateSHA256s(os.DirFS(location), func(path string) bool { return include[path] })
+	if err != nil {
+		return nil, fmt.Errorf("Failed to calculate checksums: %w", err)
+	}
+	for i := range checksums {
+		checksums[i].filename = filepath.Base(checksums[i].filename)
+	}
+	sort.Slice(checksums, func(i, j int) bool { return checksums[i].filename < checksums[j].filename })
+	return checksums, nil
+}
+
+// writeReleaseChecksumsFile writes the checksums file into the location and
+// signs it if requested. It returns the paths of the written files.
+func writeReleaseChecksumsFile(location string, checksums []fileChecksum) ([]string, error) {
+	path := filepath.Join(location, checksumsFilename)
+	if err := writeChecksums(path, checksums, false); err != nil {
+		return nil, err
+	}
+	written := []string{path}
+	if signer, key := checksumsSigner(); signer != "" {
+		signature, err := detachSign(signer, key, path)
+		if err != nil {
+			return nil, err
+		}
+		written = append(written, signature)
+	}
+	return written, nil
+}
+
+// checksumsSigner returns the signer of the checksums file and its key:
+// those of the command line, else GPG with the configured key if the
+// checksums are signed by default.
+func checksumsSigner() (string, string) {
+	signer, key := *releaseChecksumsSigner, *releaseChecksumsKey
+	if signer == "" && config.Release.GPG.SignChecksums {
+		signer = signerGPG
+	}
+	if signer == signerGPG && key == "" {
+		key = gpgKey()
+	}
+	return signer, key
+}
+
+// listReleaseAssets returns all the assets of the release.
+func listReleaseAssets(ctx context.Context, client *github.Client, owner, repo string, release *github.RepositoryRelease) ([]*github.ReleaseAsset, error) {
+	var (
+		all  []*github.ReleaseAsset
+		opts = &github.ListOptions{PerPage: 100}
+	)
+	for {
+		assets, resp, err := client.Repositories.ListReleaseAssets(ctx, owner, repo, release.GetID(), opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list release assets: %w", err)
+		}
+		all = append(all, assets...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// incompleteAsset returns whether the upload of the asset failed.
+func incompleteAsset(asset *github.ReleaseAsset) bool {
+	return strings.EqualFold(asset.GetState(), "starter")
+}
+
+// releaseFile uploads the file to the release, retrying with an exponential
+// backoff. The existing asset of the same name, if any, is kept when
+// resuming if it matches the file. Otherwise it is replaced if it is
+// incomplete or if the release is a draft, else handled according to the
+// existing asset policy.
+func releaseFile(ctx context.Context, t releaseTarget, release *github.RepositoryRelease, path string, asset *github.ReleaseAsset) error {
+	client, owner, repo := t.client, t.owner, t.repo
+	filename := filepath.Base(path)
+	opts, err := uploadOptions(filename)
+	if err != nil {
+		return err
+	}
+	if asset != nil {
+		if *releaseResume && !incompleteAsset(asset) {
+			same, err := sameAsset(ctx, t, asset, path)
 			if err != nil {
-				return fmt.Errorf("failed to list release assets: %w", err)
+				return fmt.Errorf("failed to verify existing asset %q: %w", filename, err)
 			}
-			var stop bool
-			for _, asset := range assets {
-				if asset.GetName() == filename {
-					var err error
-					stop = true
-					if release.GetDraft() {
-						_, err = client.Repositories.DeleteReleaseAsset(ctx, projInfo.Owner, projInfo.Name, asset.GetID())
-						if err != nil {
-							err = fmt.Errorf("failed to delete existing asset %q: %w", filename, err)
-						}
-					} else {
-						err = fmt.Errorf("%q already exists", filename)
-					}
+			if same {
+				if opts.Label != "" && opts.Label != asset.GetLabel() {
+					_, _, err := client.Repositories.EditReleaseAsset(ctx, owner, repo, asset.GetID(), &github.ReleaseAsset{Name: &filename, Label: &opts.Label})
 					if err != nil {
-						return err
+						return fmt.Errorf("failed to update the label of %q: %w", filename, err)
 					}
-					break
 				}
+				fmt.Println(" > skipped", filename, "already uploaded")
+				return nil
 			}
-			if stop || resp.NextPage == 0 {
-				break
+		}
+		if !release.GetDraft() && !incompleteAsset(asset) {
+			policy, err := existingAssetPolicy()
+			if err != nil {
+				return err
 			}
-			opts.Page = resp.NextPage
+			switch policy {
+			case existingSkip:
+				fmt.Println(" > skipped", filename, "already exists")
+				return nil
+			case existingFail:
+				return fmt.Errorf("%q already exists", filename)
+			}
+		}
+		if _, err := client.Repositories.DeleteReleaseAsset(ctx, owner, repo, asset.GetID()); err != nil {
+			return fmt.Errorf("failed to delete existing asset %q: %w", filename, err)
+		}
+	}
+
+	maxAttempts := *allowedRetries + 1
+	attempts := 0
+	err = retry.Do(func(attempt int) (bool, error) {
+		attempts = attempt
+		uploaded, err := uploadReleaseAsset(ctx, client, owner, repo, release, opts, path)
+		if err == nil && !*releaseSkipVerify {
+			err = verifyAsset(ctx, t, uploaded, path)
+		}
+		if err == nil || attempt >= maxAttempts || !retryableError(err) {
+			return false, err
+		}
+		select {
+		case <-ctx.Done():
+			return false, err
+		case <-time.After(retry.Backoff(attempt, 2*time.Second, time.Minute)):
 		}
+		return true, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %q after %d attempts: %w", filename, attempts, err)
+	}
+	fmt.Println(" > uploaded", filename)
 
-		maxAttempts := *allowedRetries + 1
-		err = retry.Do(func(attempt int) (bool, error) {
-			again := attempt < maxAttempts
+	return nil
+}
 
-			f, err := os.Open(path)
-			if err != nil {
-				return again, err
-			}
+// retryableError returns whether the request failing with err may succeed
+// when retried. The client errors, e.g. a missing authorization or an asset
+// which already exists, fail again unlike the timeouts and the rate limits.
+func retryableError(err error) bool {
+	var (
+		rateLimit *github.RateLimitError
+		abuse     *github.AbuseRateLimitError
+		resp      *github.ErrorResponse
+	)
+	switch {
+	case errors.As(err, &rateLimit), errors.As(err, &abuse):
+		return true
+	case errors.As(err, &resp) && resp.Response != nil:
+		code := resp.Response.StatusCode
+		return code >= 500 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+	}
+	return true
+}
 
-			_, _, err = client.Repositories.UploadReleaseAsset(
-				ctx,
-				projInfo.Owner, projInfo.Name, release.GetID(),
-				&github.UploadOptions{Name: filename},
-				f)
-			if err != nil {
-				time.Sleep(2 * time.Second)
-			}
+// uploadReleaseAsset uploads the file as an asset of the release. Unlike
+// UploadReleaseAsset, the file is read through an uploadReader to report the
+// progress and limit the bandwidth.
+func uploadReleaseAsset(ctx context.Context, client *github.Client, owner, repo string, release *github.RepositoryRelease, opts *github.UploadOptions, path string) (*github.ReleaseAsset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
 
-			return again, err
-		})
+	query := url.Values{"name": {opts.Name}}
+	if opts.Label != "" {
+		query.Set("label", opts.Label)
+	}
+	mediaType := opts.MediaType
+	if mediaType == "" {
+		mediaType = mime.TypeByExtension(filepath.Ext(path))
+	}
+	req, err := client.NewUploadRequest(
+		fmt.Sprintf("repos/%s/%s/releases/%d/assets?%s", owner, repo, release.GetID(), query.Encode()),
+		newUploadReader(ctx, f, opts.Name, fi.Size()),
+		fi.Size(),
+		mediaType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	asset := new(github.ReleaseAsset)
+	if _, err := client.Do(ctx, req, asset); err != nil {
+		return nil, err
+	}
+	return asset, nil
+}
+
+// verifyAsset checks that the uploaded asset has the size and the SHA256
+// digest of the file, deleting it otherwise so that it can be uploaded again.
+func verifyAsset(ctx context.Context, t releaseTarget, asset *github.ReleaseAsset, path string) error {
+	same, err := sameAsset(ctx, t, asset, path)
+	if err == nil && !same {
+		err = errors.New("the uploaded asset doesn't match the file")
+	}
+	if err != nil {
+		_, _ = t.client.Repositories.DeleteReleaseAsset(ctx, t.owner, t.repo, asset.GetID())
+		return fmt.Errorf("failed to verify %q: %w", asset.GetName(), err)
+	}
+	return nil
+}
+
+// releaseAssetData is the data of the label templates of the release assets.
+type releaseAssetData struct {
+	Name    string
+	Version string
+	// OS and Arch are the platform of the asset, empty if it has none.
+	OS, Arch string
+}
+
+// uploadOptions returns the options of the upload of the asset, with the
+// media type and the label of the first matching release asset
+// configuration.
+func uploadOptions(filename string) (*github.UploadOptions, error) {
+	opts := &github.UploadOptions{Name: filename}
+	for _, a := range config.Release.Assets {
+		ok, err := matchGlob(a.Name, filename)
 		if err != nil {
-			return fmt.Errorf("failed to upload %q after %d attempts: %w", filename, maxAttempts, err)
+			return nil, fmt.Errorf("invalid release asset pattern %q: %w", a.Name, err)
+		}
+		if !ok {
+			continue
 		}
-		fmt.Println(" > uploaded", filename)
+		opts.MediaType = a.ContentType
+		if a.Label != "" {
+			tmpl, err := template.New(a.Name).Option("missingkey=error").Parse(a.Label)
+			if err != nil {
+				return nil, fmt.Errorf("invalid label of release asset %q: %w", a.Name, err)
+			}
+			data := releaseAssetData{Name: filename, Version: projInfo.Version}
+			if platform, ok := artifactPlatform(filename); ok {
+				data.OS, data.Arch, _ = strings.Cut(platform, "/")
+			}
+			var label strings.Builder
+			if err := tmpl.Execute(&label, data); err != nil {
+				return nil, fmt.Errorf("invalid label of release asset %q: %w", a.Name, err)
+			}
+			opts.Label = label.String()
+		}
+		break
+	}
+	return opts, nil
+}
 
-		return nil
+// sameAsset returns whether the asset has the size and the SHA256 digest of
+// the file, the asset being downloaded only if the sizes match.
+func sameAsset(ctx context.Context, t releaseTarget, asset *github.ReleaseAsset, path string) (bool, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if int64(asset.GetSize()) != fi.Size() {
+		return false, nil
+	}
+	sum, err := sha256File(os.DirFS(filepath.Dir(path)), filepath.Base(path))
+	if err != nil {
+		return false, err
+	}
+	return assetHasSHA256(ctx, t, asset, sum)
+}
+
+// assetHasSHA256 downloads the asset and returns whether its content has the
+// SHA256 digest. The asset isn't downloaded with the GitHub client, whose
+// redirects are disabled by go-github during its downloads, which would
+// break the uploads running concurrently with the same HTTP client.
+func assetHasSHA256(ctx context.Context, t releaseTarget, asset *github.ReleaseAsset, sum []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.GetURL(), nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/octet-stream")
+	if t.token != "" {
+		// The header isn't sent to the storage the asset is redirected to.
+		req.Header.Set("Authorization", "token "+t.token)
+	}
+	resp, err := releaseHTTPClient().Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return hasSHA256(resp.Body, sum)
+}
+
+// hasSHA256 returns whether the content read from r has the SHA256 digest.
+func hasSHA256(r io.Reader, sum []byte) (bool, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return false, err
 	}
+	return bytes.Equal(h.Sum(nil), sum), nil
 }
@@ -0,0 +1,318 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/prometheus/promu/util/pool"
+	"github.com/prometheus/promu/util/retry"
+)
+
+// giteaClient is a client of the REST API of a Gitea or Forgejo repository.
+type giteaClient struct {
+	restClient
+	// server is the URL of the instance, without trailing slash.
+	server      string
+	owner, repo string
+}
+
+// giteaRelease is a Gitea release.
+type giteaRelease struct {
+	ID              int64        `json:"id,omitempty"`
+	TagName         string       `json:"tag_name"`
+	TargetCommitish string       `json:"target_commitish,omitempty"`
+	Name            string       `json:"name"`
+	Body            string       `json:"body"`
+	Draft           bool         `json:"draft"`
+	Prerelease      bool         `json:"prerelease"`
+	Assets          []giteaAsset `json:"assets,omitempty"`
+}
+
+// giteaAsset is an asset of a Gitea release.
+type giteaAsset struct {
+	ID                 int64  `json:"id"`
+	Name               string `json:"name"`
+	Size               int64  `json:"size"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// newGiteaClient returns a client of the repository of the project,
+// authenticated with the GITEA_TOKEN or else the FORGEJO_TOKEN access token.
+func newGiteaClient() (*giteaClient, error) {
+	c := &giteaClient{
+		restClient: restClient{client: releaseHTTPClient(), header: "Authorization"},
+		server:     "https://" + projInfo.Host,
+		owner:      projInfo.Owner,
+		repo:       projInfo.Name,
+	}
+	for _, env := range []string{"GITEA_TOKEN", "FORGEJO_TOKEN"} {
+		if token := os.Getenv(env); token != "" {
+			c.value = "token " + token
+			return c, nil
+		}
+	}
+	return nil, errors.New("GITEA_TOKEN or FORGEJO_TOKEN not defined")
+}
+
+// url returns the URL of the path of the repository API.
+func (c *giteaClient) url(path string) string {
+	return fmt.Sprintf("%s/api/v1/repos/%s/%s/%s", c.server, url.PathEscape(c.owner), url.PathEscape(c.repo), path)
+}
+
+// assetURL returns the download URL of the asset of the release of the tag.
+func (c *giteaClient) assetURL(tag string) func(string) string {
+	return func(name string) string {
+		return fmt.Sprintf("%s/%s/%s/releases/download/%s/%s", c.server, c.owner, c.repo, tag, name)
+	}
+}
+
+// findRelease returns the release of the tag, nil if there is none. All
+// releases are listed because draft releases can't be found by tag.
+func (c *giteaClient) findRelease(ctx context.Context, tag string) (*giteaRelease, error) {
+	for page := 1; ; page++ {
+		var releases []*giteaRelease
+		if err := c.do(ctx, http.MethodGet, c.url(fmt.Sprintf("releases?limit=50&page=%d", page)), nil, "", &releases); err != nil {
+			return nil, fmt.Errorf("failed to list releases: %w", err)
+		}
+		if len(releases) == 0 {
+			return nil, nil
+		}
+		for _, r := range releases {
+			if r.TagName == tag {
+				return r, nil
+			}
+		}
+	}
+}
+
+// uploadAsset uploads the file to the release, retrying with an exponential
+// backoff.
+func (c *giteaClient) uploadAsset(ctx context.Context, release *giteaRelease, path string) error {
+	filename := filepath.Base(path)
+	u := c.url(fmt.Sprintf("releases/%d/assets?name=%s", release.ID, url.QueryEscape(filename)))
+	maxAttempts := *allowedRetries + 1
+	err := retry.Do(func(attempt int) (bool, error) {
+		again := attempt < maxAttempts
+
+		f, err := os.Open(path)
+		if err != nil {
+			return again, err
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			return again, err
+		}
+
+		// The file is streamed as the attachment field of a multipart form.
+		pr, pw := io.Pipe()
+		mw := multipart.NewWriter(pw)
+		go func() {
+			part, err := mw.CreateFormFile("attachment", filename)
+			if err == nil {
+				_, err = io.Copy(part, newUploadReader(ctx, f, filename, fi.Size()))
+			}
+			if err == nil {
+				err = mw.Close()
+			}
+			pw.CloseWithError(err)
+		}()
+		err = c.do(ctx, http.MethodPost, u, pr, mw.FormDataContentType(), nil)
+		pr.Close()
+		if err != nil && again {
+			select {
+			case <-ctx.Done():
+				return false, err
+			case <-time.After(retry.Backoff(attempt, 2*time.Second, time.Minute)):
+			}
+		}
+		return again, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload %q after %d attempts: %w", filename, maxAttempts, err)
+	}
+	fmt.Println(" > uploaded", filename)
+	return nil
+}
+
+// sameAsset returns whether the asset has the size and the SHA256 digest of
+// the file, the asset being downloaded only if the sizes match.
+func (c *giteaClient) sameAsset(ctx context.Context, asset giteaAsset, path string) (bool, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if asset.Size != fi.Size() {
+		return false, nil
+	}
+	sum, err := sha256File(os.DirFS(filepath.Dir(path)), filepath.Base(path))
+	if err != nil {
+		return false, err
+	}
+	rc, err := c.open(ctx, asset.BrowserDownloadURL)
+	if err != nil {
+		return false, err
+	}
+	defer rc.Close()
+	return hasSHA256(rc, sum)
+}
+
+// releaseToGitea uploads the files to the release of the project version,
+// creating it as a draft if it doesn't exist. Like for GitHub, existing
+// assets are only replaced in draft releases.
+func releaseToGitea(ctx context.Context, c *giteaClient, prerelease bool, files []string) error {
+	tag := fmt.Sprintf("v%s", projInfo.Version)
+	release, err := c.findRelease(ctx, tag)
+	if err != nil {
+		return err
+	}
+	if release == nil {
+		name, body, err := releaseNotes(c.owner+"/"+c.repo, tag, c.assetURL(tag), files)
+		if err != nil {
+			return err
+		}
+		release = &giteaRelease{
+			TagName:         tag,
+			TargetCommitish: projInfo.Revision,
+			Name:            name,
+			Body:            body,
+			Draft:           true,
+			Prerelease:      prerelease,
+		}
+		if err := c.doJSON(ctx, http.MethodPost, c.url("releases"), release, release); err != nil {
+			return fmt.Errorf("failed to create a draft release for %s: %w", projInfo.Version, err)
+		}
+	}
+	existing := make(map[string]giteaAsset, len(release.Assets))
+	for _, asset := range release.Assets {
+		existing[asset.Name] = asset
+	}
+
+	statusTracker.Add(files...)
+	workers := pool.New(ctx, *releaseParallelism, false)
+	for _, path := range files {
+		path := path
+		workers.Go(func(ctx context.Context) error {
+			statusTracker.Start(path)
+			err := releaseGiteaFile(ctx, c, release, path, existing)
+			statusTracker.Finish(path, err)
+			return err
+		})
+	}
+	if err := workers.Wait(); err != nil {
+		return fmt.Errorf("failed to upload all files: %w", err)
+	}
+	return nil
+}
+
+// releaseGiteaFile uploads the file to the release, keeping the existing
+// asset of the same name when resuming if it matches the file. The other
+// existing assets of published releases are handled according to the
+// existing asset policy.
+func releaseGiteaFile(ctx context.Context, c *giteaClient, release *giteaRelease, path string, existing map[string]giteaAsset) error {
+	filename := filepath.Base(path)
+	if asset, ok := existing[filename]; ok {
+		if *releaseResume {
+			same, err := c.sameAsset(ctx, asset, path)
+			if err != nil {
+				return fmt.Errorf("failed to verify existing asset %q: %w", filename, err)
+			}
+			if same {
+				fmt.Println(" > skipped", filename, "already uploaded")
+				return nil
+			}
+		}
+		if !release.Draft {
+			policy, err := existingAssetPolicy()
+			if err != nil {
+				return err
+			}
+			switch policy {
+			case existingSkip:
+				fmt.Println(" > skipped", filename, "already exists")
+				return nil
+			case existingFail:
+				return fmt.Errorf("%q already exists", filename)
+			}
+		}
+		if err := c.do(ctx, http.MethodDelete, c.url(fmt.Sprintf("releases/%d/assets/%d", release.ID, asset.ID)), nil, "", nil); err != nil {
+			return fmt.Errorf("failed to delete existing asset %q: %w", filename, err)
+		}
+	}
+	return c.uploadAsset(ctx, release, path)
+}
+
+// publishGiteaRelease publishes the draft release of the tag. Gitea has no
+// notion of latest release besides the most recent one.
+func publishGiteaRelease(ctx context.Context, c *giteaClient, tag string) error {
+	release, err := c.findRelease(ctx, tag)
+	if err != nil {
+		return err
+	}
+	if release == nil {
+		return fmt.Errorf("no release found for %s", tag)
+	}
+	update := struct {
+		Draft bool `json:"draft"`
+	}{}
+	if err := c.doJSON(ctx, http.MethodPatch, c.url(fmt.Sprintf("releases/%d", release.ID)), update, nil); err != nil {
+		return fmt.Errorf("failed to publish release %s: %w", tag, err)
+	}
+	fmt.Println(" > published release", release.Name)
+	return nil
+}
+
+// rollbackGiteaRelease deletes the draft release of the tag with its
+// assets, and the tag if requested.
+func rollbackGiteaRelease(ctx context.Context, c *giteaClient, tag string, withTag bool) error {
+	release, err := c.findRelease(ctx, tag)
+	if err != nil {
+		return err
+	}
+	if release == nil {
+		fmt.Println(" > no release found for", tag)
+	} else {
+		if !release.Draft {
+			return fmt.Errorf("release %s is already published, refusing to delete it", tag)
+		}
+		if err := c.do(ctx, http.MethodDelete, c.url(fmt.Sprintf("releases/%d", release.ID)), nil, "", nil); err != nil {
+			return fmt.Errorf("failed to delete release %q: %w", release.Name, err)
+		}
+		fmt.Println(" > deleted release", release.Name)
+	}
+	if !withTag {
+		return nil
+	}
+	// Draft releases don't create the tag so it may not exist.
+	err = c.do(ctx, http.MethodDelete, c.url("tags/"+url.PathEscape(tag)), nil, "", nil)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete tag %q: %w", tag, err)
+	}
+	fmt.Println(" > deleted tag", tag)
+	return nil
+}
@@ -0,0 +1,224 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/promu/pkg/repository"
+)
+
+// fakeGitea is a minimal Gitea API serving the releases of the owner/repo
+// repository.
+type fakeGitea struct {
+	mtx      sync.Mutex
+	srv      *httptest.Server
+	nextID   int64
+	releases []*giteaRelease
+	contents map[int64]string
+}
+
+func (f *fakeGitea) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if r.Header.Get("Authorization") != "token token" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if id, ok := strings.CutPrefix(r.URL.Path, "/attachments/"); ok {
+		var n int64
+		fmt.Sscan(id, &n)
+		io.WriteString(w, f.contents[n])
+		return
+	}
+	path, ok := strings.CutPrefix(r.URL.Path, "/api/v1/repos/owner/repo/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	var id, assetID int64
+	switch {
+	case r.Method == http.MethodGet && path == "releases":
+		releases := []*giteaRelease{}
+		if r.URL.Query().Get("page") == "1" {
+			releases = f.releases
+		}
+		json.NewEncoder(w).Encode(releases)
+	case r.Method == http.MethodPost && path == "releases":
+		var release giteaRelease
+		if err := json.NewDecoder(r.Body).Decode(&release); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.nextID++
+		release.ID = f.nextID
+		f.releases = append(f.releases, &release)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(release)
+	case r.Method == http.MethodPost && fmt.Sprintf("releases/%d/assets", f.release(path)) == path:
+		file, header, err := r.FormFile("attachment")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		b, err := io.ReadAll(file)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.nextID++
+		f.contents[f.nextID] = string(b)
+		release := f.byID(f.release(path))
+		release.Assets = append(release.Assets, giteaAsset{
+			ID:                 f.nextID,
+			Name:               header.Filename,
+			Size:               int64(len(b)),
+			BrowserDownloadURL: fmt.Sprintf("%s/attachments/%d", f.srv.URL, f.nextID),
+		})
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete && func() bool { _, err := fmt.Sscanf(path, "releases/%d/assets/%d", &id, &assetID); return err == nil }():
+		release := f.byID(id)
+		for i, asset := range release.Assets {
+			if asset.ID == assetID {
+				release.Assets = append(release.Assets[:i], release.Assets[i+1:]...)
+				break
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPatch && f.byID(f.release(path)) != nil:
+		if err := json.NewDecoder(r.Body).Decode(f.byID(f.release(path))); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "unexpected request", http.StatusNotImplemented)
+	}
+}
+
+// release returns the ID of the release of the path, 0 if none.
+func (f *fakeGitea) release(path string) int64 {
+	var id int64
+	fmt.Sscanf(path, "releases/%d", &id)
+	return id
+}
+
+func (f *fakeGitea) byID(id int64) *giteaRelease {
+	for _, r := range f.releases {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+func TestReleaseToGitea(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"CHANGELOG.md":                  "## 1.0.0 / 2026-01-01\n\n* [FEATURE] Foo.\n",
+		"foo-1.0.0.linux-amd64.tar.gz":  "linux",
+		"foo-1.0.0.darwin-arm64.tar.gz": "darwin",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	defer func(c *Config, info repository.Info, resume bool) {
+		config, projInfo, *releaseResume = c, info, resume
+	}(config, projInfo, *releaseResume)
+	config = NewConfig()
+	projInfo = repository.Info{Name: "foo", Version: "1.0.0", Revision: "abc"}
+
+	gt := &fakeGitea{contents: map[int64]string{}}
+	gt.srv = httptest.NewServer(gt)
+	defer gt.srv.Close()
+	c := &giteaClient{
+		restClient: restClient{client: gt.srv.Client(), header: "Authorization", value: "token token"},
+		server:     gt.srv.URL,
+		owner:      "owner",
+		repo:       "repo",
+	}
+	ctx := context.Background()
+
+	files := []string{
+		filepath.Join(dir, "foo-1.0.0.linux-amd64.tar.gz"),
+		filepath.Join(dir, "foo-1.0.0.darwin-arm64.tar.gz"),
+	}
+	if err := releaseToGitea(ctx, c, false, files); err != nil {
+		t.Fatal(err)
+	}
+	if len(gt.releases) != 1 {
+		t.Fatalf("expected 1 release, got %d", len(gt.releases))
+	}
+	release := gt.releases[0]
+	if release.Name != "1.0.0 / 2026-01-01" || release.TargetCommitish != "abc" || !release.Draft {
+		t.Fatalf("unexpected release %+v", release)
+	}
+
+	// Resuming keeps the matching assets and replaces the others.
+	ids := map[string]int64{}
+	for _, asset := range release.Assets {
+		ids[asset.Name] = asset.ID
+	}
+	if err := os.WriteFile(files[1], []byte("darwin2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	*releaseResume = true
+	if err := releaseToGitea(ctx, c, false, files); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, asset := range release.Assets {
+		got[asset.Name] = gt.contents[asset.ID]
+		if kept := asset.ID == ids[asset.Name]; kept != (asset.Name == "foo-1.0.0.linux-amd64.tar.gz") {
+			t.Fatalf("expected only the unchanged asset to be kept, got %s kept: %t", asset.Name, kept)
+		}
+	}
+	exp := map[string]string{
+		"foo-1.0.0.linux-amd64.tar.gz":  "linux",
+		"foo-1.0.0.darwin-arm64.tar.gz": "darwin2",
+	}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected assets %v, got %v", exp, got)
+	}
+
+	if err := publishGiteaRelease(ctx, c, "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if release.Draft {
+		t.Fatalf("expected the release to be published")
+	}
+	if err := rollbackGiteaRelease(ctx, c, "v1.0.0", false); err == nil {
+		t.Fatalf("expected error for a published release, got none")
+	}
+}
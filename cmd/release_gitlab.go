@@ -0,0 +1,277 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/prometheus/promu/pkg/repository"
+	"github.com/prometheus/promu/util/pool"
+	"github.com/prometheus/promu/util/retry"
+)
+
+const (
+	providerGitHub = repository.GitHub
+	providerGitLab = repository.GitLab
+	providerGitea  = repository.Gitea
+)
+
+// releaseProvider returns the hosting service of the releases, the
+// configured one or else the forge of the git remote, GitHub by default.
+func releaseProvider() (string, error) {
+	p := config.Release.Provider
+	if p == "" {
+		p = projInfo.Forge()
+	}
+	switch p {
+	case "", providerGitHub:
+		return providerGitHub, nil
+	case providerGitLab, providerGitea:
+		if len(config.Release.Targets) > 0 {
+			return "", errors.New("release.targets are only supported by the github provider")
+		}
+		if *releaseTargetRepo != "" {
+			return "", errors.New("--target-repo is only supported by the github provider")
+		}
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown release provider %q, expected %s, %s or %s", p, providerGitHub, providerGitLab, providerGitea)
+	}
+}
+
+// gitlabClient is a client of the REST API of a GitLab project.
+type gitlabClient struct {
+	restClient
+	// api is the URL of the v4 API, without trailing slash.
+	api string
+	// project is the ID or the path of the project.
+	project string
+	// path is the path of the project, namespace/name.
+	path string
+}
+
+// gitlabRelease is a GitLab release.
+type gitlabRelease struct {
+	Name        string `json:"name"`
+	TagName     string `json:"tag_name"`
+	Description string `json:"description,omitempty"`
+	Ref         string `json:"ref,omitempty"`
+	Assets      struct {
+		Links []gitlabLink `json:"links"`
+	} `json:"assets"`
+}
+
+// gitlabLink is a link to an asset of a GitLab release.
+type gitlabLink struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	LinkType string `json:"link_type,omitempty"`
+}
+
+// newGitLabClient returns a client of the project, authenticated with the
+// GITLAB_TOKEN personal, project or group access token or else with the
+// CI_JOB_TOKEN of the GitLab CI job. The API and the project are those of
+// the CI job if any, else derived from the git remote.
+func newGitLabClient() (*gitlabClient, error) {
+	c := &gitlabClient{
+		restClient: restClient{client: releaseHTTPClient()},
+		api:        strings.TrimSuffix(os.Getenv("CI_API_V4_URL"), "/"),
+		project:    os.Getenv("CI_PROJECT_ID"),
+		path:       os.Getenv("CI_PROJECT_PATH"),
+	}
+	if c.api == "" {
+		c.api = "https://" + projInfo.Host + "/api/v4"
+	}
+	if c.path == "" {
+		c.path = strings.TrimPrefix(projInfo.Repo, projInfo.Host+"/")
+	}
+	if c.project == "" {
+		c.project = c.path
+	}
+
+	switch {
+	case os.Getenv("GITLAB_TOKEN") != "":
+		c.header, c.value = "PRIVATE-TOKEN", os.Getenv("GITLAB_TOKEN")
+	case os.Getenv("CI_JOB_TOKEN") != "":
+		c.header, c.value = "JOB-TOKEN", os.Getenv("CI_JOB_TOKEN")
+	default:
+		return nil, errors.New("GITLAB_TOKEN or CI_JOB_TOKEN not defined")
+	}
+	return c, nil
+}
+
+// url returns the URL of the path of the project API.
+func (c *gitlabClient) url(path string) string {
+	return fmt.Sprintf("%s/projects/%s/%s", c.api, url.PathEscape(c.project), path)
+}
+
+// packagePath returns the path of the file of the generic package of the
+// project version.
+func (c *gitlabClient) packagePath(name string) string {
+	return fmt.Sprintf("packages/generic/%s/%s/%s", url.PathEscape(projInfo.Name), url.PathEscape(projInfo.Version), url.PathEscape(name))
+}
+
+// release returns the release of the tag, nil if there is none.
+func (c *gitlabClient) release(ctx context.Context, tag string) (*gitlabRelease, error) {
+	var r gitlabRelease
+	err := c.do(ctx, http.MethodGet, c.url("releases/"+url.PathEscape(tag)), nil, "", &r)
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release %s: %w", tag, err)
+	}
+	return &r, nil
+}
+
+// uploadPackageFile uploads the file to the generic package of the project
+// version, retrying with an exponential backoff, and returns its download
+// URL.
+func (c *gitlabClient) uploadPackageFile(ctx context.Context, path string) (string, error) {
+	filename := filepath.Base(path)
+	maxAttempts := *allowedRetries + 1
+	err := retry.Do(func(attempt int) (bool, error) {
+		again := attempt < maxAttempts
+
+		f, err := os.Open(path)
+		if err != nil {
+			return again, err
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			return again, err
+		}
+
+		err = c.do(ctx, http.MethodPut, c.url(c.packagePath(filename)), newUploadReader(ctx, f, filename, fi.Size()), "application/octet-stream", nil)
+		if err != nil && again {
+			select {
+			case <-ctx.Done():
+				return false, err
+			case <-time.After(retry.Backoff(attempt, 2*time.Second, time.Minute)):
+			}
+		}
+		return again, err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %q after %d attempts: %w", filename, maxAttempts, err)
+	}
+	fmt.Println(" > uploaded", filename)
+	return c.url(c.packagePath(filename)), nil
+}
+
+// releaseToGitLab uploads the files to the generic package registry of the
+// project and links them to the release of the project version. GitLab
+// releases have no drafts, so the release is only created once all the files
+// are uploaded, from the current revision if the tag doesn't exist.
+func releaseToGitLab(ctx context.Context, c *gitlabClient, files []string) error {
+	tag := fmt.Sprintf("v%s", projInfo.Version)
+	release, err := c.release(ctx, tag)
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	if release != nil {
+		for _, link := range release.Assets.Links {
+			existing[link.Name] = true
+		}
+	}
+
+	links := make([]gitlabLink, len(files))
+	statusTracker.Add(files...)
+	workers := pool.New(ctx, *releaseParallelism, false)
+	for i, path := range files {
+		i, path := i, path
+		workers.Go(func(ctx context.Context) error {
+			statusTracker.Start(path)
+			link, err := releaseGitLabFile(ctx, c, path, existing)
+			statusTracker.Finish(path, err)
+			links[i] = link
+			return err
+		})
+	}
+	if err := workers.Wait(); err != nil {
+		return fmt.Errorf("failed to upload all files: %w", err)
+	}
+
+	if release == nil {
+		name, body, err := releaseNotes(c.path, tag, func(name string) string { return c.url(c.packagePath(name)) }, files)
+		if err != nil {
+			return err
+		}
+		r := gitlabRelease{Name: name, TagName: tag, Description: body, Ref: projInfo.Revision}
+		for _, link := range links {
+			if link.URL != "" {
+				r.Assets.Links = append(r.Assets.Links, link)
+			}
+		}
+		if err := c.doJSON(ctx, http.MethodPost, c.url("releases"), r, nil); err != nil {
+			return fmt.Errorf("failed to create release %s: %w", tag, err)
+		}
+		fmt.Println(" > created release", name)
+		return nil
+	}
+
+	for _, link := range links {
+		if link.URL == "" {
+			continue
+		}
+		if err := c.doJSON(ctx, http.MethodPost, c.url("releases/"+url.PathEscape(tag)+"/assets/links"), link, nil); err != nil {
+			return fmt.Errorf("failed to link %q to release %s: %w", link.Name, tag, err)
+		}
+	}
+	return nil
+}
+
+// releaseGitLabFile uploads the file and returns its release link, which is
+// empty if the release already links to it and --resume or --skip-existing
+// is set. The link is
+// named after the configured label of the asset if any.
+func releaseGitLabFile(ctx context.Context, c *gitlabClient, path string, existing map[string]bool) (gitlabLink, error) {
+	filename := filepath.Base(path)
+	opts, err := uploadOptions(filename)
+	if err != nil {
+		return gitlabLink{}, err
+	}
+	link := gitlabLink{Name: filename, LinkType: "package"}
+	if opts.Label != "" {
+		link.Name = opts.Label
+	}
+	if isChecksumsFile(path) {
+		link.LinkType = "other"
+	}
+	if existing[link.Name] {
+		policy, err := existingAssetPolicy()
+		if err != nil {
+			return gitlabLink{}, err
+		}
+		if *releaseResume || policy == existingSkip {
+			fmt.Println(" > skipped", filename, "already released")
+			return gitlabLink{}, nil
+		}
+		return gitlabLink{}, fmt.Errorf("%q already exists", link.Name)
+	}
+
+	link.URL, err = c.uploadPackageFile(ctx, path)
+	return link, err
+}
@@ -0,0 +1,167 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/promu/pkg/repository"
+)
+
+// fakeGitLab is a minimal GitLab API serving the generic packages and the
+// releases of the group/project project.
+type fakeGitLab struct {
+	mtx      sync.Mutex
+	packages map[string]string
+	releases map[string]*gitlabRelease
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if r.Header.Get("PRIVATE-TOKEN") != "token" {
+		http.Error(w, `{"message":"401 Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	path, ok := strings.CutPrefix(r.URL.EscapedPath(), "/api/v4/projects/group%2Fproject/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	switch {
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "packages/generic/foo/1.0.0/"):
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.packages[strings.TrimPrefix(path, "packages/generic/foo/1.0.0/")] = string(b)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "releases/"):
+		release, ok := f.releases[strings.TrimPrefix(path, "releases/")]
+		if !ok {
+			http.Error(w, `{"message":"404 Not Found"}`, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(release)
+	case r.Method == http.MethodPost && path == "releases":
+		var release gitlabRelease
+		if err := json.NewDecoder(r.Body).Decode(&release); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.releases[release.TagName] = &release
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/assets/links"):
+		release, ok := f.releases[strings.TrimSuffix(strings.TrimPrefix(path, "releases/"), "/assets/links")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		var link gitlabLink
+		if err := json.NewDecoder(r.Body).Decode(&link); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		release.Assets.Links = append(release.Assets.Links, link)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		http.Error(w, "unexpected request", http.StatusNotImplemented)
+	}
+}
+
+func TestReleaseToGitLab(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"CHANGELOG.md":                   "## 1.0.0 / 2026-01-01\n\n* [FEATURE] Foo.\n",
+		"foo-1.0.0.linux-amd64.tar.gz":   "linux",
+		"foo-1.0.0.windows-amd64.tar.gz": "windows",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	defer func(c *Config, info repository.Info, resume bool) {
+		config, projInfo, *releaseResume = c, info, resume
+	}(config, projInfo, *releaseResume)
+	config = NewConfig()
+	config.Release.Assets = []ReleaseAsset{{Name: "*.tar.gz", Label: "{{.OS}} {{.Arch}}"}}
+	projInfo = repository.Info{Name: "foo", Version: "1.0.0", Revision: "abc"}
+
+	gl := &fakeGitLab{packages: map[string]string{}, releases: map[string]*gitlabRelease{}}
+	srv := httptest.NewServer(gl)
+	defer srv.Close()
+	c := &gitlabClient{
+		restClient: restClient{client: srv.Client(), header: "PRIVATE-TOKEN", value: "token"},
+		api:        srv.URL + "/api/v4",
+		project:    "group/project",
+		path:       "group/project",
+	}
+
+	files := []string{filepath.Join(dir, "foo-1.0.0.linux-amd64.tar.gz")}
+	if err := releaseToGitLab(context.Background(), c, files); err != nil {
+		t.Fatal(err)
+	}
+	release := gl.releases["v1.0.0"]
+	if release == nil {
+		t.Fatalf("expected release v1.0.0 to be created")
+	}
+	if release.Name != "1.0.0 / 2026-01-01" || release.Ref != "abc" || release.Description != "* [FEATURE] Foo." {
+		t.Fatalf("unexpected release %+v", release)
+	}
+
+	// Uploading the same file again fails unless resuming.
+	files = append(files, filepath.Join(dir, "foo-1.0.0.windows-amd64.tar.gz"))
+	if err := releaseToGitLab(context.Background(), c, files); err == nil {
+		t.Fatalf("expected error for an existing link, got none")
+	}
+	*releaseResume = true
+	if err := releaseToGitLab(context.Background(), c, files); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []gitlabLink{
+		{Name: "linux amd64", URL: c.url("packages/generic/foo/1.0.0/foo-1.0.0.linux-amd64.tar.gz"), LinkType: "package"},
+		{Name: "windows amd64", URL: c.url("packages/generic/foo/1.0.0/foo-1.0.0.windows-amd64.tar.gz"), LinkType: "package"},
+	}
+	if !reflect.DeepEqual(exp, release.Assets.Links) {
+		t.Fatalf("expected links %v, got %v", exp, release.Assets.Links)
+	}
+	expPackages := map[string]string{
+		"foo-1.0.0.linux-amd64.tar.gz":   "linux",
+		"foo-1.0.0.windows-amd64.tar.gz": "windows",
+	}
+	if !reflect.DeepEqual(expPackages, gl.packages) {
+		t.Fatalf("expected packages %v, got %v", expPackages, gl.packages)
+	}
+}
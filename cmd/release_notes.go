@@ -0,0 +1,174 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/prometheus/promu/pkg/changelog"
+	"github.com/prometheus/promu/util/sh"
+)
+
+// releaseNotesData is the data of the release notes template.
+type releaseNotesData struct {
+	Version string
+	Tag     string
+	// Repository is the path of the repository, owner/name on GitHub.
+	Repository string
+	// Changelog is the changelog entry of the version, whose Text is the
+	// default body of the release. Its Linkify method links the pull
+	// requests and the authors of the changes on GitHub.
+	Changelog *changelog.Entry
+	// Assets are the uploaded files, sorted by platform and name.
+	Assets []releaseNotesAsset
+	Images []releaseNotesImage
+}
+
+// releaseNotesAsset is a release asset in the release notes template.
+type releaseNotesAsset struct {
+	Name string
+	// URL is the download URL of the asset.
+	URL string
+	// OS and Arch are the platform of the asset, empty if it has none.
+	OS, Arch string
+	Size     int64
+	SHA256   string
+}
+
+// releaseNotesImage is a container image in the release notes template.
+type releaseNotesImage struct {
+	// Name is the reference of the image, with the tag of the release.
+	Name   string
+	Digest string
+}
+
+// releaseNotes returns the name and the body of the release of the project
+// version: the changelog entry of the version, or the rendered release notes
+// template if configured. The assets are downloaded from assetURL(name).
+func releaseNotes(repository, tag string, assetURL func(string) string, files []string) (string, string, error) {
+	format, err := changelogFormat()
+	if err != nil {
+		return "", "", err
+	}
+	f, err := os.Open("CHANGELOG.md")
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	entry, err := format.ReadEntry(f, projInfo.Version)
+	if err != nil {
+		return "", "", err
+	}
+	body := entry.Text
+	if config.Release.NotesTemplate != "" {
+		body, err = renderReleaseNotes(config.Release.NotesTemplate, entry, repository, tag, assetURL, files)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to render the release notes: %w", err)
+		}
+	}
+	return entry.Name(), body, nil
+}
+
+// githubAssetURL returns the function giving the download URLs of the
+// assets of the GitHub release.
+func githubAssetURL(owner, repo, tag string) func(string) string {
+	return func(name string) string {
+		return fmt.Sprintf("https://github.com/%s/%s/releases/download/%s/%s", owner, repo, tag, name)
+	}
+}
+
+// renderReleaseNotes renders the release notes template with the changelog
+// entry, the files uploaded to the release and the container images.
+func renderReleaseNotes(file string, entry *changelog.Entry, repository, tag string, assetURL func(string) string, files []string) (string, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := template.New(filepath.Base(file)).Option("missingkey=error").Parse(string(b))
+	if err != nil {
+		return "", err
+	}
+
+	data := releaseNotesData{
+		Version:    projInfo.Version,
+		Tag:        tag,
+		Repository: repository,
+		Changelog:  entry,
+	}
+	for _, path := range files {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		sum, err := sha256File(os.DirFS(filepath.Dir(path)), filepath.Base(path))
+		if err != nil {
+			return "", err
+		}
+		name := filepath.Base(path)
+		asset := releaseNotesAsset{
+			Name:   name,
+			URL:    assetURL(name),
+			Size:   fi.Size(),
+			SHA256: hex.EncodeToString(sum),
+		}
+		if platform, ok := artifactPlatform(name); ok {
+			asset.OS, asset.Arch, _ = strings.Cut(platform, "/")
+		}
+		data.Assets = append(data.Assets, asset)
+	}
+	sort.Slice(data.Assets, func(i, j int) bool {
+		a, b := data.Assets[i], data.Assets[j]
+		if a.OS != b.OS {
+			return a.OS < b.OS
+		}
+		if a.Arch != b.Arch {
+			return a.Arch < b.Arch
+		}
+		return a.Name < b.Name
+	})
+	for _, image := range config.Release.Images {
+		name := image + ":" + tag
+		digest, err := imageDigest(name)
+		if err != nil {
+			return "", fmt.Errorf("failed to get the digest of %s: %w", name, err)
+		}
+		data.Images = append(data.Images, releaseNotesImage{Name: name, Digest: digest})
+	}
+
+	var notes bytes.Buffer
+	if err := tmpl.Execute(&notes, data); err != nil {
+		return "", err
+	}
+	return notes.String(), nil
+}
+
+// imageDigest returns the digest of the pushed container image, of its
+// index for multi-platform images.
+func imageDigest(name string) (string, error) {
+	var out bytes.Buffer
+	err := sh.RunCommandWithOutput(&out, os.Stderr, "docker", "buildx", "imagetools", "inspect", "--format", "{{.Manifest.Digest}}", name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
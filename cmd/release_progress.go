@@ -0,0 +1,123 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// uploadChunkSize is the maximum number of bytes read at once from a file
+// when the bandwidth is limited, so that the uploads are evenly paced.
+const uploadChunkSize = 32 * 1024
+
+// uploadLimiter caps the bandwidth of all the uploads, nil if unlimited.
+var uploadLimiter *bandwidthLimiter
+
+// bandwidthLimiter paces the reads of concurrent uploads to a number of
+// bytes per second.
+type bandwidthLimiter struct {
+	mtx  sync.Mutex
+	rate int64
+	// next is when the bytes reserved so far are sent.
+	next time.Time
+}
+
+// newBandwidthLimiter returns a limiter of the given number of bytes per
+// second, nil if rate isn't positive.
+func newBandwidthLimiter(rate int64) *bandwidthLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &bandwidthLimiter{rate: rate}
+}
+
+// wait reserves n bytes and waits until they can be sent.
+func (l *bandwidthLimiter) wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	l.mtx.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	at := l.next
+	l.next = l.next.Add(time.Duration(n) * time.Second / time.Duration(l.rate))
+	l.mtx.Unlock()
+
+	if d := at.Sub(now); d > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d):
+		}
+	}
+	return nil
+}
+
+// uploadReader reads the file of an upload, limiting the bandwidth and
+// printing the progress of the upload every --progress-interval.
+type uploadReader struct {
+	ctx        context.Context
+	r          io.Reader
+	name       string
+	size, read int64
+	last       time.Time
+}
+
+// newUploadReader returns the reader of the upload of the file of the given
+// name and size.
+func newUploadReader(ctx context.Context, r io.Reader, name string, size int64) *uploadReader {
+	return &uploadReader{ctx: ctx, r: r, name: name, size: size, last: time.Now()}
+}
+
+func (u *uploadReader) Read(p []byte) (int, error) {
+	if uploadLimiter != nil {
+		if len(p) > uploadChunkSize {
+			p = p[:uploadChunkSize]
+		}
+		if err := uploadLimiter.wait(u.ctx, len(p)); err != nil {
+			return 0, err
+		}
+	}
+	n, err := u.r.Read(p)
+	u.read += int64(n)
+	if interval := *releaseProgressInterval; interval > 0 && time.Since(u.last) >= interval && u.read < u.size {
+		u.last = time.Now()
+		fmt.Printf(" > uploading %s: %s / %s (%d%%)\n", u.name, formatBytes(u.read), formatBytes(u.size), u.read*100/u.size)
+	}
+	return n, err
+}
+
+// formatBytes formats the number of bytes with a binary unit.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 4; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTP"[exp])
+}
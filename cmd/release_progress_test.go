@@ -0,0 +1,67 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestFormatBytes(t *testing.T) {
+	for _, tc := range []struct {
+		n   int64
+		exp string
+	}{
+		{n: 0, exp: "0 B"},
+		{n: 1023, exp: "1023 B"},
+		{n: 1024, exp: "1.0 KiB"},
+		{n: 1536, exp: "1.5 KiB"},
+		{n: 300 * 1024 * 1024, exp: "300.0 MiB"},
+		{n: 5 * 1024 * 1024 * 1024, exp: "5.0 GiB"},
+	} {
+		if got := formatBytes(tc.n); got != tc.exp {
+			t.Fatalf("expected %q for %d, got %q", tc.exp, tc.n, got)
+		}
+	}
+}
+
+func TestUploadReaderBandwidthLimit(t *testing.T) {
+	defer func(l *bandwidthLimiter, interval time.Duration) {
+		uploadLimiter, *releaseProgressInterval = l, interval
+	}(uploadLimiter, *releaseProgressInterval)
+	uploadLimiter, *releaseProgressInterval = newBandwidthLimiter(1024*1024), 0
+
+	// The first chunk is sent right away, the others are paced.
+	content := bytes.Repeat([]byte("x"), 512*1024)
+	start := time.Now()
+	b, err := io.ReadAll(newUploadReader(context.Background(), bytes.NewReader(content), "foo", int64(len(content))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, b) {
+		t.Fatalf("expected %d bytes, got %d", len(content), len(b))
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("expected the upload to take at least 400ms, got %s", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := io.ReadAll(newUploadReader(ctx, bytes.NewReader(content), "foo", int64(len(content)))); err == nil {
+		t.Fatalf("expected error for a canceled upload, got none")
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/google/go-github/v25/github"
+)
+
+var (
+	releasepublishcmd  = releasecmd.Command("publish", "Publish the draft Github release, marked as the latest release unless a greater version is already released")
+	releasePublishLock = releasepublishcmd.Flag("lock", "Protect the tag of the release from being updated or deleted with a repository ruleset").
+				Bool()
+	releasePublishVersion = releasepublishcmd.Arg("version", "Version of the release, the version of the project by default").
+				String()
+)
+
+// releaseUpdate holds the fields of a release edited when publishing it,
+// make_latest being unknown to the GitHub client.
+type releaseUpdate struct {
+	Draft      bool   `json:"draft"`
+	MakeLatest string `json:"make_latest"`
+}
+
+// tagRuleset is a repository ruleset applying to tags.
+type tagRuleset struct {
+	Name        string `json:"name"`
+	Target      string `json:"target"`
+	Enforcement string `json:"enforcement"`
+	Conditions  struct {
+		RefName struct {
+			Include []string `json:"include"`
+			Exclude []string `json:"exclude"`
+		} `json:"ref_name"`
+	} `json:"conditions"`
+	Rules []tagRule `json:"rules"`
+}
+
+type tagRule struct {
+	Type string `json:"type"`
+}
+
+func runReleasePublish(version string) {
+	ctx, cancel := releaseContext()
+	defer cancel()
+
+	if version == "" {
+		version = projInfo.Version
+	}
+	semVer, err := semver.NewVersion(strings.TrimPrefix(version, "v"))
+	if err != nil {
+		fatal(fmt.Errorf("invalid semver version: %w", err))
+	}
+	provider, err := releaseProvider()
+	if err != nil {
+		fatal(err)
+	}
+	switch provider {
+	case providerGitea:
+		if *releasePublishLock {
+			fatal(errors.New("--lock is only supported by the github provider"))
+		}
+		c, err := newGiteaClient()
+		if err != nil {
+			fatal(err)
+		}
+		if err := publishGiteaRelease(ctx, c, "v"+semVer.Original()); err != nil {
+			fatal(err)
+		}
+		return
+	case providerGitLab:
+		// GitLab releases have no drafts.
+		fatal(fmt.Errorf("release publish isn't supported by the %s provider", provider))
+	}
+	targets, err := releaseTargets(ctx)
+	if err != nil {
+		fatal(err)
+	}
+	var errs []error
+	for _, t := range targets {
+		if len(targets) > 1 {
+			fmt.Println(">> publishing to", t)
+		}
+		if err := publishRelease(ctx, t, semVer); err != nil {
+			if len(targets) > 1 {
+				err = fmt.Errorf("%s: %w", t, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		fatal(err)
+	}
+}
+
+// publishRelease publishes the draft release of the version in the target
+// repository, and locks its tag if requested.
+func publishRelease(ctx context.Context, t releaseTarget, semVer *semver.Version) error {
+	tag := "v" + semVer.Original()
+	release, err := findRelease(ctx, t.client, t.owner, t.repo, tag)
+	if err != nil {
+		return err
+	}
+	if release == nil {
+		return fmt.Errorf("no release found for %s", tag)
+	}
+
+	latest, err := isLatestRelease(ctx, t.client, t.owner, t.repo, release, semVer)
+	if err != nil {
+		return err
+	}
+	update := releaseUpdate{MakeLatest: fmt.Sprint(latest)}
+	req, err := t.client.NewRequest(http.MethodPatch, fmt.Sprintf("repos/%s/%s/releases/%d", t.owner, t.repo, release.GetID()), update)
+	if err != nil {
+		return err
+	}
+	if _, err := t.client.Do(ctx, req, nil); err != nil {
+		return fmt.Errorf("failed to publish release %s: %w", tag, err)
+	}
+	if latest {
+		fmt.Println(" > published release", release.GetName(), "as the latest release")
+	} else {
+		fmt.Println(" > published release", release.GetName())
+	}
+
+	if *releasePublishLock {
+		if err := lockTag(ctx, t.client, t.owner, t.repo, tag); err != nil {
+			return fmt.Errorf("failed to lock tag %s: %w", tag, err)
+		}
+		fmt.Println(" > locked tag", tag)
+	}
+	return nil
+}
+
+// isLatestRelease returns whether the release of the version should be
+// marked as the latest release, which isn't the case of pre-releases and of
+// patch releases of older minor versions.
+func isLatestRelease(ctx context.Context, client *github.Client, owner, repo string, release *github.RepositoryRelease, version *semver.Version) (bool, error) {
+	if version.Prerelease() != "" || release.GetPrerelease() {
+		return false, nil
+	}
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		releases, resp, err := client.Repositories.ListReleases(ctx, owner, repo, opts)
+		if err != nil {
+			return false, fmt.Errorf("failed to list releases: %w", err)
+		}
+		for _, r := range releases {
+			if r.GetID() == release.GetID() || r.GetDraft() || r.GetPrerelease() {
+				continue
+			}
+			v, err := semver.NewVersion(strings.TrimPrefix(r.GetTagName(), "v"))
+			if err != nil {
+				continue
+			}
+			if v.GreaterThan(version) {
+				return false, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return true, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// lockTag creates a ruleset preventing the tag from being updated or deleted.
+func lockTag(ctx context.Context, client *github.Client, owner, repo, tag string) error {
+	ruleset := tagRuleset{
+		Name:        "Release " + tag,
+		Target:      "tag",
+		Enforcement: "active",
+		Rules:       []tagRule{{"update"}, {"deletion"}},
+	}
+	ruleset.Conditions.RefName.Include = []string{"refs/tags/" + tag}
+	ruleset.Conditions.RefName.Exclude = []string{}
+	req, err := client.NewRequest(http.MethodPost, fmt.Sprintf("repos/%s/%s/rulesets", owner, repo), ruleset)
+	if err != nil {
+		return err
+	}
+	_, err = client.Do(ctx, req, nil)
+	return err
+}
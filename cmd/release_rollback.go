@@ -0,0 +1,99 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	releaserollbackcmd = releasecmd.Command("rollback", "Delete the draft Github release and its assets, e.g. after a failed release pipeline, or any release of the --target-repo repository")
+	releaseRollbackTag = releaserollbackcmd.Flag("delete-tag", "Delete the tag of the release too").
+				Bool()
+	releaseRollbackVersion = releaserollbackcmd.Arg("version", "Version of the release, the version of the project by default").
+				String()
+)
+
+func runReleaseRollback(version string) {
+	ctx, cancel := releaseContext()
+	defer cancel()
+
+	if version == "" {
+		version = projInfo.Version
+	}
+	tag := "v" + strings.TrimPrefix(version, "v")
+	provider, err := releaseProvider()
+	if err != nil {
+		fatal(err)
+	}
+	switch provider {
+	case providerGitea:
+		c, err := newGiteaClient()
+		if err != nil {
+			fatal(err)
+		}
+		if err := rollbackGiteaRelease(ctx, c, tag, *releaseRollbackTag); err != nil {
+			fatal(err)
+		}
+		return
+	case providerGitLab:
+		// GitLab releases have no drafts.
+		fatal(fmt.Errorf("release rollback isn't supported by the %s provider", provider))
+	}
+	targets, err := releaseTargets(ctx)
+	if err != nil {
+		fatal(err)
+	}
+	var errs []error
+	for _, t := range targets {
+		if len(targets) > 1 {
+			fmt.Println(">> rolling back", t)
+		}
+		if err := rollbackRelease(ctx, t, tag, *releaseRollbackTag); err != nil {
+			if len(targets) > 1 {
+				err = fmt.Errorf("%s: %w", t, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		fatal(err)
+	}
+}
+
+// rollbackRelease deletes the draft release of the tag in the target
+// repository, and the tag if requested. Published releases are left
+// untouched since users may already depend on them, unless they are
+// rehearsals in the repository of --target-repo.
+func rollbackRelease(ctx context.Context, t releaseTarget, tag string, withTag bool) error {
+	release, err := findRelease(ctx, t.client, t.owner, t.repo, tag)
+	if err != nil {
+		return err
+	}
+	if release == nil {
+		fmt.Println(" > no release found for", tag)
+		if withTag {
+			return deleteTag(ctx, t.client, t.owner, t.repo, tag)
+		}
+		return nil
+	}
+	if !release.GetDraft() && !t.scratch {
+		return fmt.Errorf("release %s is already published, refusing to delete it", tag)
+	}
+	return deleteRelease(ctx, t.client, t.owner, t.repo, release, withTag)
+}
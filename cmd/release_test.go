@@ -0,0 +1,861 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/google/go-github/v25/github"
+
+	"github.com/prometheus/promu/pkg/changelog"
+	"github.com/prometheus/promu/pkg/repository"
+)
+
+// fakeGitHub is a minimal GitHub API serving the assets of the releases and
+// the pull requests of the owner/repo repository.
+type fakeGitHub struct {
+	mtx    sync.Mutex
+	nextID int64
+	// assets holds the content of the assets by ID.
+	assets   map[int64]fakeAsset
+	releases []*github.RepositoryRelease
+	// tags are the existing tags.
+	tags map[string]bool
+	// pulls are the pull requests by number, and reviewers the reviews
+	// requested on them.
+	pulls     map[int]*github.PullRequest
+	reviewers map[int]github.ReviewersRequest
+	// corrupt is the number of next uploads whose content is truncated.
+	corrupt int
+	// status is the status of the uploads if not zero, and uploads the
+	// number of uploads.
+	status, uploads int
+	// url is the base URL of the server.
+	url string
+}
+
+type fakeAsset struct {
+	name, label, contentType string
+	content                  string
+}
+
+func newFakeGitHub(t *testing.T) (*fakeGitHub, *github.Client) {
+	f := &fakeGitHub{nextID: 1, assets: map[int64]fakeAsset{}, tags: map[string]bool{}, pulls: map[int]*github.PullRequest{}, reviewers: map[int]github.ReviewersRequest{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	client := github.NewClient(nil)
+	u, err := url.Parse(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.BaseURL, client.UploadURL = u, u
+	f.url = u.String()
+	return f, client
+}
+
+// addAsset adds an asset and returns its ID.
+func (f *fakeGitHub) addAsset(name, content string) int64 {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	id := f.nextID
+	f.nextID++
+	f.assets[id] = fakeAsset{name: name, content: content}
+	return id
+}
+
+// contents returns the content of the assets by name.
+func (f *fakeGitHub) contents() map[string]string {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	contents := map[string]string{}
+	for _, a := range f.assets {
+		contents[a.name] = a.content
+	}
+	return contents
+}
+
+func (f *fakeGitHub) asset(id int64) *github.ReleaseAsset {
+	a := f.assets[id]
+	size := len(a.content)
+	url := fmt.Sprintf("%srepos/owner/repo/releases/assets/%d", f.url, id)
+	return &github.ReleaseAsset{ID: &id, URL: &url, Name: &a.name, Label: &a.label, ContentType: &a.contentType, Size: &size, State: github.String("uploaded")}
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	switch path := strings.TrimPrefix(r.URL.Path, "/"); {
+	case r.Method == http.MethodGet && path == "repos/owner/repo/releases":
+		json.NewEncoder(w).Encode(f.releases)
+	case r.Method == http.MethodPost && path == "repos/owner/repo/releases":
+		// The assets are served for the release 1 only.
+		var release github.RepositoryRelease
+		if err := json.NewDecoder(r.Body).Decode(&release); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		release.ID = github.Int64(1)
+		f.releases = append(f.releases, &release)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(&release)
+	case r.Method == http.MethodGet && path == "repos/owner/repo/releases/1/assets":
+		ids := make([]int64, 0, len(f.assets))
+		for id := range f.assets {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		assets := make([]*github.ReleaseAsset, 0, len(ids))
+		for _, id := range ids {
+			assets = append(assets, f.asset(id))
+		}
+		json.NewEncoder(w).Encode(assets)
+	case r.Method == http.MethodPost && path == "repos/owner/repo/releases/1/assets":
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.uploads++
+		if f.status != 0 {
+			http.Error(w, http.StatusText(f.status), f.status)
+			return
+		}
+		if f.corrupt > 0 && len(b) > 0 {
+			b = b[:len(b)-1]
+			f.corrupt--
+		}
+		id := f.nextID
+		f.nextID++
+		f.assets[id] = fakeAsset{
+			name:        r.URL.Query().Get("name"),
+			label:       r.URL.Query().Get("label"),
+			contentType: r.Header.Get("Content-Type"),
+			content:     string(b),
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(f.asset(id))
+	case strings.HasPrefix(path, "repos/owner/repo/releases/assets/"):
+		id, err := strconv.ParseInt(strings.TrimPrefix(path, "repos/owner/repo/releases/assets/"), 10, 64)
+		if _, ok := f.assets[id]; err != nil || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			io.WriteString(w, f.assets[id].content)
+		case http.MethodDelete:
+			delete(f.assets, id)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	case r.Method == http.MethodPatch && strings.HasPrefix(path, "repos/owner/repo/releases/"):
+		id, err := strconv.ParseInt(strings.TrimPrefix(path, "repos/owner/repo/releases/"), 10, 64)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		var edit github.RepositoryRelease
+		if err := json.NewDecoder(r.Body).Decode(&edit); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, release := range f.releases {
+			if release.GetID() == id {
+				if edit.Body != nil {
+					release.Body = edit.Body
+				}
+				// Publishing a release creates its tag.
+				if edit.Draft != nil && !*edit.Draft {
+					release.Draft = edit.Draft
+					f.tags[release.GetTagName()] = true
+				}
+				json.NewEncoder(w).Encode(release)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "repos/owner/repo/releases/"):
+		id, err := strconv.ParseInt(strings.TrimPrefix(path, "repos/owner/repo/releases/"), 10, 64)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		for i, release := range f.releases {
+			if release.GetID() == id {
+				f.releases = append(f.releases[:i], f.releases[i+1:]...)
+				f.assets = map[int64]fakeAsset{}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	case r.Method == http.MethodPost && path == "repos/owner/repo/pulls":
+		var pull github.NewPullRequest
+		if err := json.NewDecoder(r.Body).Decode(&pull); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		number := len(f.pulls) + 1
+		f.pulls[number] = &github.PullRequest{
+			Number:  github.Int(number),
+			Title:   pull.Title,
+			Body:    pull.Body,
+			Head:    &github.PullRequestBranch{Ref: pull.Head},
+			Base:    &github.PullRequestBranch{Ref: pull.Base},
+			HTMLURL: github.String(fmt.Sprintf("https://github.com/owner/repo/pull/%d", number)),
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(f.pulls[number])
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "repos/owner/repo/pulls/") && strings.HasSuffix(path, "/requested_reviewers"):
+		number, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(path, "repos/owner/repo/pulls/"), "/requested_reviewers"))
+		if _, ok := f.pulls[number]; err != nil || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		var reviewers github.ReviewersRequest
+		if err := json.NewDecoder(r.Body).Decode(&reviewers); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.reviewers[number] = reviewers
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(f.pulls[number])
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "repos/owner/repo/pulls/"):
+		number, err := strconv.Atoi(strings.TrimPrefix(path, "repos/owner/repo/pulls/"))
+		if _, ok := f.pulls[number]; err != nil || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(f.pulls[number])
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "repos/owner/repo/git/refs/tags/"):
+		tag := strings.TrimPrefix(path, "repos/owner/repo/git/refs/tags/")
+		if !f.tags[tag] {
+			http.Error(w, "Reference does not exist", http.StatusUnprocessableEntity)
+			return
+		}
+		delete(f.tags, tag)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, fmt.Sprintf("unexpected request %s %s", r.Method, r.URL), http.StatusNotImplemented)
+	}
+}
+
+func TestReleaseFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "foo.tar.gz")
+	if err := os.WriteFile(file, []byte("foo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(c *Config, resume, skipVerify, overwrite, skipExisting bool, retries int) {
+		config, *releaseResume, *releaseSkipVerify, *releaseOverwrite, *releaseSkipExisting, *allowedRetries = c, resume, skipVerify, overwrite, skipExisting, retries
+	}(config, *releaseResume, *releaseSkipVerify, *releaseOverwrite, *releaseSkipExisting, *allowedRetries)
+	config, *allowedRetries = NewConfig(), 0
+
+	for _, tc := range []struct {
+		name         string
+		existing     string
+		draft        bool
+		resume       bool
+		overwrite    bool
+		skipExisting bool
+		corrupt      bool
+		skipVerify   bool
+		err          bool
+		exp          string
+	}{
+		{name: "new asset", exp: "foo"},
+		{name: "draft release", existing: "bar", draft: true, exp: "foo"},
+		{name: "published release", existing: "foo", err: true, exp: "foo"},
+		{name: "resumed release", existing: "foo", resume: true, exp: "foo"},
+		{name: "resumed release with different asset", existing: "fo0", resume: true, err: true, exp: "fo0"},
+		{name: "resumed draft release with different asset", existing: "bar", draft: true, resume: true, exp: "foo"},
+		{name: "overwritten published release", existing: "bar", overwrite: true, exp: "foo"},
+		{name: "skipped published release", existing: "bar", skipExisting: true, exp: "bar"},
+		{name: "resumed skipped published release", existing: "bar", resume: true, skipExisting: true, exp: "bar"},
+		{name: "conflicting policies", existing: "bar", overwrite: true, skipExisting: true, err: true, exp: "bar"},
+		{name: "corrupted upload", corrupt: true, err: true},
+		{name: "unverified corrupted upload", corrupt: true, skipVerify: true, exp: "fo"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			var asset *github.ReleaseAsset
+			if tc.existing != "" {
+				asset = gh.asset(gh.addAsset("foo.tar.gz", tc.existing))
+			}
+			if tc.corrupt {
+				gh.corrupt = 1
+			}
+			*releaseResume, *releaseSkipVerify = tc.resume, tc.skipVerify
+			*releaseOverwrite, *releaseSkipExisting = tc.overwrite, tc.skipExisting
+			release := &github.RepositoryRelease{ID: github.Int64(1), Draft: &tc.draft}
+
+			err := releaseFile(context.Background(), releaseTarget{client: client, owner: "owner", repo: "repo"}, release, file, asset)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+			exp := map[string]string{}
+			if tc.exp != "" {
+				exp["foo.tar.gz"] = tc.exp
+			}
+			if got := gh.contents(); !reflect.DeepEqual(exp, got) {
+				t.Fatalf("expected assets %v, got %v", exp, got)
+			}
+		})
+	}
+}
+
+func TestReleaseFileRetries(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "foo.tar.gz")
+	if err := os.WriteFile(file, []byte("foo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(c *Config, retries int) { config, *allowedRetries = c, retries }(config, *allowedRetries)
+	config, *allowedRetries = NewConfig(), 2
+
+	// The client errors aren't retried.
+	for _, status := range []int{http.StatusUnauthorized, http.StatusUnprocessableEntity} {
+		gh, client := newFakeGitHub(t)
+		gh.status = status
+		release := &github.RepositoryRelease{ID: github.Int64(1), Draft: github.Bool(true)}
+		if err := releaseFile(context.Background(), releaseTarget{client: client, owner: "owner", repo: "repo"}, release, file, nil); err == nil {
+			t.Fatalf("%d: expected error but got nil", status)
+		}
+		if gh.uploads != 1 {
+			t.Fatalf("%d: expected 1 upload, got %d", status, gh.uploads)
+		}
+	}
+}
+
+func TestRetryableError(t *testing.T) {
+	response := func(status int) error {
+		return &github.ErrorResponse{Response: &http.Response{StatusCode: status}}
+	}
+	for i, tc := range []struct {
+		err error
+		exp bool
+	}{
+		{errors.New("connection reset by peer"), true},
+		{fmt.Errorf("failed to verify %q: %w", "foo.tar.gz", errors.New("mismatch")), true},
+		{response(http.StatusInternalServerError), true},
+		{response(http.StatusBadGateway), true},
+		{response(http.StatusRequestTimeout), true},
+		{response(http.StatusTooManyRequests), true},
+		{&github.RateLimitError{}, true},
+		{&github.AbuseRateLimitError{}, true},
+		{response(http.StatusUnauthorized), false},
+		{response(http.StatusNotFound), false},
+		{response(http.StatusUnprocessableEntity), false},
+	} {
+		if got := retryableError(tc.err); got != tc.exp {
+			t.Errorf("%d: expected %t, got %t", i, tc.exp, got)
+		}
+	}
+}
+
+func TestUploadFiles(t *testing.T) {
+	dir := t.TempDir()
+	var (
+		exp       = map[string]string{}
+		checksums strings.Builder
+	)
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("foo-%d.tar.gz", i)
+		exp[name] = name
+		fmt.Fprintf(&checksums, "%x  %s\n", sha256.Sum256([]byte(name)), name)
+		if i%2 == 1 {
+			// The checksums file lists the base names of the assets.
+			name = filepath.Join("sub", name)
+		}
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(filepath.Base(name)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The checksums file of a previous run is replaced.
+	if err := os.WriteFile(filepath.Join(dir, checksumsFilename), []byte("stale"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	exp[checksumsFilename] = checksums.String()
+	defer func(c *Config, resume, checksums bool, parallelism int) {
+		config, *releaseResume, *releaseChecksums, *releaseParallelism = c, resume, checksums, parallelism
+	}(config, *releaseResume, *releaseChecksums, *releaseParallelism)
+	config, *releaseResume, *releaseChecksums, *releaseParallelism = NewConfig(), true, true, 4
+
+	gh, client := newFakeGitHub(t)
+	gh.addAsset("foo-0.tar.gz", "foo-0.tar.gz")
+	release := &github.RepositoryRelease{ID: github.Int64(1), Draft: github.Bool(false)}
+	files, err := releaseFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := uploadFiles(context.Background(), releaseTarget{client: client, owner: "owner", repo: "repo"}, release, files); err != nil {
+		t.Fatal(err)
+	}
+	if got := gh.contents(); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected assets %v, got %v", exp, got)
+	}
+}
+
+func TestReleaseTargets(t *testing.T) {
+	defer func(c *Config, info repository.Info, repo string) {
+		config, projInfo, *releaseTargetRepo = c, info, repo
+	}(config, projInfo, *releaseTargetRepo)
+	projInfo = repository.Info{Owner: "prometheus", Name: "prometheus"}
+	t.Setenv("GITHUB_TOKEN", "token")
+	t.Setenv("MIRROR_TOKEN", "mirror-token")
+
+	for _, tc := range []struct {
+		name       string
+		targets    []ReleaseTarget
+		targetRepo string
+		exp        []string
+		err        bool
+	}{
+		{
+			name: "project",
+			exp:  []string{"prometheus/prometheus (own)"},
+		},
+		{
+			name: "targets",
+			targets: []ReleaseTarget{
+				{Repository: "prometheus/prometheus"},
+				{Repository: "mirror/prometheus", TokenEnv: "MIRROR_TOKEN"},
+			},
+			exp: []string{"prometheus/prometheus (own)", "mirror/prometheus"},
+		},
+		{
+			name:       "target repository",
+			targets:    []ReleaseTarget{{Repository: "mirror/prometheus", TokenEnv: "MIRROR_TOKEN"}},
+			targetRepo: "prometheus/prometheus",
+			exp:        []string{"prometheus/prometheus"},
+		},
+		{
+			name:    "missing token",
+			targets: []ReleaseTarget{{Repository: "mirror/prometheus", TokenEnv: "MISSING_TOKEN"}},
+			err:     true,
+		},
+		{
+			name: "app and oidc",
+			targets: []ReleaseTarget{{
+				Repository: "mirror/prometheus",
+				App:        &GitHubApp{ID: 1, PrivateKeyEnv: "APP_KEY"},
+				OIDC:       &GitHubOIDC{URL: "https://octo-sts.dev/sts/exchange"},
+			}},
+			err: true,
+		},
+		{
+			name:    "invalid repository",
+			targets: []ReleaseTarget{{Repository: "prometheus"}},
+			err:     true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config = NewConfig()
+			config.Release.Targets = tc.targets
+			*releaseTargetRepo = tc.targetRepo
+
+			targets, err := releaseTargets(context.Background())
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, target := range targets {
+				s := target.String()
+				if target.own {
+					s += " (own)"
+				}
+				got = append(got, s)
+			}
+			if !reflect.DeepEqual(tc.exp, got) {
+				t.Fatalf("expected targets %v, got %v", tc.exp, got)
+			}
+		})
+	}
+}
+
+func TestChecksumsSigner(t *testing.T) {
+	defer func(c *Config, signer, key string) {
+		config, *releaseChecksumsSigner, *releaseChecksumsKey = c, signer, key
+	}(config, *releaseChecksumsSigner, *releaseChecksumsKey)
+
+	for _, tc := range []struct {
+		name              string
+		signer, key       string
+		configKey, envKey string
+		signChecksums     bool
+		expSigner, expKey string
+	}{
+		{name: "unsigned"},
+		{name: "flags", signer: signerMinisign, key: "minisign.key", signChecksums: true, expSigner: signerMinisign, expKey: "minisign.key"},
+		{name: "default key", signer: signerGPG, expSigner: signerGPG},
+		{name: "configured", signChecksums: true, configKey: "config", envKey: "env", expSigner: signerGPG, expKey: "config"},
+		{name: "environment", signChecksums: true, envKey: "env", expSigner: signerGPG, expKey: "env"},
+		{name: "flag key", signer: signerGPG, key: "flag", envKey: "env", expSigner: signerGPG, expKey: "flag"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("PROMU_GPG_KEY", tc.envKey)
+			config = NewConfig()
+			config.Release.GPG.Key = tc.configKey
+			config.Release.GPG.SignChecksums = tc.signChecksums
+			*releaseChecksumsSigner, *releaseChecksumsKey = tc.signer, tc.key
+
+			signer, key := checksumsSigner()
+			if signer != tc.expSigner || key != tc.expKey {
+				t.Fatalf("expected signer %q with key %q, got %q with key %q", tc.expSigner, tc.expKey, signer, key)
+			}
+		})
+	}
+}
+
+func TestReleaseHTTPClientTimeout(t *testing.T) {
+	defer func(d time.Duration) { *timeout = d }(*timeout)
+	*timeout = 50 * time.Millisecond
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(500 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	// The timeout bounds each request, not the requests made with the client.
+	client := releaseHTTPClient()
+	if _, err := client.Get(srv.URL + "/slow"); err == nil {
+		t.Fatalf("expected error for a slow request, got none")
+	}
+	for i := 0; i < 3; i++ {
+		time.Sleep(20 * time.Millisecond)
+		resp, err := client.Get(srv.URL + "/fast")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+}
+
+func TestIsLatestRelease(t *testing.T) {
+	gh, client := newFakeGitHub(t)
+	for i, r := range []struct {
+		tag               string
+		draft, prerelease bool
+	}{
+		{tag: "v2.44.0"},
+		{tag: "v2.45.0"},
+		{tag: "v2.45.1"},
+		{tag: "v2.46.0-rc.0", prerelease: true},
+		{tag: "v2.47.0", draft: true},
+		{tag: "foo"},
+	} {
+		gh.releases = append(gh.releases, &github.RepositoryRelease{
+			ID:         github.Int64(int64(i + 1)),
+			TagName:    github.String(r.tag),
+			Draft:      github.Bool(r.draft),
+			Prerelease: github.Bool(r.prerelease),
+		})
+	}
+
+	for _, tc := range []struct {
+		version string
+		exp     bool
+	}{
+		{"2.45.1", true},
+		{"2.44.1", false},
+		{"2.46.0", true},
+		{"2.47.0", true},
+		{"2.47.0-rc.0", false},
+	} {
+		release := &github.RepositoryRelease{ID: github.Int64(100)}
+		if tc.version == "2.45.1" {
+			release = gh.releases[2]
+		}
+		latest, err := isLatestRelease(context.Background(), client, "owner", "repo", release, semver.MustParse(tc.version))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if latest != tc.exp {
+			t.Fatalf("%s: expected latest %v, got %v", tc.version, tc.exp, latest)
+		}
+	}
+}
+
+func TestRollbackRelease(t *testing.T) {
+	draft := &github.RepositoryRelease{ID: github.Int64(1), TagName: github.String("v1.0.0"), Draft: github.Bool(true)}
+	published := &github.RepositoryRelease{ID: github.Int64(1), TagName: github.String("v1.0.0"), Draft: github.Bool(false)}
+	for _, tc := range []struct {
+		name    string
+		release *github.RepositoryRelease
+		tagged  bool
+		withTag bool
+		scratch bool
+		expTag  bool
+		err     bool
+	}{
+		{name: "draft", release: draft, tagged: true, expTag: true},
+		{name: "draft with tag", release: draft, tagged: true, withTag: true},
+		{name: "draft without tag", release: draft, withTag: true},
+		{name: "no release", tagged: true, withTag: true},
+		{name: "published", release: published, tagged: true, withTag: true, expTag: true, err: true},
+		{name: "published in scratch repository", release: published, tagged: true, withTag: true, scratch: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			if tc.release != nil {
+				gh.releases = []*github.RepositoryRelease{tc.release}
+			}
+			gh.tags["v1.0.0"] = tc.tagged
+			gh.addAsset("foo-1.0.0.tar.gz", "foo")
+
+			err := rollbackRelease(context.Background(), releaseTarget{client: client, owner: "owner", repo: "repo", scratch: tc.scratch}, "v1.0.0", tc.withTag)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				if len(gh.releases) != 1 || len(gh.contents()) != 1 {
+					t.Fatalf("expected the release and its assets to be kept")
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if len(gh.releases) != 0 {
+				t.Fatalf("expected no releases, got %d", len(gh.releases))
+			}
+			if gh.tags["v1.0.0"] != tc.expTag {
+				t.Fatalf("expected tag existing to be %t, got %t", tc.expTag, gh.tags["v1.0.0"])
+			}
+		})
+	}
+}
+
+func TestSplitRepository(t *testing.T) {
+	for _, tc := range []struct {
+		in          string
+		owner, repo string
+		err         bool
+	}{
+		{in: "owner/repo", owner: "owner", repo: "repo"},
+		{in: "a/b/c", err: true},
+		{in: "/b", err: true},
+		{in: "a/", err: true},
+		{in: "a", err: true},
+		{in: "", err: true},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			owner, repo, err := splitRepository(tc.in)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got %q, %q", owner, repo)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if owner != tc.owner || repo != tc.repo {
+				t.Fatalf("expected %q, %q, got %q, %q", tc.owner, tc.repo, owner, repo)
+			}
+		})
+	}
+}
+
+func TestCleanupRelease(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		tagged bool
+	}{
+		{name: "published", tagged: true},
+		// The tag of a draft release doesn't exist, which is a 422 error.
+		{name: "draft"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			gh, client := newFakeGitHub(t)
+			release := &github.RepositoryRelease{ID: github.Int64(1), Name: github.String("1.0.0"), TagName: github.String("v1.0.0"), Draft: github.Bool(!tc.tagged)}
+			gh.releases = []*github.RepositoryRelease{release}
+			gh.tags["v1.0.0"] = tc.tagged
+			gh.addAsset("foo-1.0.0.tar.gz", "foo")
+
+			cleanupRelease(context.Background(), client, "owner", "repo", release)
+			if len(gh.releases) != 0 || len(gh.contents()) != 0 {
+				t.Fatalf("expected the release and its assets to be deleted")
+			}
+			if gh.tags["v1.0.0"] {
+				t.Fatalf("expected the tag to be deleted")
+			}
+			if err := deleteTag(context.Background(), client, "owner", "repo", "v1.0.0"); err != nil {
+				t.Fatalf("expected the missing tag to be ignored, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReleaseRehearsal(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "CHANGELOG.md"), []byte("## 1.0.0 / 2026-01-01\n\n* [FEATURE] Foo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "foo-1.0.0.linux-amd64.tar.gz")
+	if err := os.WriteFile(file, []byte("foo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	defer func(c *Config, info repository.Info, cleanup bool) {
+		config, projInfo, *releaseCleanup = c, info, cleanup
+	}(config, projInfo, *releaseCleanup)
+	config = NewConfig()
+	projInfo = repository.Info{Name: "foo", Version: "1.0.0", Revision: "abc"}
+	ctx := context.Background()
+
+	// The release created by the upload is deleted with --cleanup, unlike
+	// a release which existed before.
+	*releaseCleanup = true
+	gh, client := newFakeGitHub(t)
+	target := releaseTarget{client: client, owner: "owner", repo: "repo", scratch: true}
+	if err := releaseToTarget(ctx, target, false, []string{file}); err != nil {
+		t.Fatal(err)
+	}
+	if len(gh.releases) != 0 || len(gh.contents()) != 0 {
+		t.Fatal("expected the created release and its assets to be deleted")
+	}
+	existing := &github.RepositoryRelease{ID: github.Int64(1), TagName: github.String("v1.0.0"), Draft: github.Bool(true)}
+	gh.releases = []*github.RepositoryRelease{existing}
+	if err := releaseToTarget(ctx, target, false, []string{file}); err != nil {
+		t.Fatal(err)
+	}
+	if len(gh.releases) != 1 || len(gh.contents()) != 1 {
+		t.Fatal("expected the existing release and its assets to be kept")
+	}
+
+	// The rehearsal going on with publish is deleted by rollback.
+	*releaseCleanup = false
+	gh, client = newFakeGitHub(t)
+	target = releaseTarget{client: client, owner: "owner", repo: "repo", scratch: true}
+	if err := releaseToTarget(ctx, target, false, []string{file}); err != nil {
+		t.Fatal(err)
+	}
+	if err := publishRelease(ctx, target, semver.MustParse("1.0.0")); err != nil {
+		t.Fatal(err)
+	}
+	if len(gh.releases) != 1 || gh.releases[0].GetDraft() || !gh.tags["v1.0.0"] {
+		t.Fatalf("expected the release to be published, got %+v", gh.releases)
+	}
+	if exp, got := map[string]string{"foo-1.0.0.linux-amd64.tar.gz": "foo"}, gh.contents(); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected assets %v, got %v", exp, got)
+	}
+	if err := rollbackRelease(ctx, target, "v1.0.0", true); err != nil {
+		t.Fatal(err)
+	}
+	if len(gh.releases) != 0 || len(gh.contents()) != 0 || gh.tags["v1.0.0"] {
+		t.Fatal("expected the release, its assets and its tag to be deleted")
+	}
+}
+
+func TestRenderReleaseNotes(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for _, name := range []string{"sha256sums.txt", "foo-1.0.0.linux-amd64.tar.gz", "foo-1.0.0.darwin-arm64.tar.gz"} {
+		files = append(files, filepath.Join(dir, name))
+		if err := os.WriteFile(files[len(files)-1], []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tmpl := filepath.Join(dir, "notes.tmpl")
+	err := os.WriteFile(tmpl, []byte(`{{ .Changelog.Text }}
+{{ range .Assets }}
+{{ .OS }}/{{ .Arch }} [{{ .Name }}]({{ .URL }}) {{ .Size }} {{ printf "%.8s" .SHA256 }}
+{{- end }}
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(c *Config, version string) { config, projInfo.Version = c, version }(config, projInfo.Version)
+	config, projInfo.Version = NewConfig(), "1.0.0"
+
+	entry := &changelog.Entry{Version: "1.0.0", Text: "* [FEATURE] Foo."}
+	notes, err := renderReleaseNotes(tmpl, entry, "owner/repo", "v1.0.0", githubAssetURL("owner", "repo", "v1.0.0"), files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `* [FEATURE] Foo.
+
+/ [sha256sums.txt](https://github.com/owner/repo/releases/download/v1.0.0/sha256sums.txt) 14 7899317e
+darwin/arm64 [foo-1.0.0.darwin-arm64.tar.gz](https://github.com/owner/repo/releases/download/v1.0.0/foo-1.0.0.darwin-arm64.tar.gz) 29 5b8dbf7f
+linux/amd64 [foo-1.0.0.linux-amd64.tar.gz](https://github.com/owner/repo/releases/download/v1.0.0/foo-1.0.0.linux-amd64.tar.gz) 28 788a7271
+`
+	if notes != exp {
+		t.Fatalf("expected notes:\n%s\ngot:\n%s", exp, notes)
+	}
+}
+
+func TestUploadOptions(t *testing.T) {
+	defer func(c *Config, version string) { config, projInfo.Version = c, version }(config, projInfo.Version)
+	config, projInfo.Version = NewConfig(), "1.0.0"
+	config.Release.Assets = []ReleaseAsset{
+		{Name: "*.tar.gz", ContentType: "application/gzip", Label: "{{.OS}} {{.Arch}} tarball"},
+		{Name: "sha256sums.txt", Label: "Checksums of {{.Version}}"},
+		{Name: "*", ContentType: "application/octet-stream"},
+	}
+	for _, tc := range []struct {
+		name string
+		exp  github.UploadOptions
+	}{
+		{"foo-1.0.0.linux-amd64.tar.gz", github.UploadOptions{MediaType: "application/gzip", Label: "linux amd64 tarball"}},
+		{"sha256sums.txt", github.UploadOptions{Label: "Checksums of 1.0.0"}},
+		{"foo-1.0.0.windows-amd64.zip", github.UploadOptions{MediaType: "application/octet-stream"}},
+	} {
+		tc.exp.Name = tc.name
+		opts, err := uploadOptions(tc.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *opts != tc.exp {
+			t.Fatalf("%s: expected options %+v, got %+v", tc.name, tc.exp, *opts)
+		}
+	}
+
+	config.Release.Assets = []ReleaseAsset{{Name: "*", Label: "{{.Foo}}"}}
+	if _, err := uploadOptions("foo"); err == nil {
+		t.Fatal("expected error for invalid label")
+	}
+}
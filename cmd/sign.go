@@ -0,0 +1,297 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/prometheus/promu/util/pool"
+	"github.com/prometheus/promu/util/sh"
+)
+
+var (
+	signcmd    = app.Command("sign", "Sign the archives, packages and checksums file of the location with keyless cosign signatures and certificates, or with GPG or minisign")
+	signSigner = signcmd.Flag("signer", "Signer of the files: cosign, gpg or minisign, codesign.signer or cosign by default").
+			Enum(signerCosign, signerGPG, signerMinisign)
+	signKey = signcmd.Flag("key", "ID of the GPG key, release.gpg.key or PROMU_GPG_KEY by default, or minisign secret key file, release.minisign by default").
+		String()
+	signParallelism = signcmd.Flag("parallelism", "How many files to sign in parallel").
+			Default("4").Int()
+	signForce = signcmd.Flag("force", "Sign the files again even if already signed with the same identity").
+			Bool()
+	signLocation = signcmd.Arg("location", "Location of the files to sign").Default(".tarballs").String()
+)
+
+// packageExtensions are the extensions of the packages written by promu
+// package, signed along with the archives.
+var packageExtensions = []string{".deb", ".rpm", ".msi", ".pkg", ".snap"}
+
+func runSign(location, signer, key string, force bool, parallelism int) {
+	entries, err := os.ReadDir(location)
+	if err != nil {
+		fatal(err)
+	}
+	var files []string
+	for _, e := range entries {
+		if e.Type().IsRegular() {
+			files = append(files, filepath.Join(location, e.Name()))
+		}
+	}
+	if signer == "" {
+		signer = config.Codesign.Signer
+	}
+	if signer == "" {
+		signer = signerCosign
+	}
+	cache, err := loadSignCache(signCacheFilename)
+	if err != nil {
+		fatal(err)
+	}
+	if force {
+		cache.artifacts = map[string]signCacheEntry{}
+	}
+	if _, err := signFiles(files, signer, key, cache, parallelism); err != nil {
+		fatal(err)
+	}
+}
+
+// isSignableArtifact returns whether the file is signed by promu sign and
+// promu release: an archive, a package or a checksums file.
+func isSignableArtifact(path string) bool {
+	name := filepath.Base(path)
+	if _, _, ok := splitArchiveExtension(name); ok || isChecksumsOutput(name) {
+		return true
+	}
+	for _, ext := range packageExtensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// signFiles signs the archives, packages and checksums file among the files
+// with the signer, up to parallelism at a time, skipping those of the cache
+// whose signatures still verify. The rpm packages embed their GPG signature,
+// the other files have their signatures written next to them. It returns the
+// paths of the signature files.
+func signFiles(files []string, signer, key string, cache *signCache, parallelism int) ([]string, error) {
+	var signable []string
+	for _, path := range files {
+		if isSignableArtifact(path) {
+			signable = append(signable, path)
+		}
+	}
+	identity := signIdentity(signer, key)
+	if signer == signerMinisign && key == "" {
+		if !minisignEnabled() {
+			return nil, errors.New("missing minisign secret key, set with --key or release.minisign")
+		}
+		file, cleanup, err := minisignKey()
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		key = file
+	}
+
+	signatures := make([][]string, len(signable))
+	workers := pool.New(context.Background(), parallelism, false)
+	for i, path := range signable {
+		i, path := i, path
+		workers.Go(func(context.Context) error {
+			expected := signatureFiles(signer, path)
+			verify := func() error { return verifySignatures(expected, defaultCosignIdentity(), defaultCosignIssuer) }
+			if cache.signed(path, identity, verify) {
+				fmt.Println(" > skipped", filepath.Base(path), "already signed")
+				signatures[i] = expected
+				return nil
+			}
+			var err error
+			if signer == signerGPG && strings.HasSuffix(path, ".rpm") {
+				err = rpmAddSign(path, key)
+			} else {
+				signatures[i], err = signFile(signer, key, path)
+			}
+			if err != nil {
+				return err
+			}
+			return cache.add(path, identity)
+		})
+	}
+	if err := workers.Wait(); err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, s := range signatures {
+		written = append(written, s...)
+	}
+	return written, nil
+}
+
+// signIdentity returns the identity of the signatures of the signer with the
+// key, or with the configured key if empty.
+func signIdentity(signer, key string) string {
+	switch {
+	case key != "":
+	case signer == signerCosign:
+		key = cosignKeyRef()
+		if key == "" {
+			key = "keyless"
+		}
+	case signer == signerGPG:
+		key = gpgKey()
+	case signer == signerMinisign:
+		key = config.Release.Minisign.Key
+		if key == "" {
+			key = "env:" + config.Release.Minisign.KeyEnv
+		}
+	}
+	return signer + " " + key
+}
+
+// signatureFiles returns the paths of the signature files of the file signed
+// by the signer, none for the rpm packages which embed their GPG signature.
+func signatureFiles(signer, path string) []string {
+	switch {
+	case signer == signerCosign && cosignKeyRef() == "":
+		return []string{path + ".sig", path + ".pem"}
+	case signer == signerCosign:
+		return []string{path + ".sig"}
+	case signer == signerGPG && strings.HasSuffix(path, ".rpm"):
+		return nil
+	case signer == signerGPG:
+		return []string{path + ".asc"}
+	}
+	return []string{path + ".minisig"}
+}
+
+// rpmAddSign embeds the GPG signature of the given key, or of the release
+// key if empty, into the rpm package.
+func rpmAddSign(path, key string) error {
+	if key == "" {
+		key = gpgKey()
+	}
+	if key == "" {
+		return errors.New("missing GPG key of the rpm signatures, set with --key, release.gpg.key or PROMU_GPG_KEY")
+	}
+	if err := sh.RunCommand(envOr("RPMSIGN", "rpmsign"), "--addsign", "--define", "_gpg_name "+key, path); err != nil {
+		return fmt.Errorf("Failed to sign %s: %w", filepath.Base(path), err)
+	}
+	fmt.Println(" > signed", filepath.Base(path))
+	return nil
+}
+
+// isCosignFile returns whether the file is a cosign signature or
+// certificate.
+func isCosignFile(path string) bool {
+	return strings.HasSuffix(path, ".sig") || strings.HasSuffix(path, ".pem")
+}
+
+// cosignBlob signs the file with a cosign signature, written next to it as
+// <file>.sig: a keyless signature with its certificate as <file>.pem, or a
+// signature of the KMS key of the key provider. It returns the paths of the
+// written files.
+func cosignBlob(path string) ([]string, error) {
+	signature, certificate := path+".sig", path+".pem"
+	args := []string{"sign-blob", "--yes", "--output-signature", signature}
+	written := []string{signature}
+	if key := cosignKeyRef(); key != "" {
+		args = append(args, "--key", key)
+	} else {
+		args = append(args, "--output-certificate", certificate)
+		written = append(written, certificate)
+	}
+	if err := sh.RunCommand(signerCosign, append(args, path)...); err != nil {
+		return nil, fmt.Errorf("Failed to sign %s: %w", filepath.Base(path), err)
+	}
+	fmt.Println(" > signed", filepath.Base(path))
+	return written, nil
+}
+
+// minisignEnabled returns whether the release files are signed with
+// minisign.
+func minisignEnabled() bool {
+	return config.Release.Minisign.Key != "" || config.Release.Minisign.KeyEnv != ""
+}
+
+// minisignKey returns the configured minisign secret key file, or a
+// temporary file of the secret key of the environment variable, removed by
+// the returned function.
+func minisignKey() (string, func(), error) {
+	if key := config.Release.Minisign.Key; key != "" {
+		return key, func() {}, nil
+	}
+	env := config.Release.Minisign.KeyEnv
+	secret := os.Getenv(env)
+	if secret == "" {
+		return "", nil, fmt.Errorf("%s not defined", env)
+	}
+	f, err := os.CreateTemp("", "promu-minisign")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.Remove(f.Name()) }
+	if _, err := f.WriteString(secret); err != nil {
+		f.Close()
+		cleanup()
+		return "", nil, err
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return f.Name(), cleanup, nil
+}
+
+// isSignatureFile returns whether the file is a detached GPG or minisign
+// signature.
+func isSignatureFile(path string) bool {
+	return strings.HasSuffix(path, ".minisig") || strings.HasSuffix(path, ".asc")
+}
+
+// minisignFiles signs the files with the configured minisign secret key,
+// which must not be encrypted, writing the signatures next to them as
+// <file>.minisig. Signatures and files already signed are skipped. It
+// returns the paths of the written files.
+func minisignFiles(files []string) ([]string, error) {
+	key, cleanup, err := minisignKey()
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	signed := make(map[string]bool, len(files))
+	for _, path := range files {
+		signed[path] = true
+	}
+	var written []string
+	for _, path := range files {
+		if isSignatureFile(path) || signed[path+".minisig"] {
+			continue
+		}
+		signature, err := detachSign(signerMinisign, key, path)
+		if err != nil {
+			return nil, err
+		}
+		written = append(written, signature)
+	}
+	return written, nil
+}
@@ -0,0 +1,119 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// signCacheFilename is the file of the project directory recording the
+// artifacts signed by promu codesign and promu sign, so that the re-runs of
+// interrupted release jobs skip them.
+const signCacheFilename = ".promu-signed.json"
+
+// signCache records the artifacts by path with the SHA256 checksum they had
+// once signed and the identity they were signed with. A nil cache records
+// nothing.
+type signCache struct {
+	path string
+
+	mtx       sync.Mutex
+	artifacts map[string]signCacheEntry
+}
+
+type signCacheEntry struct {
+	Identity string `json:"identity"`
+	SHA256   string `json:"sha256"`
+}
+
+// loadSignCache reads the cache file, which is created once an artifact is
+// signed if missing.
+func loadSignCache(path string) (*signCache, error) {
+	c := &signCache{path: path, artifacts: map[string]signCacheEntry{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &c.artifacts); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// signed reports whether the artifact was signed with the identity and is
+// unchanged since, and its signatures still pass verify.
+func (c *signCache) signed(artifact, identity string, verify func() error) bool {
+	if c == nil {
+		return false
+	}
+	c.mtx.Lock()
+	e, ok := c.artifacts[filepath.ToSlash(filepath.Clean(artifact))]
+	c.mtx.Unlock()
+	if !ok || e.Identity != identity || e.SHA256 != localSHA256(artifact) {
+		return false
+	}
+	return verify() == nil
+}
+
+// add records the artifact as signed with the identity and writes the cache
+// file, for the artifacts signed before an interruption to be skipped.
+func (c *signCache) add(artifact, identity string) error {
+	if c == nil {
+		return nil
+	}
+	sum := localSHA256(artifact)
+	if sum == "" {
+		return fmt.Errorf("failed to read %s", artifact)
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.artifacts[filepath.ToSlash(filepath.Clean(artifact))] = signCacheEntry{Identity: identity, SHA256: sum}
+	b, err := json.MarshalIndent(c.artifacts, "", "  ")
+	if err != nil {
+		return err
+	}
+	// The cache file is replaced at once not to be left truncated.
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// verifySignatures runs the checks of the signatures of the files, which
+// must all exist.
+func verifySignatures(files []string, identity, issuer string) error {
+	for _, file := range files {
+		if _, err := os.Stat(file); err != nil {
+			return err
+		}
+		for _, check := range signatureChecks(file, identity, issuer) {
+			if err := check.verify(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
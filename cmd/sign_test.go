@@ -0,0 +1,274 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fakeCommand puts a shell script of the given name first in PATH.
+func fakeCommand(t *testing.T, name, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts aren't supported on windows")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestReleaseFilesSign(t *testing.T) {
+	// The fake cosign writes the arguments following each output flag.
+	fakeCommand(t, "cosign", `while [ $# -gt 0 ]; do
+  case "$1" in
+    --output-signature|--output-certificate) echo signed > "$2"; shift ;;
+  esac
+  shift
+done
+`)
+	defer func(c *Config, checksums bool) { config, *releaseChecksums = c, checksums }(config, *releaseChecksums)
+	config, *releaseChecksums = NewConfig(), true
+	config.Release.Sign = true
+
+	dir := t.TempDir()
+	// The signature of the previous run is signed again instead of being
+	// uploaded as is, unlike the signature of a file which isn't released.
+	for _, name := range []string{"foo-1.0.0.linux-amd64.tar.gz", "foo-1.0.0.linux-amd64.tar.gz.sig", "foo-1.0.0.linux-arm64.tar.zst", "foo_1.0.0_amd64.deb", "foo.sbom.json.sig"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := releaseFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, filepath.Base(f))
+	}
+	sort.Strings(got)
+	exp := []string{
+		"foo-1.0.0.linux-amd64.tar.gz",
+		"foo-1.0.0.linux-amd64.tar.gz.pem",
+		"foo-1.0.0.linux-amd64.tar.gz.sig",
+		"foo-1.0.0.linux-arm64.tar.zst",
+		"foo-1.0.0.linux-arm64.tar.zst.pem",
+		"foo-1.0.0.linux-arm64.tar.zst.sig",
+		"foo.sbom.json.sig",
+		"foo_1.0.0_amd64.deb",
+		"foo_1.0.0_amd64.deb.pem",
+		"foo_1.0.0_amd64.deb.sig",
+		"sha256sums.txt",
+		"sha256sums.txt.pem",
+		"sha256sums.txt.sig",
+	}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected files %v, got %v", exp, got)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "foo-1.0.0.linux-amd64.tar.gz.sig"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "signed\n" {
+		t.Fatalf("expected the signature to be written again, got %q", b)
+	}
+}
+
+func TestIsSignableArtifact(t *testing.T) {
+	for name, exp := range map[string]bool{
+		"foo-1.0.0.linux-amd64.tar.gz":     true,
+		"foo-1.0.0.linux-amd64.tar.xz":     true,
+		"foo-1.0.0.windows-amd64.zip":      true,
+		"foo-1.0.0.x86_64.rpm":             true,
+		"sha256sums.txt":                   true,
+		"sha512sums.contents.txt":          true,
+		"checksums.json":                   true,
+		"sha256sums.txt.sig":               false,
+		"foo-1.0.0.linux-amd64.tar.gz.asc": false,
+		"README.md":                        false,
+	} {
+		if got := isSignableArtifact(filepath.Join("dist", name)); got != exp {
+			t.Errorf("%s: expected %t, got %t", name, exp, got)
+		}
+	}
+}
+
+func TestReleaseFilesMinisign(t *testing.T) {
+	// The fake minisign writes the secret key to the signature file.
+	fakeCommand(t, "minisign", `while [ $# -gt 0 ]; do
+  case "$1" in
+    -s) key="$2"; shift ;;
+    -x) cat "$key" > "$2"; shift ;;
+  esac
+  shift
+done
+`)
+	t.Setenv("MINISIGN_KEY", "secret")
+	defer func(c *Config, checksums bool, signer, key string) {
+		config, *releaseChecksums, *releaseChecksumsSigner, *releaseChecksumsKey = c, checksums, signer, key
+	}(config, *releaseChecksums, *releaseChecksumsSigner, *releaseChecksumsKey)
+	config = NewConfig()
+	config.Release.Minisign.KeyEnv = "MINISIGN_KEY"
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(t.TempDir(), "minisign.key")
+	if err := os.WriteFile(keyFile, []byte("checksums"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// The checksums file is only signed once, with the key of the flag.
+	*releaseChecksums, *releaseChecksumsSigner, *releaseChecksumsKey = true, signerMinisign, keyFile
+	for _, name := range []string{"foo-1.0.0.linux-amd64.tar.gz", "foo-1.0.0.linux-amd64.tar.gz.minisig"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := releaseFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isSignatureFile(f) {
+			got[filepath.Base(f)] = string(b)
+		} else {
+			got[filepath.Base(f)] = ""
+		}
+	}
+	exp := map[string]string{
+		"foo-1.0.0.linux-amd64.tar.gz":         "",
+		"foo-1.0.0.linux-amd64.tar.gz.minisig": "secret",
+		"sha256sums.txt":                       "",
+		"sha256sums.txt.minisig":               "checksums",
+	}
+	if !reflect.DeepEqual(exp, got) || len(files) != len(exp) {
+		t.Fatalf("expected files %v, got %v", exp, got)
+	}
+}
+
+func TestSignFiles(t *testing.T) {
+	// The fake gpg writes the key to the signature file and the fake
+	// rpmsign appends its arguments to the package.
+	fakeCommand(t, "gpg", `while [ $# -gt 0 ]; do
+  case "$1" in
+    --output) out="$2"; shift ;;
+    --local-user) key="$2"; shift ;;
+  esac
+  shift
+done
+echo "$key" > "$out"
+`)
+	fakeCommand(t, "rpmsign", `for arg in "$@"; do last="$arg"; done
+echo "$@" >> "$last"
+`)
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+	config.Release.GPG.Key = "release"
+
+	dir := t.TempDir()
+	var files []string
+	for _, name := range []string{"foo-1.0.0.linux-amd64.tar.gz", "foo-1.0.0.windows-amd64.zip", "foo_1.0.0_amd64.deb", "foo-1.0.0-1.x86_64.rpm", "README.md"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+	written, err := signFiles(files, signerGPG, "", nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range written {
+		got = append(got, filepath.Base(f))
+	}
+	exp := []string{"foo-1.0.0.linux-amd64.tar.gz.asc", "foo-1.0.0.windows-amd64.zip.asc", "foo_1.0.0_amd64.deb.asc"}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected signatures %v, got %v", exp, got)
+	}
+	if b, err := os.ReadFile(written[0]); err != nil || string(b) != "release\n" {
+		t.Fatalf("expected the signature of the release key, got %q (%v)", b, err)
+	}
+	// The rpm package embeds its signature.
+	rpm := filepath.Join(dir, "foo-1.0.0-1.x86_64.rpm")
+	b, err := os.ReadFile(rpm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "--addsign --define _gpg_name release " + rpm + "\n"; string(b) != exp {
+		t.Fatalf("expected %q, got %q", exp, b)
+	}
+}
+
+func TestSignFilesCache(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	// The fake minisign logs its calls and writes the signature file.
+	fakeCommand(t, "minisign", `while [ $# -gt 0 ]; do
+  [ "$1" = "-x" ] && out="$2"
+  shift
+done
+echo signed > "$out"
+echo "$out" >> `+log+`
+`)
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+	file := filepath.Join(dir, "foo-1.0.0.linux-amd64.tar.gz")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cache, err := loadSignCache(filepath.Join(dir, signCacheFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := func() int {
+		b, _ := os.ReadFile(log)
+		return strings.Count(string(b), "\n")
+	}
+
+	for i := 0; i < 2; i++ {
+		written, err := signFiles([]string{file}, signerMinisign, "minisign.key", cache, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp := []string{file + ".minisig"}; !reflect.DeepEqual(exp, written) {
+			t.Fatalf("expected %v, got %v", exp, written)
+		}
+	}
+	if n := calls(); n != 1 {
+		t.Fatalf("expected the signed file to be skipped, signed %d times", n)
+	}
+
+	// The file is signed again if its signature is missing.
+	if err := os.Remove(file + ".minisig"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := signFiles([]string{file}, signerMinisign, "minisign.key", cache, 2); err != nil {
+		t.Fatal(err)
+	}
+	if n := calls(); n != 2 {
+		t.Fatalf("expected the file to be signed again, signed %d times", n)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+
+	"github.com/prometheus/promu/util/packaging"
+	"github.com/prometheus/promu/util/sh"
+)
+
+const defaultSnapBase = "core22"
+
+// newSnapPackage returns the package of the snap of the target, whose file
+// paths are relative to the root of the snap, and its options.
+func newSnapPackage(t packageTarget) (*packaging.Package, packaging.SnapOptions) {
+	snap := config.Package.Snap
+	p := &packaging.Package{
+		Name:        config.Package.Name,
+		Version:     projInfo.Version,
+		Arch:        t.goarch,
+		Maintainer:  config.Package.Maintainer,
+		Description: config.Package.Description,
+		Homepage:    config.Package.Homepage,
+		License:     config.Package.License,
+	}
+	for _, binary := range config.Build.Binaries {
+		p.Files = append(p.Files, packaging.File{
+			Path:   path.Join("/bin", binary.Name),
+			Mode:   0o755,
+			Source: filepath.Join(t.binaries, binary.Name),
+		})
+	}
+	o := packaging.SnapOptions{
+		Base:        snap.Base,
+		Confinement: snap.Confinement,
+		BuildOn:     runtime.GOARCH,
+		Plugs:       snap.Plugs,
+		Arguments:   snap.Arguments,
+	}
+	if snap.Service != "" {
+		o.Service = path.Join("/bin", snap.Service)
+	}
+	return p, o
+}
+
+// createSnap writes the snapcraft project of the target, holding the
+// binaries and the generated snapcraft.yaml, to the <name>-snap/<goarch>
+// directory of the package prefix. If an image is configured, the snap is
+// then built by running snapcraft in a container of this image, which must
+// match the base of the snap.
+func createSnap(t packageTarget) error {
+	snap := config.Package.Snap
+	p, o := newSnapPackage(t)
+
+	project := filepath.Join(config.Package.Prefix, config.Package.Name+"-snap", t.goarch)
+	if err := os.RemoveAll(project); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(project, "snap"), 0o755); err != nil {
+		return err
+	}
+	for i, f := range p.Files {
+		dst := filepath.Join(project, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		if err := copyFile(f.Source, dst); err != nil {
+			return err
+		}
+		p.Files[i].Source = dst
+	}
+	var b bytes.Buffer
+	if err := packaging.WriteSnapcraft(&b, p, o); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(project, "snap", "snapcraft.yaml"), b.Bytes(), 0o644); err != nil {
+		return err
+	}
+	if snap.Image == "" {
+		fmt.Println(" >  ", project)
+		return nil
+	}
+
+	filename, err := packaging.SnapFilename(p)
+	if err != nil {
+		return err
+	}
+	fmt.Println(" >  ", filename)
+	dir, err := filepath.Abs(project)
+	if err != nil {
+		return err
+	}
+	err = sh.RunCommand("docker", "run", "--rm", "-v", dir+":/project", "-w", "/project",
+		snap.Image, "snapcraft", "pack", "--destructive-mode", "--output", filename)
+	if err != nil {
+		return fmt.Errorf("Failed to build %s: %w", filename, err)
+	}
+	return os.Rename(filepath.Join(project, filename), filepath.Join(config.Package.Prefix, filename))
+}
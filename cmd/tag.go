@@ -0,0 +1,86 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/prometheus/promu/pkg/changelog"
+	"github.com/prometheus/promu/util/sh"
+)
+
+var (
+	tagcmd       = app.Command("tag", "Create the annotated tag of the version of the VERSION file, whose CHANGELOG.md entry must exist")
+	tagChangelog = tagcmd.Flag("changelog", "Path to CHANGELOG.md, in the directory of --component if any").
+			Default("CHANGELOG.md").String()
+	tagSign = tagcmd.Flag("sign", "Sign the tag with GPG, using the key of release.gpg.key or PROMU_GPG_KEY if set").
+		Bool()
+	tagPush = tagcmd.Flag("push", "Push the tag to the remote").
+		Bool()
+	tagRemote = tagcmd.Flag("remote", "Remote to push the tag to").
+			Default("origin").String()
+	tagComponent = tagcmd.Flag("component", "Directory of the component to tag, with its own VERSION and changelog files, the tag being <dir>/vX.Y.Z").
+			String()
+)
+
+func runTag(path, component string, sign, push bool, remote string) error {
+	info, tagPrefix, err := componentInfo(component)
+	if err != nil {
+		return err
+	}
+	if _, err := info.ToSemver(); err != nil {
+		return fmt.Errorf("invalid semver version: %w", err)
+	}
+	if component != "" {
+		path = filepath.Join(component, path)
+	}
+	entry, err := readChangelogEntry(path, info.Version)
+	if err != nil {
+		return err
+	}
+
+	tag := tagPrefix + info.Version
+	if err := sh.RunCommand("git", tagArgs(tag, entry, sign, gpgKey())...); err != nil {
+		return fmt.Errorf("failed to create tag %s: %w", tag, err)
+	}
+	fmt.Println(" > created tag", tag)
+	if !push {
+		return nil
+	}
+	if err := sh.RunCommand("git", "push", remote, "refs/tags/"+tag); err != nil {
+		return fmt.Errorf("failed to push tag %s: %w", tag, err)
+	}
+	fmt.Println(" > pushed tag", tag, "to", remote)
+	return nil
+}
+
+// tagArgs returns the git arguments creating the annotated tag, signed with
+// the given GPG key (the default key if empty) if requested. The message of
+// the tag is the changelog entry.
+func tagArgs(tag string, entry *changelog.Entry, sign bool, key string) []string {
+	args := []string{"tag", "-a"}
+	if sign {
+		args = []string{"tag", "-s"}
+		if key != "" {
+			args = append(args, "-u", key)
+		}
+	}
+	message := entry.Name()
+	if entry.Text != "" {
+		message += "\n\n" + entry.Text
+	}
+	return append(args, "--cleanup=verbatim", "-m", message, tag)
+}
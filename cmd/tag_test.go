@@ -0,0 +1,115 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/promu/pkg/changelog"
+	"github.com/prometheus/promu/pkg/repository"
+)
+
+func TestTagArgs(t *testing.T) {
+	entry := &changelog.Entry{
+		Version: "1.0.0",
+		Date:    time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Text:    "* [FEATURE] Foo.",
+	}
+	message := "1.0.0 / 2026-01-01\n\n* [FEATURE] Foo."
+	for _, tc := range []struct {
+		name string
+		sign bool
+		key  string
+		exp  []string
+	}{
+		{name: "annotated", key: "ignored", exp: []string{"tag", "-a", "--cleanup=verbatim", "-m", message, "v1.0.0"}},
+		{name: "signed", sign: true, exp: []string{"tag", "-s", "--cleanup=verbatim", "-m", message, "v1.0.0"}},
+		{name: "signed with key", sign: true, key: "ABCD", exp: []string{"tag", "-s", "-u", "ABCD", "--cleanup=verbatim", "-m", message, "v1.0.0"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tagArgs("v1.0.0", entry, tc.sign, tc.key); !reflect.DeepEqual(tc.exp, got) {
+				t.Fatalf("expected args %q, got %q", tc.exp, got)
+			}
+		})
+	}
+}
+
+func TestRunTag(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "git.log")
+	// The fake git logs its arguments, only the first two and the tag name
+	// for the tag command.
+	fakeCommand(t, "git", `if [ "$1" = tag ]; then for last; do :; done; echo "$1 $2 $last" >> `+log+`; else echo "$@" >> `+log+`; fi
+`)
+	changelogPath := filepath.Join(dir, "CHANGELOG.md")
+	if err := os.WriteFile(changelogPath, []byte("## 1.0.0 / 2026-01-01\n\n* [FEATURE] Foo.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(c *Config, info repository.Info) { config, projInfo = c, info }(config, projInfo)
+	config = NewConfig()
+
+	projInfo = repository.Info{Version: "1.1.0"}
+	if err := runTag(changelogPath, "", false, true, "origin"); err == nil {
+		t.Fatalf("expected error for a missing changelog entry, got none")
+	}
+
+	projInfo = repository.Info{Version: "1.0.0"}
+	if err := runTag(changelogPath, "", false, true, "upstream"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := "tag -a v1.0.0\npush upstream refs/tags/v1.0.0\n", string(b); exp != got {
+		t.Fatalf("expected git commands %q, got %q", exp, got)
+	}
+
+	// The components are tagged with their own version and changelog.
+	if err := os.Remove(log); err != nil {
+		t.Fatal(err)
+	}
+	component := filepath.Join(dir, "collectors", "foo")
+	if err := os.MkdirAll(component, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(component, "VERSION"), []byte("0.2.0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(component, "CHANGELOG.md"), []byte("## 0.2.0 / 2026-01-01\n\n* [FEATURE] Foo.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := runTag("CHANGELOG.md", "collectors/foo", false, false, "origin"); err != nil {
+		t.Fatal(err)
+	}
+	b, err = os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, got := "tag -a collectors/foo/v0.2.0\n", string(b); exp != got {
+		t.Fatalf("expected git commands %q, got %q", exp, got)
+	}
+}
@@ -15,16 +15,17 @@
 package cmd
 
 import (
-	"archive/zip"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	kingpin "github.com/alecthomas/kingpin/v2"
-
-	"github.com/prometheus/promu/util/sh"
 )
 
 var (
@@ -46,92 +47,256 @@ func runTarball(binariesLocation string) {
 		config.Tarball.Prefix = *tarballPrefix
 	}
 
+	err := createTarballs(tarballTarget{
+		goos:     envOr("GOOS", goos),
+		goarch:   envOr("GOARCH", goarch),
+		binaries: binariesLocation,
+		prefix:   config.Tarball.Prefix,
+	})
+	if err != nil {
+		fatal(err)
+	}
+}
+
+// tarballTarget holds the parameters of the archives of a platform.
+type tarballTarget struct {
+	goos   string
+	goarch string
+	// binaries is the directory of the built binaries.
+	binaries string
+	// prefix is the directory where the archives are written.
+	prefix string
+}
+
+// createTarballs creates the archives of the target. It only reads the
+// global state so that the archives of several targets can be created
+// concurrently.
+func createTarballs(t tarballTarget) error {
 	var (
-		prefix = config.Tarball.Prefix
-		tmpDir = ".release"
-		goos   = envOr("GOOS", goos)
-		goarch = envOr("GOARCH", goarch)
-		name   = fmt.Sprintf("%s-%s.%s-%s", projInfo.Name, projInfo.Version, goos, goarch)
+		name     = fmt.Sprintf("%s-%s.%s-%s", projInfo.Name, projInfo.Version, t.goos, t.goarch)
+		platform = t.goos + "/" + t.goarch
 
 		binaries = config.Build.Binaries
 		ext      string
 	)
 
-	if goos == "windows" {
+	if t.goos == "windows" {
 		ext = ".exe"
 	}
 
-	dir := filepath.Join(tmpDir, name)
+	formats, err := archiveFormats(config.Tarball.Formats, platform)
+	if err != nil {
+		return err
+	}
 
-	if err := os.MkdirAll(dir, 0o777); err != nil {
-		fatal(fmt.Errorf("Failed to create directory: %w", err))
+	sources, err := tarballSources(config.Tarball.Files, config.Tarball.Exclude, platform)
+	if err != nil {
+		return fmt.Errorf("Failed to resolve tarball files: %w", err)
+	}
+	var (
+		debugSources []archiveSource
+		tmpDir       string
+	)
+	if config.Tarball.DebugInfo {
+		tmpDir, err = os.MkdirTemp("", "promu-debuginfo")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmpDir)
+	}
+	for _, binary := range binaries {
+		file := filepath.Join(t.binaries, fmt.Sprintf("%s%s", binary.Name, ext))
+		if config.Tarball.DebugInfo {
+			stripped, debug, err := splitDebugInfo(file, tmpDir)
+			if err != nil {
+				return err
+			}
+			file = stripped
+			debugSources = append(debugSources, fileSource(debug))
+		}
+		sources = append(sources, fileSource(file))
 	}
-	defer sh.RunCommand("rm", "-rf", tmpDir)
 
-	projectFiles := config.Tarball.Files
-	for _, file := range projectFiles {
-		sh.RunCommand("cp", "-a", file, dir)
+	// Use a fixed modification time for reproducible builds.
+	var modTime time.Time
+	if isReproducibleBuild() {
+		modTime = getBuildDate(os.Getenv(sourceDateEpoch))
+	}
+	entries, err := collectArchiveEntries(name, sources, modTime)
+	if err != nil {
+		return fmt.Errorf("Failed to collect tarball files: %w", err)
 	}
 
-	for _, binary := range binaries {
-		binaryName := fmt.Sprintf("%s%s", binary.Name, ext)
-		sh.RunCommand("cp", "-a", filepath.Join(binariesLocation, binaryName), dir)
+	if t.goos == "linux" {
+		notes, err := installNotes(binaries)
+		if err != nil {
+			return err
+		}
+		if notes != "" {
+			entries = append(entries, dataEntry(path.Join(name, installNotesFilename), []byte(notes), entries[0].modTime))
+		}
+	}
+
+	if err := os.MkdirAll(t.prefix, 0o777); err != nil {
+		return err
+	}
+	if err := writeArchives(t.prefix, name, name, entries, formats, config.Tarball.Manifest); err != nil {
+		return err
+	}
+
+	if len(debugSources) > 0 {
+		// The debug files have the same root directory as the binaries so
+		// that extracting both archives puts them side by side, hence no
+		// manifest which would replace the one of the binaries.
+		entries, err := collectArchiveEntries(name, debugSources, modTime)
+		if err != nil {
+			return fmt.Errorf("Failed to collect debug info files: %w", err)
+		}
+		if err := writeArchives(t.prefix, name+debugInfoSuffix, name, entries, formats, false); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	if !fileExists(prefix) {
-		os.Mkdir(prefix, 0o777)
+// writeArchives writes the entries, whose root directory is dir, to the
+// <name>.<extension> archives of the given formats in the prefix directory.
+// If manifest is true, a manifest of the entries is added to the archives.
+func writeArchives(prefix, name, dir string, entries []archiveEntry, formats []string, manifest bool) error {
+	if manifest {
+		content, err := archiveManifest(dir, entries)
+		if err != nil {
+			return fmt.Errorf("Failed to create manifest: %w", err)
+		}
+		entries = append(entries, dataEntry(path.Join(dir, manifestFilename), content, entries[0].modTime))
 	}
 
-	tar := fmt.Sprintf("%s.tar.gz", name)
-	fmt.Println(" >  ", tar)
-	sh.RunCommand("tar", "zcf", filepath.Join(prefix, tar), "-C", tmpDir, name)
+	for _, format := range formats {
+		var archive string
+		switch format {
+		case archiveFormatTar:
+			ext, err := tarballExtension(config.Tarball.Compression)
+			if err != nil {
+				return err
+			}
+			archive = name + ext
+			fmt.Println(" >  ", archive)
+			err = createArchive(filepath.Join(prefix, archive), func(w io.Writer) error {
+				return writeTarball(w, entries, config.Tarball.Compression)
+			})
+			if err != nil {
+				return fmt.Errorf("Could not create tarball: %w", err)
+			}
+		case archiveFormatZIP:
+			archive = name + ".zip"
+			fmt.Println(" >  ", archive)
+			err := createArchive(filepath.Join(prefix, archive), func(w io.Writer) error {
+				return writeZIP(w, entries)
+			})
+			if err != nil {
+				return fmt.Errorf("Could not create ZIP archive: %w", err)
+			}
+		}
 
-	// Windows systems don't have tar available by default. Produce archives in
-	// the common zip format additionally.
-	if goos == "windows" {
-		archive := name + ".zip"
-		fmt.Println(" >  ", archive)
-		if err := createZIP(filepath.Join(prefix, archive), dir); err != nil {
-			fatal(fmt.Errorf("Could not create ZIP archive: %w", err))
+		if config.Tarball.Manifest {
+			if err := writeChecksumFile(prefix, archive); err != nil {
+				return fmt.Errorf("Could not create checksum file: %w", err)
+			}
 		}
 	}
+	return nil
 }
 
-// createZIP creates a ZIP archive at the given path containing the specified
-// directory.
-func createZIP(path, dir string) error {
-	f, err := os.Create(path)
+// writeChecksumFile writes the SHA256 checksum of the file in the directory
+// to a sidecar <file>.sha256 file, in the format of sha256sum.
+func writeChecksumFile(dir, file string) error {
+	checksum, err := sha256File(os.DirFS(dir), file)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-
-	w := zip.NewWriter(f)
-	defer w.Close()
+	return os.WriteFile(filepath.Join(dir, file+".sha256"), []byte(fmt.Sprintf("%x  %s\n", checksum, file)), 0o644)
+}
 
-	prefix := filepath.Dir(dir)
-	walker := func(path string, info os.FileInfo, err error) error {
+// tarballSources returns the archive sources for the files of the tarball
+// configuration. Files are relative to the repository root. Plain paths are
+// copied to the root directory of the tarball while paths matching glob
+// patterns keep their location in the repository. Paths matching any of the
+// exclude patterns and files restricted to other platforms are skipped.
+func tarballSources(files []TarballFile, exclude []string, platform string) ([]archiveSource, error) {
+	var (
+		repo     = newDirFS(".")
+		sources  []archiveSource
+		seen     = map[string]struct{}{}
+		excluded = func(name string) (bool, error) {
+			return matchAnyGlob(exclude, name)
+		}
+	)
+	for _, f := range files {
+		match, err := platformFilter(f.Platforms)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("%s: %w", f.Path, err)
 		}
-		if info.IsDir() {
-			return nil
+		if !match(platform) {
+			continue
 		}
 
-		r, err := os.Open(path)
-		if err != nil {
-			return err
+		file := f.Path
+		if !isGlob(file) {
+			name := filepath.ToSlash(filepath.Clean(file))
+			if !fs.ValidPath(name) {
+				// Files outside of the repository can't be excluded.
+				sources = append(sources, fileSource(file))
+				continue
+			}
+			sources = append(sources, archiveSource{
+				fsys:    repo,
+				name:    name,
+				dest:    path.Base(name),
+				exclude: excluded,
+			})
+			continue
 		}
-		defer r.Close()
 
-		name := strings.TrimLeft(strings.TrimPrefix(path, prefix), "/")
-		f, err := w.Create(name)
+		matched := false
+		err = fs.WalkDir(repo, globPrefix(file), func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return fs.SkipDir
+				}
+				return err
+			}
+			ok, err := matchGlob(file, p)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				// Don't descend into directories deeper than the pattern.
+				if d.IsDir() && p != "." && !strings.Contains(file, "**") && strings.Count(p, "/") >= strings.Count(file, "/") {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			matched = true
+			if _, ok := seen[p]; !ok {
+				seen[p] = struct{}{}
+				sources = append(sources, archiveSource{
+					fsys:    repo,
+					name:    p,
+					exclude: excluded,
+				})
+			}
+			// The whole directory is copied.
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		})
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if !matched {
+			return nil, fmt.Errorf("pattern %s doesn't match any file", file)
 		}
-
-		_, err = io.Copy(f, r)
-		return err
 	}
-	return filepath.Walk(dir, walker)
+	return sources, nil
 }
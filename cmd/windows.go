@@ -0,0 +1,135 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"image"
+	_ "image/png" // Register the PNG decoder for icons.
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tc-hib/winres"
+	"github.com/tc-hib/winres/version"
+
+	"github.com/prometheus/promu/pkg/repository"
+)
+
+// windowsResourcesEnabled returns true if the windows section is configured.
+func windowsResourcesEnabled() bool {
+	w := config.Windows
+	return w.Company != "" || w.Product != "" || w.Description != "" || w.Copyright != "" || w.Icon != ""
+}
+
+// writeWindowsResources generates a syso file holding the version information
+// and the icon of the binary in its package directory, so that it gets linked
+// by the go tool. It returns the path of the file which should be removed once
+// the binary is built.
+func writeWindowsResources(binary Binary, goarch string, info repository.Info) (string, error) {
+	arch := winres.Arch(goarch)
+	switch arch {
+	case winres.ArchI386, winres.ArchAMD64, winres.ArchARM, winres.ArchARM64:
+	default:
+		return "", fmt.Errorf("windows resources aren't supported for %s", goarch)
+	}
+	rs, err := windowsResources(binary, info)
+	if err != nil {
+		return "", err
+	}
+
+	// The file left by an interrupted build is replaced.
+	syso := filepath.Join(filepath.FromSlash(binary.Path), fmt.Sprintf("zz_promu_windows_%s.syso", goarch))
+	f, err := os.Create(syso)
+	if err != nil {
+		return "", err
+	}
+	if err := rs.WriteObject(f, arch); err != nil {
+		f.Close()
+		os.Remove(syso)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(syso)
+		return "", err
+	}
+	return syso, nil
+}
+
+// windowsResources returns the version information and the icon of the
+// binary.
+func windowsResources(binary Binary, info repository.Info) (*winres.ResourceSet, error) {
+	var (
+		rs  = &winres.ResourceSet{}
+		w   = config.Windows
+		exe = binary.Name + ".exe"
+		vi  = version.Info{}
+	)
+	vi.SetFileVersion(info.Version)
+	vi.SetProductVersion(info.Version)
+	for k, v := range map[string]string{
+		version.CompanyName:      w.Company,
+		version.ProductName:      orDefault(w.Product, info.Name),
+		version.FileDescription:  orDefault(w.Description, binary.Name),
+		version.LegalCopyright:   w.Copyright,
+		version.InternalName:     binary.Name,
+		version.OriginalFilename: exe,
+	} {
+		if v == "" {
+			continue
+		}
+		if err := vi.Set(version.LangDefault, k, v); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", k, err)
+		}
+	}
+	rs.SetVersionInfo(vi)
+
+	if w.Icon != "" {
+		icon, err := loadIcon(w.Icon)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load icon %s: %w", w.Icon, err)
+		}
+		if err := rs.SetIcon(winres.Name("APPICON"), icon); err != nil {
+			return nil, err
+		}
+	}
+	return rs, nil
+}
+
+// loadIcon loads an icon from an ICO file or from any image that is resized
+// to the common icon sizes.
+func loadIcon(path string) (*winres.Icon, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".ico") {
+		return winres.LoadICO(f)
+	}
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	return winres.NewIconFromResizedImage(img, nil)
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,168 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tc-hib/winres"
+	"github.com/tc-hib/winres/version"
+
+	"github.com/prometheus/promu/pkg/repository"
+)
+
+func TestWindowsResources(t *testing.T) {
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+	config.Windows.Company = "Prometheus"
+	config.Windows.Copyright = "Copyright The Prometheus Authors"
+
+	rs, err := windowsResources(Binary{Name: "foo", Path: "cmd/foo"}, repository.Info{Name: "bar", Version: "1.2.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vi, err := version.FromBytes(rs.Get(winres.RT_VERSION, winres.ID(1), version.LangDefault))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := vi.Table().GetMainTranslation()
+	for k, exp := range map[string]string{
+		version.CompanyName:      "Prometheus",
+		version.ProductName:      "bar",
+		version.FileDescription:  "foo",
+		version.LegalCopyright:   "Copyright The Prometheus Authors",
+		version.InternalName:     "foo",
+		version.OriginalFilename: "foo.exe",
+		version.FileVersion:      "1.2.3",
+		version.ProductVersion:   "1.2.3",
+	} {
+		if got[k] != exp {
+			t.Errorf("expected %s %q, got %q", k, exp, got[k])
+		}
+	}
+	if icon, _ := rs.GetIcon(winres.Name("APPICON")); icon != nil {
+		t.Error("expected no icon")
+	}
+}
+
+func TestWriteWindowsResources(t *testing.T) {
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+	config.Windows.Company = "Prometheus"
+
+	dir := t.TempDir()
+	// The file left by an interrupted build is replaced.
+	left := filepath.Join(dir, "zz_promu_windows_amd64.syso")
+	if err := os.WriteFile(left, []byte("left"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	syso, err := writeWindowsResources(Binary{Name: "foo", Path: filepath.ToSlash(dir)}, "amd64", repository.Info{Version: "1.2.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if syso != left {
+		t.Fatalf("expected %s, got %s", left, syso)
+	}
+	b, err := os.ReadFile(syso)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The COFF header starts with the machine type of amd64.
+	if !bytes.HasPrefix(b, []byte{0x64, 0x86}) {
+		t.Fatalf("expected a COFF object of amd64, got %q", b)
+	}
+
+	if _, err := writeWindowsResources(Binary{Name: "foo", Path: filepath.ToSlash(dir)}, "mips", repository.Info{}); err == nil {
+		t.Fatal("expected error but got nil")
+	}
+}
+
+func TestBuildBinaryWindowsResources(t *testing.T) {
+	fakeCommand(t, "go", "exit 1")
+	defer func(c *Config) { config = c }(config)
+	config = NewConfig()
+	config.Windows.Company = "Prometheus"
+	defer func(o, a string) { goos, goarch = o, a }(goos, goarch)
+	goos, goarch = "windows", "amd64"
+
+	dir := t.TempDir()
+	if err := buildBinary(buildOptions{prefix: dir, stdout: io.Discard}, Binary{Name: "foo", Path: filepath.ToSlash(dir)}); err == nil {
+		t.Fatal("expected error but got nil")
+	}
+	// The resources are removed even though the build failed.
+	matches, err := filepath.Glob(filepath.Join(dir, "*.syso"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) > 0 {
+		t.Fatalf("expected no syso file, got %v", matches)
+	}
+}
+
+func TestLoadIcon(t *testing.T) {
+	dir := t.TempDir()
+	var b bytes.Buffer
+	if err := png.Encode(&b, image.NewRGBA(image.Rect(0, 0, 64, 64))); err != nil {
+		t.Fatal(err)
+	}
+	pngPath := filepath.Join(dir, "icon.png")
+	if err := os.WriteFile(pngPath, b.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	icon, err := loadIcon(pngPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.Reset()
+	if err := icon.SaveICO(&b); err != nil {
+		t.Fatal(err)
+	}
+	icoPath := filepath.Join(dir, "icon.ICO")
+	if err := os.WriteFile(icoPath, b.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadIcon(icoPath); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "icon.txt"), []byte("not an image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{filepath.Join(dir, "missing.png"), filepath.Join(dir, "icon.txt")} {
+		if _, err := loadIcon(path); err == nil {
+			t.Fatalf("%s: expected error but got nil", path)
+		}
+	}
+}
+
+func TestOrDefault(t *testing.T) {
+	for _, tc := range []struct {
+		s, def, exp string
+	}{
+		{s: "foo", def: "bar", exp: "foo"},
+		{s: "", def: "bar", exp: "bar"},
+		{s: "", def: "", exp: ""},
+	} {
+		if got := orDefault(tc.s, tc.def); got != tc.exp {
+			t.Errorf("orDefault(%q, %q): expected %q, got %q", tc.s, tc.def, tc.exp, got)
+		}
+	}
+}
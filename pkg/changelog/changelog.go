@@ -32,42 +32,88 @@ const (
 	kindFeature
 	kindEnhancement
 	kindBugfix
+	kindSecurity
+	kindDeprecation
 )
 
+var kindNames = map[Kind]string{
+	kindChange:      "CHANGE",
+	kindFeature:     "FEATURE",
+	kindEnhancement: "ENHANCEMENT",
+	kindBugfix:      "BUGFIX",
+	kindSecurity:    "SECURITY",
+	kindDeprecation: "DEPRECATION",
+}
+
 func (k Kind) String() string {
-	switch k {
-	case kindChange:
-		return "CHANGE"
-	case kindFeature:
-		return "FEATURE"
-	case kindEnhancement:
-		return "ENHANCEMENT"
-	case kindBugfix:
-		return "BUGFIX"
+	return kindNames[k]
+}
+
+// KindOrder is an order of the changes by kinds. The nil order is the
+// default one: SECURITY, CHANGE, DEPRECATION, FEATURE, ENHANCEMENT and
+// BUGFIX.
+type KindOrder []Kind
+
+// defaultKindOrder is the default order of the changes by kinds.
+var defaultKindOrder = KindOrder{kindSecurity, kindChange, kindDeprecation, kindFeature, kindEnhancement, kindBugfix}
+
+// ParseKindOrder returns the order of the changes by kinds in which the
+// given kinds come first, in the given order, followed by the others in the
+// default order.
+func ParseKindOrder(names []string) (KindOrder, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	var order KindOrder
+	seen := map[Kind]bool{}
+	for _, name := range names {
+		kinds := ParseKinds(strings.ToUpper(name))
+		if len(kinds) != 1 || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("unknown kind %q", name)
+		}
+		if seen[kinds[0]] {
+			return nil, fmt.Errorf("duplicate kind %q", name)
+		}
+		seen[kinds[0]] = true
+		order = append(order, kinds[0])
+	}
+	for _, k := range defaultKindOrder {
+		if !seen[k] {
+			order = append(order, k)
+		}
+	}
+	return order, nil
+}
+
+// rank returns the rank of the kind in the order.
+func (o KindOrder) rank(k Kind) int {
+	if o == nil {
+		o = defaultKindOrder
+	}
+	for i, ok := range o {
+		if ok == k {
+			return i
+		}
 	}
-	return ""
+	return len(o)
 }
 
-// Kinds is a list of Kind which implements sort.Interface.
+// Kinds is a list of Kind which implements sort.Interface, in the default
+// order.
 type Kinds []Kind
 
 func (k Kinds) Len() int           { return len(k) }
-func (k Kinds) Less(i, j int) bool { return k[i] < k[j] }
+func (k Kinds) Less(i, j int) bool { return defaultKindOrder.rank(k[i]) < defaultKindOrder.rank(k[j]) }
 func (k Kinds) Swap(i, j int)      { k[i], k[j] = k[j], k[i] }
 
 // ParseKinds converts a slash-separated list of Kind to a list of Kind.
 func ParseKinds(s string) Kinds {
 	m := make(map[Kind]struct{})
-	for _, k := range strings.Split(s, "/") {
-		switch k {
-		case "CHANGE":
-			m[kindChange] = struct{}{}
-		case "FEATURE":
-			m[kindFeature] = struct{}{}
-		case "ENHANCEMENT":
-			m[kindEnhancement] = struct{}{}
-		case "BUGFIX":
-			m[kindBugfix] = struct{}{}
+	for _, name := range strings.Split(s, "/") {
+		for k, n := range kindNames {
+			if n == name {
+				m[k] = struct{}{}
+			}
 		}
 	}
 
@@ -91,47 +137,75 @@ func (k Kinds) String() string {
 type Change struct {
 	Text  string
 	Kinds Kinds
+	// References are the numbers of the pull requests and issues referenced
+	// at the end of the text, e.g. "#1234".
+	References []int
+	// Authors are the handles of the authors credited at the end of the
+	// text, e.g. "@alice", without the "@".
+	Authors []string
 }
 
 type Changes []Change
 
-func (c Changes) Sorted() error {
-	less := func(k1, k2 Kinds) bool {
-		if len(k1) == 0 {
-			return len(k2) == 0
-		}
-		if len(k2) == 0 {
-			return true
-		}
-
-		n := len(k1)
-		if len(k1) > len(k2) {
-			n = len(k2)
-		}
-		for j := 0; j < n; j++ {
-			if k1[j] == k2[j] {
-				continue
-			}
-			return k1[j] < k2[j]
-		}
-		return len(k1) <= len(k2)
-	}
-
+// Sorted returns an error if the changes aren't ordered by kinds in the
+// order.
+func (c Changes) Sorted(order KindOrder) error {
 	for i := 0; i < len(c)-1; i++ {
 		k1, k2 := c[i].Kinds, c[i+1].Kinds
-		if !less(k1, k2) {
+		if !kindsOrdered(order, k1, k2) {
 			return fmt.Errorf("%q should be after %q", c[i].Text, c[i+1].Text)
 		}
 	}
 	return nil
 }
 
+// Sort sorts the changes by kinds in the order, preserving the order of
+// changes with the same kinds.
+func (c Changes) Sort(order KindOrder) {
+	sort.SliceStable(c, func(i, j int) bool {
+		return !kindsOrdered(order, c[j].Kinds, c[i].Kinds)
+	})
+}
+
+// kindsOrdered reports whether a change with kinds k1 can be listed before a
+// change with kinds k2 in the order. The kinds of each change are compared
+// in the order too. Changes without kind are listed last.
+func kindsOrdered(order KindOrder, k1, k2 Kinds) bool {
+	if len(k1) == 0 {
+		return len(k2) == 0
+	}
+	if len(k2) == 0 {
+		return true
+	}
+
+	k1, k2 = order.sorted(k1), order.sorted(k2)
+	n := len(k1)
+	if len(k1) > len(k2) {
+		n = len(k2)
+	}
+	for j := 0; j < n; j++ {
+		if k1[j] == k2[j] {
+			continue
+		}
+		return order.rank(k1[j]) < order.rank(k2[j])
+	}
+	return len(k1) <= len(k2)
+}
+
+// sorted returns a copy of the kinds sorted in the order.
+func (o KindOrder) sorted(kinds Kinds) Kinds {
+	sorted := append(Kinds(nil), kinds...)
+	sort.SliceStable(sorted, func(i, j int) bool { return o.rank(sorted[i]) < o.rank(sorted[j]) })
+	return sorted
+}
+
 // Entry represents an entry in the changelog.
 type Entry struct {
 	Version string
 	Date    time.Time
 	Changes Changes
 	Text    string
+	Format  Format
 }
 
 const dateFormat = "2006-01-02"
@@ -141,16 +215,53 @@ func (c Entry) Name() string {
 	return fmt.Sprintf("%s / %s", c.Version, c.Date.Format(dateFormat))
 }
 
+// reUnreleased matches the header of the section of the unreleased changes,
+// e.g. "## Unreleased", "## [Unreleased]" or "## main / unreleased".
+var reUnreleased = regexp.MustCompile(`(?i)^## (?:unreleased|\[unreleased\]|(?:main|master) / unreleased)\s*$`)
+
+// reHeader matches the header of an entry, e.g. "## 1.0.0 / 2024-01-02", the
+// submatches being its version and date.
+var reHeader = regexp.MustCompile(`^#{1,2} (\S+) / (\d{4}-\d{2}-\d{2})`)
+
+// headerMatcher matches the header of an entry, and returns its date, empty
+// for the section of the unreleased changes.
+type headerMatcher func(line string) (date string, ok bool)
+
+// matchUnreleased matches the header of the section of the unreleased
+// changes.
+func matchUnreleased(line string) (string, bool) {
+	return "", reUnreleased.MatchString(line)
+}
+
+// IsUnreleased returns whether the line is the header of the section of the
+// unreleased changes.
+func IsUnreleased(line string) bool {
+	return reUnreleased.MatchString(line)
+}
+
 // ReadEntry reads the entry for the given version from the changelog file.
 // It returns an error if the version is not found.
 func ReadEntry(r io.Reader, version string) (*Entry, error) {
-	reHeader, err := regexp.Compile(fmt.Sprintf(`^#{1,2} %s / (\d{4}-\d{2}-\d{2})`, regexp.QuoteMeta(version)))
+	entry, err := readEntry(r, Prometheus.matchHeader(version), version)
 	if err != nil {
 		return nil, err
 	}
+	if entry == nil {
+		return nil, fmt.Errorf(
+			"unable to locate release information in changelog for version %q, expected format: %q",
+			version,
+			reHeader)
+	}
+	return entry, nil
+}
+
+// readEntry reads the entry whose header matches. It returns nil if there is
+// none.
+func readEntry(r io.Reader, match headerMatcher, version string) (*Entry, error) {
 	reChange := regexp.MustCompile(`^\* \[([^\]]+)\]`)
 
 	var (
+		found   bool
 		reading bool
 		lines   []string
 
@@ -159,11 +270,14 @@ func ReadEntry(r io.Reader, version string) (*Entry, error) {
 	)
 	for (len(lines) == 0 || reading) && scanner.Scan() {
 		line := scanner.Text()
-		m := reHeader.FindStringSubmatch(line)
+		date, ok := match(line)
 		switch {
-		case len(m) > 0:
-			reading = true
-			t, err := time.Parse(dateFormat, m[1])
+		case ok:
+			found, reading = true, true
+			if date == "" {
+				continue
+			}
+			t, err := time.Parse(dateFormat, date)
 			if err != nil {
 				return nil, fmt.Errorf("invalid changelog date: %w", err)
 			}
@@ -176,19 +290,15 @@ func ReadEntry(r io.Reader, version string) (*Entry, error) {
 			}
 			m := reChange.FindStringSubmatch(line)
 			if len(m) > 1 {
-				entry.Changes = append(entry.Changes, Change{Text: line, Kinds: ParseKinds(m[1])})
+				entry.Changes = append(entry.Changes, NewChange(line, ParseKinds(m[1])))
 			}
 			lines = append(lines, line)
 		}
 	}
 
-	if entry.Date.IsZero() {
-		return nil, fmt.Errorf(
-			"unable to locate release information in changelog for version %q, expected format: %q",
-			version,
-			reHeader)
+	if !found {
+		return nil, nil
 	}
-
 	entry.Text = strings.Join(lines, "\n")
 	return &entry, nil
 }
@@ -16,7 +16,10 @@ package changelog
 
 import (
 	"bytes"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -266,6 +269,10 @@ func TestKinds(t *testing.T) {
 			in:  "BUGFIX/INVALID",
 			exp: Kinds{kindBugfix},
 		},
+		{
+			in:  "DEPRECATION/SECURITY/FEATURE",
+			exp: Kinds{kindSecurity, kindDeprecation, kindFeature},
+		},
 		{
 			in: "INVALID",
 		},
@@ -279,6 +286,56 @@ func TestKinds(t *testing.T) {
 	}
 }
 
+func TestParseKindOrder(t *testing.T) {
+	changes := Changes{
+		{Text: "* [BUGFIX] Some fix.", Kinds: Kinds{kindBugfix}},
+		{Text: "* [SECURITY] Some security fix.", Kinds: Kinds{kindSecurity}},
+		{Text: "* [CHANGE] Some change.", Kinds: Kinds{kindChange}},
+		{Text: "* [DEPRECATION] Some deprecation.", Kinds: Kinds{kindDeprecation}},
+	}
+	for _, tc := range []struct {
+		order []string
+		exp   []string
+		err   bool
+	}{
+		{
+			exp: []string{"* [SECURITY] Some security fix.", "* [CHANGE] Some change.", "* [DEPRECATION] Some deprecation.", "* [BUGFIX] Some fix."},
+		},
+		{
+			order: []string{"change", "BUGFIX"},
+			exp:   []string{"* [CHANGE] Some change.", "* [BUGFIX] Some fix.", "* [SECURITY] Some security fix.", "* [DEPRECATION] Some deprecation."},
+		},
+		{order: []string{"CHANGE", "CHANGE"}, err: true},
+		{order: []string{"CHANGE/BUGFIX"}, err: true},
+		{order: []string{"IMPROVEMENT"}, err: true},
+	} {
+		t.Run(strings.Join(tc.order, ","), func(t *testing.T) {
+			order, err := ParseKindOrder(tc.order)
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			sorted := append(Changes(nil), changes...)
+			sorted.Sort(order)
+			var got []string
+			for _, c := range sorted {
+				got = append(got, c.Text)
+			}
+			if !reflect.DeepEqual(tc.exp, got) {
+				t.Fatalf("expected:\n%v\ngot:\n%v", tc.exp, got)
+			}
+			if err := sorted.Sorted(order); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
 func TestChangesSorted(t *testing.T) {
 	for _, tc := range []struct {
 		in Changes
@@ -434,7 +491,7 @@ func TestChangesSorted(t *testing.T) {
 		},
 	} {
 		t.Run("", func(t *testing.T) {
-			err := tc.in.Sorted()
+			err := tc.in.Sorted(nil)
 			if tc.err {
 				if err == nil {
 					t.Fatal("expected error but got nil")
@@ -447,3 +504,717 @@ func TestChangesSorted(t *testing.T) {
 		})
 	}
 }
+
+func TestFragment(t *testing.T) {
+	for _, tc := range []struct {
+		f   Fragment
+		exp string
+		err bool
+	}{
+		{
+			f:   Fragment{Kind: "FEATURE", Description: "Add a new feature.", PR: 1234},
+			exp: "* [FEATURE] Add a new feature. #1234",
+		},
+		{
+			f:   Fragment{Kind: "bugfix/enhancement", Description: "Fix\nsomething."},
+			exp: "* [ENHANCEMENT/BUGFIX] Fix something.",
+		},
+		{
+			f:   Fragment{Kind: "FEATURE"},
+			err: true,
+		},
+		{
+			f:   Fragment{Kind: "IMPROVEMENT", Description: "Unknown kind."},
+			err: true,
+		},
+	} {
+		err := tc.f.Validate()
+		if tc.err {
+			if err == nil {
+				t.Fatalf("%v: expected error, got none", tc.f)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tc.f, err)
+		}
+		if got := tc.f.Change().Text; got != tc.exp {
+			t.Fatalf("expected %q, got %q", tc.exp, got)
+		}
+	}
+}
+
+func TestNewEntry(t *testing.T) {
+	date := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	entry := NewEntry("1.0.0", date, []Fragment{
+		{Kind: "BUGFIX", Description: "Some fix."},
+		{Kind: "FEATURE", Description: "Some feature."},
+		{Kind: "CHANGE", Description: "Some change."},
+		{Kind: "FEATURE", Description: "Another feature."},
+	}, nil)
+
+	exp := `## 1.0.0 / 2024-01-02
+
+* [CHANGE] Some change.
+* [FEATURE] Some feature.
+* [FEATURE] Another feature.
+* [BUGFIX] Some fix.
+`
+	if got := entry.String(); got != exp {
+		t.Fatalf("expected:\n%s\ngot:\n%s", exp, got)
+	}
+	if err := entry.Changes.Sorted(nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKeepAChangelogReadEntry(t *testing.T) {
+	in := `# Changelog
+
+## [Unreleased]
+
+### Added
+
+- Unreleased feature.
+
+## [1.0.0] - 2024-01-02
+
+### Added
+
+- Some feature.
+- Another feature.
+
+### Fixed
+
+- Some fix.
+
+## [0.9.0] - 2023-12-01
+
+### Removed
+
+- Some removal.
+
+[1.0.0]: https://github.com/prometheus/foo/compare/v0.9.0...v1.0.0
+[0.9.0]: https://github.com/prometheus/foo/releases/tag/v0.9.0
+`
+	for _, tc := range []struct {
+		version string
+		exp     Entry
+		err     bool
+	}{
+		{version: "1.1.0", err: true},
+		{
+			version: "1.0.0",
+			exp: Entry{
+				Version: "1.0.0",
+				Date:    time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+				Changes: Changes{
+					{Text: "- Some feature.", Kinds: Kinds{kindFeature}},
+					{Text: "- Another feature.", Kinds: Kinds{kindFeature}},
+					{Text: "- Some fix.", Kinds: Kinds{kindBugfix}},
+				},
+				Text:   "### Added\n\n- Some feature.\n- Another feature.\n\n### Fixed\n\n- Some fix.",
+				Format: KeepAChangelog,
+			},
+		},
+		{
+			// The link reference definitions aren't part of the last entry.
+			version: "0.9.0",
+			exp: Entry{
+				Version: "0.9.0",
+				Date:    time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC),
+				Changes: Changes{{Text: "- Some removal.", Kinds: Kinds{kindChange}}},
+				Text:    "### Removed\n\n- Some removal.",
+				Format:  KeepAChangelog,
+			},
+		},
+	} {
+		t.Run(tc.version, func(t *testing.T) {
+			entry, err := KeepAChangelog.ReadEntry(bytes.NewBufferString(in), tc.version)
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected error but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error but got: %v", err)
+			}
+			if !reflect.DeepEqual(tc.exp, *entry) {
+				t.Fatalf("expected:\n%#v\ngot:\n%#v", tc.exp, *entry)
+			}
+		})
+	}
+
+	for _, in := range []string{
+		"## [1.0.0] - 2024-01-02\n\n### Improved\n\n- Unknown section.\n",
+		"## [1.0.0] - 2024-01-02\n\n- No section.\n",
+	} {
+		if _, err := KeepAChangelog.ReadEntry(bytes.NewBufferString(in), "1.0.0"); err == nil {
+			t.Fatalf("expected error for %q but got nil", in)
+		}
+	}
+}
+
+func TestKeepAChangelogNewEntry(t *testing.T) {
+	date := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	entry := KeepAChangelog.NewEntry("1.0.0", date, []Fragment{
+		{Kind: "BUGFIX", Description: "Some fix.", PR: 12},
+		{Kind: "FEATURE", Description: "Some feature."},
+		{Kind: "ENHANCEMENT", Description: "Some enhancement."},
+		{Kind: "CHANGE", Description: "Some change."},
+	}, nil)
+
+	exp := `## [1.0.0] - 2024-01-02
+
+### Added
+
+- Some feature.
+
+### Changed
+
+- Some change.
+- Some enhancement.
+
+### Fixed
+
+- Some fix. #12
+`
+	if got := entry.String(); got != exp {
+		t.Fatalf("expected:\n%s\ngot:\n%s", exp, got)
+	}
+
+	// The entry reads back the same.
+	read, err := KeepAChangelog.ReadEntry(bytes.NewBufferString(exp), "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Text != entry.Text {
+		t.Fatalf("expected text:\n%s\ngot:\n%s", entry.Text, read.Text)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, exp := range map[string]Format{
+		"":               Prometheus,
+		"prometheus":     Prometheus,
+		"keepachangelog": KeepAChangelog,
+	} {
+		f, err := ParseFormat(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f != exp {
+			t.Fatalf("expected %s for %q, got %s", exp, in, f)
+		}
+	}
+	if _, err := ParseFormat("towncrier"); err == nil {
+		t.Fatal("expected error but got nil")
+	}
+}
+
+func TestReadUnreleased(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		format Format
+		in     string
+		exp    *Entry
+	}{
+		{
+			name: "none",
+			in:   "## 1.0.0 / 2024-01-02\n\n* [BUGFIX] Some fix.\n",
+		},
+		{
+			name: "prometheus",
+			in:   "# Changelog\n\n## main / unreleased\n\n* [FEATURE] Some feature.\n\n## 1.0.0 / 2024-01-02\n\n* [BUGFIX] Some fix.\n",
+			exp: &Entry{
+				Changes: Changes{{Text: "* [FEATURE] Some feature.", Kinds: Kinds{kindFeature}}},
+				Text:    "* [FEATURE] Some feature.\n",
+			},
+		},
+		{
+			name:   "keepachangelog",
+			format: KeepAChangelog,
+			in:     "## [Unreleased]\n\n### Fixed\n\n- Some fix.\n\n## [1.0.0] - 2024-01-02\n\n### Added\n\n- Some feature.\n",
+			exp: &Entry{
+				Changes: Changes{{Text: "- Some fix.", Kinds: Kinds{kindBugfix}}},
+				Text:    "### Fixed\n\n- Some fix.",
+				Format:  KeepAChangelog,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			entry, err := tc.format.ReadUnreleased(bytes.NewBufferString(tc.in))
+			if err != nil {
+				t.Fatalf("expected no error but got: %v", err)
+			}
+			if !reflect.DeepEqual(tc.exp, entry) {
+				t.Fatalf("expected:\n%#v\ngot:\n%#v", tc.exp, entry)
+			}
+		})
+	}
+}
+
+func TestWriteFragment(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), FragmentsDir)
+	for _, tc := range []struct {
+		f       Fragment
+		expFile string
+		err     bool
+	}{
+		{f: Fragment{Kind: "bugfix/enhancement", Description: "Fix\nsomething.", PR: 12}, expFile: "12.yml"},
+		{f: Fragment{Kind: "FEATURE", Description: "Add the `foo` flag!"}, expFile: "add-the-foo-flag.yml"},
+		// The fragment of a pull request isn't overwritten.
+		{f: Fragment{Kind: "BUGFIX", Description: "Fix something else.", PR: 12}, err: true},
+		{f: Fragment{Kind: "IMPROVEMENT", Description: "Unknown kind."}, err: true},
+	} {
+		path, err := WriteFragment(dir, tc.f)
+		if tc.err {
+			if err == nil {
+				t.Fatalf("%v: expected error, got none", tc.f)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tc.f, err)
+		}
+		if exp := filepath.Join(dir, tc.expFile); path != exp {
+			t.Fatalf("expected %s, got %s", exp, path)
+		}
+	}
+
+	fragments, err := ReadFragments(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range fragments {
+		got = append(got, f.Change().Text)
+	}
+	exp := []string{"* [ENHANCEMENT/BUGFIX] Fix something. #12", "* [FEATURE] Add the `foo` flag!"}
+	if !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected changes %q, got %q", exp, got)
+	}
+}
+
+func TestInsertEntry(t *testing.T) {
+	date := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	entry := NewEntry("1.1.0", date, []Fragment{
+		{Kind: "FEATURE", Description: "Some feature.", PR: 2},
+	}, nil)
+
+	for _, tc := range []struct {
+		name  string
+		entry *Entry
+		in    string
+		exp   string
+	}{
+		{
+			name:  "empty",
+			entry: entry,
+			in:    "",
+			exp: `## 1.1.0 / 2024-02-01
+
+* [FEATURE] Some feature. #2
+`,
+		},
+		{
+			name:  "title",
+			entry: entry,
+			in: `# Changelog
+
+## 1.0.0 / 2024-01-02
+
+* [BUGFIX] Some fix.
+`,
+			exp: `# Changelog
+
+## 1.1.0 / 2024-02-01
+
+* [FEATURE] Some feature. #2
+
+## 1.0.0 / 2024-01-02
+
+* [BUGFIX] Some fix.
+`,
+		},
+		{
+			name:  "no title",
+			entry: entry,
+			in: `## 1.0.0 / 2024-01-02
+
+* [BUGFIX] Some fix.
+`,
+			exp: `## 1.1.0 / 2024-02-01
+
+* [FEATURE] Some feature. #2
+
+## 1.0.0 / 2024-01-02
+
+* [BUGFIX] Some fix.
+`,
+		},
+		{
+			name:  "unreleased",
+			entry: entry,
+			in: `## Unreleased
+
+## 1.0.0 / 2024-01-02
+`,
+			exp: `## Unreleased
+
+## 1.1.0 / 2024-02-01
+
+* [FEATURE] Some feature. #2
+
+## 1.0.0 / 2024-01-02
+`,
+		},
+		{
+			name:  "replaced",
+			entry: entry,
+			in: `# Changelog
+
+## 1.1.0 / 2024-01-31
+
+* [BUGFIX] Stale fix.
+
+## 1.0.0 / 2024-01-02
+
+* [BUGFIX] Some fix.
+`,
+			exp: `# Changelog
+
+## 1.1.0 / 2024-02-01
+
+* [FEATURE] Some feature. #2
+
+## 1.0.0 / 2024-01-02
+
+* [BUGFIX] Some fix.
+`,
+		},
+		{
+			name: "replaced keepachangelog",
+			entry: KeepAChangelog.NewEntry("1.1.0", date, []Fragment{
+				{Kind: "FEATURE", Description: "Some feature.", PR: 2},
+			}, nil),
+			in: `## [1.1.0] - 2024-01-31
+
+### Fixed
+
+- Stale fix.
+
+[1.1.0]: https://github.com/prometheus/foo/releases/tag/v1.1.0
+`,
+			exp: `## [1.1.0] - 2024-02-01
+
+### Added
+
+- Some feature. #2
+
+[1.1.0]: https://github.com/prometheus/foo/releases/tag/v1.1.0
+`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := string(InsertEntry([]byte(tc.in), tc.entry)); got != tc.exp {
+				t.Fatalf("expected:\n%s\ngot:\n%s", tc.exp, got)
+			}
+		})
+	}
+}
+
+func TestRemoveEntry(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		format  Format
+		version string
+		in      string
+		exp     string
+	}{
+		{
+			name:    "middle",
+			version: "1.1.0-rc.0",
+			in: `## 1.1.0-rc.1 / 2024-02-02
+
+* [BUGFIX] Some fix.
+
+## 1.1.0-rc.0 / 2024-02-01
+
+* [FEATURE] Some feature.
+
+## 1.0.0 / 2024-01-02
+
+* [FEATURE] Initial release.
+`,
+			exp: `## 1.1.0-rc.1 / 2024-02-02
+
+* [BUGFIX] Some fix.
+
+## 1.0.0 / 2024-01-02
+
+* [FEATURE] Initial release.
+`,
+		},
+		{
+			name:    "last",
+			version: "1.0.0",
+			in: `# Changelog
+
+## 1.1.0 / 2024-02-01
+
+* [FEATURE] Some feature.
+
+## 1.0.0 / 2024-01-02
+
+* [FEATURE] Initial release.
+`,
+			exp: `# Changelog
+
+## 1.1.0 / 2024-02-01
+
+* [FEATURE] Some feature.
+`,
+		},
+		{
+			name:    "missing",
+			version: "1.2.0",
+			in: `## 1.1.0 / 2024-02-01
+
+* [FEATURE] Some feature.
+`,
+			exp: `## 1.1.0 / 2024-02-01
+
+* [FEATURE] Some feature.
+`,
+		},
+		{
+			name:    "keep a changelog",
+			format:  KeepAChangelog,
+			version: "1.1.0-rc.0",
+			in: `## [1.1.0-rc.0] - 2024-02-01
+
+### Added
+
+- Some feature.
+
+## [1.0.0] - 2024-01-02
+
+### Added
+
+- Initial release.
+
+[1.1.0-rc.0]: https://github.com/prometheus/foo/releases/tag/v1.1.0-rc.0
+[1.0.0]: https://github.com/prometheus/foo/releases/tag/v1.0.0
+`,
+			exp: `## [1.0.0] - 2024-01-02
+
+### Added
+
+- Initial release.
+
+[1.0.0]: https://github.com/prometheus/foo/releases/tag/v1.0.0
+`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := string(RemoveEntry([]byte(tc.in), tc.format, tc.version)); got != tc.exp {
+				t.Fatalf("expected:\n%s\ngot:\n%s", tc.exp, got)
+			}
+		})
+	}
+}
+
+func TestWriteEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	entry := NewEntry("1.0.0", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), []Fragment{
+		{Kind: "BUGFIX", Description: "Some fix."},
+	}, nil)
+	// Writing the entry again doesn't duplicate it.
+	for i := 0; i < 2; i++ {
+		if err := WriteEntry(path, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "## 1.0.0 / 2024-01-02\n\n* [BUGFIX] Some fix.\n"; string(b) != exp {
+		t.Fatalf("expected:\n%s\ngot:\n%s", exp, b)
+	}
+}
+
+func TestNewChange(t *testing.T) {
+	for _, tc := range []struct {
+		text       string
+		references []int
+		authors    []string
+		linked     string
+	}{
+		{
+			text:   "* [FEATURE] Some feature.",
+			linked: "* [FEATURE] Some feature.",
+		},
+		{
+			text:       "* [FEATURE] Some feature. #1234",
+			references: []int{1234},
+			linked:     "* [FEATURE] Some feature. [#1234](https://github.com/prometheus/foo/pull/1234)",
+		},
+		{
+			text:       "* [BUGFIX] Fix #12 in foo. #13, #14 (@alice, @bob-2)",
+			references: []int{13, 14},
+			authors:    []string{"alice", "bob-2"},
+			linked:     "* [BUGFIX] Fix #12 in foo. [#13](https://github.com/prometheus/foo/pull/13), [#14](https://github.com/prometheus/foo/pull/14) ([@alice](https://github.com/alice), [@bob-2](https://github.com/bob-2))",
+		},
+		{
+			text:       "- Some change (#56)",
+			references: []int{56},
+			linked:     "- Some change ([#56](https://github.com/prometheus/foo/pull/56))",
+		},
+		{
+			text:   "* [CHANGE] Mention user@example.com#1.",
+			linked: "* [CHANGE] Mention user@example.com#1.",
+		},
+	} {
+		t.Run(tc.text, func(t *testing.T) {
+			c := NewChange(tc.text, nil)
+			if !reflect.DeepEqual(tc.references, c.References) {
+				t.Fatalf("expected references %v, got %v", tc.references, c.References)
+			}
+			if !reflect.DeepEqual(tc.authors, c.Authors) {
+				t.Fatalf("expected authors %q, got %q", tc.authors, c.Authors)
+			}
+			if got := c.Linkify("https://github.com/prometheus/foo/"); got != tc.linked {
+				t.Fatalf("expected %q, got %q", tc.linked, got)
+			}
+		})
+	}
+}
+
+func TestEntryLinkify(t *testing.T) {
+	in := `## 1.0.0 / 2024-01-02
+
+* [FEATURE] Some feature. #1 @alice
+  Details of #2.
+* [BUGFIX] Some fix.`
+	entry, err := ReadEntry(bytes.NewBufferString(in), "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `* [FEATURE] Some feature. [#1](https://github.com/prometheus/foo/pull/1) [@alice](https://github.com/alice)
+  Details of #2.
+* [BUGFIX] Some fix.`
+	if got := entry.Linkify("https://github.com/prometheus/foo"); got != exp {
+		t.Fatalf("expected:\n%s\ngot:\n%s", exp, got)
+	}
+}
+
+func TestReadEntries(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		format   Format
+		in       string
+		versions []string
+		err      bool
+	}{
+		{
+			name: "prometheus",
+			in: `# Changelog
+
+## Unreleased
+
+* [FEATURE] Unreleased feature.
+
+## 1.1.0 / 2024-02-01
+
+* [FEATURE] Some feature.
+
+# 1.0.0 / 2024-01-02
+
+* [BUGFIX] Some fix.
+`,
+			versions: []string{"1.1.0", "1.0.0"},
+		},
+		{
+			name:   "keepachangelog",
+			format: KeepAChangelog,
+			in: `# Changelog
+
+## [1.1.0] - 2024-02-01
+
+### Added
+
+- Some feature.
+
+## [1.0.0] - 2024-01-02
+
+### Fixed
+
+- Some fix.
+
+[1.1.0]: https://github.com/prometheus/foo/compare/v1.0.0...v1.1.0
+[1.0.0]: https://github.com/prometheus/foo/releases/tag/v1.0.0
+`,
+			versions: []string{"1.1.0", "1.0.0"},
+		},
+		{
+			// The headers read by ReadEntry are accepted.
+			name:   "yanked",
+			format: KeepAChangelog,
+			in: `## [1.1.0] - 2024-02-01 [YANKED]
+
+### Added
+
+- Some feature.
+
+## [1.0.0] - 2024-01-02
+
+### Fixed
+
+- Some fix.
+`,
+			versions: []string{"1.1.0", "1.0.0"},
+		},
+		{
+			name: "invalid header",
+			in: `## 1.1.0 / 2024-02-01
+
+## 1.0.0 (2024-01-02)
+`,
+			err: true,
+		},
+		{
+			name:   "invalid section",
+			format: KeepAChangelog,
+			in: `## [1.0.0] - 2024-01-02
+
+### Improved
+
+- Something.
+`,
+			err: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, err := tc.format.ReadEntries(bytes.NewBufferString(tc.in))
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var versions []string
+			for _, e := range entries {
+				versions = append(versions, e.Version)
+			}
+			if !reflect.DeepEqual(tc.versions, versions) {
+				t.Fatalf("expected versions %v, got %v", tc.versions, versions)
+			}
+		})
+	}
+}
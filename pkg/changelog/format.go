@@ -0,0 +1,324 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package changelog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Format is the format of a changelog.
+type Format int
+
+const (
+	// Prometheus is the format of the Prometheus projects, with
+	// "## 1.0.0 / 2024-01-02" headers and "* [KIND] Description." changes.
+	Prometheus Format = iota
+	// KeepAChangelog is the format of https://keepachangelog.com, with
+	// "## [1.0.0] - 2024-01-02" headers and changes grouped in "### Added",
+	// "### Changed", "### Deprecated", "### Removed", "### Fixed" and
+	// "### Security" sections.
+	KeepAChangelog
+)
+
+// ParseFormat returns the format of the given name, Prometheus if empty.
+func ParseFormat(s string) (Format, error) {
+	switch s {
+	case "", "prometheus":
+		return Prometheus, nil
+	case "keepachangelog":
+		return KeepAChangelog, nil
+	}
+	return 0, fmt.Errorf("unknown changelog format %q, expected prometheus or keepachangelog", s)
+}
+
+func (f Format) String() string {
+	if f == KeepAChangelog {
+		return "keepachangelog"
+	}
+	return "prometheus"
+}
+
+// keepAChangelogSections are the sections of a Keep a Changelog entry in
+// their usual order, with the kind of their changes.
+var keepAChangelogSections = []struct {
+	name string
+	kind Kind
+}{
+	{"Added", kindFeature},
+	{"Changed", kindChange},
+	{"Deprecated", kindDeprecation},
+	{"Removed", kindChange},
+	{"Fixed", kindBugfix},
+	{"Security", kindSecurity},
+}
+
+// keepAChangelogSection returns the section of the changes of the kind.
+func keepAChangelogSection(k Kind) string {
+	switch k {
+	case kindFeature:
+		return "Added"
+	case kindDeprecation:
+		return "Deprecated"
+	case kindBugfix:
+		return "Fixed"
+	case kindSecurity:
+		return "Security"
+	}
+	return "Changed"
+}
+
+// ReadEntry reads the entry for the given version from the changelog file in
+// the format. It returns an error if the version is not found.
+func (f Format) ReadEntry(r io.Reader, version string) (*Entry, error) {
+	if f != KeepAChangelog {
+		return ReadEntry(r, version)
+	}
+
+	entry, err := readKeepAChangelogEntry(r, f.matchHeader(version), version)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, fmt.Errorf(
+			"unable to locate release information in changelog for version %q, expected format: %q",
+			version,
+			f.headerRegexp())
+	}
+	return entry, nil
+}
+
+// ReadUnreleased reads the section of the unreleased changes from the
+// changelog file in the format, whose version and date are empty. It returns
+// nil if there is none.
+func (f Format) ReadUnreleased(r io.Reader) (*Entry, error) {
+	if f == KeepAChangelog {
+		return readKeepAChangelogEntry(r, matchUnreleased, "")
+	}
+	return readEntry(r, matchUnreleased, "")
+}
+
+// reKeepAChangelogHeader matches the header of a Keep a Changelog entry,
+// e.g. "## [1.0.0] - 2024-01-02", the submatches being its version and date.
+var reKeepAChangelogHeader = regexp.MustCompile(`^## \[?([^\]\s]+)\]? - (\d{4}-\d{2}-\d{2})`)
+
+// headerRegexp returns the regexp matching the headers of the entries in the
+// format, the submatches being their version and date.
+func (f Format) headerRegexp() *regexp.Regexp {
+	if f == KeepAChangelog {
+		return reKeepAChangelogHeader
+	}
+	return reHeader
+}
+
+// matchHeader returns the matcher of the header of the entry of the version
+// in the format.
+func (f Format) matchHeader(version string) headerMatcher {
+	re := f.headerRegexp()
+	return func(line string) (string, bool) {
+		m := re.FindStringSubmatch(line)
+		if m == nil || m[1] != version {
+			return "", false
+		}
+		return m[2], true
+	}
+}
+
+// ReadEntries reads all the entries of the changelog file in the format, in
+// the order of the file, skipping the section of the unreleased changes. It
+// returns an error if a section header isn't the header of an entry with a
+// version and a date.
+func (f Format) ReadEntries(r io.Reader) ([]*Entry, error) {
+	reVersionHeader := f.headerRegexp()
+
+	var (
+		entries []*Entry
+		// section are the lines of the entry of version, from its header
+		// at line start.
+		section []string
+		version string
+		start   int
+	)
+	readSection := func() error {
+		if version == "" {
+			return nil
+		}
+		text := strings.TrimRight(strings.Join(section, "\n"), "\n")
+		entry, err := f.ReadEntry(strings.NewReader(text), version)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", start, err)
+		}
+		entries = append(entries, entry)
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		m := reVersionHeader.FindStringSubmatch(line)
+		if m == nil && !strings.HasPrefix(line, "## ") {
+			section = append(section, line)
+			continue
+		}
+		if err := readSection(); err != nil {
+			return nil, err
+		}
+		section, version, start = []string{line}, "", n
+		switch {
+		case m != nil:
+			version = m[1]
+		case !IsUnreleased(line):
+			return nil, fmt.Errorf("line %d: invalid entry header %q", n, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := readSection(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// readKeepAChangelogEntry reads the Keep a Changelog entry whose header
+// matches. It returns nil if there is none.
+func readKeepAChangelogEntry(r io.Reader, match headerMatcher, version string) (*Entry, error) {
+	var (
+		reSection = regexp.MustCompile(`^### (.+?)\s*$`)
+		reChange  = regexp.MustCompile(`^[-*] `)
+
+		found   bool
+		reading bool
+		kinds   Kinds
+		lines   []string
+
+		entry   = Entry{Version: version, Format: KeepAChangelog}
+		scanner = bufio.NewScanner(r)
+	)
+	for (len(lines) == 0 || reading) && scanner.Scan() {
+		line := scanner.Text()
+		date, ok := match(line)
+		switch {
+		case ok:
+			found, reading = true, true
+			if date == "" {
+				continue
+			}
+			t, err := time.Parse(dateFormat, date)
+			if err != nil {
+				return nil, fmt.Errorf("invalid changelog date: %w", err)
+			}
+			entry.Date = t
+		case strings.HasPrefix(line, "## "):
+			reading = false
+		case reading:
+			if len(lines) == 0 && strings.TrimSpace(line) == "" || reLinkDefinition.MatchString(line) {
+				continue
+			}
+			if m := reSection.FindStringSubmatch(line); m != nil {
+				kinds = nil
+				for _, s := range keepAChangelogSections {
+					if strings.EqualFold(s.name, m[1]) {
+						kinds = Kinds{s.kind}
+					}
+				}
+				if kinds == nil {
+					return nil, fmt.Errorf("unknown changelog section %q", m[1])
+				}
+			} else if reChange.MatchString(line) {
+				if kinds == nil {
+					return nil, fmt.Errorf("change %q outside of a section", line)
+				}
+				entry.Changes = append(entry.Changes, NewChange(line, kinds))
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	if !found {
+		return nil, nil
+	}
+	entry.Text = strings.TrimRight(strings.Join(lines, "\n"), "\n")
+	return &entry, nil
+}
+
+// NewEntry returns the entry in the format compiled from the fragments. The
+// changes are sorted by kinds in the order or, for Keep a Changelog, grouped
+// in the section of their first kind.
+func (f Format) NewEntry(version string, date time.Time, fragments []Fragment, order KindOrder) *Entry {
+	changes := make(Changes, 0, len(fragments))
+	for _, fragment := range fragments {
+		c := fragment.Change()
+		if f == KeepAChangelog {
+			c.Text = "- " + reChangeKinds.ReplaceAllString(c.Text, "")
+		}
+		changes = append(changes, c)
+	}
+	return f.NewEntryFromChanges(version, date, changes, order)
+}
+
+// NewEntryFromChanges returns the entry in the format listing the changes,
+// whose text must be in the format too. The changes are sorted by kinds in
+// the order or, for Keep a Changelog, grouped in the section of their first
+// kind.
+func (f Format) NewEntryFromChanges(version string, date time.Time, changes Changes, order KindOrder) *Entry {
+	sorted := append(Changes(nil), changes...)
+	sorted.Sort(order)
+	entry := Entry{Version: version, Date: date, Format: f}
+	if f != KeepAChangelog {
+		entry.Changes = sorted
+		lines := make([]string, 0, len(sorted))
+		for _, c := range sorted {
+			lines = append(lines, c.Text)
+		}
+		entry.Text = strings.Join(lines, "\n")
+		return &entry
+	}
+
+	bySection := map[string]Changes{}
+	for _, c := range sorted {
+		section := "Changed"
+		if len(c.Kinds) > 0 {
+			section = keepAChangelogSection(c.Kinds[0])
+		}
+		bySection[section] = append(bySection[section], c)
+	}
+	var sections []string
+	for _, s := range keepAChangelogSections {
+		changes, ok := bySection[s.name]
+		if !ok {
+			continue
+		}
+		lines := []string{"### " + s.name, ""}
+		for _, c := range changes {
+			lines = append(lines, c.Text)
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+		entry.Changes = append(entry.Changes, changes...)
+	}
+	entry.Text = strings.Join(sections, "\n\n")
+	return &entry
+}
+
+// reLinkDefinition matches the link reference definitions which usually end
+// Keep a Changelog files.
+var reLinkDefinition = regexp.MustCompile(`^\[[^\]]+\]: `)
+
+// reChangeKinds matches the kinds of a change line of the Prometheus format.
+var reChangeKinds = regexp.MustCompile(`^\* \[[^\]]+\] `)
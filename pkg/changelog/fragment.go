@@ -0,0 +1,169 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package changelog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// FragmentsDir is the default directory of the changelog fragments.
+const FragmentsDir = ".changelog"
+
+// Fragment is a change stored in its own YAML file, usually one per pull
+// request, which avoids conflicts on CHANGELOG.md.
+type Fragment struct {
+	// Kind is a slash-separated list of kinds (e.g. "ENHANCEMENT/BUGFIX").
+	Kind        string
+	Description string
+	PR          int `yaml:"pr,omitempty"`
+
+	// File is the path of the fragment file.
+	File string `yaml:"-"`
+}
+
+// Validate returns an error if the fragment is incomplete or uses unknown
+// kinds.
+func (f Fragment) Validate() error {
+	if strings.TrimSpace(f.Description) == "" {
+		return errors.New("missing description")
+	}
+	if f.Kind == "" {
+		return errors.New("missing kind")
+	}
+	for _, k := range strings.Split(f.Kind, "/") {
+		if len(ParseKinds(strings.ToUpper(k))) == 0 {
+			return fmt.Errorf("unknown kind %q", k)
+		}
+	}
+	if f.PR < 0 {
+		return fmt.Errorf("invalid pull request number %d", f.PR)
+	}
+	return nil
+}
+
+// Change returns the changelog line of the fragment.
+func (f Fragment) Change() Change {
+	kinds := ParseKinds(strings.ToUpper(f.Kind))
+	text := fmt.Sprintf("* [%s] %s", kinds, strings.Join(strings.Fields(f.Description), " "))
+	if f.PR > 0 {
+		text = fmt.Sprintf("%s #%d", text, f.PR)
+	}
+	return NewChange(text, kinds)
+}
+
+// ReadFragments reads and validates the *.yaml and *.yml fragment files of
+// the directory, ordered by file name. It returns no fragment if the
+// directory doesn't exist.
+func ReadFragments(dir string) ([]Fragment, error) {
+	var files []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		m, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, m...)
+	}
+	sort.Strings(files)
+
+	fragments := make([]Fragment, 0, len(files))
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var f Fragment
+		if err := yaml.UnmarshalStrict(b, &f); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if err := f.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		f.File = file
+		fragments = append(fragments, f)
+	}
+	return fragments, nil
+}
+
+// reFragmentName matches the characters replaced in the names of the
+// fragment files derived from descriptions.
+var reFragmentName = regexp.MustCompile(`[^a-z0-9]+`)
+
+// WriteFragment validates the fragment and writes it to a new file of the
+// directory, which is created if needed. The file is named after the pull
+// request of the fragment if any, else after its description. It returns
+// the path of the file.
+func WriteFragment(dir string, f Fragment) (string, error) {
+	if err := f.Validate(); err != nil {
+		return "", err
+	}
+	f.Kind = ParseKinds(strings.ToUpper(f.Kind)).String()
+	f.Description = strings.Join(strings.Fields(f.Description), " ")
+
+	name := strings.Trim(reFragmentName.ReplaceAllString(strings.ToLower(f.Description), "-"), "-")
+	if len(name) > 50 {
+		name = strings.TrimRight(name[:50], "-")
+	}
+	if name == "" {
+		name = "change"
+	}
+	if f.PR > 0 {
+		name = strconv.Itoa(f.PR)
+	}
+	b, err := yaml.Marshal(f)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name+".yml")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return "", fmt.Errorf("%s already exists", path)
+		}
+		return "", err
+	}
+	if _, err := file.Write(b); err != nil {
+		file.Close()
+		return "", err
+	}
+	return path, file.Close()
+}
+
+// NewEntry returns the entry compiled from the fragments, with the changes
+// sorted by kinds in the order.
+func NewEntry(version string, date time.Time, fragments []Fragment, order KindOrder) *Entry {
+	return Prometheus.NewEntry(version, date, fragments, order)
+}
+
+// String returns the entry formatted as a CHANGELOG.md section.
+func (c Entry) String() string {
+	if c.Format == KeepAChangelog {
+		return fmt.Sprintf("## [%s] - %s\n\n%s\n", c.Version, c.Date.Format(dateFormat), c.Text)
+	}
+	return fmt.Sprintf("## %s\n\n%s\n", c.Name(), c.Text)
+}
@@ -0,0 +1,99 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package changelog
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// reTrailingReference matches the last reference or author handle at
+	// the end of a change, e.g. " #1234", " (#1234)" or ", @alice)".
+	reTrailingReference = regexp.MustCompile(`(?:^|[\s(,])(?:#\d+|@[A-Za-z0-9][A-Za-z0-9-]*)[),]*\s*$`)
+	// reReference matches a reference or an author handle.
+	reReference = regexp.MustCompile(`#(\d+)|@([A-Za-z0-9][A-Za-z0-9-]*)`)
+)
+
+// NewChange returns the change of the text with the given kinds, parsing the
+// references and the authors at the end of the text.
+func NewChange(text string, kinds Kinds) Change {
+	c := Change{Text: text, Kinds: kinds}
+	_, refs := splitReferences(text)
+	for _, m := range reReference.FindAllStringSubmatch(refs, -1) {
+		if m[1] != "" {
+			n, err := strconv.Atoi(m[1])
+			if err == nil {
+				c.References = append(c.References, n)
+			}
+			continue
+		}
+		c.Authors = append(c.Authors, m[2])
+	}
+	return c
+}
+
+// splitReferences splits the text of a change into its description and the
+// trailing references and authors.
+func splitReferences(text string) (string, string) {
+	description := text
+	for {
+		loc := reTrailingReference.FindStringIndex(description)
+		if loc == nil {
+			break
+		}
+		description = description[:loc[0]]
+	}
+	description = strings.TrimRight(description, " \t(,")
+	return description, text[len(description):]
+}
+
+// Linkify returns the text of the change with the trailing references linked
+// to the pull requests of the GitHub repository of the given URL, e.g.
+// https://github.com/prometheus/promu, and the authors to their profiles.
+func (c Change) Linkify(repoURL string) string {
+	repoURL = strings.TrimSuffix(repoURL, "/")
+	profileURL := "https://github.com"
+	if u, err := url.Parse(repoURL); err == nil && u.Host != "" {
+		profileURL = u.Scheme + "://" + u.Host
+	}
+
+	description, refs := splitReferences(c.Text)
+	return description + reReference.ReplaceAllStringFunc(refs, func(ref string) string {
+		if strings.HasPrefix(ref, "#") {
+			return fmt.Sprintf("[%s](%s/pull/%s)", ref, repoURL, ref[1:])
+		}
+		return fmt.Sprintf("[%s](%s/%s)", ref, profileURL, ref[1:])
+	})
+}
+
+// Linkify returns the text of the entry with the references and the authors
+// of its changes linked, see Change.Linkify.
+func (c Entry) Linkify(repoURL string) string {
+	linked := make(map[string]string, len(c.Changes))
+	for _, change := range c.Changes {
+		linked[change.Text] = change.Linkify(repoURL)
+	}
+	lines := strings.Split(c.Text, "\n")
+	for i, line := range lines {
+		if l, ok := linked[line]; ok {
+			lines[i] = l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
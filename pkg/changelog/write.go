@@ -0,0 +1,116 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package changelog
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// InsertEntry returns the changelog content with the entry replacing the
+// existing entry of the same version, or else inserted before the first
+// version section, after the unreleased section if any. The rest of the
+// content is preserved, including the link reference definitions following
+// the last entry.
+func InsertEntry(content []byte, entry *Entry) []byte {
+	lines := strings.SplitAfter(string(content), "\n")
+	match := entry.Format.matchHeader(entry.Version)
+
+	// start and end delimit the lines replaced by the entry.
+	start := -1
+	for i, line := range lines {
+		if _, ok := match(line); ok {
+			start = i
+			break
+		}
+	}
+	end := start + 1
+	if start >= 0 {
+		for ; end < len(lines) && !strings.HasPrefix(lines[end], "## ") && !reLinkDefinition.MatchString(lines[end]); end++ {
+		}
+	} else {
+		start = 0
+		for ; start < len(lines); start++ {
+			if strings.HasPrefix(lines[start], "## ") && !IsUnreleased(strings.TrimRight(lines[start], "\n")) {
+				break
+			}
+		}
+		end = start
+	}
+
+	var b strings.Builder
+	head := strings.Join(lines[:start], "")
+	b.WriteString(head)
+	if head != "" && !strings.HasSuffix(head, "\n\n") {
+		if !strings.HasSuffix(head, "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(entry.String())
+	if tail := strings.Join(lines[end:], ""); tail != "" {
+		b.WriteString("\n")
+		b.WriteString(tail)
+	}
+	return []byte(b.String())
+}
+
+// RemoveEntry returns the changelog content in the format without the entry
+// of the version and its link reference definition, if any. The content is
+// unchanged if there is no such entry.
+func RemoveEntry(content []byte, format Format, version string) []byte {
+	lines := strings.SplitAfter(string(content), "\n")
+	match := format.matchHeader(version)
+	link := "[" + version + "]: "
+
+	var (
+		kept     []string
+		removing bool
+		removed  bool
+	)
+	for _, line := range lines {
+		if _, ok := match(line); ok {
+			removing, removed = true, true
+			continue
+		}
+		if removing && (strings.HasPrefix(line, "## ") || reLinkDefinition.MatchString(line)) {
+			removing = false
+		}
+		if removing || strings.HasPrefix(line, link) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if !removed {
+		return content
+	}
+	// The blank line preceding a removed last entry is removed too.
+	out := strings.Join(kept, "")
+	if strings.HasSuffix(out, "\n\n") && !strings.HasSuffix(string(content), "\n\n") {
+		out = strings.TrimRight(out, "\n") + "\n"
+	}
+	return []byte(out)
+}
+
+// WriteEntry writes the entry to the changelog file with InsertEntry,
+// creating the file if it doesn't exist.
+func WriteEntry(path string, entry *Entry) error {
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return os.WriteFile(path, InsertEntry(content, entry), 0o644)
+}
@@ -0,0 +1,291 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// gitState is the state of the git repository of the project.
+type gitState struct {
+	// branch is HEAD if detached.
+	branch string
+	// remote is the remote of the branch, origin by default.
+	remote    string
+	remoteURL string
+	revision  string
+}
+
+// openRepository opens the git repository of the working directory with
+// go-git. It returns a nil repository outside of a git repository, and an
+// error if go-git can't read it.
+func openRepository() (*git.Repository, error) {
+	repo, err := git.PlainOpenWithOptions(".", &git.PlainOpenOptions{
+		DetectDotGit:          true,
+		EnableDotGitCommonDir: true,
+	})
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, nil
+	}
+	return repo, err
+}
+
+// readGitState reads the state of the repository with go-git. The remote
+// URL is empty if the remote doesn't exist.
+func readGitState(repo *git.Repository) (gitState, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return gitState{}, fmt.Errorf("unable to get the current branch: %w", err)
+	}
+	s := gitState{branch: "HEAD", remote: "origin", revision: head.Hash().String()}
+	if head.Name().IsBranch() {
+		s.branch = head.Name().Short()
+	}
+
+	cfg, err := repo.Config()
+	if err != nil {
+		return s, err
+	}
+	if b, ok := cfg.Branches[s.branch]; ok && b.Remote != "" {
+		s.remote = b.Remote
+	}
+	if r, ok := cfg.Remotes[s.remote]; ok && len(r.URLs) > 0 {
+		s.remoteURL = r.URLs[0]
+	}
+	return s, nil
+}
+
+// readGitStateWithBinary reads the state of the repository with the git
+// binary, for the repositories go-git can't read. The remote URL is empty if
+// the remote doesn't exist.
+func readGitStateWithBinary() (gitState, error) {
+	branch, err := shellOutputWithError("git", "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return gitState{}, fmt.Errorf("unable to get the current branch: %w", err)
+	}
+	remote, err := shellOutputWithError("git", "config", "--get", fmt.Sprintf("branch.%s.remote", branch))
+	if err != nil {
+		// default to origin.
+		remote = "origin"
+	}
+	return gitState{
+		branch:    branch,
+		remote:    remote,
+		remoteURL: shellOutput("git", "config", "--get", fmt.Sprintf("remote.%s.url", remote)),
+		revision:  shellOutput("git", "rev-parse", "HEAD"),
+	}, nil
+}
+
+// inGitRepository returns whether the working directory is in a git
+// repository according to the git binary, false if it isn't installed.
+func inGitRepository() bool {
+	if _, err := exec.LookPath("git"); err != nil {
+		return false
+	}
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Stdout, cmd.Stderr = nil, nil
+	return cmd.Run() == nil
+}
+
+// errShallowClone is returned by describe when no tag is found in the
+// history of a shallow clone, which might lack the tagged commit.
+var errShallowClone = errors.New("no tag found in the history of the shallow clone, fetch the whole history with the tags, e.g. with fetch-depth: 0 in GitHub Actions")
+
+// describe returns the description of HEAD like git describe --tags
+// --always --dirty: the nearest tag, followed by the number of commits since
+// the tag and the abbreviated revision if it isn't HEAD, or the abbreviated
+// revision without tag, with a -dirty suffix if the tracked files are
+// modified. The description without tag of a shallow clone is returned with
+// errShallowClone.
+func describe(repo *git.Repository) (string, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+	tags, err := commitTags(repo)
+	if err != nil {
+		return "", err
+	}
+
+	// The nearest tagged commit is found breadth-first.
+	var tagged *object.Commit
+	err = walkCommits(repo, head.Hash(), func(c *object.Commit) bool {
+		if _, ok := tags[c.Hash]; ok && tagged == nil {
+			tagged = c
+		}
+		return tagged == nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	abbrev := head.Hash().String()[:7]
+	desc := abbrev
+	if tagged != nil {
+		desc = tags[tagged.Hash]
+		if tagged.Hash != head.Hash() {
+			depth, err := commitsSince(repo, head.Hash(), tagged.Hash)
+			if err != nil {
+				return "", err
+			}
+			desc = fmt.Sprintf("%s-%d-g%s", desc, depth, abbrev)
+		}
+	}
+
+	dirty, err := isDirty(repo)
+	if err != nil {
+		return "", err
+	}
+	if dirty {
+		desc += "-dirty"
+	}
+	if tagged == nil {
+		if shallow, err := repo.Storer.Shallow(); err == nil && len(shallow) > 0 {
+			return desc, errShallowClone
+		}
+	}
+	return desc, nil
+}
+
+// deepen fetches the whole history and the tags of the shallow clone with
+// the git binary.
+func deepen() error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return err
+	}
+	_, err := shellOutputWithError("git", "fetch", "--quiet", "--unshallow", "--tags")
+	return err
+}
+
+// commitTags returns the names of the tags by the commit they point to. Of
+// several tags of a commit, the annotated ones and then the greatest names
+// are preferred.
+func commitTags(repo *git.Repository) (map[plumbing.Hash]string, error) {
+	iter, err := repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		tags      = map[plumbing.Hash]string{}
+		annotated = map[plumbing.Hash]bool{}
+	)
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		hash, isAnnotated := ref.Hash(), false
+		if tag, err := repo.TagObject(hash); err == nil {
+			c, err := tag.Commit()
+			if err != nil {
+				// The tags of other objects than commits are ignored.
+				return nil
+			}
+			hash, isAnnotated = c.Hash, true
+		}
+		name := ref.Name().Short()
+		if existing, ok := tags[hash]; ok {
+			if annotated[hash] && !isAnnotated || annotated[hash] == isAnnotated && existing > name {
+				return nil
+			}
+		}
+		tags[hash], annotated[hash] = name, isAnnotated
+		return nil
+	})
+	return tags, err
+}
+
+// walkCommits calls fn with the commits reachable from the commit of the
+// hash, each once, breadth-first. The parents of the commits for which fn
+// returns false aren't walked, nor those of the boundary commits of a
+// shallow clone, which aren't fetched.
+func walkCommits(repo *git.Repository, from plumbing.Hash, fn func(c *object.Commit) bool) error {
+	shallow, err := repo.Storer.Shallow()
+	if err != nil {
+		return err
+	}
+	boundary := make(map[plumbing.Hash]bool, len(shallow))
+	for _, h := range shallow {
+		boundary[h] = true
+	}
+	seen := map[plumbing.Hash]bool{from: true}
+	queue := []plumbing.Hash{from}
+	for len(queue) > 0 {
+		c, err := repo.CommitObject(queue[0])
+		if err != nil {
+			return fmt.Errorf("commit %s: %w", queue[0], err)
+		}
+		queue = queue[1:]
+		if !fn(c) || boundary[c.Hash] {
+			continue
+		}
+		for _, p := range c.ParentHashes {
+			if !seen[p] {
+				seen[p] = true
+				queue = append(queue, p)
+			}
+		}
+	}
+	return nil
+}
+
+// commitsSince returns the number of commits reachable from the commit of
+// the hash head which aren't reachable from the commit of the hash base.
+func commitsSince(repo *git.Repository, head, base plumbing.Hash) (int, error) {
+	reachable := map[plumbing.Hash]bool{}
+	err := walkCommits(repo, base, func(c *object.Commit) bool {
+		reachable[c.Hash] = true
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	err = walkCommits(repo, head, func(c *object.Commit) bool {
+		if reachable[c.Hash] {
+			return false
+		}
+		n++
+		return true
+	})
+	return n, err
+}
+
+// isDirty returns whether the tracked files of the worktree are modified,
+// false for a bare repository.
+func isDirty(repo *git.Repository) (bool, error) {
+	wt, err := repo.Worktree()
+	if errors.Is(err, git.ErrIsBareRepository) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return false, err
+	}
+	for _, s := range status {
+		if s.Staging == git.Untracked && s.Worktree == git.Untracked {
+			continue
+		}
+		if s.Staging != git.Unmodified || s.Worktree != git.Unmodified {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -15,6 +15,7 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
@@ -25,11 +26,22 @@ import (
 	"strings"
 
 	"github.com/Masterminds/semver/v3"
+	"github.com/go-git/go-git/v5"
+)
+
+// Forges hosting repositories.
+const (
+	GitHub = "github"
+	GitLab = "gitlab"
+	// Gitea includes Forgejo, whose API is the same.
+	Gitea = "gitea"
 )
 
 // Info represents current project useful information.
 type Info struct {
-	Branch   string
+	Branch string
+	// Host is the host of the git remote, empty outside of a git repository.
+	Host     string
 	Name     string
 	Owner    string
 	Repo     string
@@ -49,7 +61,8 @@ func shellOutputWithError(cmd string, arg ...string) (string, error) {
 	return strings.Trim(string(out), " \n\r"), err
 }
 
-// NewInfo returns a new Info.
+// NewInfo returns a new Info. The git repository is read with go-git, the
+// git binary only being run for the repositories go-git can't read.
 func NewInfo(warnf func(error)) (Info, error) {
 	if warnf == nil {
 		warnf = func(error) {}
@@ -60,9 +73,13 @@ func NewInfo(warnf func(error)) (Info, error) {
 		err  error
 	)
 
-	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
-	cmd.Stdout, cmd.Stderr = nil, nil
-	if err := cmd.Run(); err != nil {
+	gitRepo, err := openRepository()
+	if err != nil {
+		warnf(fmt.Errorf("unable to read the git repository, falling back to the git binary: %w", err))
+	}
+	// The git binary is only run if go-git can't read the repository, or
+	// finds none while git might, e.g. with GIT_DIR.
+	if gitRepo == nil && !inGitRepository() {
 		// Not a git repository.
 		repo, err := os.Getwd()
 		if err != nil {
@@ -84,41 +101,65 @@ func NewInfo(warnf func(error)) (Info, error) {
 			Revision: "non-git",
 		}
 	} else {
-		branch, err := shellOutputWithError("git", "rev-parse", "--abbrev-ref", "HEAD")
-		if err != nil {
-			return info, fmt.Errorf("unable to get the current branch: %w", err)
+		var state gitState
+		if gitRepo != nil {
+			state, err = readGitState(gitRepo)
+		} else {
+			state, err = readGitStateWithBinary()
 		}
-
-		remote, err := shellOutputWithError("git", "config", "--get", fmt.Sprintf("branch.%s.remote", branch))
 		if err != nil {
-			// default to origin.
-			remote = "origin"
+			return info, err
+		}
+		if state.branch == "HEAD" {
+			// CI checkouts are usually detached.
+			if ref := ciRef(); ref != "" {
+				state.branch = ref
+			}
 		}
 
-		repoURL, err := shellOutputWithError("git", "config", "--get", fmt.Sprintf("remote.%s.url", remote))
-		if err != nil {
-			warnf(fmt.Errorf("unable to get repository location for remote %q: %w", remote, err))
+		if state.remoteURL == "" {
+			warnf(fmt.Errorf("unable to get repository location for remote %q", state.remote))
 		}
+		repoURL := state.remoteURL
 		repo, err := repoLocation(repoURL)
 		if err != nil {
 			return info, fmt.Errorf("couldn't parse repository location: %q: %w", repoURL, err)
 		}
+		host, _, _ := strings.Cut(repo, "/")
 		info = Info{
-			Branch:   branch,
+			Branch:   state.branch,
+			Host:     host,
 			Name:     filepath.Base(repo),
 			Owner:    filepath.Base(filepath.Dir(repo)),
 			Repo:     repo,
-			Revision: shellOutput("git", "rev-parse", "HEAD"),
+			Revision: state.revision,
 		}
 	}
 
-	info.Version, err = findVersion()
+	info.Version, err = findVersion(gitRepo)
 	if err != nil {
 		warnf(fmt.Errorf("unable to find project's version: %w", err))
 	}
 	return info, nil
 }
 
+// ciRef returns the branch or tag built by the CI, from the environment
+// variables of GitHub Actions and GitLab CI, or an empty string if unknown.
+func ciRef() string {
+	if ref := os.Getenv("GITHUB_HEAD_REF"); ref != "" {
+		// The source branch of a pull request.
+		return ref
+	}
+	if ref := os.Getenv("GITHUB_REF"); ref != "" {
+		for _, prefix := range []string{"refs/heads/", "refs/tags/"} {
+			if strings.HasPrefix(ref, prefix) {
+				return strings.TrimPrefix(ref, prefix)
+			}
+		}
+	}
+	return os.Getenv("CI_COMMIT_REF_NAME")
+}
+
 // Convert SCP-like URL to SSH URL(e.g. [user@]host.xz:path/to/repo.git/)
 // ref. http://git-scm.com/docs/git-fetch#_git_urls
 // (golang hasn't supported Perl-like negative look-behind match)
@@ -146,7 +187,12 @@ func repoLocation(repo string) (string, error) {
 	return repo, nil
 }
 
-func findVersion() (string, error) {
+// findVersion returns the version of the VERSION file, or else the
+// description of HEAD by its nearest tag, read from the git repository if
+// not nil. A shallow clone without tag in its history is deepened first, its
+// description without tag being returned with errShallowClone if that
+// fails.
+func findVersion(repo *git.Repository) (string, error) {
 	for _, file := range []string{"VERSION", "version/VERSION"} {
 		b, err := os.ReadFile(file)
 		if err != nil {
@@ -155,9 +201,42 @@ func findVersion() (string, error) {
 		return strings.Trim(string(b), "\n\r "), nil
 	}
 
+	if repo != nil {
+		desc, err := describe(repo)
+		if errors.Is(err, errShallowClone) {
+			// The shallow clone is deepened to find the tag if possible.
+			if deepen() == nil {
+				if repo, openErr := openRepository(); openErr == nil && repo != nil {
+					desc, err = describe(repo)
+				}
+			}
+		}
+		if err == nil || errors.Is(err, errShallowClone) {
+			return strings.TrimPrefix(desc, "v"), err
+		}
+	}
 	return strings.TrimPrefix(shellOutput("git", "describe", "--tags", "--always", "--dirty"), "v"), nil
 }
 
+// Forge returns the forge hosting the repository, guessed from its host:
+// GitHub, GitLab or Gitea, or an empty string if unknown. Self-hosted
+// instances are only recognized if the name of the forge is part of their
+// host.
+func (i Info) Forge() string {
+	host := strings.ToLower(i.Host)
+	switch {
+	case host == "":
+		return ""
+	case strings.Contains(host, "github"):
+		return GitHub
+	case strings.Contains(host, "gitlab"):
+		return GitLab
+	case host == "codeberg.org" || strings.Contains(host, "gitea") || strings.Contains(host, "forgejo"):
+		return Gitea
+	}
+	return ""
+}
+
 // ToSemver returns a *semver.Version from Info.
 func (i Info) ToSemver() (*semver.Version, error) {
 	if strings.HasPrefix(i.Version, "v") {
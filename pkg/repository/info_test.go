@@ -15,7 +15,16 @@
 package repository
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
 func TestToSemver(t *testing.T) {
@@ -76,3 +85,164 @@ func TestRepoLocation(t *testing.T) {
 		}
 	}
 }
+
+func TestForge(t *testing.T) {
+	for host, exp := range map[string]string{
+		"github.com":          GitHub,
+		"github.example.com":  GitHub,
+		"gitlab.com":          GitLab,
+		"gitlab.fr":           GitLab,
+		"codeberg.org":        Gitea,
+		"gitea.example.com":   Gitea,
+		"forgejo.example.com": Gitea,
+		"git.example.com":     "",
+		"":                    "",
+	} {
+		if got := (Info{Host: host}).Forge(); got != exp {
+			t.Errorf("Forge(%q): expected %q, got %q", host, exp, got)
+		}
+	}
+}
+
+func TestNewInfoGoGit(t *testing.T) {
+	// The git binary isn't needed to read the repository.
+	t.Setenv("PATH", "")
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{"git@github.com:prometheus/foo.git"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := func(content string) plumbing.Hash {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wt.Add("main.go"); err != nil {
+			t.Fatal(err)
+		}
+		hash, err := wt.Commit(content, &git.CommitOptions{
+			Author: &object.Signature{Name: "Prometheus", Email: "prometheus@example.com", When: time.Unix(0, 0)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	tagged := commit("package main // 1")
+	if _, err := repo.CreateTag("v1.0.0", tagged, nil); err != nil {
+		t.Fatal(err)
+	}
+	head := commit("package main // 2")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	info, err := NewInfo(func(err error) { t.Errorf("unexpected warning: %v", err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Info{
+		Branch:   "master",
+		Host:     "github.com",
+		Name:     "foo",
+		Owner:    "prometheus",
+		Repo:     "github.com/prometheus/foo",
+		Revision: head.String(),
+		Version:  "1.0.0-1-g" + head.String()[:7],
+	}
+	if info != exp {
+		t.Fatalf("expected %+v, got %+v", exp, info)
+	}
+
+	// The modified tracked files make the version dirty, the untracked ones
+	// don't.
+	if err := os.WriteFile(filepath.Join(dir, "new.go"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := findVersion(repo); err != nil || v != exp.Version {
+		t.Fatalf("expected version %q, got %q (%v)", exp.Version, v, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := findVersion(repo); err != nil || v != exp.Version+"-dirty" {
+		t.Fatalf("expected version %q, got %q (%v)", exp.Version+"-dirty", v, err)
+	}
+}
+
+func TestCIRef(t *testing.T) {
+	for _, tc := range []struct {
+		env map[string]string
+		exp string
+	}{
+		{env: map[string]string{}},
+		{env: map[string]string{"GITHUB_REF": "refs/heads/release-1.0"}, exp: "release-1.0"},
+		{env: map[string]string{"GITHUB_REF": "refs/tags/v1.0.0"}, exp: "v1.0.0"},
+		{env: map[string]string{"GITHUB_REF": "refs/pull/1/merge", "GITHUB_HEAD_REF": "feature"}, exp: "feature"},
+		{env: map[string]string{"CI_COMMIT_REF_NAME": "main"}, exp: "main"},
+	} {
+		for _, name := range []string{"GITHUB_HEAD_REF", "GITHUB_REF", "CI_COMMIT_REF_NAME"} {
+			t.Setenv(name, tc.env[name])
+		}
+		if got := ciRef(); got != tc.exp {
+			t.Errorf("ciRef() with %v: expected %q, got %q", tc.env, tc.exp, got)
+		}
+	}
+}
+
+func TestDescribeShallow(t *testing.T) {
+	// The shallow clone can't be deepened without the git binary.
+	t.Setenv("PATH", "")
+	repo, err := git.PlainInit(t.TempDir(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var commits []plumbing.Hash
+	for i := 0; i < 3; i++ {
+		hash, err := wt.Commit("commit", &git.CommitOptions{
+			AllowEmptyCommits: true,
+			Author:            &object.Signature{Name: "Prometheus", Email: "prometheus@example.com", When: time.Unix(int64(i), 0)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		commits = append(commits, hash)
+	}
+	if _, err := repo.CreateTag("v1.0.0", commits[1], nil); err != nil {
+		t.Fatal(err)
+	}
+	abbrev := commits[2].String()[:7]
+
+	// The tag is found if the tagged commit is part of the shallow clone.
+	if err := repo.Storer.SetShallow([]plumbing.Hash{commits[1]}); err != nil {
+		t.Fatal(err)
+	}
+	if desc, err := describe(repo); err != nil || desc != "v1.0.0-1-g"+abbrev {
+		t.Fatalf("expected %q, got %q (%v)", "v1.0.0-1-g"+abbrev, desc, err)
+	}
+
+	if err := repo.Storer.SetShallow([]plumbing.Hash{commits[2]}); err != nil {
+		t.Fatal(err)
+	}
+	if desc, err := describe(repo); !errors.Is(err, errShallowClone) || desc != abbrev {
+		t.Fatalf("expected %q and %v, got %q (%v)", abbrev, errShallowClone, desc, err)
+	}
+}
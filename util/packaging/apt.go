@@ -0,0 +1,199 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packaging
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// AptRelease is the name of the Release file of an APT repository, which is
+// signed into InRelease and Release.gpg.
+const AptRelease = "Release"
+
+// WriteAptRepository writes the index of a flat APT repository holding the
+// given deb files, whose paths are relative to dir, into dir: the Packages
+// file, gzipped or not, and the Release file listing their checksums. The
+// repository is used with the "deb <url> ./" source.
+func WriteAptRepository(dir string, debs []string, origin string, date time.Time) error {
+	var (
+		packages bytes.Buffer
+		archs    = map[string]bool{}
+	)
+	for _, deb := range debs {
+		control, err := debRepositoryEntry(dir, deb)
+		if err != nil {
+			return fmt.Errorf("%s: %w", deb, err)
+		}
+		if arch := debControlField(control, "Architecture"); arch != "all" {
+			archs[arch] = true
+		}
+		packages.WriteString(control)
+		packages.WriteString("\n")
+	}
+
+	var gzipped bytes.Buffer
+	gw, err := gzip.NewWriterLevel(&gzipped, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := gw.Write(packages.Bytes()); err != nil {
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	indexes := []struct {
+		name string
+		data []byte
+	}{
+		{"Packages", packages.Bytes()},
+		{"Packages.gz", gzipped.Bytes()},
+	}
+
+	architectures := make([]string, 0, len(archs))
+	for arch := range archs {
+		architectures = append(architectures, arch)
+	}
+	sort.Strings(architectures)
+	var release strings.Builder
+	fmt.Fprintf(&release, "Origin: %s\nLabel: %s\n", origin, origin)
+	fmt.Fprintf(&release, "Date: %s\n", date.UTC().Format(time.RFC1123))
+	fmt.Fprintf(&release, "Architectures: %s\n", strings.Join(architectures, " "))
+	for _, sum := range []struct {
+		name string
+		hash func() hash.Hash
+	}{
+		{"MD5Sum", md5.New},
+		{"SHA1", sha1.New},
+		{"SHA256", sha256.New},
+	} {
+		fmt.Fprintf(&release, "%s:\n", sum.name)
+		for _, index := range indexes {
+			h := sum.hash()
+			h.Write(index.data)
+			fmt.Fprintf(&release, " %x %d %s\n", h.Sum(nil), len(index.data), index.name)
+		}
+	}
+
+	for _, index := range indexes {
+		if err := os.WriteFile(filepath.Join(dir, index.name), index.data, 0o644); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(filepath.Join(dir, AptRelease), []byte(release.String()), 0o644)
+}
+
+// debRepositoryEntry returns the paragraph of the Packages index of the deb
+// file: its control file followed by its location and checksums.
+func debRepositoryEntry(dir, deb string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(dir, deb))
+	if err != nil {
+		return "", err
+	}
+	control, err := debControlFile(b)
+	if err != nil {
+		return "", err
+	}
+	var entry strings.Builder
+	entry.WriteString(strings.TrimRight(control, "\n"))
+	fmt.Fprintf(&entry, "\nFilename: %s\nSize: %d\n", path.Clean(filepath.ToSlash(deb)), len(b))
+	fmt.Fprintf(&entry, "MD5sum: %x\nSHA1: %x\nSHA256: %x\n", md5.Sum(b), sha1.Sum(b), sha256.Sum256(b))
+	return entry.String(), nil
+}
+
+// debControlFile returns the control file of the deb package.
+func debControlFile(deb []byte) (string, error) {
+	if !bytes.HasPrefix(deb, []byte("!<arch>\n")) {
+		return "", errors.New("not a deb package")
+	}
+	for b := deb[8:]; len(b) >= 60; {
+		name := strings.TrimSuffix(strings.TrimSpace(string(b[:16])), "/")
+		size, err := strconv.ParseInt(strings.TrimSpace(string(b[48:58])), 10, 64)
+		if err != nil || size > int64(len(b)-60) {
+			return "", errors.New("invalid ar archive")
+		}
+		data := b[60 : 60+size]
+		b = b[60+size+size%2:]
+		if !strings.HasPrefix(name, "control.tar") {
+			continue
+		}
+
+		var r io.Reader = bytes.NewReader(data)
+		switch path.Ext(name) {
+		case ".gz":
+			if r, err = gzip.NewReader(r); err != nil {
+				return "", err
+			}
+		case ".xz":
+			if r, err = xz.NewReader(r); err != nil {
+				return "", err
+			}
+		case ".zst":
+			zr, err := zstd.NewReader(r)
+			if err != nil {
+				return "", err
+			}
+			defer zr.Close()
+			r = zr
+		}
+		tr := tar.NewReader(r)
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+			if path.Clean(h.Name) == "control" {
+				control, err := io.ReadAll(tr)
+				return string(control), err
+			}
+		}
+	}
+	return "", errors.New("missing control file")
+}
+
+// debControlField returns the value of a single-line field of a control
+// file.
+func debControlField(control, field string) string {
+	s := bufio.NewScanner(strings.NewReader(control))
+	for s.Scan() {
+		if value, ok := strings.CutPrefix(s.Text(), field+":"); ok {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,259 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packaging
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// archLinuxVersion returns the pkgver of the package. Hyphens aren't allowed
+// and "_" keeps pre-releases sorting before the release for pacman.
+func (p *Package) archLinuxVersion() string {
+	return strings.ReplaceAll(p.Version, "-", "_")
+}
+
+// ArchLinuxFilename returns the conventional file name of the Arch Linux
+// package.
+func ArchLinuxFilename(p *Package) (string, error) {
+	arch, err := ArchLinuxArch(p.Arch)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%s-%s-%s.pkg.tar.zst", p.Name, p.archLinuxVersion(), p.release(), arch), nil
+}
+
+// WriteArchLinux writes the Arch Linux package to w, as a zstd compressed
+// tarball holding the .PKGINFO metadata, the .MTREE list of files, the
+// .INSTALL scripts and the installed files.
+func WriteArchLinux(w io.Writer, p *Package) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	arch, err := ArchLinuxArch(p.Arch)
+	if err != nil {
+		return err
+	}
+
+	entries, size, err := treeEntries(p)
+	if err != nil {
+		return err
+	}
+	metadata := []treeEntry{
+		dataEntry(".PKGINFO", archLinuxPkgInfo(p, arch, size)),
+	}
+	if install := ArchLinuxInstall(p.Scripts); install != "" {
+		metadata = append(metadata, dataEntry(".INSTALL", install))
+	}
+	mtree, err := archLinuxMtree(p, append(metadata, entries...))
+	if err != nil {
+		return err
+	}
+	metadata = append(metadata, treeEntry{
+		File: File{Mode: 0o644, Data: mtree},
+		name: ".MTREE",
+		size: int64(len(mtree)),
+	})
+
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(zw)
+	for _, e := range append(metadata, entries...) {
+		h := &tar.Header{
+			Name:    e.name,
+			Mode:    int64(e.Mode.Perm()),
+			Uname:   "root",
+			Gname:   "root",
+			ModTime: p.ModTime,
+		}
+		switch {
+		case e.Mode.IsDir():
+			h.Typeflag = tar.TypeDir
+			h.Name += "/"
+		case e.Mode&fs.ModeSymlink != 0:
+			h.Typeflag = tar.TypeSymlink
+			h.Linkname = e.Link
+		default:
+			h.Typeflag = tar.TypeReg
+			h.Size = e.size
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		r, err := e.open()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func dataEntry(name, data string) treeEntry {
+	return treeEntry{
+		File: File{Mode: 0o644, Data: []byte(data)},
+		name: name,
+		size: int64(len(data)),
+	}
+}
+
+// archLinuxPkgInfo returns the .PKGINFO file of the package.
+func archLinuxPkgInfo(p *Package, arch string, size int64) string {
+	var b strings.Builder
+	b.WriteString("# Generated by promu\n")
+	fmt.Fprintf(&b, "pkgname = %s\n", p.Name)
+	fmt.Fprintf(&b, "pkgbase = %s\n", p.Name)
+	fmt.Fprintf(&b, "pkgver = %s-%s\n", p.archLinuxVersion(), p.release())
+	fmt.Fprintf(&b, "pkgdesc = %s\n", p.summary())
+	if p.Homepage != "" {
+		fmt.Fprintf(&b, "url = %s\n", p.Homepage)
+	}
+	fmt.Fprintf(&b, "builddate = %d\n", p.ModTime.Unix())
+	if p.Maintainer != "" {
+		fmt.Fprintf(&b, "packager = %s\n", p.Maintainer)
+	}
+	fmt.Fprintf(&b, "size = %d\n", size)
+	fmt.Fprintf(&b, "arch = %s\n", arch)
+	if p.License != "" {
+		fmt.Fprintf(&b, "license = %s\n", p.License)
+	}
+	for _, field := range []struct {
+		name      string
+		relations []string
+	}{
+		{"depend", p.Depends},
+		{"optdepend", p.Recommends},
+		{"conflict", p.Conflicts},
+		{"provides", p.Provides},
+	} {
+		for _, relation := range field.relations {
+			fmt.Fprintf(&b, "%s = %s\n", field.name, relation)
+		}
+	}
+	for _, f := range p.sortedFiles() {
+		if f.Config {
+			fmt.Fprintf(&b, "backup = %s\n", strings.TrimPrefix(f.Path, "/"))
+		}
+	}
+	return b.String()
+}
+
+// ArchLinuxInstall returns the .INSTALL file running the scripts, which is
+// sourced by pacman, or an empty string if there is no script. The scripts
+// are run by /bin/sh, like for the other formats, and the pre-install and
+// post-install scripts also run on upgrade.
+func ArchLinuxInstall(s Scripts) string {
+	var b strings.Builder
+	for _, fn := range []struct {
+		name, script, upgrade string
+	}{
+		{"pre_install", s.PreInstall, "pre_upgrade"},
+		{"post_install", s.PostInstall, "post_upgrade"},
+		{"pre_remove", s.PreRemove, ""},
+		{"post_remove", s.PostRemove, ""},
+	} {
+		if fn.script == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "%s() {\n\t/bin/sh -s -- \"$@\" <<'PROMU_SCRIPT'\n%s", fn.name, fn.script)
+		if !strings.HasSuffix(fn.script, "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString("PROMU_SCRIPT\n}\n\n")
+		if fn.upgrade != "" {
+			fmt.Fprintf(&b, "%s() {\n\t%s \"$@\"\n}\n\n", fn.upgrade, fn.name)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// archLinuxMtree returns the gzipped .MTREE file listing the attributes and
+// the checksums of the files, which pacman uses to check the installed
+// files.
+func archLinuxMtree(p *Package, entries []treeEntry) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString("#mtree\n/set type=file uid=0 gid=0 mode=644\n")
+	for _, e := range entries {
+		fmt.Fprintf(&b, "./%s time=%d.0", mtreeEscape(e.name), p.ModTime.Unix())
+		switch {
+		case e.Mode.IsDir():
+			fmt.Fprintf(&b, " mode=%o type=dir", e.Mode.Perm())
+		case e.Mode&fs.ModeSymlink != 0:
+			fmt.Fprintf(&b, " mode=%o type=link link=%s", e.Mode.Perm(), mtreeEscape(e.Link))
+		default:
+			if e.Mode.Perm() != 0o644 {
+				fmt.Fprintf(&b, " mode=%o", e.Mode.Perm())
+			}
+			r, err := e.open()
+			if err != nil {
+				return nil, err
+			}
+			md5sum, sha256sum := md5.New(), sha256.New()
+			_, err = io.Copy(io.MultiWriter(md5sum, sha256sum), r)
+			r.Close()
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&b, " size=%d md5digest=%x sha256digest=%x", e.size, md5sum.Sum(nil), sha256sum.Sum(nil))
+		}
+		b.WriteString("\n")
+	}
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(gw, b.String()); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mtreeEscape escapes the whitespaces, the non-printable characters and the
+// backslashes of a path with their octal value.
+func mtreeEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || c == '\\' || c == '#' {
+			fmt.Fprintf(&b, "\\%03o", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,289 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packaging
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+)
+
+// DebFilename returns the conventional file name of the Debian package.
+func DebFilename(p *Package) (string, error) {
+	arch, err := debArch(p.Arch)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s_%s_%s.deb", p.Name, p.version(), arch), nil
+}
+
+// WriteDeb writes the Debian package to w.
+func WriteDeb(w io.Writer, p *Package) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	arch, err := debArch(p.Arch)
+	if err != nil {
+		return err
+	}
+
+	data, md5sums, size, err := debData(p)
+	if err != nil {
+		return fmt.Errorf("failed to create data archive: %w", err)
+	}
+	control, err := debControl(p, arch, md5sums, size)
+	if err != nil {
+		return fmt.Errorf("failed to create control archive: %w", err)
+	}
+
+	if _, err := io.WriteString(w, "!<arch>\n"); err != nil {
+		return err
+	}
+	for _, member := range []struct {
+		name string
+		data []byte
+	}{
+		{"debian-binary", []byte("2.0\n")},
+		{"control.tar.gz", control},
+		{"data.tar.gz", data},
+	} {
+		if err := writeArMember(w, member.name, member.data, p.ModTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeArMember writes a file to an ar archive.
+func writeArMember(w io.Writer, name string, data []byte, modTime time.Time) error {
+	header := fmt.Sprintf("%-16s%-12d%-6d%-6d%-8o%-10d`\n", name, modTime.Unix(), 0, 0, 0o644, len(data))
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	// Members are aligned on 2 bytes.
+	if len(data)%2 != 0 {
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// debData returns the gzipped tarball of the installed files, including
+// their parent directories, the md5sums file listing their checksums and
+// their installed size in KiB.
+func debData(p *Package) ([]byte, []byte, int64, error) {
+	var (
+		buf     bytes.Buffer
+		md5sums bytes.Buffer
+		size    int64
+		gw      = gzip.NewWriter(&buf)
+		tw      = tar.NewWriter(gw)
+		dirs    = map[string]bool{"/": true}
+	)
+	writeDir := func(dir string) error {
+		return tw.WriteHeader(debHeader(p, "."+strings.TrimSuffix(dir, "/")+"/", tar.TypeDir, 0o755))
+	}
+	if err := writeDir("/"); err != nil {
+		return nil, nil, 0, err
+	}
+
+	for _, f := range p.sortedFiles() {
+		// Create the missing parent directories.
+		var parents []string
+		for dir := path.Dir(f.Path); !dirs[dir]; dir = path.Dir(dir) {
+			parents = append(parents, dir)
+			dirs[dir] = true
+		}
+		for i := len(parents) - 1; i >= 0; i-- {
+			if err := writeDir(parents[i]); err != nil {
+				return nil, nil, 0, err
+			}
+		}
+
+		name := "." + f.Path
+		switch {
+		case f.Mode.IsDir():
+			if dirs[f.Path] {
+				continue
+			}
+			dirs[f.Path] = true
+			if err := tw.WriteHeader(debHeader(p, name+"/", tar.TypeDir, f.Mode.Perm())); err != nil {
+				return nil, nil, 0, err
+			}
+		case f.Mode&fs.ModeSymlink != 0:
+			h := debHeader(p, name, tar.TypeSymlink, 0o777)
+			h.Linkname = f.Link
+			if err := tw.WriteHeader(h); err != nil {
+				return nil, nil, 0, err
+			}
+		default:
+			fileSize, err := f.size()
+			if err != nil {
+				return nil, nil, 0, err
+			}
+			h := debHeader(p, name, tar.TypeReg, f.Mode.Perm())
+			h.Size = fileSize
+			if err := tw.WriteHeader(h); err != nil {
+				return nil, nil, 0, err
+			}
+			r, err := f.open()
+			if err != nil {
+				return nil, nil, 0, err
+			}
+			sum := md5.New()
+			_, err = io.Copy(io.MultiWriter(tw, sum), r)
+			r.Close()
+			if err != nil {
+				return nil, nil, 0, err
+			}
+			fmt.Fprintf(&md5sums, "%x  %s\n", sum.Sum(nil), strings.TrimPrefix(f.Path, "/"))
+			size += fileSize
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, nil, 0, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, nil, 0, err
+	}
+	return buf.Bytes(), md5sums.Bytes(), (size + 1023) / 1024, nil
+}
+
+func debHeader(p *Package, name string, typ byte, mode fs.FileMode) *tar.Header {
+	return &tar.Header{
+		Name:     name,
+		Typeflag: typ,
+		Mode:     int64(mode),
+		Uname:    "root",
+		Gname:    "root",
+		ModTime:  p.ModTime,
+		Format:   tar.FormatGNU,
+	}
+}
+
+// controlFile is a file of the control archive.
+type controlFile struct {
+	name string
+	data string
+	mode fs.FileMode
+}
+
+// debControl returns the gzipped tarball holding the metadata and the
+// maintainer scripts of the package.
+func debControl(p *Package, arch string, md5sums []byte, size int64) ([]byte, error) {
+	var control strings.Builder
+	fmt.Fprintf(&control, "Package: %s\n", p.Name)
+	fmt.Fprintf(&control, "Version: %s\n", p.version())
+	fmt.Fprintf(&control, "Architecture: %s\n", arch)
+	if p.Maintainer != "" {
+		fmt.Fprintf(&control, "Maintainer: %s\n", p.Maintainer)
+	}
+	for _, field := range []struct {
+		name      string
+		relations []string
+	}{
+		{"Depends", p.Depends},
+		{"Recommends", p.Recommends},
+		{"Conflicts", p.Conflicts},
+		{"Provides", p.Provides},
+	} {
+		if len(field.relations) > 0 {
+			fmt.Fprintf(&control, "%s: %s\n", field.name, strings.Join(field.relations, ", "))
+		}
+	}
+	fmt.Fprintf(&control, "Installed-Size: %d\n", size)
+	control.WriteString("Priority: optional\n")
+	if p.Homepage != "" {
+		fmt.Fprintf(&control, "Homepage: %s\n", p.Homepage)
+	}
+	fmt.Fprintf(&control, "Description: %s\n", debDescription(p))
+
+	files := []controlFile{
+		{"control", control.String(), 0o644},
+		{"md5sums", string(md5sums), 0o644},
+	}
+	var conffiles strings.Builder
+	for _, f := range p.sortedFiles() {
+		if f.Config {
+			fmt.Fprintln(&conffiles, f.Path)
+		}
+	}
+	if conffiles.Len() > 0 {
+		files = append(files, controlFile{"conffiles", conffiles.String(), 0o644})
+	}
+	for _, s := range []struct{ name, script string }{
+		{"preinst", p.Scripts.PreInstall},
+		{"postinst", p.Scripts.PostInstall},
+		{"prerm", p.Scripts.PreRemove},
+		{"postrm", p.Scripts.PostRemove},
+	} {
+		if s.script != "" {
+			files = append(files, controlFile{s.name, s.script, 0o755})
+		}
+	}
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	if err := tw.WriteHeader(debHeader(p, "./", tar.TypeDir, 0o755)); err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		h := debHeader(p, "./"+f.name, tar.TypeReg, f.mode)
+		h.Size = int64(len(f.data))
+		if err := tw.WriteHeader(h); err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(tw, f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// debDescription returns the description formatted for the control file:
+// the synopsis followed by the extended description, indented, with empty
+// lines replaced by ".".
+func debDescription(p *Package) string {
+	lines := strings.Split(strings.TrimSpace(p.Description), "\n")
+	var b strings.Builder
+	b.WriteString(p.summary())
+	for _, line := range lines[1:] {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			line = "."
+		}
+		b.WriteString("\n " + line)
+	}
+	return b.String()
+}
@@ -0,0 +1,156 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packaging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// Inspect writes a human readable description of the package: its metadata,
+// the tree of its installed files, all owned by root:root, and its scripts.
+// Nothing is read from the sources of the files but their size.
+func Inspect(w io.Writer, p *Package) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Package:     %s %s (%s)\n", p.Name, p.Version, p.Arch)
+	for _, field := range []struct{ name, value string }{
+		{"Maintainer", p.Maintainer},
+		{"Vendor", p.Vendor},
+		{"Homepage", p.Homepage},
+		{"License", p.License},
+		{"Description", p.summary()},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(&b, "%-12s %s\n", field.name+":", field.value)
+		}
+	}
+
+	for _, field := range []struct {
+		name      string
+		relations []string
+	}{
+		{"Depends", p.Depends},
+		{"Recommends", p.Recommends},
+		{"Conflicts", p.Conflicts},
+		{"Provides", p.Provides},
+	} {
+		if len(field.relations) > 0 {
+			fmt.Fprintf(&b, "%-12s %s\n", field.name+":", strings.Join(field.relations, ", "))
+		}
+	}
+
+	b.WriteString("Files:\n")
+	for _, f := range p.sortedFiles() {
+		size := "-"
+		if n, err := f.size(); err == nil {
+			size = fmt.Sprint(n)
+		}
+		fmt.Fprintf(&b, "  %s root:root %10s %s", f.Mode, size, f.Path)
+		switch {
+		case f.Mode&os.ModeSymlink != 0:
+			fmt.Fprintf(&b, " -> %s", f.Link)
+		case f.Source != "":
+			fmt.Fprintf(&b, " (%s)", f.Source)
+		}
+		if f.Config {
+			b.WriteString(" [config]")
+		}
+		b.WriteString("\n")
+	}
+
+	for _, s := range namedScripts(p.Scripts) {
+		if s.script == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "Script %s:\n", s.name)
+		for _, line := range strings.Split(strings.TrimRight(s.script, "\n"), "\n") {
+			fmt.Fprintf(&b, "  | %s\n", line)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Lint returns the problems of the package which don't prevent it from
+// being built but are likely mistakes: missing metadata, conflicting or
+// invalid paths, missing sources and misplaced configuration files.
+func Lint(p *Package) []string {
+	var warnings []string
+	for _, field := range []struct{ name, value string }{
+		{"maintainer", p.Maintainer},
+		{"description", p.Description},
+		{"homepage", p.Homepage},
+		{"license", p.License},
+	} {
+		if field.value == "" {
+			warnings = append(warnings, fmt.Sprintf("missing %s", field.name))
+		}
+	}
+
+	files := make(map[string]File, len(p.Files))
+	for _, f := range p.Files {
+		if !path.IsAbs(f.Path) || path.Clean(f.Path) != f.Path || f.Path == "/" {
+			warnings = append(warnings, fmt.Sprintf("invalid file path %q", f.Path))
+			continue
+		}
+		if _, ok := files[f.Path]; ok {
+			warnings = append(warnings, fmt.Sprintf("conflicting files for path %s", f.Path))
+			continue
+		}
+		files[f.Path] = f
+	}
+	for _, f := range p.sortedFiles() {
+		for dir := path.Dir(f.Path); dir != "/" && dir != "."; dir = path.Dir(dir) {
+			if parent, ok := files[dir]; ok && !parent.Mode.IsDir() {
+				warnings = append(warnings, fmt.Sprintf("%s is installed below %s, which isn't a directory", f.Path, dir))
+				break
+			}
+		}
+		if f.Source != "" {
+			info, err := os.Stat(f.Source)
+			switch {
+			case err != nil:
+				warnings = append(warnings, fmt.Sprintf("missing source of %s: %v", f.Path, err))
+			case !info.Mode().IsRegular():
+				warnings = append(warnings, fmt.Sprintf("source %s of %s isn't a regular file", f.Source, f.Path))
+			}
+		}
+		if f.Config && !strings.HasPrefix(f.Path, "/etc/") {
+			warnings = append(warnings, fmt.Sprintf("configuration file %s is outside of /etc", f.Path))
+		}
+	}
+
+	for _, s := range namedScripts(p.Scripts) {
+		if s.script != "" && !strings.HasPrefix(s.script, "#!") {
+			warnings = append(warnings, fmt.Sprintf("%s script has no shebang", s.name))
+		}
+	}
+	return warnings
+}
+
+type namedScript struct{ name, script string }
+
+// namedScripts returns the scripts with their names, in the order they run.
+func namedScripts(s Scripts) []namedScript {
+	return []namedScript{
+		{"preinstall", s.PreInstall},
+		{"postinstall", s.PostInstall},
+		{"preremove", s.PreRemove},
+		{"postremove", s.PostRemove},
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packaging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// MacOSPkgFilename returns the file name of the macOS installer of the
+// package, named like the release tarballs.
+func MacOSPkgFilename(p *Package) (string, error) {
+	switch p.Arch {
+	case "amd64", "arm64":
+	default:
+		return "", fmt.Errorf("unsupported architecture %s", p.Arch)
+	}
+	return fmt.Sprintf("%s-%s.darwin-%s.pkg", p.Name, p.Version, p.Arch), nil
+}
+
+// WriteMacOSPkg writes the macOS flat component package of the package to w,
+// as a xar archive holding the PackageInfo metadata, the bill of materials
+// listing the installed files, the payload and the scripts. The bill of
+// materials has to be created from the installed files by mkbom, with root
+// as their owner and wheel as their group.
+//
+// Only the pre-install and post-install scripts are supported, macOS having
+// no uninstaller. The package isn't signed.
+func WriteMacOSPkg(w io.Writer, p *Package, identifier string, bom []byte) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if identifier == "" {
+		return errors.New("missing package identifier")
+	}
+	if p.Scripts.PreRemove != "" || p.Scripts.PostRemove != "" {
+		return errors.New("removal scripts aren't supported by macOS packages")
+	}
+	entries, size, err := treeEntries(p)
+	if err != nil {
+		return err
+	}
+	// The payload holds the root directory too.
+	entries = append([]treeEntry{{File: File{Path: "/", Mode: fs.ModeDir | 0o755}, name: ""}}, entries...)
+	payload, err := macOSCpio(entries, p.ModTime)
+	if err != nil {
+		return err
+	}
+
+	var info strings.Builder
+	fmt.Fprintf(&info, "<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<pkg-info format-version=\"2\" identifier=%s version=%s install-location=\"/\" auth=\"root\">\n",
+		xmlAttr(identifier), xmlAttr(p.Version))
+	fmt.Fprintf(&info, "    <payload numberOfFiles=\"%d\" installKBytes=\"%d\"/>\n", len(entries), (size+1023)/1024)
+	files := []xarFile{
+		{name: "Bom", data: bom},
+		{name: "Payload", data: payload},
+	}
+	var scripts []treeEntry
+	for _, s := range []struct{ name, script string }{
+		{"preinstall", p.Scripts.PreInstall},
+		{"postinstall", p.Scripts.PostInstall},
+	} {
+		if s.script == "" {
+			continue
+		}
+		if len(scripts) == 0 {
+			info.WriteString("    <scripts>\n")
+			scripts = append(scripts, treeEntry{File: File{Path: "/", Mode: fs.ModeDir | 0o755}})
+		}
+		fmt.Fprintf(&info, "        <%s file=\"./%s\"/>\n", s.name, s.name)
+		scripts = append(scripts, treeEntry{File: File{Mode: 0o755, Data: []byte(s.script)}, name: s.name, size: int64(len(s.script))})
+	}
+	if len(scripts) > 0 {
+		info.WriteString("    </scripts>\n")
+		archive, err := macOSCpio(scripts, p.ModTime)
+		if err != nil {
+			return err
+		}
+		files = append(files, xarFile{name: "Scripts", data: archive})
+	}
+	info.WriteString("</pkg-info>\n")
+	files = append(files, xarFile{name: "PackageInfo", data: []byte(info.String())})
+
+	return writeXar(w, files, p.ModTime)
+}
+
+// macOSCpio returns the gzipped cpio archive, in the odc format used by
+// macOS, of the entries, which are owned by root:wheel. Their names are
+// prefixed with "./".
+func macOSCpio(entries []treeEntry, modTime time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	for i, e := range append(entries, treeEntry{name: "TRAILER!!!"}) {
+		name := "./" + e.name
+		if e.name == "" {
+			name = "."
+		}
+		var (
+			mode = uint32(e.Mode.Perm())
+			size = e.size
+			data io.ReadCloser
+		)
+		switch {
+		case e.name == "TRAILER!!!":
+			name, mode = e.name, 0
+		case e.Mode.IsDir():
+			mode |= 0o40000
+		case e.Mode&fs.ModeSymlink != 0:
+			mode |= 0o120000
+			data = io.NopCloser(strings.NewReader(e.Link))
+		default:
+			mode |= 0o100000
+			r, err := e.open()
+			if err != nil {
+				return nil, err
+			}
+			data = r
+		}
+		nlink := 1
+		if e.Mode.IsDir() {
+			nlink = 2
+		}
+		fmt.Fprintf(gw, "070707%06o%06o%06o%06o%06o%06o%06o%011o%06o%011o%s\x00",
+			0, i+1, mode, 0, 0, nlink, 0, modTime.Unix(), len(name)+1, size, name)
+		if data != nil {
+			_, err := io.CopyN(gw, data, size)
+			data.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// xarFile is a regular file stored uncompressed in a xar archive.
+type xarFile struct {
+	name string
+	data []byte
+}
+
+// writeXar writes the xar archive of the files to w. The table of contents
+// is checked with a SHA1 checksum stored at the beginning of the heap, where
+// code signing tools also expect it.
+func writeXar(w io.Writer, files []xarFile, modTime time.Time) error {
+	var toc strings.Builder
+	toc.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<xar>\n <toc>\n")
+	toc.WriteString("  <checksum style=\"sha1\">\n   <offset>0</offset>\n   <size>20</size>\n  </checksum>\n")
+	fmt.Fprintf(&toc, "  <creation-time>%s</creation-time>\n", modTime.UTC().Format("2006-01-02T15:04:05"))
+	offset := sha1.Size
+	for i, f := range files {
+		sum := sha1.Sum(f.data)
+		fmt.Fprintf(&toc, "  <file id=\"%d\">\n   <data>\n", i+1)
+		fmt.Fprintf(&toc, "    <length>%d</length>\n    <offset>%d</offset>\n    <size>%d</size>\n", len(f.data), offset, len(f.data))
+		toc.WriteString("    <encoding style=\"application/octet-stream\"/>\n")
+		fmt.Fprintf(&toc, "    <extracted-checksum style=\"sha1\">%x</extracted-checksum>\n", sum)
+		fmt.Fprintf(&toc, "    <archived-checksum style=\"sha1\">%x</archived-checksum>\n   </data>\n", sum)
+		fmt.Fprintf(&toc, "   <mtime>%s</mtime>\n", modTime.UTC().Format(time.RFC3339))
+		toc.WriteString("   <user>root</user>\n   <uid>0</uid>\n   <group>wheel</group>\n   <gid>0</gid>\n   <mode>0644</mode>\n")
+		fmt.Fprintf(&toc, "   <type>file</type>\n   <name>%s</name>\n  </file>\n", f.name)
+		offset += len(f.data)
+	}
+	toc.WriteString(" </toc>\n</xar>\n")
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := io.WriteString(zw, toc.String()); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	header := make([]byte, 0, 28)
+	header = binary.BigEndian.AppendUint32(header, 0x78617221) // "xar!"
+	header = binary.BigEndian.AppendUint16(header, 28)
+	header = binary.BigEndian.AppendUint16(header, 1)
+	header = binary.BigEndian.AppendUint64(header, uint64(compressed.Len()))
+	header = binary.BigEndian.AppendUint64(header, uint64(toc.Len()))
+	header = binary.BigEndian.AppendUint32(header, 1) // SHA1
+	sum := sha1.Sum(compressed.Bytes())
+	for _, b := range [][]byte{header, compressed.Bytes(), sum[:]} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	for _, f := range files {
+		if _, err := w.Write(f.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LaunchdPlist returns the property list of a launchd job, with the given
+// label, running the program with its arguments at boot and restarting it if
+// it exits.
+func LaunchdPlist(label string, program []string) string {
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	b.WriteString("<!DOCTYPE plist PUBLIC \"-//Apple//DTD PLIST 1.0//EN\" \"http://www.apple.com/DTDs/PropertyList-1.0.dtd\">\n")
+	b.WriteString("<plist version=\"1.0\">\n<dict>\n")
+	fmt.Fprintf(&b, "\t<key>Label</key>\n\t<string>%s</string>\n", xmlText(label))
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, arg := range program {
+		fmt.Fprintf(&b, "\t\t<string>%s</string>\n", xmlText(arg))
+	}
+	b.WriteString("\t</array>\n")
+	b.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n\t<key>KeepAlive</key>\n\t<true/>\n")
+	b.WriteString("</dict>\n</plist>\n")
+	return b.String()
+}
@@ -0,0 +1,212 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packaging
+
+import (
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// msiArchs maps the Go architectures to the Windows Installer platforms.
+var msiArchs = map[string]string{
+	"386":   "x86",
+	"amd64": "x64",
+	"arm64": "arm64",
+}
+
+// MSIArch returns the Windows Installer platform of the Go architecture.
+func MSIArch(goarch string) (string, error) {
+	arch, ok := msiArchs[goarch]
+	if !ok {
+		return "", fmt.Errorf("unsupported architecture %s", goarch)
+	}
+	return arch, nil
+}
+
+// MSIFilename returns the file name of the Windows installer of the package,
+// named like the release tarballs.
+func MSIFilename(p *Package) (string, error) {
+	if _, err := MSIArch(p.Arch); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%s.windows-%s.msi", p.Name, p.Version, p.Arch), nil
+}
+
+// MSIOptions are the Windows specific parameters of an installer.
+type MSIOptions struct {
+	// Manufacturer is the publisher of the product, the name of the package
+	// if empty.
+	Manufacturer string
+	// UpgradeCode identifies the product across versions. It is derived
+	// from the package name if empty.
+	UpgradeCode string
+	// Service is the path of the binary registered as an automatically
+	// started Windows service named after the package, if not empty.
+	Service string
+	// ServiceArguments are the command-line arguments of the service.
+	ServiceArguments string
+	// Path adds the installation directory to the system PATH.
+	Path bool
+}
+
+var msiVersion = regexp.MustCompile(`^\d+(\.\d+){0,3}`)
+
+// WriteWXS writes the WiX source of the Windows installer of the package, to
+// be compiled into an MSI by the WiX toolset or wixl. The paths of the files
+// are relative to the installation directory, a directory named after the
+// package in Program Files, e.g. /prometheus.exe. Only regular files are
+// supported. The installer upgrades any installed version of the product.
+func WriteWXS(w io.Writer, p *Package, o MSIOptions) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	arch, err := MSIArch(p.Arch)
+	if err != nil {
+		return err
+	}
+	// Windows Installer versions only have numeric fields and pre-releases
+	// can't be told apart from the release.
+	version := msiVersion.FindString(p.Version)
+	if version == "" {
+		return fmt.Errorf("invalid MSI version %q", p.Version)
+	}
+	if o.Manufacturer == "" {
+		o.Manufacturer = p.Name
+	}
+	if o.UpgradeCode == "" {
+		o.UpgradeCode = msiGUID(p.Name, "upgrade")
+	}
+	programFiles, win64 := "ProgramFiles64Folder", "yes"
+	if arch == "x86" {
+		programFiles, win64 = "ProgramFilesFolder", "no"
+	}
+
+	files := p.sortedFiles()
+	service := false
+	for _, f := range files {
+		if !f.Mode.IsRegular() {
+			return fmt.Errorf("%s isn't a regular file", f.Path)
+		}
+		service = service || f.Path == o.Service
+	}
+	if o.Service != "" && !service {
+		return fmt.Errorf("service binary %s isn't in the package", o.Service)
+	}
+
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"utf-8\"?>\n<!-- Generated by promu -->\n")
+	b.WriteString("<Wix xmlns=\"http://schemas.microsoft.com/wix/2006/wi\">\n")
+	fmt.Fprintf(&b, "  <Product Id=\"%s\" Name=%s Language=\"1033\" Version=\"%s\" Manufacturer=%s UpgradeCode=\"%s\">\n",
+		msiGUID(p.Name, "product", version, arch), xmlAttr(p.Name), version, xmlAttr(o.Manufacturer), o.UpgradeCode)
+	fmt.Fprintf(&b, "    <Package InstallerVersion=\"500\" Compressed=\"yes\" InstallScope=\"perMachine\" Platform=\"%s\" Description=%s Manufacturer=%s/>\n",
+		arch, xmlAttr(p.summary()), xmlAttr(o.Manufacturer))
+	fmt.Fprintf(&b, "    <MajorUpgrade AllowSameVersionUpgrades=\"yes\" DowngradeErrorMessage=%s/>\n",
+		xmlAttr("A newer version of "+p.Name+" is already installed."))
+	b.WriteString("    <Media Id=\"1\" Cabinet=\"product.cab\" EmbedCab=\"yes\"/>\n")
+	if p.Homepage != "" {
+		fmt.Fprintf(&b, "    <Property Id=\"ARPURLINFOABOUT\" Value=%s/>\n", xmlAttr(p.Homepage))
+	}
+
+	b.WriteString("    <Directory Id=\"TARGETDIR\" Name=\"SourceDir\">\n")
+	fmt.Fprintf(&b, "      <Directory Id=\"%s\">\n", programFiles)
+	fmt.Fprintf(&b, "        <Directory Id=\"INSTALLDIR\" Name=%s>\n", xmlAttr(p.Name))
+	var (
+		components []string
+		dirs       = []string{"/"}
+		indent     = "          "
+	)
+	for i, f := range files {
+		// Close the directories which don't hold the file and open its
+		// missing parent directories.
+		dir := path.Dir(f.Path)
+		for !strings.HasPrefix(dir+"/", strings.TrimSuffix(dirs[len(dirs)-1], "/")+"/") {
+			dirs = dirs[:len(dirs)-1]
+			indent = indent[2:]
+			b.WriteString(indent + "</Directory>\n")
+		}
+		var parents []string
+		for d := dir; d != dirs[len(dirs)-1]; d = path.Dir(d) {
+			parents = append(parents, d)
+		}
+		for j := len(parents) - 1; j >= 0; j-- {
+			fmt.Fprintf(&b, "%s<Directory Id=\"d%x\" Name=%s>\n", indent, sha1.Sum([]byte(parents[j])), xmlAttr(path.Base(parents[j])))
+			dirs = append(dirs, parents[j])
+			indent += "  "
+		}
+
+		id := fmt.Sprintf("c%d", i)
+		components = append(components, id)
+		fmt.Fprintf(&b, "%s<Component Id=\"%s\" Guid=\"%s\" Win64=\"%s\">\n", indent, id, msiGUID(p.Name, "component", arch, f.Path), win64)
+		fmt.Fprintf(&b, "%s  <File Id=\"f%d\" Name=%s Source=%s KeyPath=\"yes\"/>\n", indent, i, xmlAttr(path.Base(f.Path)), xmlAttr(f.Source))
+		if f.Path == o.Service {
+			fmt.Fprintf(&b, "%s  <ServiceInstall Id=\"service\" Name=%s DisplayName=%s Description=%s Type=\"ownProcess\" Start=\"auto\" ErrorControl=\"normal\"",
+				indent, xmlAttr(p.Name), xmlAttr(p.Name), xmlAttr(p.summary()))
+			if o.ServiceArguments != "" {
+				fmt.Fprintf(&b, " Arguments=%s", xmlAttr(o.ServiceArguments))
+			}
+			b.WriteString("/>\n")
+			fmt.Fprintf(&b, "%s  <ServiceControl Id=\"service\" Name=%s Start=\"install\" Stop=\"both\" Remove=\"uninstall\" Wait=\"yes\"/>\n", indent, xmlAttr(p.Name))
+		}
+		b.WriteString(indent + "</Component>\n")
+	}
+	for len(dirs) > 1 {
+		dirs = dirs[:len(dirs)-1]
+		indent = indent[2:]
+		b.WriteString(indent + "</Directory>\n")
+	}
+	if o.Path {
+		components = append(components, "path")
+		fmt.Fprintf(&b, "%s<Component Id=\"path\" Guid=\"%s\" Win64=\"%s\">\n", indent, msiGUID(p.Name, "path", arch), win64)
+		fmt.Fprintf(&b, "%s  <CreateFolder/>\n", indent)
+		fmt.Fprintf(&b, "%s  <Environment Id=\"path\" Name=\"PATH\" Value=\"[INSTALLDIR]\" Action=\"set\" Part=\"last\" System=\"yes\" Permanent=\"no\"/>\n", indent)
+		b.WriteString(indent + "</Component>\n")
+	}
+	b.WriteString("        </Directory>\n      </Directory>\n    </Directory>\n")
+
+	b.WriteString("    <Feature Id=\"Complete\" Level=\"1\">\n")
+	for _, id := range components {
+		fmt.Fprintf(&b, "      <ComponentRef Id=\"%s\"/>\n", id)
+	}
+	b.WriteString("    </Feature>\n  </Product>\n</Wix>\n")
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// msiGUID returns a GUID derived from the given strings, like a version 5
+// UUID, so that the installers of a project are reproducible.
+func msiGUID(values ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(values, "\x00")))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return strings.ToUpper(fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16]))
+}
+
+// xmlAttr returns the string as a quoted XML attribute value.
+func xmlAttr(s string) string {
+	return `"` + xmlText(s) + `"`
+}
+
+// xmlText returns the string escaped for XML.
+func xmlText(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
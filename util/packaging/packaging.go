@@ -0,0 +1,261 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package packaging writes Debian, RPM and Arch Linux packages of prebuilt
+// binaries, and the WiX sources of Windows installers.
+package packaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// File is a regular file, a directory or a symbolic link installed by a
+// package.
+type File struct {
+	// Path is the absolute path of the installed file.
+	Path string
+	// Mode holds the type and the permissions of the file.
+	Mode fs.FileMode
+	// Source is the file on disk holding the content of a regular file.
+	// Data is used instead if Source is empty.
+	Source string
+	Data   []byte
+	// Link is the target of a symbolic link.
+	Link string
+	// Config marks configuration files, which aren't replaced on upgrade
+	// if they were modified.
+	Config bool
+}
+
+// open returns the content of a regular file.
+func (f File) open() (io.ReadCloser, error) {
+	if f.Source == "" {
+		return io.NopCloser(bytes.NewReader(f.Data)), nil
+	}
+	return os.Open(f.Source)
+}
+
+// size returns the size of the content of the file.
+func (f File) size() (int64, error) {
+	switch {
+	case f.Mode.IsDir():
+		return 0, nil
+	case f.Mode&fs.ModeSymlink != 0:
+		return int64(len(f.Link)), nil
+	case f.Source == "":
+		return int64(len(f.Data)), nil
+	}
+	info, err := os.Stat(f.Source)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// Scripts are the shell scripts run by the package manager when the package
+// is installed or removed.
+type Scripts struct {
+	PreInstall  string
+	PostInstall string
+	PreRemove   string
+	PostRemove  string
+}
+
+// Package is a Linux package.
+type Package struct {
+	Name string
+	// Version is the version of the project. Pre-release versions (e.g.
+	// "2.0.0-rc.0") are converted so that they sort before the release.
+	Version string
+	// Release is the RPM release number, 1 if empty.
+	Release string
+	// Arch is the Go architecture of the binaries (e.g. "amd64" or
+	// "armv7"), mapped to the architecture names of each format.
+	Arch        string
+	Maintainer  string
+	Description string
+	Homepage    string
+	License     string
+	Vendor      string
+	// Depends, Recommends, Conflicts and Provides are the relationships
+	// of the package with other packages, in the syntax of its format,
+	// e.g. "libsystemd0 (>= 245)" for deb or "systemd-libs >= 245" for rpm.
+	// Recommended packages are optional dependencies for Arch Linux.
+	Depends    []string
+	Recommends []string
+	Conflicts  []string
+	Provides   []string
+	Files      []File
+	Scripts    Scripts
+	// ModTime is the modification time of the files and the build time of
+	// the package.
+	ModTime time.Time
+}
+
+// Validate returns an error if mandatory fields are missing or if the files
+// of the package conflict.
+func (p *Package) Validate() error {
+	switch {
+	case p.Name == "":
+		return errors.New("missing package name")
+	case p.Version == "":
+		return errors.New("missing package version")
+	case p.Arch == "":
+		return errors.New("missing package architecture")
+	}
+	seen := make(map[string]bool, len(p.Files))
+	for _, f := range p.Files {
+		if !path.IsAbs(f.Path) || path.Clean(f.Path) != f.Path || f.Path == "/" {
+			return fmt.Errorf("invalid file path %q", f.Path)
+		}
+		if seen[f.Path] {
+			return fmt.Errorf("conflicting files for path %s", f.Path)
+		}
+		seen[f.Path] = true
+		if f.Config && !f.Mode.IsRegular() {
+			return fmt.Errorf("configuration file %s isn't a regular file", f.Path)
+		}
+	}
+	return nil
+}
+
+// version returns the version of the package, where the "-" of pre-releases
+// is replaced by "~" which sorts before anything, even the end of the
+// string, for both dpkg and rpm.
+func (p *Package) version() string {
+	return strings.ReplaceAll(p.Version, "-", "~")
+}
+
+func (p *Package) release() string {
+	if p.Release == "" {
+		return "1"
+	}
+	return p.Release
+}
+
+// summary returns the first line of the description.
+func (p *Package) summary() string {
+	summary, _, _ := strings.Cut(strings.TrimSpace(p.Description), "\n")
+	if summary == "" {
+		return p.Name
+	}
+	return summary
+}
+
+// sortedFiles returns the files of the package sorted by path.
+func (p *Package) sortedFiles() []File {
+	files := make([]File, len(p.Files))
+	copy(files, p.Files)
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+// treeEntry is a file of the tree of installed files of a package.
+type treeEntry struct {
+	File
+	// name is the path of the file in the package, without leading slash.
+	name string
+	size int64
+}
+
+// treeEntries returns the installed files, with their missing parent
+// directories, and their total size.
+func treeEntries(p *Package) ([]treeEntry, int64, error) {
+	var (
+		entries []treeEntry
+		total   int64
+		dirs    = map[string]bool{"/": true}
+	)
+	for _, f := range p.sortedFiles() {
+		var parents []string
+		for dir := path.Dir(f.Path); !dirs[dir]; dir = path.Dir(dir) {
+			parents = append(parents, dir)
+			dirs[dir] = true
+		}
+		for i := len(parents) - 1; i >= 0; i-- {
+			entries = append(entries, treeEntry{
+				File: File{Path: parents[i], Mode: fs.ModeDir | 0o755},
+				name: strings.TrimPrefix(parents[i], "/"),
+			})
+		}
+		if f.Mode.IsDir() {
+			if dirs[f.Path] {
+				continue
+			}
+			dirs[f.Path] = true
+		}
+
+		size, err := f.size()
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, treeEntry{File: f, name: strings.TrimPrefix(f.Path, "/"), size: size})
+		total += size
+	}
+	return entries, total, nil
+}
+
+// archNames maps the Go architectures to their Debian, RPM and Arch Linux
+// names. Arch Linux doesn't support all of them.
+var archNames = map[string]struct{ deb, rpm, archLinux string }{
+	"386":      {"i386", "i386", "i686"},
+	"amd64":    {"amd64", "x86_64", "x86_64"},
+	"arm64":    {"arm64", "aarch64", "aarch64"},
+	"armv5":    {"armel", "armv5tel", "arm"},
+	"armv6":    {"armhf", "armv6hl", "armv6h"},
+	"armv7":    {"armhf", "armv7hl", "armv7h"},
+	"loong64":  {"loong64", "loongarch64", "loong64"},
+	"mips":     {"mips", "mips", ""},
+	"mipsle":   {"mipsel", "mipsel", ""},
+	"mips64":   {"mips64", "mips64", ""},
+	"mips64le": {"mips64el", "mips64el", ""},
+	"ppc64":    {"ppc64", "ppc64", ""},
+	"ppc64le":  {"ppc64el", "ppc64le", ""},
+	"riscv64":  {"riscv64", "riscv64", "riscv64"},
+	"s390x":    {"s390x", "s390x", ""},
+}
+
+func debArch(goarch string) (string, error) {
+	a, ok := archNames[goarch]
+	if !ok {
+		return "", fmt.Errorf("unsupported architecture %s", goarch)
+	}
+	return a.deb, nil
+}
+
+func rpmArch(goarch string) (string, error) {
+	a, ok := archNames[goarch]
+	if !ok {
+		return "", fmt.Errorf("unsupported architecture %s", goarch)
+	}
+	return a.rpm, nil
+}
+
+// ArchLinuxArch returns the Arch Linux name of the Go architecture.
+func ArchLinuxArch(goarch string) (string, error) {
+	a := archNames[goarch]
+	if a.archLinux == "" {
+		return "", fmt.Errorf("unsupported architecture %s", goarch)
+	}
+	return a.archLinux, nil
+}
@@ -0,0 +1,891 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packaging
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func testPackage() *Package {
+	return &Package{
+		Name:        "foo",
+		Version:     "1.2.0-rc.0",
+		Arch:        "armv7",
+		Maintainer:  "Foo <foo@example.com>",
+		Description: "Foo exporter.\n\nIt exports foo.\n",
+		License:     "Apache-2.0",
+		Files: []File{
+			{Path: "/usr/bin/foo", Mode: 0o755, Data: []byte("binary")},
+			{Path: "/etc/foo/foo.yml", Mode: 0o644, Data: []byte("a: 1\n"), Config: true},
+			{Path: "/etc/foo/link.yml", Mode: fs.ModeSymlink | 0o777, Link: "foo.yml"},
+		},
+		Scripts: Scripts{PostInstall: "#!/bin/sh\necho installed\n"},
+		ModTime: time.Unix(1700000000, 0),
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		files []File
+		err   bool
+	}{
+		{files: []File{{Path: "/usr/bin/foo"}}},
+		{files: []File{{Path: "usr/bin/foo"}}, err: true},
+		{files: []File{{Path: "/usr/bin/../foo"}}, err: true},
+		{files: []File{{Path: "/usr/bin/foo"}, {Path: "/usr/bin/foo"}}, err: true},
+		{files: []File{{Path: "/etc/foo", Mode: fs.ModeDir, Config: true}}, err: true},
+	} {
+		p := &Package{Name: "foo", Version: "1.0.0", Arch: "amd64", Files: tc.files}
+		if err := p.Validate(); (err != nil) != tc.err {
+			t.Fatalf("%v: expected error %v, got %v", tc.files, tc.err, err)
+		}
+	}
+}
+
+func TestWriteDeb(t *testing.T) {
+	p := testPackage()
+	var buf bytes.Buffer
+	if err := WriteDeb(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	filename, err := DebFilename(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filename != "foo_1.2.0~rc.0_armhf.deb" {
+		t.Fatalf("expected foo_1.2.0~rc.0_armhf.deb, got %s", filename)
+	}
+
+	members := readAr(t, buf.Bytes())
+	if string(members["debian-binary"]) != "2.0\n" {
+		t.Fatalf("expected debian-binary 2.0, got %q", members["debian-binary"])
+	}
+
+	control := readTarGz(t, members["control.tar.gz"])
+	expControl := `Package: foo
+Version: 1.2.0~rc.0
+Architecture: armhf
+Maintainer: Foo <foo@example.com>
+Installed-Size: 1
+Priority: optional
+Description: Foo exporter.
+ .
+ It exports foo.
+`
+	if control["./control"] != expControl {
+		t.Fatalf("expected control:\n%s\ngot:\n%s", expControl, control["./control"])
+	}
+	if control["./conffiles"] != "/etc/foo/foo.yml\n" {
+		t.Fatalf("expected conffiles /etc/foo/foo.yml, got %q", control["./conffiles"])
+	}
+	if control["./postinst"] != p.Scripts.PostInstall {
+		t.Fatalf("expected postinst %q, got %q", p.Scripts.PostInstall, control["./postinst"])
+	}
+	if _, ok := control["./preinst"]; ok {
+		t.Fatal("expected no preinst script")
+	}
+	if !strings.Contains(control["./md5sums"], "  usr/bin/foo\n") {
+		t.Fatalf("expected md5sums to list usr/bin/foo, got %q", control["./md5sums"])
+	}
+
+	data := readTarGz(t, members["data.tar.gz"])
+	exp := map[string]string{
+		"./":                 "",
+		"./etc/":             "",
+		"./etc/foo/":         "",
+		"./etc/foo/foo.yml":  "a: 1\n",
+		"./etc/foo/link.yml": "-> foo.yml",
+		"./usr/":             "",
+		"./usr/bin/":         "",
+		"./usr/bin/foo":      "binary",
+	}
+	if !reflect.DeepEqual(exp, data) {
+		t.Fatalf("expected data %v, got %v", exp, data)
+	}
+}
+
+// readAr returns the content of the members of an ar archive.
+func readAr(t *testing.T, b []byte) map[string][]byte {
+	t.Helper()
+	if !bytes.HasPrefix(b, []byte("!<arch>\n")) {
+		t.Fatal("missing ar magic")
+	}
+	b = b[8:]
+	members := map[string][]byte{}
+	for len(b) > 0 {
+		if len(b) < 60 {
+			t.Fatalf("truncated ar header %q", b)
+		}
+		name := strings.TrimSpace(string(b[:16]))
+		size, err := strconv.Atoi(strings.TrimSpace(string(b[48:58])))
+		if err != nil {
+			t.Fatal(err)
+		}
+		members[name] = b[60 : 60+size]
+		b = b[60+size+size%2:]
+	}
+	return members
+}
+
+// readTarGz returns the content of the regular files of a gzipped tarball,
+// "-> target" for symbolic links and nothing for directories.
+func readTarGz(t *testing.T, b []byte) map[string]string {
+	t.Helper()
+	gr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	files := map[string]string{}
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Uname != "root" || h.Gname != "root" {
+			t.Fatalf("%s: expected root:root owner, got %s:%s", h.Name, h.Uname, h.Gname)
+		}
+		switch h.Typeflag {
+		case tar.TypeSymlink:
+			files[h.Name] = "-> " + h.Linkname
+		default:
+			content, err := io.ReadAll(tr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files[h.Name] = string(content)
+		}
+	}
+}
+
+func TestWriteRPM(t *testing.T) {
+	p := testPackage()
+	var buf bytes.Buffer
+	if err := WriteRPM(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	filename, err := RPMFilename(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filename != "foo-1.2.0~rc.0-1.armv7hl.rpm" {
+		t.Fatalf("expected foo-1.2.0~rc.0-1.armv7hl.rpm, got %s", filename)
+	}
+
+	b := buf.Bytes()
+	if !bytes.HasPrefix(b, []byte{0xed, 0xab, 0xee, 0xdb, 3, 0}) {
+		t.Fatal("missing RPM lead magic")
+	}
+	sig, n := readRPMHeader(t, b[96:], rpmTagHeaderSignatures)
+	// The signature is aligned on 8 bytes.
+	n += (8 - n%8) % 8
+	rest := b[96+n:]
+	header, n := readRPMHeader(t, rest, rpmTagHeaderImmutable)
+	payload := rest[n:]
+
+	if size := sig.int32s(t, rpmSigTagSize)[0]; int(size) != len(rest) {
+		t.Fatalf("expected signature size %d, got %d", len(rest), size)
+	}
+	digest := sha256.Sum256(rest[:n])
+	if exp, got := hex.EncodeToString(digest[:]), sig.strings(t, rpmSigTagSHA256)[0]; exp != got {
+		t.Fatalf("expected header digest %s, got %s", exp, got)
+	}
+
+	for tag, exp := range map[int32]string{
+		rpmTagName:       "foo",
+		rpmTagVersion:    "1.2.0~rc.0",
+		rpmTagRelease:    "1",
+		rpmTagSummary:    "Foo exporter.",
+		rpmTagArch:       "armv7hl",
+		rpmTagOS:         "linux",
+		rpmTagSourceRPM:  "foo-1.2.0~rc.0-1.src.rpm",
+		rpmTagPostIn:     p.Scripts.PostInstall,
+		rpmTagPostInProg: "/bin/sh",
+	} {
+		if got := header.strings(t, tag)[0]; got != exp {
+			t.Fatalf("tag %d: expected %q, got %q", tag, exp, got)
+		}
+	}
+	if exp, got := []string{"/etc/foo/", "/usr/bin/"}, header.strings(t, rpmTagDirNames); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected dir names %v, got %v", exp, got)
+	}
+	if exp, got := []string{"foo.yml", "link.yml", "foo"}, header.strings(t, rpmTagBaseNames); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected base names %v, got %v", exp, got)
+	}
+	if exp, got := []int32{0, 0, 1}, header.int32s(t, rpmTagDirIndexes); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected dir indexes %v, got %v", exp, got)
+	}
+	if exp, got := []int32{rpmFileConfig | rpmFileNoReplace, 0, 0}, header.int32s(t, rpmTagFileFlags); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected file flags %v, got %v", exp, got)
+	}
+	if exp, got := []string{"", "foo.yml", ""}, header.strings(t, rpmTagFileLinkTos); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected link targets %v, got %v", exp, got)
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cpio, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := sig.int32s(t, rpmSigTagPayloadSize)[0]; int(size) != len(cpio) {
+		t.Fatalf("expected payload size %d, got %d", len(cpio), size)
+	}
+	exp := map[string]string{
+		"./etc/foo/foo.yml":  "a: 1\n",
+		"./etc/foo/link.yml": "foo.yml",
+		"./usr/bin/foo":      "binary",
+	}
+	if got := readCPIO(t, cpio); !reflect.DeepEqual(exp, got) {
+		t.Fatalf("expected payload %v, got %v", exp, got)
+	}
+}
+
+// testRPMHeader holds the raw values of the tags of an RPM header.
+type testRPMHeader map[int32][]byte
+
+func (h testRPMHeader) strings(t *testing.T, tag int32) []string {
+	t.Helper()
+	v, ok := h[tag]
+	if !ok {
+		t.Fatalf("missing tag %d", tag)
+	}
+	return strings.Split(strings.TrimSuffix(string(v), "\x00"), "\x00")
+}
+
+func (h testRPMHeader) int32s(t *testing.T, tag int32) []int32 {
+	t.Helper()
+	v, ok := h[tag]
+	if !ok {
+		t.Fatalf("missing tag %d", tag)
+	}
+	res := make([]int32, len(v)/4)
+	for i := range res {
+		res[i] = int32(binary.BigEndian.Uint32(v[4*i:]))
+	}
+	return res
+}
+
+// readRPMHeader decodes an RPM header and returns it with its size.
+func readRPMHeader(t *testing.T, b []byte, region int32) (testRPMHeader, int) {
+	t.Helper()
+	if !bytes.HasPrefix(b, []byte{0x8e, 0xad, 0xe8, 0x01}) {
+		t.Fatal("missing header magic")
+	}
+	var (
+		il    = int(binary.BigEndian.Uint32(b[8:]))
+		dl    = int(binary.BigEndian.Uint32(b[12:]))
+		index = b[16 : 16+16*il]
+		store = b[16+16*il : 16+16*il+dl]
+		h     = testRPMHeader{}
+	)
+	type entry struct{ tag, typ, offset, count int32 }
+	entries := make([]entry, il)
+	for i := range entries {
+		e := index[16*i:]
+		entries[i] = entry{
+			tag:    int32(binary.BigEndian.Uint32(e)),
+			typ:    int32(binary.BigEndian.Uint32(e[4:])),
+			offset: int32(binary.BigEndian.Uint32(e[8:])),
+			count:  int32(binary.BigEndian.Uint32(e[12:])),
+		}
+	}
+
+	r := entries[0]
+	if r.tag != region || r.typ != rpmTypeBin || r.count != 16 || int(r.offset) != dl-16 {
+		t.Fatalf("invalid region entry %+v", r)
+	}
+	trailer := store[r.offset:]
+	if int32(binary.BigEndian.Uint32(trailer)) != region || int32(binary.BigEndian.Uint32(trailer[8:])) != int32(-16*il) {
+		t.Fatalf("invalid region trailer %x", trailer)
+	}
+
+	for i, e := range entries[1:] {
+		end := int(r.offset)
+		if i+2 < len(entries) {
+			end = int(entries[i+2].offset)
+		}
+		if e.offset < 0 || int(e.offset) > end {
+			t.Fatalf("tag %d: invalid offset %d", e.tag, e.offset)
+		}
+		v := store[e.offset:end]
+		switch e.typ {
+		case rpmTypeInt16:
+			v = v[:2*e.count]
+		case rpmTypeInt32:
+			if e.offset%4 != 0 {
+				t.Fatalf("tag %d: unaligned offset %d", e.tag, e.offset)
+			}
+			v = v[:4*e.count]
+		case rpmTypeBin:
+			v = v[:e.count]
+		default:
+			// Skip the padding of the next value.
+			end := 0
+			for j := int32(0); j < e.count; j++ {
+				end += bytes.IndexByte(v[end:], 0) + 1
+			}
+			v = v[:end]
+		}
+		h[e.tag] = v
+	}
+	return h, 16 + 16*il + dl
+}
+
+// readCPIO returns the content of the files of a cpio archive in the "new
+// ASCII" format.
+func readCPIO(t *testing.T, b []byte) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	pad := func(n int) int { return (4 - n%4) % 4 }
+	for off := 0; ; {
+		h := b[off:]
+		if string(h[:6]) != "070701" {
+			t.Fatalf("invalid cpio magic at %d", off)
+		}
+		field := func(i int) int {
+			v, err := strconv.ParseInt(string(h[6+8*i:14+8*i]), 16, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return int(v)
+		}
+		size, nameSize := field(6), field(11)
+		name := string(h[110 : 110+nameSize-1])
+		if name == "TRAILER!!!" {
+			return files
+		}
+		start := 110 + nameSize
+		start += pad(start)
+		files[name] = string(h[start : start+size])
+		off += start + size
+		off += pad(off)
+	}
+}
+
+func TestWriteArchLinux(t *testing.T) {
+	p := testPackage()
+	var buf bytes.Buffer
+	if err := WriteArchLinux(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	filename, err := ArchLinuxFilename(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filename != "foo-1.2.0_rc.0-1-armv7h.pkg.tar.zst" {
+		t.Fatalf("expected foo-1.2.0_rc.0-1-armv7h.pkg.tar.zst, got %s", filename)
+	}
+
+	zr, err := zstd.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+	var (
+		names []string
+		files = map[string]string{}
+	)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, h.Name)
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[h.Name] = string(content)
+	}
+
+	exp := []string{".PKGINFO", ".INSTALL", ".MTREE", "etc/", "etc/foo/", "etc/foo/foo.yml", "etc/foo/link.yml", "usr/", "usr/bin/", "usr/bin/foo"}
+	if !reflect.DeepEqual(exp, names) {
+		t.Fatalf("expected files %v, got %v", exp, names)
+	}
+	expPkgInfo := `# Generated by promu
+pkgname = foo
+pkgbase = foo
+pkgver = 1.2.0_rc.0-1
+pkgdesc = Foo exporter.
+builddate = 1700000000
+packager = Foo <foo@example.com>
+size = 18
+arch = armv7h
+license = Apache-2.0
+backup = etc/foo/foo.yml
+`
+	if files[".PKGINFO"] != expPkgInfo {
+		t.Fatalf("expected .PKGINFO:\n%s\ngot:\n%s", expPkgInfo, files[".PKGINFO"])
+	}
+	if !strings.Contains(files[".INSTALL"], "post_install() {") || !strings.Contains(files[".INSTALL"], p.Scripts.PostInstall) {
+		t.Fatalf("expected .INSTALL to run the post-install script, got:\n%s", files[".INSTALL"])
+	}
+}
+
+func TestRelations(t *testing.T) {
+	p := testPackage()
+	p.Depends = []string{"libsystemd0 (>= 245)", "adduser"}
+	p.Recommends = []string{"logrotate"}
+	p.Conflicts = []string{"bar"}
+	p.Provides = []string{"foo-exporter"}
+	var buf bytes.Buffer
+	if err := WriteDeb(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	control := readTarGz(t, readAr(t, buf.Bytes())["control.tar.gz"])["./control"]
+	for _, exp := range []string{
+		"Depends: libsystemd0 (>= 245), adduser\n",
+		"Recommends: logrotate\n",
+		"Conflicts: bar\n",
+		"Provides: foo-exporter\n",
+	} {
+		if !strings.Contains(control, exp) {
+			t.Fatalf("expected %q in control file:\n%s", exp, control)
+		}
+	}
+
+	p.Depends = []string{"systemd-libs >= 245", "shadow-utils"}
+	buf.Reset()
+	if err := WriteRPM(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	_, n := readRPMHeader(t, buf.Bytes()[96:], rpmTagHeaderSignatures)
+	n += (8 - n%8) % 8
+	header, _ := readRPMHeader(t, buf.Bytes()[96+n:], rpmTagHeaderImmutable)
+	for _, tc := range []struct {
+		nameTag, versionTag, flagsTag int32
+		names, versions               []string
+		flags                         []int32
+	}{
+		{rpmTagRequireName, rpmTagRequireVersion, rpmTagRequireFlags, []string{"systemd-libs", "shadow-utils"}, []string{"245", ""}, []int32{rpmSenseGreater | rpmSenseEqual, 0}},
+		{rpmTagProvideName, rpmTagProvideVersion, rpmTagProvideFlags, []string{"foo", "foo-exporter"}, []string{"1.2.0~rc.0-1", ""}, []int32{rpmSenseEqual, 0}},
+		{rpmTagConflictName, rpmTagConflictVersion, rpmTagConflictFlags, []string{"bar"}, []string{""}, []int32{0}},
+		{rpmTagRecommendName, rpmTagRecommendVersion, rpmTagRecommendFlags, []string{"logrotate"}, []string{""}, []int32{0}},
+	} {
+		names := header.strings(t, tc.nameTag)
+		// The requirements of rpm and of the scripts come first.
+		names = names[len(names)-len(tc.names):]
+		versions := header.strings(t, tc.versionTag)
+		versions = versions[len(versions)-len(tc.versions):]
+		flags := header.int32s(t, tc.flagsTag)
+		flags = flags[len(flags)-len(tc.flags):]
+		if !reflect.DeepEqual(tc.names, names) || !reflect.DeepEqual(tc.versions, versions) || !reflect.DeepEqual(tc.flags, flags) {
+			t.Fatalf("tag %d: expected %v %v %v, got %v %v %v", tc.nameTag, tc.names, tc.versions, tc.flags, names, versions, flags)
+		}
+	}
+	p.Depends = []string{"systemd-libs ~> 245"}
+	if err := WriteRPM(io.Discard, p); err == nil {
+		t.Fatal("expected error for invalid RPM dependency")
+	}
+
+	p.Depends = []string{"systemd-libs>=245"}
+	info := archLinuxPkgInfo(p, "x86_64", 0)
+	for _, exp := range []string{
+		"depend = systemd-libs>=245\n",
+		"optdepend = logrotate\n",
+		"conflict = bar\n",
+		"provides = foo-exporter\n",
+	} {
+		if !strings.Contains(info, exp) {
+			t.Fatalf("expected %q in .PKGINFO:\n%s", exp, info)
+		}
+	}
+}
+
+func TestWritePKGBUILD(t *testing.T) {
+	p := testPackage()
+	p.Files[0].Source = "foo-1.2.0-rc.0.linux-*/foo"
+	p.Files[1].Source = "foo.yml"
+	var buf bytes.Buffer
+	err := WritePKGBUILD(&buf, p, []string{"amd64", "arm64"}, []PKGBUILDSource{
+		{Arch: "amd64", Name: "foo-1.2.0-rc.0.linux-amd64.tar.gz", URL: "https://example.com/foo-1.2.0-rc.0.linux-amd64.tar.gz", SHA256: "abc"},
+		{Arch: "arm64", Name: "foo-1.2.0-rc.0.linux-arm64.tar.gz", URL: "https://example.com/foo-1.2.0-rc.0.linux-arm64.tar.gz"},
+		{Name: "foo.yml", URL: "https://example.com/conf/config.yml"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, exp := range []string{
+		"pkgname=foo-bin\n",
+		"pkgver=1.2.0_rc.0\n",
+		"arch=('x86_64' 'aarch64')\n",
+		"provides=('foo')\n",
+		"backup=('etc/foo/foo.yml')\n",
+		"install=foo-bin.install\n",
+		"source=('foo.yml::https://example.com/conf/config.yml')\nsha256sums=('SKIP')\n",
+		"source_x86_64=('https://example.com/foo-1.2.0-rc.0.linux-amd64.tar.gz')\nsha256sums_x86_64=('abc')\n",
+		"\tinstall -Dm755 \"$srcdir\"/'foo-1.2.0-rc.0.linux-'*'/foo' \"$pkgdir\"'/usr/bin/foo'\n",
+		"\tln -s 'foo.yml' \"$pkgdir\"'/etc/foo/link.yml'\n",
+	} {
+		if !strings.Contains(buf.String(), exp) {
+			t.Fatalf("expected PKGBUILD to contain %q, got:\n%s", exp, buf.String())
+		}
+	}
+}
+
+func TestWriteWXS(t *testing.T) {
+	p := &Package{
+		Name:        "foo",
+		Version:     "1.2.0-rc.0",
+		Arch:        "arm64",
+		Description: "Foo & bar.",
+		Files: []File{
+			{Path: "/foo.exe", Mode: 0o755, Source: "foo.exe"},
+			{Path: "/docs/a/README.md", Mode: 0o644, Source: "README.md"},
+			{Path: "/docs/b.md", Mode: 0o644, Source: "b.md"},
+			{Path: "/zzz.exe", Mode: 0o755, Source: "zzz.exe"},
+		},
+	}
+	var buf bytes.Buffer
+	if err := WriteWXS(&buf, p, MSIOptions{Service: "/foo.exe", Path: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wxs struct {
+		Product struct {
+			Version string `xml:"Version,attr"`
+			Package struct {
+				Platform    string `xml:"Platform,attr"`
+				Description string `xml:"Description,attr"`
+			}
+			Feature struct {
+				Refs []struct {
+					ID string `xml:"Id,attr"`
+				} `xml:"ComponentRef"`
+			}
+		}
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &wxs); err != nil {
+		t.Fatalf("invalid WiX source: %v\n%s", err, buf.String())
+	}
+	if wxs.Product.Version != "1.2.0" || wxs.Product.Package.Platform != "arm64" || wxs.Product.Package.Description != "Foo & bar." {
+		t.Fatalf("unexpected product %+v", wxs.Product)
+	}
+	if len(wxs.Product.Feature.Refs) != 5 {
+		t.Fatalf("expected 5 components, got %d", len(wxs.Product.Feature.Refs))
+	}
+	for _, exp := range []string{
+		"<ServiceInstall Id=\"service\" Name=\"foo\"",
+		"<Environment Id=\"path\" Name=\"PATH\" Value=\"[INSTALLDIR]\"",
+	} {
+		if !strings.Contains(buf.String(), exp) {
+			t.Fatalf("expected WiX source to contain %q, got:\n%s", exp, buf.String())
+		}
+	}
+
+	if !regexp.MustCompile(`Name="a">\s*<Component Id="c0"`).MatchString(buf.String()) {
+		t.Fatalf("expected docs/a/README.md in the a directory, got:\n%s", buf.String())
+	}
+
+	p.Files = append(p.Files, File{Path: "/link", Mode: fs.ModeSymlink | 0o777, Link: "foo.exe"})
+	if err := WriteWXS(&buf, p, MSIOptions{}); err == nil {
+		t.Fatal("expected error for symbolic link")
+	}
+}
+
+func TestWriteMacOSPkg(t *testing.T) {
+	p := &Package{
+		Name:    "foo",
+		Version: "1.2.0",
+		Arch:    "arm64",
+		Files: []File{
+			{Path: "/usr/local/bin/foo", Mode: 0o755, Data: []byte("binary")},
+		},
+		Scripts: Scripts{PostInstall: "#!/bin/sh\ntrue\n"},
+		ModTime: time.Unix(1700000000, 0),
+	}
+	var buf bytes.Buffer
+	if err := WriteMacOSPkg(&buf, p, "com.example.foo", []byte("BOMStore")); err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.Bytes()
+	if string(b[:4]) != "xar!" || binary.BigEndian.Uint16(b[4:]) != 28 {
+		t.Fatalf("invalid xar header %x", b[:28])
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(b[28 : 28+binary.BigEndian.Uint64(b[8:])]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var toc struct {
+		Files []struct {
+			Name   string `xml:"name"`
+			Offset int    `xml:"data>offset"`
+			Length int    `xml:"data>length"`
+		} `xml:"toc>file"`
+	}
+	if err := xml.NewDecoder(zr).Decode(&toc); err != nil {
+		t.Fatal(err)
+	}
+	heap := b[28+binary.BigEndian.Uint64(b[8:]):]
+	files := map[string][]byte{}
+	for _, f := range toc.Files {
+		files[f.Name] = heap[f.Offset : f.Offset+f.Length]
+	}
+	if len(files) != 4 || string(files["Bom"]) != "BOMStore" {
+		t.Fatalf("unexpected files %v", files)
+	}
+	for _, exp := range []string{
+		`identifier="com.example.foo" version="1.2.0"`,
+		`<payload numberOfFiles="5" installKBytes="1"/>`,
+		`<postinstall file="./postinstall"/>`,
+	} {
+		if !strings.Contains(string(files["PackageInfo"]), exp) {
+			t.Fatalf("expected PackageInfo to contain %q, got:\n%s", exp, files["PackageInfo"])
+		}
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(files["Payload"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for len(payload) > 0 {
+		nameSize, _ := strconv.ParseInt(string(payload[59:65]), 8, 64)
+		size, _ := strconv.ParseInt(string(payload[65:76]), 8, 64)
+		names = append(names, string(payload[76:76+nameSize-1]))
+		payload = payload[76+nameSize+size:]
+	}
+	exp := []string{".", "./usr", "./usr/local", "./usr/local/bin", "./usr/local/bin/foo", "TRAILER!!!"}
+	if !reflect.DeepEqual(names, exp) {
+		t.Fatalf("expected payload %v, got %v", exp, names)
+	}
+}
+
+func TestWriteSnapcraft(t *testing.T) {
+	p := &Package{
+		Name:    "foo",
+		Version: "1.2.0-rc.0",
+		Arch:    "armv7",
+		Files: []File{
+			{Path: "/bin/foo", Mode: 0o755, Data: []byte("binary")},
+			{Path: "/bin/footool", Mode: 0o755, Data: []byte("binary")},
+			{Path: "/foo.yml", Mode: 0o644, Data: []byte("config")},
+		},
+	}
+	var buf bytes.Buffer
+	err := WriteSnapcraft(&buf, p, SnapOptions{
+		Base:      "core22",
+		BuildOn:   "amd64",
+		Plugs:     []string{"network"},
+		Service:   "/bin/foo",
+		Arguments: []string{"--config.file=foo.yml"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, exp := range []string{
+		"version: 1.2.0-rc.0\n",
+		"grade: devel\n",
+		"confinement: strict\n",
+		"- build-on:\n  - amd64\n  build-for:\n  - armhf\n",
+		"  foo:\n    command: bin/foo --config.file=foo.yml\n    daemon: simple\n    plugs:\n    - network\n",
+		"  footool:\n    command: bin/footool\n",
+	} {
+		if !strings.Contains(buf.String(), exp) {
+			t.Fatalf("expected snapcraft.yaml to contain %q, got:\n%s", exp, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "foo.yml:") {
+		t.Fatalf("expected no application for foo.yml, got:\n%s", buf.String())
+	}
+}
+
+func TestWriteRepositories(t *testing.T) {
+	dir := t.TempDir()
+	p := testPackage()
+	for _, w := range []struct {
+		name  string
+		write func(io.Writer, *Package) error
+	}{
+		{"foo.deb", WriteDeb},
+		{"foo.rpm", WriteRPM},
+	} {
+		var buf bytes.Buffer
+		if err := w.write(&buf, p); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, w.name), buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	date := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	if err := WriteAptRepository(dir, []string{"foo.deb"}, "foo", date); err != nil {
+		t.Fatal(err)
+	}
+	packages, err := os.ReadFile(filepath.Join(dir, "Packages"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, exp := range []string{"Package: foo\n", "Version: 1.2.0~rc.0\n", "Architecture: armhf\n", "Filename: foo.deb\n"} {
+		if !strings.Contains(string(packages), exp) {
+			t.Fatalf("expected Packages to contain %q, got:\n%s", exp, packages)
+		}
+	}
+	release, err := os.ReadFile(filepath.Join(dir, AptRelease))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := fmt.Sprintf("SHA256:\n %x %d Packages\n", sha256.Sum256(packages), len(packages))
+	for _, exp := range []string{"Date: Fri, 02 Jan 2026 03:04:05 UTC\n", "Architectures: armhf\n", exp} {
+		if !strings.Contains(string(release), exp) {
+			t.Fatalf("expected Release to contain %q, got:\n%s", exp, release)
+		}
+	}
+
+	if err := WriteYumRepository(dir, []string{"foo.rpm"}, date); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(YumRepomd)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var repomd struct {
+		Data []struct {
+			Type     string `xml:"type,attr"`
+			Location struct {
+				Href string `xml:"href,attr"`
+			} `xml:"location"`
+		} `xml:"data"`
+	}
+	if err := xml.Unmarshal(b, &repomd); err != nil {
+		t.Fatal(err)
+	}
+	if len(repomd.Data) != 3 || repomd.Data[0].Type != "primary" {
+		t.Fatalf("unexpected repomd.xml:\n%s", b)
+	}
+	f, err := os.Open(filepath.Join(dir, filepath.FromSlash(repomd.Data[0].Location.Href)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var primary struct {
+		Packages []struct {
+			Name    string `xml:"name"`
+			Arch    string `xml:"arch"`
+			Version struct {
+				Ver string `xml:"ver,attr"`
+				Rel string `xml:"rel,attr"`
+			} `xml:"version"`
+			Location struct {
+				Href string `xml:"href,attr"`
+			} `xml:"location"`
+			Files []string `xml:"format>file"`
+		} `xml:"package"`
+	}
+	if err := xml.NewDecoder(gr).Decode(&primary); err != nil {
+		t.Fatal(err)
+	}
+	if len(primary.Packages) != 1 {
+		t.Fatalf("expected 1 package, got %d", len(primary.Packages))
+	}
+	pkg := primary.Packages[0]
+	if pkg.Name != "foo" || pkg.Arch != "armv7hl" || pkg.Version.Ver != "1.2.0~rc.0" || pkg.Version.Rel != "1" || pkg.Location.Href != "foo.rpm" {
+		t.Fatalf("unexpected package %+v", pkg)
+	}
+	expFiles := []string{"/etc/foo/foo.yml", "/etc/foo/link.yml", "/usr/bin/foo"}
+	if !reflect.DeepEqual(pkg.Files, expFiles) {
+		t.Fatalf("expected files %v, got %v", expFiles, pkg.Files)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Inspect(&buf, testPackage()); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"Package:     foo 1.2.0-rc.0 (armv7)\n",
+		"Maintainer:  Foo <foo@example.com>\n",
+		"  -rw-r--r-- root:root          5 /etc/foo/foo.yml [config]\n",
+		"  Lrwxrwxrwx root:root          7 /etc/foo/link.yml -> foo.yml\n",
+		"Script postinstall:\n  | #!/bin/sh\n  | echo installed\n",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Fatalf("expected %q in:\n%s", expected, buf.String())
+		}
+	}
+}
+
+func TestLint(t *testing.T) {
+	if warnings := Lint(&Package{Name: "foo", Maintainer: "foo", Description: "foo", Homepage: "foo", License: "foo"}); len(warnings) != 0 {
+		t.Fatalf("expected no warnings, got %q", warnings)
+	}
+
+	p := testPackage()
+	p.Maintainer = ""
+	p.Files = append(p.Files,
+		File{Path: "/etc/foo/foo.yml", Mode: 0o644},
+		File{Path: "/usr/bin/foo/bar", Mode: 0o644},
+		File{Path: "/usr/bin/bar", Mode: 0o755, Source: filepath.Join(t.TempDir(), "bar")},
+		File{Path: "/usr/share/foo.yml", Mode: 0o644, Config: true},
+	)
+	p.Scripts.PreRemove = "echo removed\n"
+	warnings := Lint(p)
+	for i, expected := range []string{
+		"missing maintainer",
+		"missing homepage",
+		"conflicting files for path /etc/foo/foo.yml",
+		"missing source of /usr/bin/bar",
+		"/usr/bin/foo/bar is installed below /usr/bin/foo, which isn't a directory",
+		"configuration file /usr/share/foo.yml is outside of /etc",
+		"preremove script has no shebang",
+	} {
+		if i >= len(warnings) || !strings.HasPrefix(warnings[i], expected) {
+			t.Fatalf("expected warning %q, got %q", expected, warnings)
+		}
+	}
+	if len(warnings) != 7 {
+		t.Fatalf("expected 7 warnings, got %q", warnings)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packaging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// PKGBUILDSource is a file downloaded by a PKGBUILD.
+type PKGBUILDSource struct {
+	// Arch is the Go architecture of the source, empty if the source is used
+	// for all architectures.
+	Arch string
+	// Name is the file name of the source in $srcdir.
+	Name string
+	// URL is the location of the source, empty for local files next to the
+	// PKGBUILD.
+	URL string
+	// SHA256 is the hex-encoded checksum of the source. It isn't checked if
+	// empty.
+	SHA256 string
+}
+
+// PKGBUILDName returns the name of the package built by the PKGBUILD of the
+// package, suffixed with -bin as it installs prebuilt binaries.
+func PKGBUILDName(p *Package) string {
+	return p.Name + "-bin"
+}
+
+// WritePKGBUILD writes a PKGBUILD installing the files of the package for
+// the given Go architectures, the architecture of the package being ignored.
+// The Source of the files is their path relative
+// to $srcdir, where "*" matches any characters, e.g. to find the binaries in
+// the extracted release tarball of any architecture.
+//
+// The package provides and conflicts with the package built from the sources,
+// in addition to its own relationships.
+// Its scripts, if any, must be written to the <name>.install file next to the
+// PKGBUILD, see ArchLinuxInstall.
+func WritePKGBUILD(w io.Writer, p *Package, archs []string, sources []PKGBUILDSource) error {
+	if len(archs) == 0 {
+		return errors.New("no architecture")
+	}
+	valid := *p
+	valid.Arch = archs[0]
+	if err := valid.Validate(); err != nil {
+		return err
+	}
+	var b strings.Builder
+	if p.Maintainer != "" {
+		fmt.Fprintf(&b, "# Maintainer: %s\n", p.Maintainer)
+	}
+	b.WriteString("# Generated by promu\n\n")
+
+	name := PKGBUILDName(p)
+	fmt.Fprintf(&b, "pkgname=%s\n", name)
+	fmt.Fprintf(&b, "pkgver=%s\n", p.archLinuxVersion())
+	fmt.Fprintf(&b, "pkgrel=%s\n", p.release())
+	fmt.Fprintf(&b, "pkgdesc=%s\n", shellQuote(p.summary()))
+
+	pkgArchs := make([]string, 0, len(archs))
+	archNames := make(map[string]string, len(archs))
+	for _, goarch := range archs {
+		arch, err := ArchLinuxArch(goarch)
+		if err != nil {
+			return err
+		}
+		pkgArchs = append(pkgArchs, arch)
+		archNames[goarch] = arch
+	}
+	writeShellArray(&b, "arch", pkgArchs)
+	if p.Homepage != "" {
+		fmt.Fprintf(&b, "url=%s\n", shellQuote(p.Homepage))
+	}
+	if p.License != "" {
+		writeShellArray(&b, "license", []string{p.License})
+	}
+	if len(p.Depends) > 0 {
+		writeShellArray(&b, "depends", p.Depends)
+	}
+	if len(p.Recommends) > 0 {
+		writeShellArray(&b, "optdepends", p.Recommends)
+	}
+	writeShellArray(&b, "provides", append([]string{p.Name}, p.Provides...))
+	writeShellArray(&b, "conflicts", append([]string{p.Name}, p.Conflicts...))
+	var backup []string
+	for _, f := range p.sortedFiles() {
+		if f.Config {
+			backup = append(backup, strings.TrimPrefix(f.Path, "/"))
+		}
+	}
+	if len(backup) > 0 {
+		writeShellArray(&b, "backup", backup)
+	}
+	if ArchLinuxInstall(p.Scripts) != "" {
+		fmt.Fprintf(&b, "install=%s.install\n", name)
+	}
+
+	// Sources of all architectures come first, followed by the sources of
+	// each architecture.
+	for _, goarch := range append([]string{""}, archs...) {
+		var urls, sums []string
+		for _, s := range sources {
+			if s.Arch != goarch {
+				continue
+			}
+			url := s.URL
+			if url == "" {
+				url = s.Name
+			} else if s.Name != path.Base(s.URL) {
+				url = s.Name + "::" + s.URL
+			}
+			urls = append(urls, url)
+			sum := s.SHA256
+			if sum == "" {
+				sum = "SKIP"
+			}
+			sums = append(sums, sum)
+		}
+		if len(urls) == 0 {
+			continue
+		}
+		suffix := ""
+		if goarch != "" {
+			suffix = "_" + archNames[goarch]
+		}
+		b.WriteString("\n")
+		writeShellArray(&b, "source"+suffix, urls)
+		writeShellArray(&b, "sha256sums"+suffix, sums)
+	}
+
+	b.WriteString("\npackage() {\n")
+	for _, f := range p.sortedFiles() {
+		dst := `"$pkgdir"` + shellQuote(f.Path)
+		switch {
+		case f.Mode.IsDir():
+			fmt.Fprintf(&b, "\tinstall -dm%o %s\n", f.Mode.Perm(), dst)
+		case f.Mode&fs.ModeSymlink != 0:
+			fmt.Fprintf(&b, "\tinstall -dm755 \"$pkgdir\"%s\n", shellQuote(path.Dir(f.Path)))
+			fmt.Fprintf(&b, "\tln -s %s %s\n", shellQuote(f.Link), dst)
+		default:
+			fmt.Fprintf(&b, "\tinstall -Dm%o \"$srcdir\"/%s %s\n", f.Mode.Perm(), shellGlob(f.Source), dst)
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeShellArray(b *strings.Builder, name string, values []string) {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, shellQuote(v))
+	}
+	fmt.Fprintf(b, "%s=(%s)\n", name, strings.Join(quoted, " "))
+}
+
+// shellQuote quotes the string for the shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellGlob quotes the string for the shell, except for the "*" wildcards.
+func shellGlob(s string) string {
+	parts := strings.Split(s, "*")
+	for i, p := range parts {
+		if p != "" {
+			parts[i] = shellQuote(p)
+		}
+	}
+	return strings.Join(parts, "*")
+}
@@ -0,0 +1,626 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packaging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// RPMFilename returns the conventional file name of the RPM package.
+func RPMFilename(p *Package) (string, error) {
+	arch, err := rpmArch(p.Arch)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%s-%s.%s.rpm", p.Name, p.version(), p.release(), arch), nil
+}
+
+// Tags of the RPM headers, see
+// https://rpm-software-management.github.io/rpm/manual/tags.html.
+const (
+	rpmTagHeaderSignatures = 62
+	rpmTagHeaderImmutable  = 63
+	rpmTagHeaderI18NTable  = 100
+
+	rpmSigTagSHA1        = 269
+	rpmSigTagSHA256      = 273
+	rpmSigTagSize        = 1000
+	rpmSigTagMD5         = 1004
+	rpmSigTagPayloadSize = 1007
+
+	rpmTagName              = 1000
+	rpmTagVersion           = 1001
+	rpmTagRelease           = 1002
+	rpmTagSummary           = 1004
+	rpmTagDescription       = 1005
+	rpmTagBuildTime         = 1006
+	rpmTagBuildHost         = 1007
+	rpmTagSize              = 1009
+	rpmTagVendor            = 1011
+	rpmTagLicense           = 1014
+	rpmTagPackager          = 1015
+	rpmTagGroup             = 1016
+	rpmTagURL               = 1020
+	rpmTagOS                = 1021
+	rpmTagArch              = 1022
+	rpmTagPreIn             = 1023
+	rpmTagPostIn            = 1024
+	rpmTagPreUn             = 1025
+	rpmTagPostUn            = 1026
+	rpmTagFileSizes         = 1028
+	rpmTagFileModes         = 1030
+	rpmTagFileRDevs         = 1033
+	rpmTagFileMTimes        = 1034
+	rpmTagFileDigests       = 1035
+	rpmTagFileLinkTos       = 1036
+	rpmTagFileFlags         = 1037
+	rpmTagFileUserName      = 1039
+	rpmTagFileGroupName     = 1040
+	rpmTagSourceRPM         = 1044
+	rpmTagFileVerifyFlags   = 1045
+	rpmTagProvideName       = 1047
+	rpmTagRequireFlags      = 1048
+	rpmTagRequireName       = 1049
+	rpmTagRequireVersion    = 1050
+	rpmTagConflictFlags     = 1053
+	rpmTagConflictName      = 1054
+	rpmTagConflictVersion   = 1055
+	rpmTagPreInProg         = 1085
+	rpmTagPostInProg        = 1086
+	rpmTagPreUnProg         = 1087
+	rpmTagPostUnProg        = 1088
+	rpmTagFileDevices       = 1095
+	rpmTagFileINodes        = 1096
+	rpmTagFileLangs         = 1097
+	rpmTagProvideFlags      = 1112
+	rpmTagProvideVersion    = 1113
+	rpmTagDirIndexes        = 1116
+	rpmTagBaseNames         = 1117
+	rpmTagDirNames          = 1118
+	rpmTagPayloadFormat     = 1124
+	rpmTagPayloadCompressor = 1125
+	rpmTagPayloadFlags      = 1126
+	rpmTagFileDigestAlgo    = 5011
+	rpmTagRecommendName     = 5046
+	rpmTagRecommendVersion  = 5047
+	rpmTagRecommendFlags    = 5048
+	rpmTagPayloadDigest     = 5092
+	rpmTagPayloadDigestAlgo = 5093
+)
+
+// Types of the values of the RPM headers.
+const (
+	rpmTypeInt16       = 3
+	rpmTypeInt32       = 4
+	rpmTypeString      = 6
+	rpmTypeBin         = 7
+	rpmTypeStringArray = 8
+	rpmTypeI18NString  = 9
+)
+
+const (
+	rpmFileConfig    = 1 << 0
+	rpmFileNoReplace = 1 << 4
+
+	rpmSenseLess    = 1 << 1
+	rpmSenseGreater = 1 << 2
+	rpmSenseEqual   = 1 << 3
+	rpmSenseRPMLib  = 1 << 24
+
+	rpmDigestSHA256 = 8
+)
+
+// rpmHeaderEntry is a tag of an RPM header with its encoded value.
+type rpmHeaderEntry struct {
+	tag   int32
+	typ   int32
+	count int32
+	data  []byte
+}
+
+// rpmHeader is an RPM header, the structure holding both the metadata and
+// the signatures of a package.
+type rpmHeader struct {
+	entries []rpmHeaderEntry
+}
+
+func (h *rpmHeader) add(tag, typ int32, count int, data []byte) {
+	h.entries = append(h.entries, rpmHeaderEntry{tag: tag, typ: typ, count: int32(count), data: data})
+}
+
+func (h *rpmHeader) addString(tag int32, s string) {
+	h.add(tag, rpmTypeString, 1, append([]byte(s), 0))
+}
+
+func (h *rpmHeader) addI18NString(tag int32, s string) {
+	h.add(tag, rpmTypeI18NString, 1, append([]byte(s), 0))
+}
+
+func (h *rpmHeader) addStrings(tag int32, values ...string) {
+	var data []byte
+	for _, s := range values {
+		data = append(append(data, s...), 0)
+	}
+	h.add(tag, rpmTypeStringArray, len(values), data)
+}
+
+func (h *rpmHeader) addInt32(tag int32, values ...int32) {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint32(data[4*i:], uint32(v))
+	}
+	h.add(tag, rpmTypeInt32, len(values), data)
+}
+
+func (h *rpmHeader) addInt16(tag int32, values ...int16) {
+	data := make([]byte, 2*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint16(data[2*i:], uint16(v))
+	}
+	h.add(tag, rpmTypeInt16, len(values), data)
+}
+
+func (h *rpmHeader) addBin(tag int32, data []byte) {
+	h.add(tag, rpmTypeBin, len(data), data)
+}
+
+// bytes encodes the header, with a region tag covering all the entries.
+func (h *rpmHeader) bytes(region int32) []byte {
+	entries := make([]rpmHeaderEntry, len(h.entries))
+	copy(entries, h.entries)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+
+	var (
+		index = make([]byte, 0, 16*(len(entries)+1))
+		store []byte
+	)
+	appendIndex := func(tag, typ, offset, count int32) {
+		for _, v := range []int32{tag, typ, offset, count} {
+			index = binary.BigEndian.AppendUint32(index, uint32(v))
+		}
+	}
+	// The region tag comes first and points to its trailer, at the end of
+	// the store.
+	appendIndex(region, rpmTypeBin, 0, 16)
+	for _, e := range entries {
+		align := 1
+		switch e.typ {
+		case rpmTypeInt16:
+			align = 2
+		case rpmTypeInt32:
+			align = 4
+		}
+		for len(store)%align != 0 {
+			store = append(store, 0)
+		}
+		appendIndex(e.tag, e.typ, int32(len(store)), e.count)
+		store = append(store, e.data...)
+	}
+	binary.BigEndian.PutUint32(index[8:], uint32(len(store)))
+	for _, v := range []int32{region, rpmTypeBin, -16 * int32(len(entries)+1), 16} {
+		store = binary.BigEndian.AppendUint32(store, uint32(v))
+	}
+
+	b := []byte{0x8e, 0xad, 0xe8, 0x01, 0, 0, 0, 0}
+	b = binary.BigEndian.AppendUint32(b, uint32(len(entries)+1))
+	b = binary.BigEndian.AppendUint32(b, uint32(len(store)))
+	return append(append(b, index...), store...)
+}
+
+// rpmFile is a file of the RPM payload.
+type rpmFile struct {
+	File
+	size   int64
+	digest string
+}
+
+// WriteRPM writes the RPM package to w.
+func WriteRPM(w io.Writer, p *Package) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	arch, err := rpmArch(p.Arch)
+	if err != nil {
+		return err
+	}
+
+	files := make([]rpmFile, 0, len(p.Files))
+	for _, f := range p.sortedFiles() {
+		size, err := f.size()
+		if err != nil {
+			return err
+		}
+		files = append(files, rpmFile{File: f, size: size})
+	}
+	payload, payloadSize, err := rpmPayload(files, p)
+	if err != nil {
+		return fmt.Errorf("failed to create payload: %w", err)
+	}
+	h, err := rpmMainHeader(p, arch, files, payload)
+	if err != nil {
+		return err
+	}
+	header := h.bytes(rpmTagHeaderImmutable)
+
+	var sig rpmHeader
+	sig.addInt32(rpmSigTagSize, int32(len(header)+len(payload)))
+	md5sum := md5.New()
+	md5sum.Write(header)
+	md5sum.Write(payload)
+	sig.addBin(rpmSigTagMD5, md5sum.Sum(nil))
+	sig.addInt32(rpmSigTagPayloadSize, int32(payloadSize))
+	sha1sum := sha1.Sum(header)
+	sig.addString(rpmSigTagSHA1, hex.EncodeToString(sha1sum[:]))
+	sha256sum := sha256.Sum256(header)
+	sig.addString(rpmSigTagSHA256, hex.EncodeToString(sha256sum[:]))
+	signature := sig.bytes(rpmTagHeaderSignatures)
+	// The signature header is aligned on 8 bytes.
+	for len(signature)%8 != 0 {
+		signature = append(signature, 0)
+	}
+
+	for _, b := range [][]byte{rpmLead(p), signature, header, payload} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rpmLead returns the lead of the package, which only remains for
+// compatibility with old tools.
+func rpmLead(p *Package) []byte {
+	lead := make([]byte, 96)
+	copy(lead, []byte{0xed, 0xab, 0xee, 0xdb, 3, 0})
+	// Binary package type and architecture number, unused.
+	binary.BigEndian.PutUint16(lead[6:], 0)
+	binary.BigEndian.PutUint16(lead[8:], 1)
+	name := fmt.Sprintf("%s-%s-%s", p.Name, p.version(), p.release())
+	if len(name) > 65 {
+		name = name[:65]
+	}
+	copy(lead[10:], name)
+	// Linux OS number and header-style signature type.
+	binary.BigEndian.PutUint16(lead[76:], 1)
+	binary.BigEndian.PutUint16(lead[78:], 5)
+	return lead
+}
+
+// rpmPayload returns the gzipped cpio archive of the files and its
+// uncompressed size. It also sets the SHA256 digest of the regular files.
+func rpmPayload(files []rpmFile, p *Package) ([]byte, int64, error) {
+	var (
+		buf bytes.Buffer
+		gw  = gzip.NewWriter(&buf)
+		cw  = &countingWriter{w: gw}
+	)
+	for i := range files {
+		f := &files[i]
+		if err := writeCPIOHeader(cw, "."+f.Path, i+1, rpmFileMode(f.Mode), p.ModTime.Unix(), f.size); err != nil {
+			return nil, 0, err
+		}
+		switch {
+		case f.Mode.IsDir():
+		case f.Mode&fs.ModeSymlink != 0:
+			if _, err := io.WriteString(cw, f.Link); err != nil {
+				return nil, 0, err
+			}
+		default:
+			r, err := f.open()
+			if err != nil {
+				return nil, 0, err
+			}
+			sum := sha256.New()
+			n, err := io.Copy(io.MultiWriter(cw, sum), r)
+			r.Close()
+			if err != nil {
+				return nil, 0, err
+			}
+			if n != f.size {
+				return nil, 0, fmt.Errorf("%s changed while being read", f.Path)
+			}
+			f.digest = hex.EncodeToString(sum.Sum(nil))
+		}
+		if err := cpioPad(cw); err != nil {
+			return nil, 0, err
+		}
+	}
+	if err := writeCPIOHeader(cw, "TRAILER!!!", 0, 0, 0, 0); err != nil {
+		return nil, 0, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), cw.n, nil
+}
+
+// writeCPIOHeader writes the header of a file, followed by its name, in the
+// "new ASCII" cpio format.
+func writeCPIOHeader(w *countingWriter, name string, ino int, mode uint32, mtime, size int64) error {
+	nlink := 1
+	if mode&0o40000 != 0 {
+		nlink = 2
+	}
+	_, err := fmt.Fprintf(w, "070701%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%s\x00",
+		ino, mode, 0, 0, nlink, mtime, size, 0, 0, 0, 0, len(name)+1, 0, name)
+	if err != nil {
+		return err
+	}
+	return cpioPad(w)
+}
+
+// cpioPad aligns the cpio archive on 4 bytes.
+func cpioPad(w *countingWriter) error {
+	if pad := (4 - w.n%4) % 4; pad > 0 {
+		_, err := w.Write(make([]byte, pad))
+		return err
+	}
+	return nil
+}
+
+// rpmFileMode returns the mode of the file in the format of stat(2).
+func rpmFileMode(mode fs.FileMode) uint32 {
+	m := uint32(mode.Perm())
+	switch {
+	case mode.IsDir():
+		m |= 0o40000
+	case mode&fs.ModeSymlink != 0:
+		m |= 0o120000
+	default:
+		m |= 0o100000
+	}
+	return m
+}
+
+// rpmDependency is a requirement or a capability of a package.
+type rpmDependency struct {
+	name    string
+	version string
+	flags   int32
+}
+
+// rpmOperators maps the comparison operators of the dependencies to their
+// flags.
+var rpmOperators = map[string]int32{
+	"<":  rpmSenseLess,
+	"<=": rpmSenseLess | rpmSenseEqual,
+	"=":  rpmSenseEqual,
+	">=": rpmSenseGreater | rpmSenseEqual,
+	">":  rpmSenseGreater,
+}
+
+// parseRPMDependencies parses dependencies of the form "name", or
+// "name op version" with op being <, <=, =, >= or >.
+func parseRPMDependencies(dependencies []string) ([]rpmDependency, error) {
+	deps := make([]rpmDependency, 0, len(dependencies))
+	for _, d := range dependencies {
+		fields := strings.Fields(d)
+		switch len(fields) {
+		case 1:
+			deps = append(deps, rpmDependency{name: fields[0]})
+			continue
+		case 3:
+			if flags, ok := rpmOperators[fields[1]]; ok {
+				deps = append(deps, rpmDependency{fields[0], fields[2], flags})
+				continue
+			}
+		}
+		return nil, fmt.Errorf("invalid RPM dependency %q", d)
+	}
+	return deps, nil
+}
+
+// addDependencies adds the names, versions and flags of the dependencies
+// under the given tags.
+func (h *rpmHeader) addDependencies(nameTag, versionTag, flagsTag int32, deps []rpmDependency) {
+	var (
+		names    = make([]string, 0, len(deps))
+		versions = make([]string, 0, len(deps))
+		flags    = make([]int32, 0, len(deps))
+	)
+	for _, d := range deps {
+		names = append(names, d.name)
+		versions = append(versions, d.version)
+		flags = append(flags, d.flags)
+	}
+	h.addStrings(nameTag, names...)
+	h.addStrings(versionTag, versions...)
+	h.addInt32(flagsTag, flags...)
+}
+
+// rpmMainHeader returns the header holding the metadata of the package.
+func rpmMainHeader(p *Package, arch string, files []rpmFile, payload []byte) (*rpmHeader, error) {
+	var (
+		h       rpmHeader
+		version = p.version()
+		release = p.release()
+	)
+	h.addStrings(rpmTagHeaderI18NTable, "C")
+	h.addString(rpmTagName, p.Name)
+	h.addString(rpmTagVersion, version)
+	h.addString(rpmTagRelease, release)
+	h.addI18NString(rpmTagSummary, p.summary())
+	h.addI18NString(rpmTagDescription, strings.TrimSpace(p.Description))
+	h.addInt32(rpmTagBuildTime, int32(p.ModTime.Unix()))
+	h.addString(rpmTagBuildHost, "localhost")
+	if p.Vendor != "" {
+		h.addString(rpmTagVendor, p.Vendor)
+	}
+	if p.License != "" {
+		h.addString(rpmTagLicense, p.License)
+	}
+	if p.Maintainer != "" {
+		h.addString(rpmTagPackager, p.Maintainer)
+	}
+	h.addI18NString(rpmTagGroup, "Unspecified")
+	if p.Homepage != "" {
+		h.addString(rpmTagURL, p.Homepage)
+	}
+	h.addString(rpmTagOS, "linux")
+	h.addString(rpmTagArch, arch)
+	// Binary packages are told apart from source packages by this tag.
+	h.addString(rpmTagSourceRPM, fmt.Sprintf("%s-%s-%s.src.rpm", p.Name, version, release))
+
+	requires := []rpmDependency{
+		{"rpmlib(CompressedFileNames)", "3.0.4-1", rpmSenseLess | rpmSenseEqual | rpmSenseRPMLib},
+		{"rpmlib(FileDigests)", "4.6.0-1", rpmSenseLess | rpmSenseEqual | rpmSenseRPMLib},
+		{"rpmlib(PayloadFilesHavePrefix)", "4.0-1", rpmSenseLess | rpmSenseEqual | rpmSenseRPMLib},
+	}
+	scripts := []struct {
+		script       string
+		tag, progTag int32
+	}{
+		{p.Scripts.PreInstall, rpmTagPreIn, rpmTagPreInProg},
+		{p.Scripts.PostInstall, rpmTagPostIn, rpmTagPostInProg},
+		{p.Scripts.PreRemove, rpmTagPreUn, rpmTagPreUnProg},
+		{p.Scripts.PostRemove, rpmTagPostUn, rpmTagPostUnProg},
+	}
+	shell := false
+	for _, s := range scripts {
+		if s.script != "" {
+			h.addString(s.tag, s.script)
+			h.addString(s.progTag, "/bin/sh")
+			shell = true
+		}
+	}
+	if shell {
+		requires = append(requires, rpmDependency{"/bin/sh", "", 0})
+	}
+	// The package provides itself in addition to the configured
+	// capabilities.
+	for _, r := range []struct {
+		deps                          []rpmDependency
+		relations                     []string
+		nameTag, versionTag, flagsTag int32
+	}{
+		{requires, p.Depends, rpmTagRequireName, rpmTagRequireVersion, rpmTagRequireFlags},
+		{[]rpmDependency{{p.Name, version + "-" + release, rpmSenseEqual}}, p.Provides, rpmTagProvideName, rpmTagProvideVersion, rpmTagProvideFlags},
+		{nil, p.Conflicts, rpmTagConflictName, rpmTagConflictVersion, rpmTagConflictFlags},
+		{nil, p.Recommends, rpmTagRecommendName, rpmTagRecommendVersion, rpmTagRecommendFlags},
+	} {
+		deps, err := parseRPMDependencies(r.relations)
+		if err != nil {
+			return nil, err
+		}
+		if deps = append(r.deps, deps...); len(deps) > 0 {
+			h.addDependencies(r.nameTag, r.versionTag, r.flagsTag, deps)
+		}
+	}
+
+	if len(files) > 0 {
+		var (
+			n           = len(files)
+			total       int64
+			sizes       = make([]int32, n)
+			modes       = make([]int16, n)
+			rdevs       = make([]int16, n)
+			mtimes      = make([]int32, n)
+			digests     = make([]string, n)
+			links       = make([]string, n)
+			flags       = make([]int32, n)
+			users       = make([]string, n)
+			groups      = make([]string, n)
+			verifyFlags = make([]int32, n)
+			devices     = make([]int32, n)
+			inodes      = make([]int32, n)
+			langs       = make([]string, n)
+			dirIndexes  = make([]int32, n)
+			baseNames   = make([]string, n)
+			dirNames    []string
+			dirs        = map[string]int32{}
+		)
+		for i, f := range files {
+			total += f.size
+			sizes[i] = int32(f.size)
+			modes[i] = int16(rpmFileMode(f.Mode))
+			mtimes[i] = int32(p.ModTime.Unix())
+			digests[i] = f.digest
+			links[i] = f.Link
+			if f.Config {
+				flags[i] = rpmFileConfig | rpmFileNoReplace
+			}
+			users[i] = "root"
+			groups[i] = "root"
+			verifyFlags[i] = -1
+			devices[i] = 1
+			inodes[i] = int32(i + 1)
+
+			dir := path.Dir(f.Path) + "/"
+			if dir == "//" {
+				dir = "/"
+			}
+			idx, ok := dirs[dir]
+			if !ok {
+				idx = int32(len(dirNames))
+				dirs[dir] = idx
+				dirNames = append(dirNames, dir)
+			}
+			dirIndexes[i] = idx
+			baseNames[i] = path.Base(f.Path)
+		}
+		h.addInt32(rpmTagSize, int32(total))
+		h.addInt32(rpmTagFileSizes, sizes...)
+		h.addInt16(rpmTagFileModes, modes...)
+		h.addInt16(rpmTagFileRDevs, rdevs...)
+		h.addInt32(rpmTagFileMTimes, mtimes...)
+		h.addStrings(rpmTagFileDigests, digests...)
+		h.addStrings(rpmTagFileLinkTos, links...)
+		h.addInt32(rpmTagFileFlags, flags...)
+		h.addStrings(rpmTagFileUserName, users...)
+		h.addStrings(rpmTagFileGroupName, groups...)
+		h.addInt32(rpmTagFileVerifyFlags, verifyFlags...)
+		h.addInt32(rpmTagFileDevices, devices...)
+		h.addInt32(rpmTagFileINodes, inodes...)
+		h.addStrings(rpmTagFileLangs, langs...)
+		h.addInt32(rpmTagDirIndexes, dirIndexes...)
+		h.addStrings(rpmTagBaseNames, baseNames...)
+		h.addStrings(rpmTagDirNames, dirNames...)
+		h.addInt32(rpmTagFileDigestAlgo, rpmDigestSHA256)
+	} else {
+		h.addInt32(rpmTagSize, 0)
+	}
+
+	h.addString(rpmTagPayloadFormat, "cpio")
+	h.addString(rpmTagPayloadCompressor, "gzip")
+	h.addString(rpmTagPayloadFlags, "9")
+	payloadDigest := sha256.Sum256(payload)
+	h.addStrings(rpmTagPayloadDigest, hex.EncodeToString(payloadDigest[:]))
+	h.addInt32(rpmTagPayloadDigestAlgo, rpmDigestSHA256)
+	return &h, nil
+}
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
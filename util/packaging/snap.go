@@ -0,0 +1,181 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packaging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// snapArchs are the Go architectures supported by snaps, which share their
+// Debian names.
+var snapArchs = map[string]bool{
+	"386":     true,
+	"amd64":   true,
+	"arm64":   true,
+	"armv7":   true,
+	"ppc64le": true,
+	"riscv64": true,
+	"s390x":   true,
+}
+
+// SnapArch returns the snap name of the Go architecture.
+func SnapArch(goarch string) (string, error) {
+	if !snapArchs[goarch] {
+		return "", fmt.Errorf("unsupported architecture %s", goarch)
+	}
+	return debArch(goarch)
+}
+
+// SnapFilename returns the conventional file name of the snap of the
+// package.
+func SnapFilename(p *Package) (string, error) {
+	arch, err := SnapArch(p.Arch)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s_%s_%s.snap", p.Name, p.Version, arch), nil
+}
+
+// SnapOptions are the snap specific parameters of a package.
+type SnapOptions struct {
+	// Base is the base snap, e.g. core22.
+	Base string
+	// Confinement is strict, classic or devmode.
+	Confinement string
+	// BuildOn is the Go architecture of the host building the snap.
+	BuildOn string
+	// Plugs are the interfaces connected to the applications.
+	Plugs []string
+	// Service is the path of the binary run as a daemon, if not empty,
+	// with its Arguments.
+	Service   string
+	Arguments []string
+}
+
+type snapcraft struct {
+	Name          string              `yaml:"name"`
+	Base          string              `yaml:"base"`
+	Version       string              `yaml:"version"`
+	Summary       string              `yaml:"summary"`
+	Description   string              `yaml:"description"`
+	License       string              `yaml:"license,omitempty"`
+	Website       string              `yaml:"website,omitempty"`
+	Grade         string              `yaml:"grade"`
+	Confinement   string              `yaml:"confinement"`
+	Architectures []snapArchitecture  `yaml:"architectures"`
+	Parts         map[string]snapPart `yaml:"parts"`
+	Apps          map[string]snapApp  `yaml:"apps"`
+}
+
+type snapArchitecture struct {
+	BuildOn  []string `yaml:"build-on"`
+	BuildFor []string `yaml:"build-for"`
+}
+
+type snapPart struct {
+	Plugin string `yaml:"plugin"`
+	Source string `yaml:"source"`
+}
+
+type snapApp struct {
+	Command string   `yaml:"command"`
+	Daemon  string   `yaml:"daemon,omitempty"`
+	Plugs   []string `yaml:"plugs,omitempty"`
+}
+
+// WriteSnapcraft writes the snapcraft.yaml of the package, whose files are
+// dumped as is into the snap from the directory of the snapcraft project.
+// Their paths are relative to the root of the snap and the binaries, i.e. the
+// executable files, are exposed as applications named after them.
+// Pre-release versions are published with the devel grade.
+func WriteSnapcraft(w io.Writer, p *Package, o SnapOptions) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if o.Base == "" {
+		return errors.New("missing snap base")
+	}
+	arch, err := SnapArch(p.Arch)
+	if err != nil {
+		return err
+	}
+	buildOn, err := SnapArch(o.BuildOn)
+	if err != nil {
+		return err
+	}
+	s := snapcraft{
+		Name:        p.Name,
+		Base:        o.Base,
+		Version:     p.Version,
+		Summary:     p.summary(),
+		Description: strings.TrimSpace(p.Description),
+		License:     p.License,
+		Website:     p.Homepage,
+		Grade:       "stable",
+		Confinement: o.Confinement,
+		Architectures: []snapArchitecture{
+			{BuildOn: []string{buildOn}, BuildFor: []string{arch}},
+		},
+		Parts: map[string]snapPart{p.Name: {Plugin: "dump", Source: "."}},
+		Apps:  map[string]snapApp{},
+	}
+	if strings.Contains(p.Version, "-") {
+		s.Grade = "devel"
+	}
+	if s.Description == "" {
+		s.Description = s.Summary
+	}
+	if s.Confinement == "" {
+		s.Confinement = "strict"
+	}
+
+	service := false
+	for _, f := range p.sortedFiles() {
+		if !f.Mode.IsRegular() || f.Mode.Perm()&0o111 == 0 {
+			continue
+		}
+		app := snapApp{
+			Command: strings.TrimPrefix(f.Path, "/"),
+			Plugs:   o.Plugs,
+		}
+		if f.Path == o.Service {
+			service = true
+			app.Daemon = "simple"
+			for _, arg := range o.Arguments {
+				app.Command += " " + arg
+			}
+		}
+		s.Apps[path.Base(f.Path)] = app
+	}
+	if o.Service != "" && !service {
+		return fmt.Errorf("service binary %s isn't in the package", o.Service)
+	}
+
+	b, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "# Generated by promu\n"); err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
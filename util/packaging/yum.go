@@ -0,0 +1,345 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packaging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// YumRepomd is the path of the index of the metadata of a yum repository,
+// which is signed into repomd.xml.asc.
+const YumRepomd = "repodata/repomd.xml"
+
+// rpmSenses maps the comparison flags of the RPM dependencies to their
+// repository metadata names.
+var rpmSenses = map[int32]string{
+	2:  "LT",
+	4:  "GT",
+	8:  "EQ",
+	10: "LE",
+	12: "GE",
+}
+
+// rpmPackageInfo is the metadata of an RPM package.
+type rpmPackageInfo struct {
+	href     string
+	checksum string
+	size     int
+	modTime  int64
+	// start and end are the offsets of the main header in the package.
+	start, end int
+	header     map[int32]rpmHeaderValue
+}
+
+// rpmHeaderValue is the raw value of an RPM header entry.
+type rpmHeaderValue struct {
+	typ   int32
+	count int
+	data  []byte
+}
+
+func (p *rpmPackageInfo) string(tag int32) string {
+	v, ok := p.header[tag]
+	if !ok {
+		return ""
+	}
+	s, _, _ := bytes.Cut(v.data, []byte{0})
+	return string(s)
+}
+
+func (p *rpmPackageInfo) strings(tag int32) []string {
+	v, ok := p.header[tag]
+	if !ok {
+		return nil
+	}
+	values := strings.SplitN(string(v.data), "\x00", v.count+1)
+	return values[:min(v.count, len(values))]
+}
+
+func (p *rpmPackageInfo) ints(tag int32) []int32 {
+	v, ok := p.header[tag]
+	if !ok {
+		return nil
+	}
+	values := make([]int32, 0, v.count)
+	switch v.typ {
+	case rpmTypeInt16:
+		for i := 0; i < v.count && 2*i+2 <= len(v.data); i++ {
+			values = append(values, int32(binary.BigEndian.Uint16(v.data[2*i:])))
+		}
+	case rpmTypeInt32:
+		for i := 0; i < v.count && 4*i+4 <= len(v.data); i++ {
+			values = append(values, int32(binary.BigEndian.Uint32(v.data[4*i:])))
+		}
+	}
+	return values
+}
+
+func (p *rpmPackageInfo) int(tag int32) int64 {
+	if values := p.ints(tag); len(values) > 0 {
+		return int64(uint32(values[0]))
+	}
+	return 0
+}
+
+// parseRPMHeader parses the header at the beginning of b, returning its
+// entries and its size.
+func parseRPMHeader(b []byte) (map[int32]rpmHeaderValue, int, error) {
+	if len(b) < 16 || !bytes.Equal(b[:4], []byte{0x8e, 0xad, 0xe8, 0x01}) {
+		return nil, 0, errors.New("invalid RPM header")
+	}
+	var (
+		count = int(binary.BigEndian.Uint32(b[8:]))
+		size  = int(binary.BigEndian.Uint32(b[12:]))
+		store = 16 + 16*count
+	)
+	if store+size > len(b) {
+		return nil, 0, errors.New("truncated RPM header")
+	}
+	entries := make(map[int32]rpmHeaderValue, count)
+	for i := 0; i < count; i++ {
+		e := b[16+16*i:]
+		var (
+			tag    = int32(binary.BigEndian.Uint32(e))
+			typ    = int32(binary.BigEndian.Uint32(e[4:]))
+			offset = int(binary.BigEndian.Uint32(e[8:]))
+			n      = int(binary.BigEndian.Uint32(e[12:]))
+		)
+		if offset < 0 || offset > size {
+			return nil, 0, errors.New("invalid RPM header entry")
+		}
+		entries[tag] = rpmHeaderValue{typ: typ, count: n, data: b[store+offset : store+size]}
+	}
+	return entries, store + size, nil
+}
+
+// readRPMPackage reads the metadata of the RPM package.
+func readRPMPackage(dir, rpm string) (*rpmPackageInfo, error) {
+	b, err := os.ReadFile(filepath.Join(dir, rpm))
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filepath.Join(dir, rpm))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 96 || !bytes.Equal(b[:4], []byte{0xed, 0xab, 0xee, 0xdb}) {
+		return nil, errors.New("not an RPM package")
+	}
+	_, sigSize, err := parseRPMHeader(b[96:])
+	if err != nil {
+		return nil, err
+	}
+	start := 96 + sigSize
+	start += (8 - start%8) % 8
+	if start > len(b) {
+		return nil, errors.New("truncated RPM package")
+	}
+	header, size, err := parseRPMHeader(b[start:])
+	if err != nil {
+		return nil, err
+	}
+	return &rpmPackageInfo{
+		href:     path.Clean(filepath.ToSlash(rpm)),
+		checksum: fmt.Sprintf("%x", sha256.Sum256(b)),
+		size:     len(b),
+		modTime:  info.ModTime().Unix(),
+		start:    start,
+		end:      start + size,
+		header:   header,
+	}, nil
+}
+
+// rpmEVR splits an RPM dependency version into its epoch, version and
+// release.
+func rpmEVR(evr string) (string, string, string) {
+	epoch, vr, ok := strings.Cut(evr, ":")
+	if !ok {
+		epoch, vr = "0", evr
+	}
+	v, r, _ := strings.Cut(vr, "-")
+	return epoch, v, r
+}
+
+// WriteYumRepository writes the metadata of a yum repository holding the
+// given RPM files, whose paths are relative to dir, into the repodata
+// directory of dir: the primary, filelists and other indexes, and the
+// repomd.xml file listing them. The repository is used with the
+// "baseurl=<url>" setting.
+func WriteYumRepository(dir string, rpms []string, date time.Time) error {
+	var primary, filelists, other strings.Builder
+	fmt.Fprintf(&primary, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<metadata xmlns=\"http://linux.duke.edu/metadata/common\" xmlns:rpm=\"http://linux.duke.edu/metadata/rpm\" packages=\"%d\">\n", len(rpms))
+	fmt.Fprintf(&filelists, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<filelists xmlns=\"http://linux.duke.edu/metadata/filelists\" packages=\"%d\">\n", len(rpms))
+	fmt.Fprintf(&other, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<otherdata xmlns=\"http://linux.duke.edu/metadata/other\" packages=\"%d\">\n", len(rpms))
+	for _, rpm := range rpms {
+		p, err := readRPMPackage(dir, rpm)
+		if err != nil {
+			return fmt.Errorf("%s: %w", rpm, err)
+		}
+		writeYumPackage(&primary, &filelists, &other, p)
+	}
+	primary.WriteString("</metadata>\n")
+	filelists.WriteString("</filelists>\n")
+	other.WriteString("</otherdata>\n")
+
+	// The indexes are named after their checksum, the previous ones are
+	// removed.
+	repodata := filepath.Join(dir, path.Dir(YumRepomd))
+	if err := os.RemoveAll(repodata); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(repodata, 0o755); err != nil {
+		return err
+	}
+	var repomd strings.Builder
+	repomd.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<repomd xmlns=\"http://linux.duke.edu/metadata/repo\" xmlns:rpm=\"http://linux.duke.edu/metadata/rpm\">\n")
+	fmt.Fprintf(&repomd, "  <revision>%d</revision>\n", date.Unix())
+	for _, data := range []struct {
+		typ     string
+		content string
+	}{
+		{"primary", primary.String()},
+		{"filelists", filelists.String()},
+		{"other", other.String()},
+	} {
+		var gzipped bytes.Buffer
+		gw := gzip.NewWriter(&gzipped)
+		if _, err := gw.Write([]byte(data.content)); err != nil {
+			return err
+		}
+		if err := gw.Close(); err != nil {
+			return err
+		}
+		sum := fmt.Sprintf("%x", sha256.Sum256(gzipped.Bytes()))
+		name := sum + "-" + data.typ + ".xml.gz"
+		if err := os.WriteFile(filepath.Join(repodata, name), gzipped.Bytes(), 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(&repomd, "  <data type=\"%s\">\n", data.typ)
+		fmt.Fprintf(&repomd, "    <checksum type=\"sha256\">%s</checksum>\n", sum)
+		fmt.Fprintf(&repomd, "    <open-checksum type=\"sha256\">%x</open-checksum>\n", sha256.Sum256([]byte(data.content)))
+		fmt.Fprintf(&repomd, "    <location href=\"%s\"/>\n", path.Join(path.Dir(YumRepomd), name))
+		fmt.Fprintf(&repomd, "    <timestamp>%d</timestamp>\n", date.Unix())
+		fmt.Fprintf(&repomd, "    <size>%d</size>\n    <open-size>%d</open-size>\n", gzipped.Len(), len(data.content))
+		repomd.WriteString("  </data>\n")
+	}
+	repomd.WriteString("</repomd>\n")
+	return os.WriteFile(filepath.Join(dir, filepath.FromSlash(YumRepomd)), []byte(repomd.String()), 0o644)
+}
+
+// writeYumPackage writes the entries of the package to the primary,
+// filelists and other indexes of a yum repository.
+func writeYumPackage(primary, filelists, other *strings.Builder, p *rpmPackageInfo) {
+	var (
+		name    = xmlText(p.string(rpmTagName))
+		arch    = xmlText(p.string(rpmTagArch))
+		version = fmt.Sprintf("<version epoch=\"0\" ver=%s rel=%s/>", xmlAttr(p.string(rpmTagVersion)), xmlAttr(p.string(rpmTagRelease)))
+	)
+
+	// Directories are flagged in the indexes and the primary index only
+	// lists the files which are commonly depended on.
+	var (
+		files, primaryFiles []string
+		dirnames            = p.strings(rpmTagDirNames)
+		indexes             = p.ints(rpmTagDirIndexes)
+		modes               = p.ints(rpmTagFileModes)
+	)
+	for i, base := range p.strings(rpmTagBaseNames) {
+		if i >= len(indexes) || int(indexes[i]) >= len(dirnames) {
+			break
+		}
+		dir := dirnames[indexes[i]]
+		entry := "<file>" + xmlText(dir+base) + "</file>"
+		if i < len(modes) && fs.FileMode(modes[i])&0o170000 == 0o040000 {
+			entry = "<file type=\"dir\">" + xmlText(dir+base) + "</file>"
+		}
+		files = append(files, entry)
+		if strings.HasPrefix(dir, "/etc/") || strings.HasSuffix(dir, "bin/") {
+			primaryFiles = append(primaryFiles, entry)
+		}
+	}
+
+	fmt.Fprintf(primary, "<package type=\"rpm\">\n  <name>%s</name>\n  <arch>%s</arch>\n  %s\n", name, arch, version)
+	fmt.Fprintf(primary, "  <checksum type=\"sha256\" pkgid=\"YES\">%s</checksum>\n", p.checksum)
+	fmt.Fprintf(primary, "  <summary>%s</summary>\n  <description>%s</description>\n", xmlText(p.string(rpmTagSummary)), xmlText(p.string(rpmTagDescription)))
+	fmt.Fprintf(primary, "  <packager>%s</packager>\n  <url>%s</url>\n", xmlText(p.string(rpmTagPackager)), xmlText(p.string(rpmTagURL)))
+	fmt.Fprintf(primary, "  <time file=\"%d\" build=\"%d\"/>\n", p.modTime, p.int(rpmTagBuildTime))
+	fmt.Fprintf(primary, "  <size package=\"%d\" installed=\"%d\" archive=\"%d\"/>\n", p.size, p.int(rpmTagSize), p.size-p.end)
+	fmt.Fprintf(primary, "  <location href=%s/>\n  <format>\n", xmlAttr(p.href))
+	fmt.Fprintf(primary, "    <rpm:license>%s</rpm:license>\n    <rpm:vendor>%s</rpm:vendor>\n", xmlText(p.string(rpmTagLicense)), xmlText(p.string(rpmTagVendor)))
+	fmt.Fprintf(primary, "    <rpm:group>%s</rpm:group>\n    <rpm:buildhost>%s</rpm:buildhost>\n", xmlText(p.string(rpmTagGroup)), xmlText(p.string(rpmTagBuildHost)))
+	fmt.Fprintf(primary, "    <rpm:sourcerpm>%s</rpm:sourcerpm>\n", xmlText(p.string(rpmTagSourceRPM)))
+	fmt.Fprintf(primary, "    <rpm:header-range start=\"%d\" end=\"%d\"/>\n", p.start, p.end)
+	for _, deps := range []struct {
+		name            string
+		names, versions []string
+		flags           []int32
+		skipRPMLib      bool
+	}{
+		{"provides", p.strings(rpmTagProvideName), p.strings(rpmTagProvideVersion), p.ints(rpmTagProvideFlags), false},
+		{"requires", p.strings(rpmTagRequireName), p.strings(rpmTagRequireVersion), p.ints(rpmTagRequireFlags), true},
+		{"conflicts", p.strings(rpmTagConflictName), p.strings(rpmTagConflictVersion), p.ints(rpmTagConflictFlags), false},
+		{"recommends", p.strings(rpmTagRecommendName), p.strings(rpmTagRecommendVersion), p.ints(rpmTagRecommendFlags), false},
+	} {
+		var entries []string
+		for i, dep := range deps.names {
+			if deps.skipRPMLib && strings.HasPrefix(dep, "rpmlib(") {
+				continue
+			}
+			entry := "<rpm:entry name=" + xmlAttr(dep)
+			if i < len(deps.flags) && i < len(deps.versions) {
+				if sense, ok := rpmSenses[deps.flags[i]&0xf]; ok && deps.versions[i] != "" {
+					epoch, v, r := rpmEVR(deps.versions[i])
+					entry += fmt.Sprintf(" flags=\"%s\" epoch=%s ver=%s", sense, xmlAttr(epoch), xmlAttr(v))
+					if r != "" {
+						entry += " rel=" + xmlAttr(r)
+					}
+				}
+			}
+			entries = append(entries, entry+"/>")
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		fmt.Fprintf(primary, "    <rpm:%s>\n", deps.name)
+		for _, entry := range entries {
+			fmt.Fprintf(primary, "      %s\n", entry)
+		}
+		fmt.Fprintf(primary, "    </rpm:%s>\n", deps.name)
+	}
+	for _, file := range primaryFiles {
+		fmt.Fprintf(primary, "    %s\n", file)
+	}
+	primary.WriteString("  </format>\n</package>\n")
+
+	fmt.Fprintf(filelists, "<package pkgid=\"%s\" name=\"%s\" arch=\"%s\">\n  %s\n", p.checksum, name, arch, version)
+	for _, file := range files {
+		fmt.Fprintf(filelists, "  %s\n", file)
+	}
+	filelists.WriteString("</package>\n")
+
+	fmt.Fprintf(other, "<package pkgid=\"%s\" name=\"%s\" arch=\"%s\">\n  %s\n</package>\n", p.checksum, name, arch, version)
+}
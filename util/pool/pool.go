@@ -0,0 +1,113 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Func represents functions run by the pool.
+type Func func(ctx context.Context) error
+
+// Pool runs functions concurrently with a bounded number of workers.
+type Pool struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	failFast bool
+	sem      chan struct{}
+	wg       sync.WaitGroup
+
+	mtx    sync.Mutex
+	errs   []error
+	failed bool
+}
+
+// New returns a pool running at most workers functions at the same time.
+// When failFast is true, the context passed to the functions is canceled as
+// soon as one of them fails and the functions which haven't started yet are
+// skipped.
+func New(ctx context.Context, workers int, failFast bool) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &Pool{
+		ctx:      ctx,
+		cancel:   cancel,
+		failFast: failFast,
+		sem:      make(chan struct{}, workers),
+	}
+}
+
+// Go runs fn as soon as a worker is available. It blocks until then.
+func (p *Pool) Go(fn Func) {
+	select {
+	case p.sem <- struct{}{}:
+	case <-p.ctx.Done():
+		p.skip()
+		return
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer func() {
+			<-p.sem
+			p.wg.Done()
+		}()
+		if p.ctx.Err() != nil {
+			p.skip()
+			return
+		}
+		if err := fn(p.ctx); err != nil {
+			p.fail(err)
+		}
+	}()
+}
+
+// Wait waits for all the functions to return. It returns the errors of the
+// failed functions joined together, or nil if none failed.
+func (p *Pool) Wait() error {
+	p.wg.Wait()
+	p.cancel()
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return errors.Join(p.errs...)
+}
+
+func (p *Pool) fail(err error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.errs = append(p.errs, err)
+	p.failed = true
+	if p.failFast {
+		p.cancel()
+	}
+}
+
+// skip records that a function didn't run. The context error is only
+// reported when the cancellation doesn't come from a failure in fail-fast
+// mode, and only once.
+func (p *Pool) skip() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.failed {
+		return
+	}
+	p.errs = append(p.errs, p.ctx.Err())
+	p.failed = true
+}
@@ -0,0 +1,97 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolBoundsWorkers(t *testing.T) {
+	var (
+		running, max atomic.Int32
+		p            = New(context.Background(), 3, false)
+	)
+	for i := 0; i < 20; i++ {
+		p.Go(func(context.Context) error {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := max.Load()
+				if n <= m || max.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			return nil
+		})
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if m := max.Load(); m > 3 {
+		t.Fatalf("expected at most 3 concurrent functions, got %d", m)
+	}
+}
+
+func TestPoolAggregatesErrors(t *testing.T) {
+	var (
+		err1 = errors.New("1")
+		err2 = errors.New("2")
+		p    = New(context.Background(), 2, false)
+	)
+	for _, err := range []error{err1, nil, err2} {
+		err := err
+		p.Go(func(context.Context) error { return err })
+	}
+	err := p.Wait()
+	if !errors.Is(err, err1) || !errors.Is(err, err2) {
+		t.Fatalf("expected both errors, got %v", err)
+	}
+}
+
+func TestPoolFailFast(t *testing.T) {
+	var (
+		errFail = errors.New("fail")
+		ran     atomic.Int32
+		p       = New(context.Background(), 1, true)
+	)
+	p.Go(func(context.Context) error { return errFail })
+	for i := 0; i < 5; i++ {
+		p.Go(func(context.Context) error {
+			ran.Add(1)
+			return nil
+		})
+	}
+	err := p.Wait()
+	if !errors.Is(err, errFail) || errors.Is(err, context.Canceled) {
+		t.Fatalf("expected only %v, got %v", errFail, err)
+	}
+	if n := ran.Load(); n != 0 {
+		t.Fatalf("expected no function to run after the failure, %d did", n)
+	}
+}
+
+func TestPoolCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p := New(ctx, 1, false)
+	p.Go(func(context.Context) error { return nil })
+	if err := p.Wait(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+}
@@ -14,6 +14,8 @@
 
 package retry
 
+import "time"
+
 // Func represents functions that can be retried.
 type Func func(attempt int) (retry bool, err error)
 
@@ -32,3 +34,16 @@ func Do(fn Func) error {
 	}
 	return err
 }
+
+// Backoff returns the delay before retrying after the given failed attempt,
+// starting at base and doubling with each attempt up to max.
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
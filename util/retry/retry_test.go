@@ -0,0 +1,39 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	for _, tc := range []struct {
+		attempt   int
+		base, max time.Duration
+		exp       time.Duration
+	}{
+		{attempt: 0, base: time.Second, max: time.Minute, exp: time.Second},
+		{attempt: 1, base: time.Second, max: time.Minute, exp: time.Second},
+		{attempt: 2, base: time.Second, max: time.Minute, exp: 2 * time.Second},
+		{attempt: 4, base: time.Second, max: time.Minute, exp: 8 * time.Second},
+		{attempt: 7, base: time.Second, max: time.Minute, exp: time.Minute},
+		{attempt: 1000, base: time.Second, max: time.Minute, exp: time.Minute},
+		{attempt: 1, base: 2 * time.Minute, max: time.Minute, exp: time.Minute},
+	} {
+		if got := Backoff(tc.attempt, tc.base, tc.max); got != tc.exp {
+			t.Errorf("Backoff(%d, %s, %s): expected %s, got %s", tc.attempt, tc.base, tc.max, tc.exp, got)
+		}
+	}
+}
@@ -16,6 +16,7 @@ package sh
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"regexp"
@@ -27,14 +28,30 @@ var Verbose bool
 
 // RunCommand executes a shell command.
 func RunCommand(name string, arg ...string) error {
+	return RunCommandWithOutput(os.Stdout, os.Stderr, name, arg...)
+}
+
+// RunCommandWithOutput executes a shell command, writing its standard output
+// and error to the given writers.
+func RunCommandWithOutput(stdout, stderr io.Writer, name string, arg ...string) error {
+	return RunCommandWithEnv(stdout, stderr, nil, name, arg...)
+}
+
+// RunCommandWithEnv executes a shell command with the environment of the
+// process and the given variables, in the form key=value, writing its
+// standard output and error to the given writers.
+func RunCommandWithEnv(stdout, stderr io.Writer, env []string, name string, arg ...string) error {
 	if Verbose {
 		cmdText := name + " " + strings.Join(arg, " ")
 		fmt.Fprintln(os.Stderr, " + ", cmdText)
 	}
 	cmd := exec.Command(name, arg...)
-	cmd.Stdout = os.Stdout
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdout = stdout
 	cmd.Stdin = os.Stdin
-	cmd.Stderr = os.Stderr
+	cmd.Stderr = stderr
 	return cmd.Run()
 }
 
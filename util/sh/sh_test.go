@@ -14,6 +14,9 @@
 package sh
 
 import (
+	"bytes"
+	"os"
+	"os/exec"
 	"strings"
 	"testing"
 )
@@ -28,3 +31,19 @@ func TestSplitParameters(t *testing.T) {
 		}
 	}
 }
+
+func TestRunCommandWithEnv(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+	var out bytes.Buffer
+	if err := RunCommandWithEnv(&out, &out, []string{"PROMU_TEST=foo"}, "sh", "-c", "echo $PROMU_TEST"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "foo\n" {
+		t.Fatalf("expected %q, got %q", "foo\n", out.String())
+	}
+	if _, ok := os.LookupEnv("PROMU_TEST"); ok {
+		t.Fatal("expected the environment of the process to be unchanged")
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright © 2026 Prometheus Team
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package status tracks the progress of the tasks of a long running command
+// and serves it over HTTP.
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// State is the state of a task.
+type State string
+
+// States of the tasks.
+const (
+	Pending   State = "pending"
+	Running   State = "running"
+	Succeeded State = "succeeded"
+	Failed    State = "failed"
+)
+
+var states = []State{Pending, Running, Succeeded, Failed}
+
+// Task is the status of a task.
+type Task struct {
+	Name     string     `json:"name"`
+	State    State      `json:"state"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// Status is the status of a command.
+type Status struct {
+	Command string    `json:"command"`
+	Started time.Time `json:"started"`
+	// Tasks counts the tasks by state.
+	Tasks map[State]int `json:"tasks"`
+	// ETA is the estimated number of seconds before all tasks are finished,
+	// based on the time elapsed since the start of the command per finished
+	// task, which accounts for the tasks run in parallel.
+	ETA     *float64 `json:"eta_seconds,omitempty"`
+	Details []Task   `json:"details"`
+}
+
+// Tracker tracks the tasks of a command. All methods are safe for concurrent
+// use. Add, Start and Finish do nothing on a nil Tracker so that callers
+// don't need to check whether tracking is enabled.
+type Tracker struct {
+	mtx     sync.Mutex
+	command string
+	started time.Time
+	tasks   []*Task
+	byName  map[string]*Task
+	now     func() time.Time
+}
+
+// NewTracker returns a tracker for the command.
+func NewTracker(command string) *Tracker {
+	return &Tracker{
+		command: command,
+		started: time.Now(),
+		byName:  map[string]*Task{},
+		now:     time.Now,
+	}
+}
+
+// Add adds pending tasks. Tasks which already exist are ignored.
+func (t *Tracker) Add(names ...string) {
+	if t == nil {
+		return
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	for _, name := range names {
+		t.task(name)
+	}
+}
+
+// task returns the task with the given name, adding it if needed.
+func (t *Tracker) task(name string) *Task {
+	task, ok := t.byName[name]
+	if !ok {
+		task = &Task{Name: name, State: Pending}
+		t.tasks = append(t.tasks, task)
+		t.byName[name] = task
+	}
+	return task
+}
+
+// Start marks the task as running.
+func (t *Tracker) Start(name string) {
+	if t == nil {
+		return
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	task := t.task(name)
+	now := t.now()
+	task.State = Running
+	task.Started = &now
+}
+
+// Finish marks the task as failed if err isn't nil and as succeeded
+// otherwise. Tasks which are already finished are left untouched.
+func (t *Tracker) Finish(name string, err error) {
+	if t == nil {
+		return
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	task := t.task(name)
+	if task.State == Succeeded || task.State == Failed {
+		return
+	}
+	now := t.now()
+	if task.Started == nil {
+		task.Started = &now
+	}
+	task.Finished = &now
+	task.State = Succeeded
+	if err != nil {
+		task.State = Failed
+		task.Error = err.Error()
+	}
+}
+
+// Status returns the current status.
+func (t *Tracker) Status() Status {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	s := Status{
+		Command: t.command,
+		Started: t.started,
+		Tasks:   make(map[State]int, len(states)),
+		Details: make([]Task, 0, len(t.tasks)),
+	}
+	for _, state := range states {
+		s.Tasks[state] = 0
+	}
+	for _, task := range t.tasks {
+		s.Tasks[task.State]++
+		s.Details = append(s.Details, *task)
+	}
+
+	finished := s.Tasks[Succeeded] + s.Tasks[Failed]
+	if remaining := len(t.tasks) - finished; finished > 0 {
+		elapsed := t.now().Sub(t.started)
+		eta := (elapsed / time.Duration(finished) * time.Duration(remaining)).Seconds()
+		s.ETA = &eta
+	}
+	return s
+}
+
+// ServeHTTP serves the status in JSON on /status and in the Prometheus text
+// format on /metrics.
+func (t *Tracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s := t.Status()
+	switch r.URL.Path {
+	case "/", "/status":
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(s)
+	case "/metrics":
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetrics(w, s)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeMetrics(w http.ResponseWriter, s Status) {
+	command := strconv.Quote(s.Command)
+
+	fmt.Fprintln(w, "# HELP promu_tasks Number of tasks of the command by state.")
+	fmt.Fprintln(w, "# TYPE promu_tasks gauge")
+	for _, state := range states {
+		fmt.Fprintf(w, "promu_tasks{command=%s,state=%q} %d\n", command, state, s.Tasks[state])
+	}
+
+	fmt.Fprintln(w, "# HELP promu_start_time_seconds Start time of the command since unix epoch in seconds.")
+	fmt.Fprintln(w, "# TYPE promu_start_time_seconds gauge")
+	fmt.Fprintf(w, "promu_start_time_seconds{command=%s} %d\n", command, s.Started.Unix())
+
+	if s.ETA != nil {
+		fmt.Fprintln(w, "# HELP promu_eta_seconds Estimated number of seconds before all tasks are finished.")
+		fmt.Fprintln(w, "# TYPE promu_eta_seconds gauge")
+		fmt.Fprintf(w, "promu_eta_seconds{command=%s} %g\n", command, *s.ETA)
+	}
+}
+
+// ListenAndServe listens on the TCP address and serves the status in the
+// background until the process exits.
+func (t *Tracker) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	go http.Serve(l, t)
+	return nil
+}
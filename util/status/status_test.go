@@ -0,0 +1,109 @@
+// Copyright 2026 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	var (
+		now     = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		tracker = NewTracker("crossbuild")
+	)
+	tracker.now = func() time.Time { return now }
+	tracker.started = now
+
+	tracker.Add("linux/amd64", "linux/arm64", "darwin/amd64", "windows/amd64")
+	tracker.Start("linux/amd64")
+	now = now.Add(time.Minute)
+	tracker.Finish("linux/amd64", nil)
+	tracker.Start("linux/arm64")
+	now = now.Add(time.Minute)
+	tracker.Finish("linux/arm64", errors.New("boom"))
+	// Finished tasks are left untouched.
+	tracker.Finish("linux/arm64", nil)
+	tracker.Start("darwin/amd64")
+
+	s := tracker.Status()
+	exp := map[State]int{Pending: 1, Running: 1, Succeeded: 1, Failed: 1}
+	for state, n := range exp {
+		if s.Tasks[state] != n {
+			t.Fatalf("expected %d %s tasks, got %d", n, state, s.Tasks[state])
+		}
+	}
+	if s.Details[1].Error != "boom" {
+		t.Fatalf("expected error boom, got %q", s.Details[1].Error)
+	}
+	if s.ETA == nil || *s.ETA != 120 {
+		t.Fatalf("expected ETA of 120s, got %v", s.ETA)
+	}
+
+	rec := httptest.NewRecorder()
+	tracker.ServeHTTP(rec, httptest.NewRequest("GET", "/status", nil))
+	var got Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Command != "crossbuild" || len(got.Details) != 4 {
+		t.Fatalf("unexpected status %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	tracker.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	for _, line := range []string{
+		`promu_tasks{command="crossbuild",state="failed"} 1`,
+		`promu_eta_seconds{command="crossbuild"} 120`,
+	} {
+		if !strings.Contains(rec.Body.String(), line+"\n") {
+			t.Fatalf("expected metrics to contain %q, got:\n%s", line, rec.Body.String())
+		}
+	}
+}
+
+func TestTrackerParallelETA(t *testing.T) {
+	var (
+		now     = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		tracker = NewTracker("crossbuild")
+	)
+	tracker.now = func() time.Time { return now }
+	tracker.started = now
+
+	// 4 of the 8 tasks ran in parallel for a minute.
+	tasks := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	tracker.Add(tasks...)
+	for _, task := range tasks[:4] {
+		tracker.Start(task)
+	}
+	now = now.Add(time.Minute)
+	for _, task := range tasks[:4] {
+		tracker.Finish(task, nil)
+	}
+
+	if s := tracker.Status(); s.ETA == nil || *s.ETA != 60 {
+		t.Fatalf("expected ETA of 60s, got %v", s.ETA)
+	}
+}
+
+func TestNilTracker(t *testing.T) {
+	var tracker *Tracker
+	tracker.Add("foo")
+	tracker.Start("foo")
+	tracker.Finish("foo", nil)
+}